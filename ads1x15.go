@@ -0,0 +1,210 @@
+package gadget
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// Default I2C address, with ADDR tied to GND.
+	ADS1x15Address byte = 0x48
+
+	adsRegConversion byte = 0x00
+	adsRegConfig     byte = 0x01
+
+	adsOSSingle   uint16 = 1 << 15 // Start a single conversion.
+	adsModeSingle uint16 = 1 << 8  // Single-shot / power-down mode.
+	adsCompOff    uint16 = 0x0003  // Disable the comparator.
+)
+
+// ADSInput selects the input(s) an ADS1x15 conversion measures.
+type ADSInput byte
+
+const (
+	ADSDiff01 ADSInput = iota // AIN0 - AIN1
+	ADSDiff03                 // AIN0 - AIN3
+	ADSDiff13                 // AIN1 - AIN3
+	ADSDiff23                 // AIN2 - AIN3
+	ADSAIN0                   // AIN0 - GND
+	ADSAIN1                   // AIN1 - GND
+	ADSAIN2                   // AIN2 - GND
+	ADSAIN3                   // AIN3 - GND
+)
+
+// ADSGain sets the programmable gain amplifier's full scale range.
+type ADSGain byte
+
+const (
+	ADSGainTwoThirds ADSGain = iota // ±6.144V
+	ADSGain1                        // ±4.096V
+	ADSGain2                        // ±2.048V
+	ADSGain4                        // ±1.024V
+	ADSGain8                        // ±0.512V
+	ADSGain16                       // ±0.256V
+)
+
+var (
+	// Full scale range in millivolts of each gain.
+	adsFullScale = []float64{6144, 4096, 2048, 1024, 512, 256}
+
+	// Supported data rates (samples per second), indexed by DR code.
+	ads1115Rates = []int{8, 16, 32, 64, 128, 250, 475, 860}
+	ads1015Rates = []int{128, 250, 490, 920, 1600, 2400, 3300, 3300}
+)
+
+// ADS1x15 is a TI ADS1115 (16-bit) or ADS1015 (12-bit) I2C ADC.
+type ADS1x15 struct {
+	dev   *I2CDevice
+	addr  byte
+	bits  uint  // Resolution of the converter.
+	rates []int // Supported data rates.
+
+	// Gain used by the next conversion. Defaults to ADSGain2.
+	Gain ADSGain
+
+	// Data rate in samples per second, rounded up to the next rate
+	// the chip supports. Defaults to 128 (ADS1115) or 1600 (ADS1015).
+	DataRate int
+
+	m          sync.Mutex
+	continuous bool
+	contGain   ADSGain // Gain of the running continuous conversion.
+}
+
+// NewADS1115 returns the ADS1115 at the given address. I2CConfig must
+// already have been called.
+func NewADS1115(b *Board, addr byte) *ADS1x15 {
+	return &ADS1x15{
+		dev:      b.I2CDevice(addr),
+		addr:     addr,
+		bits:     16,
+		rates:    ads1115Rates,
+		Gain:     ADSGain2,
+		DataRate: 128,
+	}
+}
+
+// NewADS1015 returns the ADS1015 at the given address. I2CConfig must
+// already have been called.
+func NewADS1015(b *Board, addr byte) *ADS1x15 {
+	return &ADS1x15{
+		dev:      b.I2CDevice(addr),
+		addr:     addr,
+		bits:     12,
+		rates:    ads1015Rates,
+		Gain:     ADSGain2,
+		DataRate: 1600,
+	}
+}
+
+// Requires reports that the ADC needs I2C, see AddDriver.
+func (a *ADS1x15) Requires() Requirements {
+	return a.dev.board.i2cRequirements()
+}
+
+// Read performs a single-shot conversion and returns the result
+// in millivolts.
+func (a *ADS1x15) Read(in ADSInput) (mV float64, err error) {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	if a.continuous {
+		return 0, fmt.Errorf("ADS1x15 at 0x%02X is in continuous mode", a.addr)
+	}
+
+	cfg, rate, err := a.config(in)
+	if err != nil {
+		return 0, err
+	}
+	if err = a.writeConfig(cfg | adsOSSingle | adsModeSingle); err != nil {
+		return 0, err
+	}
+
+	// Wait one conversion period, plus a little for the oscillator's
+	// ±10% tolerance.
+	time.Sleep(time.Second/time.Duration(rate) + time.Millisecond)
+	return a.readConversion(a.Gain)
+}
+
+// StartContinuous starts converting the input continuously. The latest
+// result is returned by ReadContinuous.
+func (a *ADS1x15) StartContinuous(in ADSInput) (err error) {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	cfg, _, err := a.config(in)
+	if err != nil {
+		return err
+	}
+	if err = a.writeConfig(cfg); err != nil {
+		return err
+	}
+	a.continuous, a.contGain = true, a.Gain
+	return
+}
+
+// ReadContinuous returns the latest continuous conversion in millivolts.
+func (a *ADS1x15) ReadContinuous() (mV float64, err error) {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	if !a.continuous {
+		return 0, fmt.Errorf("ADS1x15 at 0x%02X is not in continuous mode", a.addr)
+	}
+	return a.readConversion(a.contGain)
+}
+
+// StopContinuous stops continuous conversion, putting the chip back
+// into its power-down state.
+func (a *ADS1x15) StopContinuous() (err error) {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	if err = a.writeConfig(adsModeSingle | adsCompOff); err != nil {
+		return err
+	}
+	a.continuous = false
+	return
+}
+
+// Builds the config register (without the OS and MODE bits) and
+// returns the actual data rate used.
+func (a *ADS1x15) config(in ADSInput) (cfg uint16, rate int, err error) {
+	if in > ADSAIN3 {
+		return 0, 0, fmt.Errorf("Invalid ADS1x15 input: %d", in)
+	}
+	if int(a.Gain) >= len(adsFullScale) {
+		return 0, 0, fmt.Errorf("Invalid ADS1x15 gain: %d", a.Gain)
+	}
+
+	dr := len(a.rates) - 1
+	for i, r := range a.rates {
+		if r >= a.DataRate {
+			dr = i
+			break
+		}
+	}
+
+	cfg = uint16(in)<<12 | uint16(a.Gain)<<9 | uint16(dr)<<5 | adsCompOff
+	return cfg, a.rates[dr], nil
+}
+
+func (a *ADS1x15) writeConfig(cfg uint16) error {
+	return a.dev.WriteUint16(adsRegConfig, cfg)
+}
+
+// Reads the conversion register and converts it to millivolts.
+func (a *ADS1x15) readConversion(gain ADSGain) (mV float64, err error) {
+	raw, err := a.dev.ReadInt16(adsRegConversion)
+	if err != nil {
+		return 0, err
+	}
+	return adsToMillivolts(raw, a.bits, gain), nil
+}
+
+// Converts a raw, left justified conversion result to millivolts.
+func adsToMillivolts(raw int16, bits uint, gain ADSGain) float64 {
+	v := raw >> (16 - bits)
+	return float64(v) * adsFullScale[gain] / float64(int(1)<<(bits-1))
+}
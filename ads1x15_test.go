@@ -0,0 +1,42 @@
+package gadget
+
+import (
+	"testing"
+)
+
+func TestADSConfig(t *testing.T) {
+	a := NewADS1115(nil, ADS1x15Address)
+	a.DataRate = 100 // Rounds up to 128.
+
+	cfg, rate, err := a.config(ADSAIN1)
+	if err != nil {
+		t.Fatalf("config returned error: %s", err)
+	}
+	if want := uint16(0x5483); cfg != want || rate != 128 {
+		t.Fatalf("config = 0x%04X @ %d SPS, want 0x%04X @ 128 SPS", cfg, rate, want)
+	}
+
+	if _, _, err := a.config(ADSInput(8)); err == nil {
+		t.Fatalf("Invalid input should return an error")
+	}
+}
+
+func TestADSToMillivolts(t *testing.T) {
+	tests := []struct {
+		raw  int16
+		bits uint
+		gain ADSGain
+		want float64
+	}{
+		{0x7FFF, 16, ADSGain2, 2047.9375},
+		{-0x8000, 16, ADSGain2, -2048},
+		{0x4000, 16, ADSGain1, 2048},
+		{0x7FF0, 12, ADSGain2, 2047}, // 12-bit results are left justified.
+		{-0x0010, 12, ADSGain2, -1},
+	}
+	for _, tt := range tests {
+		if got := adsToMillivolts(tt.raw, tt.bits, tt.gain); got != tt.want {
+			t.Errorf("adsToMillivolts(%d, %d, %d) = %v, want %v", tt.raw, tt.bits, tt.gain, got, tt.want)
+		}
+	}
+}
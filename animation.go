@@ -0,0 +1,206 @@
+package gadget
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// How often running animations step.
+const animationInterval = 20 * time.Millisecond
+
+// ErrInterrupted is the result of an Animation stopped, or replaced by
+// another on the same output, before it finished.
+var ErrInterrupted = errors.New("Animation interrupted")
+
+// Easing maps an animation's progress in time, 0-1, to how far along its
+// value is, from 0 at the start to 1 at the end. Some overshoot in
+// between.
+type Easing func(t float64) float64
+
+// Linear moves at a constant speed.
+func Linear(t float64) float64 { return t }
+
+// EaseInQuad accelerates from rest.
+func EaseInQuad(t float64) float64 { return t * t }
+
+// EaseOutQuad decelerates to rest.
+func EaseOutQuad(t float64) float64 { return t * (2 - t) }
+
+// EaseInOutQuad accelerates, then decelerates.
+func EaseInOutQuad(t float64) float64 {
+	if t < 0.5 {
+		return 2 * t * t
+	}
+	return 1 - 2*(1-t)*(1-t)
+}
+
+// EaseInCubic accelerates from rest, more sharply than EaseInQuad.
+func EaseInCubic(t float64) float64 { return t * t * t }
+
+// EaseOutCubic decelerates to rest, more sharply than EaseOutQuad.
+func EaseOutCubic(t float64) float64 { return 1 - math.Pow(1-t, 3) }
+
+// EaseInOutCubic accelerates, then decelerates.
+func EaseInOutCubic(t float64) float64 {
+	if t < 0.5 {
+		return 4 * t * t * t
+	}
+	return 1 - 4*math.Pow(1-t, 3)
+}
+
+// EaseOutElastic overshoots the end and springs back around it.
+func EaseOutElastic(t float64) float64 {
+	if t <= 0 || t >= 1 {
+		return t
+	}
+	return math.Pow(2, -10*t)*math.Sin((t*10-0.75)*2*math.Pi/3) + 1
+}
+
+// Animation is an output changing over time, e.g. a servo moving, run
+// in the background by its board.
+type Animation struct {
+	board *Board
+	start time.Time
+
+	// Called every step with the time since the start, until it returns
+	// false or an error.
+	step func(elapsed time.Duration) (bool, error)
+
+	m         sync.Mutex // Held while stepping, so Stop waits for a step.
+	done      chan bool
+	finished  bool
+	err       error
+	callbacks []func(error)
+}
+
+// Stop interrupts the animation, leaving its output where it is. It is
+// finished with ErrInterrupted. Stopping a finished animation is a
+// no-op.
+func (a *Animation) Stop() {
+	a.finish(ErrInterrupted)
+}
+
+// Done returns a channel closed when the animation finishes.
+func (a *Animation) Done() <-chan bool {
+	return a.done
+}
+
+// Wait waits for the animation to finish. It returns nil if it ran to
+// the end, ErrInterrupted if it was stopped, or the error writing its
+// output.
+func (a *Animation) Wait() error {
+	<-a.done
+
+	a.m.Lock()
+	defer a.m.Unlock()
+	return a.err
+}
+
+// OnDone calls fn with the animation's result, as returned by Wait,
+// once it finishes. If it already has, fn is called right away.
+func (a *Animation) OnDone(fn func(error)) {
+	a.m.Lock()
+	if a.finished {
+		err := a.err
+		a.m.Unlock()
+		fn(err)
+		return
+	}
+	a.callbacks = append(a.callbacks, fn)
+	a.m.Unlock()
+}
+
+// Runs one step, finishing the animation if it is over.
+func (a *Animation) tick(now time.Time) {
+	a.m.Lock()
+	if a.finished {
+		a.m.Unlock()
+		return
+	}
+	more, err := a.step(now.Sub(a.start))
+	a.m.Unlock()
+
+	if err != nil || !more {
+		a.finish(err)
+	}
+}
+
+func (a *Animation) finish(err error) {
+	a.m.Lock()
+	if a.finished {
+		a.m.Unlock()
+		return
+	}
+	a.finished, a.err = true, err
+	callbacks := a.callbacks
+	a.callbacks = nil
+	close(a.done)
+	a.m.Unlock()
+
+	a.board.am.Lock()
+	delete(a.board.animations, a)
+	a.board.am.Unlock()
+
+	for _, fn := range callbacks {
+		fn(err)
+	}
+}
+
+// Starts an animation. All of a board's animations are stepped together
+// every animationInterval, by a goroutine running while there are any.
+func (b *Board) animate(step func(elapsed time.Duration) (bool, error)) *Animation {
+	a := &Animation{board: b, start: time.Now(), step: step, done: make(chan bool)}
+
+	b.am.Lock()
+	defer b.am.Unlock()
+
+	if b.animations == nil {
+		b.animations = make(map[*Animation]bool)
+	}
+	b.animations[a] = true
+	if !b.animating {
+		b.animating = true
+		go b.runAnimations()
+	}
+	return a
+}
+
+// Steps the board's animations until there are none left.
+func (b *Board) runAnimations() {
+	t := time.NewTicker(animationInterval)
+	defer t.Stop()
+
+	for now := range t.C {
+		b.am.Lock()
+		if len(b.animations) == 0 {
+			b.animating = false
+			b.am.Unlock()
+			return
+		}
+		anims := make([]*Animation, 0, len(b.animations))
+		for a := range b.animations {
+			anims = append(anims, a)
+		}
+		b.am.Unlock()
+
+		for _, a := range anims {
+			a.tick(now)
+		}
+	}
+}
+
+// Stops every running animation.
+func (b *Board) stopAnimations() {
+	b.am.Lock()
+	anims := make([]*Animation, 0, len(b.animations))
+	for a := range b.animations {
+		anims = append(anims, a)
+	}
+	b.am.Unlock()
+
+	for _, a := range anims {
+		a.Stop()
+	}
+}
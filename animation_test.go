@@ -0,0 +1,65 @@
+package gadget
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEasing(t *testing.T) {
+	for name, ease := range map[string]Easing{
+		"Linear":         Linear,
+		"EaseInQuad":     EaseInQuad,
+		"EaseOutQuad":    EaseOutQuad,
+		"EaseInOutQuad":  EaseInOutQuad,
+		"EaseInCubic":    EaseInCubic,
+		"EaseOutCubic":   EaseOutCubic,
+		"EaseInOutCubic": EaseInOutCubic,
+		"EaseOutElastic": EaseOutElastic,
+	} {
+		if v0, v1 := ease(0), ease(1); math.Abs(v0) > 1e-9 || math.Abs(v1-1) > 1e-9 {
+			t.Fatalf("%s(0), %s(1) = %f, %f, want 0, 1", name, name, v0, v1)
+		}
+	}
+	if v := EaseInOutQuad(0.25); v != 0.125 {
+		t.Fatalf("EaseInOutQuad(0.25) = %f, want 0.125", v)
+	}
+	if v := EaseOutElastic(0.2); v <= 1 {
+		t.Fatalf("EaseOutElastic(0.2) = %f, expected overshoot", v)
+	}
+}
+
+func TestServoMoveTo(t *testing.T) {
+	b := newTestBoard(t, nil, nil, map[byte][]Capability{9: {{SERVO, 14}}})
+
+	s, err := NewServo(b, 9)
+	if err != nil {
+		t.Fatalf("NewServo: %s", err)
+	}
+
+	result := make(chan error, 1)
+	a := s.MoveTo(180, 60*time.Millisecond, EaseInOutCubic)
+	a.OnDone(func(err error) { result <- err })
+	if err = a.Wait(); err != nil {
+		t.Fatalf("MoveTo = %v, want nil", err)
+	}
+	if err = <-result; err != nil {
+		t.Fatalf("OnDone got %v, want nil", err)
+	}
+	if s.Angle() != 180 {
+		t.Fatalf("Angle = %f, want 180", s.Angle())
+	}
+
+	// A new move interrupts the running one.
+	slow := s.MoveTo(0, time.Minute, nil)
+	time.Sleep(3 * animationInterval)
+	if err = s.MoveTo(90, 0, nil).Wait(); err != nil {
+		t.Fatalf("MoveTo = %v", err)
+	}
+	if err = slow.Wait(); err != ErrInterrupted {
+		t.Fatalf("Interrupted move = %v, want ErrInterrupted", err)
+	}
+	if s.Angle() != 90 {
+		t.Fatalf("Angle = %f, want 90", s.Angle())
+	}
+}
@@ -0,0 +1,97 @@
+package gadget
+
+import (
+	"fmt"
+	"time"
+)
+
+// A Batch collects mode changes, writes and configuration to be sent to
+// the board as one burst, e.g. to initialize a board the same way every
+// time:
+//
+//	err := b.Batch().
+//		PinMode(13, OUTPUT).
+//		DigitalWrite(13, HIGH).
+//		PinMode(9, PWM).
+//		AnalogWrite(9, 128).
+//		Send()
+//
+// Nothing is sent until Send.
+type Batch struct {
+	board *Board
+	steps []func() error
+}
+
+// Batch returns an empty Batch for the board.
+func (b *Board) Batch() *Batch {
+	return &Batch{board: b}
+}
+
+func (ba *Batch) add(step func() error) *Batch {
+	ba.steps = append(ba.steps, step)
+	return ba
+}
+
+// PinMode adds SetPinMode.
+func (ba *Batch) PinMode(pin, mode byte) *Batch {
+	return ba.add(func() error { return ba.board.SetPinMode(pin, mode) })
+}
+
+// DigitalWrite adds DigitalWrite.
+func (ba *Batch) DigitalWrite(pin, s byte) *Batch {
+	return ba.add(func() error { return ba.board.DigitalWrite(pin, s) })
+}
+
+// WritePort adds WritePort.
+func (ba *Batch) WritePort(port, mask, values byte) *Batch {
+	return ba.add(func() error { return ba.board.WritePort(port, mask, values) })
+}
+
+// AnalogWrite adds AnalogWrite.
+func (ba *Batch) AnalogWrite(pin, v byte) *Batch {
+	return ba.add(func() error { return ba.board.AnalogWrite(pin, v) })
+}
+
+// ServoConfig adds ServoConfig.
+func (ba *Batch) ServoConfig(pin byte, minPulse, maxPulse int) *Batch {
+	return ba.add(func() error { return ba.board.ServoConfig(pin, minPulse, maxPulse) })
+}
+
+// ServoWrite adds ServoWrite.
+func (ba *Batch) ServoWrite(pin byte, v int) *Batch {
+	return ba.add(func() error { return ba.board.ServoWrite(pin, v) })
+}
+
+// PinReporting adds SetPinReporting.
+func (ba *Batch) PinReporting(pin byte, report bool) *Batch {
+	return ba.add(func() error { return ba.board.SetPinReporting(pin, report) })
+}
+
+// SamplingInterval adds SetSamplingInterval.
+func (ba *Batch) SamplingInterval(d time.Duration) *Batch {
+	return ba.add(func() error { return ba.board.SetSamplingInterval(d) })
+}
+
+// Do adds fn, for anything else that sends messages, such as a
+// driver's setup.
+func (ba *Batch) Do(fn func(b *Board) error) *Batch {
+	return ba.add(func() error { return fn(ba.board) })
+}
+
+// Send runs the steps in order and writes their messages to the serial
+// port in a single write. It stops at the first failing step, still
+// sending the messages of the steps before it. Messages sent by other
+// goroutines during Send are included in the same write.
+func (ba *Batch) Send() (err error) {
+	ba.board.out.hold()
+	for i, step := range ba.steps {
+		if err = step(); err != nil {
+			err = fmt.Errorf("Batch step %d: %s", i+1, err)
+			break
+		}
+	}
+	if ferr := ba.board.out.release(); err == nil {
+		err = ferr
+	}
+	return
+}
@@ -0,0 +1,44 @@
+package gadget
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+func TestBatch(t *testing.T) {
+	var l writeLog
+	caps := []Capability{{INPUT, 1}, {OUTPUT, 1}, {PWM, 8}}
+	b := newTestBoard(t, &l, nil, map[byte][]Capability{9: caps, 13: caps})
+	n := l.count()
+
+	err := b.Batch().
+		DigitalWrite(13, HIGH).
+		PinMode(9, PWM).
+		AnalogWrite(9, 128).
+		Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.count() != n+1 {
+		t.Fatalf("Expected one write, got %d", l.count()-n)
+	}
+	var want []byte
+	want = append(want, firmatawire.DigitalWrite(1, 0x20)...)
+	want = append(want, firmatawire.SetMode(9, PWM)...)
+	want = append(want, firmatawire.AnalogWrite(9, 128)...)
+	if !bytes.Equal(l.writes[n], want) {
+		t.Fatalf("Batch wrote % X, want % X", l.writes[n], want)
+	}
+
+	// Steps before a failure are still sent.
+	err = b.Batch().DigitalWrite(13, LOW).AnalogWrite(13, 1).DigitalWrite(9, HIGH).Send()
+	if err == nil || !strings.HasPrefix(err.Error(), "Batch step 2") {
+		t.Fatalf("Expected step 2 to fail, got %v", err)
+	}
+	if l.count() != n+2 || !bytes.Equal(l.writes[n+1], firmatawire.DigitalWrite(1, 0)) {
+		t.Fatalf("Expected the first step to be sent, got %v", l.writes[n+1:])
+	}
+}
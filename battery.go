@@ -0,0 +1,219 @@
+package gadget
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// How often a battery is checked while started.
+	defaultBatteryInterval = 5 * time.Second
+
+	// Percentage points above LowPercent a low battery must read
+	// before it counts as charged again, so load changes near the
+	// threshold do not flap.
+	batteryHysteresis = 5
+)
+
+// BatteryEvent is published on TopicBattery when a Battery goes low or
+// recovers.
+type BatteryEvent struct {
+	Pin     byte
+	Voltage float64
+	Percent float64
+	Low     bool
+}
+
+// CellLevel pairs a cell's resting voltage with its charge in percent.
+type CellLevel struct {
+	Volts   float64
+	Percent float64
+}
+
+// Chemistry is a battery type's discharge curve, per cell, highest
+// voltage first.
+type Chemistry struct {
+	Name  string
+	Curve []CellLevel
+}
+
+// Resting voltage curves of common chemistries. Under load the voltage
+// sags, so the charge reads low while current is drawn.
+var (
+	LiPo = Chemistry{Name: "LiPo", Curve: []CellLevel{
+		{4.20, 100}, {4.11, 90}, {4.02, 80}, {3.95, 70}, {3.87, 60},
+		{3.84, 50}, {3.80, 40}, {3.77, 30}, {3.73, 20}, {3.69, 10}, {3.27, 0},
+	}}
+	LiFePO4 = Chemistry{Name: "LiFePO4", Curve: []CellLevel{
+		{3.40, 100}, {3.33, 90}, {3.30, 70}, {3.27, 40}, {3.25, 30},
+		{3.20, 20}, {3.00, 10}, {2.50, 0},
+	}}
+	NiMH = Chemistry{Name: "NiMH", Curve: []CellLevel{
+		{1.40, 100}, {1.30, 90}, {1.25, 70}, {1.22, 50}, {1.18, 30},
+		{1.12, 10}, {1.00, 0},
+	}}
+	LeadAcid = Chemistry{Name: "Lead acid", Curve: []CellLevel{
+		{2.12, 100}, {2.08, 90}, {2.04, 70}, {2.01, 60}, {1.98, 40},
+		{1.94, 20}, {1.89, 0},
+	}}
+)
+
+// Percent returns the charge of a cell at the given voltage, clamped to
+// 0-100.
+func (c Chemistry) Percent(cellVolts float64) float64 {
+	pts := c.Curve
+	if len(pts) == 0 {
+		return 0
+	}
+	switch {
+	case cellVolts >= pts[0].Volts:
+		return pts[0].Percent
+	case cellVolts <= pts[len(pts)-1].Volts:
+		return pts[len(pts)-1].Percent
+	}
+
+	// Find the first point below and interpolate from the one above it.
+	i := sort.Search(len(pts), func(i int) bool { return pts[i].Volts < cellVolts })
+	hi, lo := pts[i-1], pts[i]
+	t := (cellVolts - lo.Volts) / (hi.Volts - lo.Volts)
+	return lo.Percent + t*(hi.Percent-lo.Percent)
+}
+
+// Battery monitors a battery pack's voltage on an analog pin, through a
+// resistor divider bringing it under the analog reference.
+type Battery struct {
+	board  *Board
+	pin    byte // Normal (not A0 style) pin number.
+	r1, r2 float64
+	chem   Chemistry
+	cells  int
+
+	// Charge, in percent, below which the battery is low. An event is
+	// published on TopicBattery whenever the battery crosses it.
+	// Defaults to 20.
+	LowPercent float64
+
+	// How often the battery is checked while started.
+	Interval time.Duration
+
+	// Number of readings averaged per measurement, defaults to 5.
+	Samples     int
+	SampleDelay time.Duration
+
+	m    sync.Mutex
+	low  bool
+	stop func()
+}
+
+// NewBattery returns a Battery of cells cells of the given chemistry,
+// read on an analog pin through a divider of r1, from the pack to the
+// pin, and r2, from the pin to ground. Analog reporting is turned on.
+func NewBattery(b *Board, pin byte, r1, r2 float64, chem Chemistry, cells int) (bat *Battery, err error) {
+	if r1 < 0 || r2 <= 0 {
+		return nil, fmt.Errorf("Invalid battery divider: %gΩ/%gΩ", r1, r2)
+	}
+	if cells < 1 {
+		return nil, fmt.Errorf("Invalid cell count: %d", cells)
+	}
+	if err = b.ensurePinMode(pin, ANALOG); err != nil {
+		return nil, err
+	}
+	if err = b.SetPinReporting(pin, true); err != nil {
+		return nil, err
+	}
+
+	bat = &Battery{
+		board:       b,
+		pin:         pin,
+		r1:          r1,
+		r2:          r2,
+		chem:        chem,
+		cells:       cells,
+		LowPercent:  20,
+		Interval:    defaultBatteryInterval,
+		Samples:     5,
+		SampleDelay: defaultSampleDelay,
+	}
+	return
+}
+
+// MaxVoltage returns the highest pack voltage the divider can measure.
+func (bat *Battery) MaxVoltage() float64 {
+	return bat.board.AnalogReference() * (bat.r1 + bat.r2) / bat.r2
+}
+
+// Voltage returns the (averaged) pack voltage.
+func (bat *Battery) Voltage() (v float64, err error) {
+	n := max(bat.Samples, 1)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			time.Sleep(bat.SampleDelay)
+		}
+		r, err := bat.board.ReadVoltage(bat.pin)
+		if err != nil {
+			return 0, err
+		}
+		v += r
+	}
+	return v / float64(n) * (bat.r1 + bat.r2) / bat.r2, nil
+}
+
+// Percent returns the estimated charge, 0-100.
+func (bat *Battery) Percent() (float64, error) {
+	v, err := bat.Voltage()
+	if err != nil {
+		return 0, err
+	}
+	return bat.chem.Percent(v / float64(bat.cells)), nil
+}
+
+// Start checks the battery in the background, publishing a
+// BatteryEvent on TopicBattery when it goes low or recovers.
+func (bat *Battery) Start() (err error) {
+	bat.m.Lock()
+	defer bat.m.Unlock()
+
+	if bat.stop != nil {
+		return fmt.Errorf("Battery on pin %d already started", bat.pin)
+	}
+	bat.stop = poll(bat.Interval, bat.check)
+	return
+}
+
+// Halt stops checking.
+func (bat *Battery) Halt() (err error) {
+	bat.m.Lock()
+	defer bat.m.Unlock()
+
+	if bat.stop != nil {
+		bat.stop()
+		bat.stop = nil
+	}
+	return
+}
+
+// Publishes an event if the charge crossed LowPercent.
+func (bat *Battery) check() {
+	v, err := bat.Voltage()
+	if err != nil {
+		return
+	}
+	pct := bat.chem.Percent(v / float64(bat.cells))
+
+	bat.m.Lock()
+	changed := false
+	switch {
+	case !bat.low && pct < bat.LowPercent:
+		bat.low, changed = true, true
+	case bat.low && pct >= bat.LowPercent+batteryHysteresis:
+		bat.low, changed = false, true
+	}
+	low := bat.low
+	bat.m.Unlock()
+
+	if changed {
+		bat.board.bus.Publish(Event{Topic: TopicBattery, Data: BatteryEvent{Pin: bat.pin, Voltage: v, Percent: pct, Low: low}})
+	}
+}
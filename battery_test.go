@@ -0,0 +1,52 @@
+package gadget
+
+import (
+	"math"
+	"testing"
+)
+
+func TestChemistryPercent(t *testing.T) {
+	for _, tc := range []struct {
+		volts, want float64
+	}{
+		{4.30, 100},
+		{4.20, 100},
+		{3.84, 50},
+		{3.82, 45}, // Halfway between 3.84 and 3.80.
+		{3.00, 0},
+	} {
+		if got := LiPo.Percent(tc.volts); math.Abs(got-tc.want) > 1e-9 {
+			t.Fatalf("LiPo.Percent(%.2f) = %f, want %f", tc.volts, got, tc.want)
+		}
+	}
+}
+
+func TestBattery(t *testing.T) {
+	b := newTestBoard(t, nil, map[byte][]Capability{14: {{ANALOG, 10}}}, nil)
+	b.SetAnalogReference(5)
+
+	// A 3S LiPo through a 20k/10k divider, reading up to 15V.
+	bat, err := NewBattery(b, 14, 20000, 10000, LiPo, 3)
+	if err != nil {
+		t.Fatalf("NewBattery: %s", err)
+	}
+	bat.Samples = 1
+	if v := bat.MaxVoltage(); v != 15 {
+		t.Fatalf("MaxVoltage = %f, want 15", v)
+	}
+
+	sub := b.bus.Subscribe(TopicBattery, 4)
+	for _, cell := range []float64{3.95, 3.70, 3.75, 3.80} { // 70, 12.5, 25, 40%.
+		b.pins[14].analogVal = int(math.Round(cell * 3 / 15 * 1023))
+		bat.check()
+	}
+	if len(sub.C) != 2 {
+		t.Fatalf("Expected a low and a recovered event, got %d", len(sub.C))
+	}
+	if e := (<-sub.C).Data.(BatteryEvent); !e.Low || math.Abs(e.Voltage-11.1) > 0.02 {
+		t.Fatalf("First event %+v, want low at 11.1V", e)
+	}
+	if e := (<-sub.C).Data.(BatteryEvent); e.Low {
+		t.Fatalf("Second event %+v, want recovered", e)
+	}
+}
@@ -0,0 +1,85 @@
+package gadget
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+// The rates tried by DetectBaud by default, most common first.
+var commonBauds = []int{57600, 115200, 9600, 38400, 19200}
+
+// How long DetectBaud waits at each rate. Long enough for a board reset
+// by opening the port to get through its bootloader.
+const baudProbeTimeout = 3 * time.Second
+
+// DetectBaud finds the baud rate of the Firmata on device by opening it
+// at each rate in turn, 57600, 115200, 9600, 38400 and 19200 if none are
+// given, and sending version queries until one is answered.
+func DetectBaud(device string, rates ...int) (int, error) {
+	return detectBaud(func(baud int) (io.ReadWriteCloser, error) {
+		return OpenSerial(device, baud)
+	}, baudProbeTimeout, rates)
+}
+
+func detectBaud(open func(baud int) (io.ReadWriteCloser, error), timeout time.Duration, rates []int) (int, error) {
+	if len(rates) == 0 {
+		rates = commonBauds
+	}
+	for _, baud := range rates {
+		port, err := open(baud)
+		if err != nil {
+			return 0, err
+		}
+		ok := probeBaud(port, timeout)
+		port.Close()
+		if ok {
+			return baud, nil
+		}
+	}
+	return 0, fmt.Errorf("No Firmata answered at %v baud", rates)
+}
+
+// Sends version queries on port until a reply arrives or timeout.
+func probeBaud(port io.ReadWriteCloser, timeout time.Duration) bool {
+	found := make(chan bool, 1)
+	go func() {
+		d := firmatawire.NewDecoder(port)
+		for {
+			f, err := d.Next()
+			var serr *firmatawire.SyncError
+			switch {
+			case errors.As(err, &serr):
+				// Noise, as expected at the wrong rate.
+			case err != nil:
+				return // Closed.
+			case isVersionReply(f.Data):
+				found <- true
+				return
+			}
+		}
+	}()
+
+	query := time.NewTicker(timeout / 10)
+	defer query.Stop()
+	expired := time.After(timeout)
+	for {
+		port.Write([]byte{reportVersion})
+		select {
+		case <-found:
+			return true
+		case <-expired:
+			return false
+		case <-query.C:
+		}
+	}
+}
+
+// Reports whether a frame is a plausible version reply. Noise at the
+// wrong rate can look like a frame, but rarely with a Firmata version.
+func isVersionReply(data []byte) bool {
+	return len(data) == 3 && data[0] == reportVersion && (data[1] == 2 || data[1] == 3)
+}
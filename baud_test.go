@@ -0,0 +1,46 @@
+package gadget
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestDetectBaud(t *testing.T) {
+	var tried []int
+	open := func(baud int) (io.ReadWriteCloser, error) {
+		tried = append(tried, baud)
+		host, board := net.Pipe()
+		go func() {
+			buf := make([]byte, 16)
+			for {
+				n, err := board.Read(buf)
+				if err != nil {
+					return
+				}
+				if baud != 115200 {
+					board.Write([]byte{0xF9, 0x85, 0x13}) // Noise.
+					continue
+				}
+				for _, c := range buf[:n] {
+					if c == reportVersion {
+						board.Write([]byte{reportVersion, 2, 5})
+					}
+				}
+			}
+		}()
+		return host, nil
+	}
+
+	baud, err := detectBaud(open, 50*time.Millisecond, nil)
+	if err != nil || baud != 115200 {
+		t.Fatalf("detectBaud = %d, %v; want 115200", baud, err)
+	}
+	if len(tried) != 2 || tried[0] != 57600 {
+		t.Fatalf("Expected 57600 then 115200, tried %v", tried)
+	}
+	if _, err = detectBaud(open, 10*time.Millisecond, []int{9600}); err == nil {
+		t.Fatalf("Expected no answer at 9600")
+	}
+}
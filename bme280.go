@@ -0,0 +1,180 @@
+package gadget
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// Default I2C address, with SDO tied to GND. 0x77 with SDO high.
+	BME280Address byte = 0x76
+
+	bmeRegChipID   byte = 0xD0
+	bmeRegCalib1   byte = 0x88 // 26 bytes, temperature and pressure.
+	bmeRegCalib2   byte = 0xE1 // 7 bytes, humidity.
+	bmeRegCtrlHum  byte = 0xF2
+	bmeRegCtrlMeas byte = 0xF4
+	bmeRegData     byte = 0xF7 // 8 bytes, pressure, temperature, humidity.
+
+	bmp280ChipID byte = 0x58
+	bme280ChipID byte = 0x60
+
+	bmeForcedMode byte = 0x01
+	bmeOversample byte = 0x01 // 1x oversampling of every measurement.
+
+	// Max measurement time at 1x oversampling is 9.3ms.
+	bmeMeasureDelay = 10 * time.Millisecond
+)
+
+// Factory calibration coefficients of a BME280.
+type bme280Calib struct {
+	t1         uint16
+	t2, t3     int16
+	p1         uint16
+	p2, p3, p4 int16
+	p5, p6, p7 int16
+	p8, p9     int16
+	h1, h3     uint8
+	h2, h4, h5 int16
+	h6         int8
+}
+
+// BME280 is a Bosch BME280 temperature, pressure and humidity sensor.
+// The BMP280, which lacks the humidity sensor, is supported as well.
+type BME280 struct {
+	board       *Board
+	addr        byte
+	hasHumidity bool
+	cal         bme280Calib
+}
+
+// NewBME280 returns the BME280 or BMP280 at the given address, reading
+// its calibration coefficients. I2CConfig must already have been called.
+func NewBME280(b *Board, addr byte) (s *BME280, err error) {
+	id, err := b.I2CReadRegister(addr, bmeRegChipID, 1)
+	if err != nil {
+		return nil, err
+	}
+	if id[0] != bme280ChipID && id[0] != bmp280ChipID {
+		return nil, fmt.Errorf("Device at 0x%02X is not a BME280/BMP280, chip id 0x%02X", addr, id[0])
+	}
+
+	s = &BME280{
+		board:       b,
+		addr:        addr,
+		hasHumidity: id[0] == bme280ChipID,
+	}
+
+	c, err := b.I2CReadRegister(addr, bmeRegCalib1, 26)
+	if err != nil {
+		return nil, err
+	}
+	s.cal = bme280Calib{
+		t1: le16(c, 0),
+		t2: int16(le16(c, 2)),
+		t3: int16(le16(c, 4)),
+		p1: le16(c, 6),
+		p2: int16(le16(c, 8)),
+		p3: int16(le16(c, 10)),
+		p4: int16(le16(c, 12)),
+		p5: int16(le16(c, 14)),
+		p6: int16(le16(c, 16)),
+		p7: int16(le16(c, 18)),
+		p8: int16(le16(c, 20)),
+		p9: int16(le16(c, 22)),
+		h1: c[25],
+	}
+
+	if s.hasHumidity {
+		h, err := b.I2CReadRegister(addr, bmeRegCalib2, 7)
+		if err != nil {
+			return nil, err
+		}
+		s.cal.h2 = int16(le16(h, 0))
+		s.cal.h3 = h[2]
+		s.cal.h4 = int16(int8(h[3]))<<4 | int16(h[4]&0x0F)
+		s.cal.h5 = int16(int8(h[5]))<<4 | int16(h[4]>>4)
+		s.cal.h6 = int8(h[6])
+	}
+	return
+}
+
+// Requires reports that the sensor needs I2C, see AddDriver.
+func (s *BME280) Requires() Requirements {
+	return s.board.i2cRequirements()
+}
+
+// Read triggers a measurement and returns the compensated result.
+func (s *BME280) Read() (e Environment, err error) {
+	if s.hasHumidity {
+		// ctrl_hum only takes effect after ctrl_meas is written.
+		if err = s.board.I2CWrite(s.addr, []byte{bmeRegCtrlHum, bmeOversample}); err != nil {
+			return e, err
+		}
+	}
+	meas := bmeOversample<<5 | bmeOversample<<2 | bmeForcedMode
+	if err = s.board.I2CWrite(s.addr, []byte{bmeRegCtrlMeas, meas}); err != nil {
+		return e, err
+	}
+	time.Sleep(bmeMeasureDelay)
+
+	d, err := s.board.I2CReadRegister(s.addr, bmeRegData, 8)
+	if err != nil {
+		return e, err
+	}
+	adcP := int32(d[0])<<12 | int32(d[1])<<4 | int32(d[2])>>4
+	adcT := int32(d[3])<<12 | int32(d[4])<<4 | int32(d[5])>>4
+	adcH := int32(d[6])<<8 | int32(d[7])
+
+	var tFine float64
+	e.Temperature, tFine = s.cal.temperature(adcT)
+	e.Pressure = s.cal.pressure(adcP, tFine)
+	if s.hasHumidity {
+		e.Humidity = s.cal.humidity(adcH, tFine)
+	}
+	return
+}
+
+// The compensation formulas below are the floating point versions
+// from the BME280 datasheet, section 8.1.
+
+func (c *bme280Calib) temperature(adc int32) (t, tFine float64) {
+	v1 := (float64(adc)/16384 - float64(c.t1)/1024) * float64(c.t2)
+	v2 := float64(adc)/131072 - float64(c.t1)/8192
+	v2 = v2 * v2 * float64(c.t3)
+	tFine = v1 + v2
+	return tFine / 5120, tFine
+}
+
+func (c *bme280Calib) pressure(adc int32, tFine float64) (p float64) {
+	v1 := tFine/2 - 64000
+	v2 := v1 * v1 * float64(c.p6) / 32768
+	v2 = v2 + v1*float64(c.p5)*2
+	v2 = v2/4 + float64(c.p4)*65536
+	v1 = (float64(c.p3)*v1*v1/524288 + float64(c.p2)*v1) / 524288
+	v1 = (1 + v1/32768) * float64(c.p1)
+	if v1 == 0 {
+		return 0 // Avoid dividing by zero.
+	}
+
+	p = 1048576 - float64(adc)
+	p = (p - v2/4096) * 6250 / v1
+	v1 = float64(c.p9) * p * p / 2147483648
+	v2 = p * float64(c.p8) / 32768
+	return p + (v1+v2+float64(c.p7))/16
+}
+
+func (c *bme280Calib) humidity(adc int32, tFine float64) (h float64) {
+	h = tFine - 76800
+	h = (float64(adc) - (float64(c.h4)*64 + float64(c.h5)/16384*h)) *
+		(float64(c.h2) / 65536 * (1 + float64(c.h6)/67108864*h*(1+float64(c.h3)/67108864*h)))
+	h = h * (1 - float64(c.h1)*h/524288)
+
+	switch {
+	case h > 100:
+		return 100
+	case h < 0:
+		return 0
+	}
+	return
+}
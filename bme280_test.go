@@ -0,0 +1,45 @@
+package gadget
+
+import (
+	"math"
+	"testing"
+)
+
+// The BME280 humidity compensation in 32-bit integers, from the
+// datasheet's section 4.2.3, returning %RH in Q22.10 format.
+func bme280HumidityInt(c bme280Calib, adc, tFine int32) uint32 {
+	v := tFine - 76800
+	v = ((adc<<14 - int32(c.h4)<<20 - int32(c.h5)*v + 16384) >> 15) *
+		(((v*int32(c.h6)>>10*(v*int32(c.h3)>>11+32768)>>10+2097152)*int32(c.h2) + 8192) >> 14)
+	v = v - (v>>15*(v>>15)>>7*int32(c.h1))>>4
+	v = max(0, min(v, 419430400))
+	return uint32(v >> 12)
+}
+
+func TestBME280Compensate(t *testing.T) {
+	// The worked example from the BMP280 datasheet, section 3.12, whose
+	// temperature and pressure formulas the BME280 shares.
+	c := bme280Calib{
+		t1: 27504, t2: 26435, t3: -1000,
+		p1: 36477, p2: -10685, p3: 3024, p4: 2855, p5: 140,
+		p6: -7, p7: 15500, p8: -14600, p9: 6000,
+		h1: 75, h2: 362, h3: 0, h4: 324, h5: 50, h6: 30,
+	}
+
+	temp, tFine := c.temperature(519888)
+	if math.Abs(temp-25.08) > 0.005 || int32(tFine) != 128422 {
+		t.Errorf("Temperature = %.3f, t_fine %.0f; want 25.08, 128422", temp, tFine)
+	}
+	if p := c.pressure(415148, tFine); math.Abs(p-100653.27) > 0.01 {
+		t.Errorf("Pressure = %.2f, want 100653.27", p)
+	}
+
+	// The datasheet has no humidity example, so check against its
+	// integer formula.
+	for _, adc := range []int32{25000, 28000, 32000, 36000} {
+		want := float64(bme280HumidityInt(c, adc, int32(tFine))) / 1024
+		if h := c.humidity(adc, tFine); math.Abs(h-want) > 0.05 {
+			t.Errorf("Humidity(%d) = %.3f, want %.3f", adc, h, want)
+		}
+	}
+}
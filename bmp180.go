@@ -0,0 +1,140 @@
+package gadget
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// Fixed I2C address of the BMP180.
+	BMP180Address byte = 0x77
+
+	bmp180RegChipID byte = 0xD0
+	bmp180RegCalib  byte = 0xAA // 22 bytes.
+	bmp180RegCtrl   byte = 0xF4
+	bmp180RegData   byte = 0xF6
+
+	bmp180ChipID   byte = 0x55
+	bmp180CmdTemp  byte = 0x2E
+	bmp180CmdPress byte = 0x34
+)
+
+// Factory calibration coefficients of a BMP180.
+type bmp180Calib struct {
+	ac1, ac2, ac3 int16
+	ac4, ac5, ac6 uint16
+	b1, b2        int16
+	mb, mc, md    int16
+}
+
+// BMP180 is a Bosch BMP180 temperature and pressure sensor.
+type BMP180 struct {
+	board *Board
+	cal   bmp180Calib
+
+	// Pressure oversampling setting, 0 (ultra low power) to
+	// 3 (ultra high resolution).
+	Oversampling uint
+}
+
+// NewBMP180 returns the BMP180, reading its calibration coefficients.
+// I2CConfig must already have been called.
+func NewBMP180(b *Board) (s *BMP180, err error) {
+	id, err := b.I2CReadRegister(BMP180Address, bmp180RegChipID, 1)
+	if err != nil {
+		return nil, err
+	}
+	if id[0] != bmp180ChipID {
+		return nil, fmt.Errorf("Device at 0x%02X is not a BMP180, chip id 0x%02X", BMP180Address, id[0])
+	}
+
+	c, err := b.I2CReadRegister(BMP180Address, bmp180RegCalib, 22)
+	if err != nil {
+		return nil, err
+	}
+	s = &BMP180{
+		board: b,
+		cal: bmp180Calib{
+			ac1: int16(be16(c, 0)),
+			ac2: int16(be16(c, 2)),
+			ac3: int16(be16(c, 4)),
+			ac4: be16(c, 6),
+			ac5: be16(c, 8),
+			ac6: be16(c, 10),
+			b1:  int16(be16(c, 12)),
+			b2:  int16(be16(c, 14)),
+			mb:  int16(be16(c, 16)),
+			mc:  int16(be16(c, 18)),
+			md:  int16(be16(c, 20)),
+		},
+	}
+	return
+}
+
+// Read measures the temperature and pressure.
+func (s *BMP180) Read() (e Environment, err error) {
+	oss := s.Oversampling
+	if oss > 3 {
+		return e, fmt.Errorf("Invalid BMP180 oversampling setting: %d", oss)
+	}
+
+	d, err := s.measure(bmp180CmdTemp, 5*time.Millisecond, 2)
+	if err != nil {
+		return e, err
+	}
+	ut := int64(be16(d, 0))
+
+	delay := time.Duration(2+3<<oss) * time.Millisecond
+	d, err = s.measure(bmp180CmdPress+byte(oss<<6), delay, 3)
+	if err != nil {
+		return e, err
+	}
+	up := (int64(d[0])<<16 | int64(d[1])<<8 | int64(d[2])) >> (8 - oss)
+
+	t, p := s.cal.compensate(ut, up, oss)
+	e.Temperature = float64(t) / 10
+	e.Pressure = float64(p)
+	return
+}
+
+// Starts a measurement, waits for it and reads n result bytes.
+func (s *BMP180) measure(cmd byte, delay time.Duration, n int) ([]byte, error) {
+	if err := s.board.I2CWrite(BMP180Address, []byte{bmp180RegCtrl, cmd}); err != nil {
+		return nil, err
+	}
+	time.Sleep(delay)
+	return s.board.I2CReadRegister(BMP180Address, bmp180RegData, n)
+}
+
+// Returns the temperature in 0.1°C and pressure in Pa, using the
+// integer algorithm from the BMP180 datasheet, section 3.5. Shifts are
+// used where the datasheet divides by powers of 2, matching its
+// rounding of negative values.
+func (c *bmp180Calib) compensate(ut, up int64, oss uint) (t, p int64) {
+	x1 := (ut - int64(c.ac6)) * int64(c.ac5) >> 15
+	x2 := int64(c.mc) << 11 / (x1 + int64(c.md))
+	b5 := x1 + x2
+	t = (b5 + 8) >> 4
+
+	b6 := b5 - 4000
+	x1 = int64(c.b2) * (b6 * b6 >> 12) >> 11
+	x2 = int64(c.ac2) * b6 >> 11
+	x3 := x1 + x2
+	b3 := ((int64(c.ac1)*4+x3)<<oss + 2) >> 2
+	x1 = int64(c.ac3) * b6 >> 13
+	x2 = int64(c.b1) * (b6 * b6 >> 12) >> 16
+	x3 = (x1 + x2 + 2) >> 2
+	b4 := int64(c.ac4) * int64(uint32(x3+32768)) >> 15
+	b7 := int64(uint32(up-b3)) * int64(50000>>oss)
+	if b7 < 0x80000000 {
+		p = b7 * 2 / b4
+	} else {
+		p = b7 / b4 * 2
+	}
+
+	x1 = (p >> 8) * (p >> 8)
+	x1 = x1 * 3038 >> 16
+	x2 = -7357 * p >> 16
+	p = p + (x1+x2+3791)>>4
+	return
+}
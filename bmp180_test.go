@@ -0,0 +1,23 @@
+package gadget
+
+import (
+	"testing"
+)
+
+// Uses the worked example from the BMP180 datasheet.
+func TestBMP180Compensate(t *testing.T) {
+	c := bmp180Calib{
+		ac1: 408, ac2: -72, ac3: -14383,
+		ac4: 32741, ac5: 32757, ac6: 23153,
+		b1: 6190, b2: 4,
+		mb: -32768, mc: -8711, md: 2868,
+	}
+
+	temp, press := c.compensate(27898, 23843, 0)
+	if temp != 150 {
+		t.Errorf("Temperature = %d, want 150", temp)
+	}
+	if press != 69964 {
+		t.Errorf("Pressure = %d, want 69964", press)
+	}
+}
@@ -3,30 +3,54 @@ package gadget
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"github.com/ZachMassia/goserial"
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
 	"io"
-	"log"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// ErrReadTimeout is published on TopicError when the board has sent
+// nothing for the time set by SetReadTimeout.
+var ErrReadTimeout = errors.New("Timed out waiting for data from the board")
+
 type Board struct {
-	cfg    *serial.Config     // Port and baud rate
-	fd     uintptr            // Serial port file descriptor.
-	buf    *bufio.Reader      // Buffered reading from serial.
-	serial io.ReadWriteCloser // The serial connection.
+	cfg    *portConfig          // Port and baud rate
+	buf    *bufio.Reader        // Buffered reading from serial.
+	dec    *firmatawire.Decoder // Splits buf into messages.
+	serial io.ReadWriteCloser   // The serial connection.
+	out    *batchWriter         // Batches writes to queue, see SetWriteDelay.
+	queue  *queueWriter         // Writes to serial from its own goroutine.
 
-	maj, min byte   // Firmware version
-	firmware string // The name of the sketch uploaded to the board.
+	maj, min     byte   // Protocol version
+	fwMaj, fwMin byte   // Firmware version
+	firmware     string // The name of the sketch uploaded to the board.
 
 	// Has the initial pin capability response been handled.
 	pinsInitialized bool
 
+	// Closed when the capability response asked for by
+	// RefreshCapabilities arrives.
+	refreshed chan bool
+
 	// The pins are stored in structs, with the key being that pins number.
 	// Analog pins do not use the A0 numbering.
 	pins map[byte]*pin
 
+	// The same pins indexed by port and position in the port, for
+	// handling port messages. Missing pins are nil.
+	ports [addressablePins / 8][8]*pin
+
+	// The digital ports the board has been told to report.
+	portReporting [addressablePins / 8]bool
+
 	// A mapping of normal pin number to their analog (A0 style) numbers.
 	// A value of 0x7F (127) means the pin is digital only.
 	analogMapping map[byte]byte
@@ -38,6 +62,31 @@ type Board struct {
 
 	// A mapping of message handlers, the key is the command byte.
 	msgHandlers cbMap
+	hm          sync.RWMutex // Handlers can be added while running.
+
+	// Pin changes and board events are published here.
+	bus *EventBus
+
+	// Tasks scheduled with Every, After and Cron.
+	tasks map[*Task]bool
+	tm    sync.Mutex
+
+	// Running animations, and whether their goroutine is.
+	animations map[*Animation]bool
+	animating  bool
+	am         sync.Mutex
+
+	// I2C replies are passed to the waiting read on this channel.
+	i2cReplies chan i2cReplyData
+	i2cMutex   sync.Mutex // Only one I2C read may be in flight.
+
+	// SPI devices and encoders set up so far, which numbers the next.
+	spiDevices byte
+	encoders   byte
+
+	// Pin state replies are passed to the waiting QueryPinState.
+	pinStates     chan []byte
+	pinStateMutex sync.Mutex
 
 	// Used to notify when the firmware reponse comes in and the
 	// board is ready to communicate.
@@ -50,14 +99,83 @@ type Board struct {
 
 	// The message handling goroutine listens on this channel
 	// for the close event.
-	quit chan bool
+	quit      chan bool
+	closeOnce sync.Once
+
+	// The running Heartbeat, if any.
+	hb  *heartbeat
+	hbm sync.Mutex
+
+	// Drivers added with AddDriver, halted on Close, and the pins
+	// they claimed by their Requirements.
+	drivers []Driver
+	claims  map[byte]string
+	shared  map[byte][]string
+	dm      sync.Mutex
+
+	// Where log messages go, see WithLogger.
+	logger *slog.Logger
+
+	// Traces every message if set, see WithTrace.
+	tracer *tracer
+
+	// Traffic counters, see Stats.
+	stats counters
+
+	// The last SetSamplingInterval, restored if the board resets.
+	sampling time.Duration
+
+	// The analog reference in volts, see SetAnalogReference. Zero
+	// means the default.
+	analogRef float64
+
+	// See SetReadTimeout. Zero waits forever.
+	readTimeout atomic.Int64
+
+	// See WithSettleDelay and WithoutReset.
+	settle  time.Duration
+	noReset bool
+
+	// Reopen the port when a Heartbeat finds the board not answering,
+	// see WithReconnect.
+	reconnect bool
+
+	// Send SYSTEM_RESET on Close, see WithResetOnClose.
+	resetOnClose bool
+
+	// The rates to try, see WithBaudDetection.
+	detectBauds []int
+
+	// Uploads firmware if New finds none, see WithFirmwareUpload.
+	upload func(ctx context.Context, device string) error
 }
 
 // New returns a fully configured Board, with the message handling
-// loop running in it's own goroutine.
-func New(device string) (b *Board, err error) {
+// loop running in it's own goroutine. Options such as WithLogger are
+// applied before the port is opened.
+func New(device string, opts ...Option) (b *Board, err error) {
+	b, err = open(device, opts...)
+	if b == nil || b.upload == nil || (err == nil && looksLikeFirmata(b.firmware)) {
+		return
+	}
+
+	// Wrong or no firmware, see WithFirmwareUpload.
+	reason := err
+	if reason == nil {
+		reason = fmt.Errorf("Unexpected firmware '%s'", b.firmware)
+		b.Close()
+	}
+	b.log().Warn("Uploading firmware", "device", device, "reason", reason)
+	if err = b.upload(context.Background(), device); err != nil {
+		return nil, fmt.Errorf("Error uploading firmware (%s): %s", reason, err)
+	}
+	return open(device, opts...)
+}
+
+// Opens the board once, see New.
+func open(device string, opts ...Option) (b *Board, err error) {
 	b = &Board{
-		cfg: &serial.Config{
+		cfg: &portConfig{
 			Name: device,
 			Baud: defaultBaud,
 		},
@@ -66,20 +184,47 @@ func New(device string) (b *Board, err error) {
 		quit:            make(chan bool),
 		pins:            make(map[byte]*pin),
 		analogMapping:   make(map[byte]byte),
+		i2cReplies:      make(chan i2cReplyData, 1),
+		pinStates:       make(chan []byte, 1),
+		bus:             NewEventBus(),
 	}
-
-	b.serial, err, b.fd = serial.OpenPort(b.cfg)
-	if err != nil {
-		return nil, err
+	for _, opt := range opts {
+		opt(b)
+	}
+	if b.cfg.Baud <= 0 {
+		return nil, fmt.Errorf("Invalid baud rate: %d", b.cfg.Baud)
 	}
 
-	err = serial.Flush(b.fd, serial.TCIOFLUSH)
-	if err != nil {
+	if b.serial == nil && b.detectBauds != nil {
+		if b.cfg.Baud, err = DetectBaud(b.cfg.Name, b.detectBauds...); err != nil {
+			return nil, err
+		}
+		b.log().Info("Detected baud rate", "device", b.cfg.Name, "baud", b.cfg.Baud)
+	}
+	if b.serial == nil {
+		if b.serial, err = OpenSerial(b.cfg.Name, b.cfg.Baud); err != nil {
+			return nil, err
+		}
+		if b.reconnect {
+			b.serial = newReopenablePort(b.serial, func() (io.ReadWriteCloser, error) {
+				return OpenSerial(b.cfg.Name, b.cfg.Baud)
+			})
+		}
+	}
+	time.Sleep(b.settle)
+	if err = resetBuffers(b.serial); err != nil {
 		b.serial.Close()
 		return nil, fmt.Errorf("Error flushing port: %s", err)
 	}
 
 	b.buf = bufio.NewReader(b.serial)
+	b.dec = firmatawire.NewDecoder(b.buf)
+	var w io.Writer = countingWriter{b.serial, &b.stats}
+	if b.tracer != nil {
+		w = traceWriter{w, b.tracer}
+	}
+	b.queue = newQueueWriter(w, b.reportError)
+	b.out = newBatchWriter(b.queue)
 
 	err = b.init()
 	if err != nil {
@@ -94,16 +239,13 @@ func New(device string) (b *Board, err error) {
 // has been properly established.
 func (b *Board) init() (err error) {
 	// Register the callbacks.
-	b.msgHandlers = cbMap{
-		reportVersion:         b.handleReportVersion,
-		reportFirmware:        b.handleReportFirmware,
-		capabilityResponse:    b.handleCapabilityResponse,
-		analogMappingResponse: b.handleAnalogMappingResponse,
-		analogMessage:         b.handleAnalogMessage,
-		digitalMessage:        b.handleDigitalMessage,
-	}
+	b.msgHandlers = b.coreHandlers()
 	// Start the message loop.
 	b.run()
+	if b.noReset {
+		// The board will not announce itself.
+		b.sendSysex([]byte{reportFirmware})
+	}
 
 	timeout := time.After(15 * time.Second)
 
@@ -114,6 +256,9 @@ func (b *Board) init() (err error) {
 			b.sendCapabilityQuery()
 
 		case <-b.ready:
+			b.log().Info("Board ready", "device", b.cfg.Name, "firmware", b.firmware,
+				"version", b.Version(), "pins", len(b.pins))
+			b.bus.Publish(Event{Topic: TopicReady})
 			return
 
 		case <-timeout:
@@ -122,38 +267,57 @@ func (b *Board) init() (err error) {
 	}
 }
 
+// Returns the handlers for the core Firmata messages.
+func (b *Board) coreHandlers() cbMap {
+	return cbMap{
+		reportVersion:         b.handleReportVersion,
+		reportFirmware:        b.handleReportFirmware,
+		capabilityResponse:    b.handleCapabilityResponse,
+		analogMappingResponse: b.handleAnalogMappingResponse,
+		analogMessage:         b.handleAnalogMessage,
+		digitalMessage:        b.handleDigitalMessage,
+		i2cReply:              b.handleI2CReply,
+		pinStateResponse:      b.handlePinStateResponse,
+	}
+}
+
 func (b *Board) run() {
+	timedOut := false
 	iterate := func() {
-		msg := message{}
-		header, _ := b.buf.ReadByte()
-
-		// Sysex commands have their own header so check for that first.
+		if d := time.Duration(b.readTimeout.Load()); d > 0 {
+			b.serial.(deadlineReader).SetReadDeadline(time.Now().Add(d))
+		}
+		f, err := b.dec.Next()
+		var serr *firmatawire.SyncError
 		switch {
-		case header == startSysex:
-			// Read until sysexEnd
-			data, err := b.buf.ReadBytes(endSysex)
-			if err != nil {
-				log.Printf("Error reading sysex data: %s", err)
-				return
+		case errors.As(err, &serr):
+			b.stats.bytesIn.Add(int64(serr.Discarded))
+			b.reportError(err)
+		case errors.Is(err, os.ErrDeadlineExceeded):
+			// Report once per silence, not every timeout.
+			if !timedOut {
+				timedOut = true
+				b.reportError(ErrReadTimeout)
+			}
+		case err != nil:
+			select {
+			case <-b.quit:
+				// Closing the port interrupted the read.
+			default:
+				b.stats.parseErrors.Add(1)
+				b.reportError(err)
 			}
-			msg.t = sysexMsg
-			msg.data = append([]byte{header}, data...)
-			b.handleCallback(msg)
-
 		default:
-			// Read the two MIDI data bytes
-			lsb, err := b.buf.ReadByte()
-			if err != nil {
-				log.Printf("Error reading MIDI lsb: %s", err)
-				return
+			timedOut = false
+			b.stats.bytesIn.Add(int64(len(f.Data)))
+			b.stats.framesIn[f.Command()].Add(1)
+			if b.tracer != nil {
+				b.tracer.frame("<", f.At, f.Data)
 			}
-			msb, err := b.buf.ReadByte()
-			if err != nil {
-				log.Printf("Error reading MIDI msb: %s", err)
-				return
+			msg := message{t: midiMsg, data: f.Data, at: f.At}
+			if f.Sysex() {
+				msg.t = sysexMsg
 			}
-			msg.t = midiMsg
-			msg.data = []byte{header, lsb, msb}
 			b.handleCallback(msg)
 		}
 	}
@@ -171,36 +335,155 @@ func (b *Board) run() {
 	}()
 }
 
+// Implemented by serial ports that support read timeouts.
+type deadlineReader interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// SetReadTimeout sets how long the message loop waits for data before
+// publishing ErrReadTimeout on TopicError, which can be used to notice a
+// board that has stopped responding. The loop also checks for Close
+// between reads, so a hung port cannot keep it running. Zero, the
+// default, waits forever.
+//
+// An error is returned if the serial port does not support timeouts.
+func (b *Board) SetReadTimeout(d time.Duration) error {
+	dr, ok := b.serial.(deadlineReader)
+	if !ok {
+		return fmt.Errorf("Serial port does not support read timeouts")
+	}
+	if err := dr.SetReadDeadline(time.Time{}); err != nil {
+		return fmt.Errorf("Serial port does not support read timeouts: %s", err)
+	}
+	b.readTimeout.Store(int64(d))
+	return nil
+}
+
+// Resyncs returns how many times the board's message stream was found
+// out of step, e.g. because of a dropped byte. Each is also published
+// on TopicError.
+func (b *Board) Resyncs() int64 {
+	return b.dec.Resyncs()
+}
+
+// Logs, traces and publishes an error talking to the board.
+func (b *Board) reportError(err error) {
+	b.log().Error(err.Error())
+	if b.tracer != nil {
+		b.tracer.error(time.Now(), err)
+	}
+	b.bus.Publish(Event{Topic: TopicError, Err: err})
+}
+
 func (b *Board) handleCallback(msg message) {
-	var cmd byte
-
-	switch msg.t {
-	case midiMsg:
-		// Some messages include data in the command byte, but
-		// here we only need the base command.
-		if msg.data[0] < 0xF0 {
-			cmd = msg.data[0] & 0xF0 // Remove data from the command
-		} else {
-			cmd = msg.data[0]
-		}
-	case sysexMsg:
-		// The second byte is used as the cmd, since the first contains the sysexStart byte.
-		cmd = msg.data[1]
+	cmd := firmatawire.Command(msg.data)
+
+	// Checked first since building the fields costs more than the
+	// message itself.
+	if l := b.log(); l.Enabled(context.Background(), slog.LevelDebug) {
+		l.Debug("Received message", "cmd", firmatawire.CommandName(cmd), "data", fmt.Sprintf("% X", msg.data))
 	}
 
 	// Try to call the handler
-	if cb, ok := b.msgHandlers[cmd]; ok {
-		cb(msg)
+	b.hm.RLock()
+	cb, ok := b.msgHandlers[cmd]
+	b.hm.RUnlock()
+	if ok {
+		b.dispatch(cmd, cb, msg)
+	}
+}
+
+// Calls a handler, reporting a panic on TopicError instead of letting
+// it kill the message loop.
+func (b *Board) dispatch(cmd byte, cb callback, msg message) {
+	defer func() {
+		if r := recover(); r != nil {
+			b.log().Debug("Handler panic", "cmd", firmatawire.CommandName(cmd), "stack", string(debug.Stack()))
+			b.reportError(fmt.Errorf("Handler for %s panicked: %v", firmatawire.CommandName(cmd), r))
+		}
+	}()
+	cb(msg)
+}
+
+// Adds a handler for the command byte cmd, used by drivers for
+// firmware extensions. Handlers for the same command are called in
+// the order they were added.
+func (b *Board) addHandler(cmd byte, cb callback) {
+	b.hm.Lock()
+	defer b.hm.Unlock()
+
+	if prev, ok := b.msgHandlers[cmd]; ok {
+		b.msgHandlers[cmd] = func(m message) {
+			prev(m)
+			cb(m)
+		}
+		return
+	}
+	b.msgHandlers[cmd] = cb
+}
+
+// Turns off reporting of every pin, a port at a time for digital pins.
+func (b *Board) stopReporting() {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	for _, p := range b.pins {
+		if p.reporting {
+			b.setReporting(p, false)
+		}
+	}
+}
+
+// Sets the pin's reporting. Digital pins report by port, so the port's
+// message is only sent when the first of its pins starts reporting or
+// the last one stops. Must be called with b.m held.
+func (b *Board) setReporting(p *pin, on bool) error {
+	if !p.isInput() {
+		return p.setReporting(on)
+	}
+	p.reporting = on
+	return b.updatePortReporting(p.port)
+}
+
+// Turns the port's reporting on while any of its input pins report, and
+// off once none do. Must be called with b.m held.
+func (b *Board) updatePortReporting(port byte) (err error) {
+	if port == noPort {
+		return fmt.Errorf("Pins above %d have no port to report", addressablePins-1)
+	}
+	want := false
+	for _, p := range b.ports[port] {
+		if p != nil && p.isInput() && p.reporting {
+			want = true
+		}
+	}
+	if want != b.portReporting[port] {
+		b.portReporting[port] = want
+		_, err = b.out.Write(firmatawire.ReportDigitalPort(port, want))
 	}
+	return
 }
 
-// Initializes the pins if it has not already been done.
-func (b *Board) initPins(analog, digital map[byte][]byte) {
-	if b.pinsInitialized {
-		// TODO: Use sync.Once to avoid this check?
-		return // Nothing to do here.
+// Sets the pin's mode, releasing its port's reporting if it was a
+// reporting input. Must be called with b.m held.
+func (b *Board) setMode(p *pin, mode byte) (err error) {
+	wasReporting := p.isInput() && p.reporting
+	if err = p.setMode(mode); err != nil {
+		return err
+	}
+	b.log().Debug("Set pin mode", "pin", p.num, "mode", PinModeString[mode])
+	b.publishPin(p.num, KindMode, int(mode), time.Now())
+	if wasReporting {
+		p.reporting = false
+		return b.updatePortReporting(p.port)
 	}
+	return
+}
 
+// Builds the pin table from a capability response. The first call
+// lets New return; later ones, e.g. after RefreshCapabilities, update
+// the table in place.
+func (b *Board) initPins(analog, digital map[byte][]Capability) {
 	// The analogMappingReponse must be handled before the pins
 	// can be initialized.
 	if len(b.analogMapping) == 0 {
@@ -215,18 +498,72 @@ func (b *Board) initPins(analog, digital map[byte][]byte) {
 		return
 	}
 
-	b.analogToNormal = make([]byte, len(b.analogMapping))
-
 	b.m.Lock()
-	defer b.m.Unlock()
+	first := !b.pinsInitialized
+	b.setPins(analog, digital)
+	b.pinsInitialized = true
+	refreshed := b.refreshed
+	b.refreshed = nil
+	b.m.Unlock()
+
+	if first {
+		// Send the ready message to New() so it can return.
+		b.ready <- true
+	}
+	if refreshed != nil {
+		close(refreshed)
+	}
+}
+
+// HardwareChange lists the pins that differ from the board's previous
+// capability report, published on TopicHardware. A change after a
+// reset usually means a different board was plugged into the port.
+type HardwareChange struct {
+	Added   []byte // Pins that did not exist.
+	Removed []byte // Pins that no longer exist.
+	Changed []byte // Pins whose modes or analog number differ.
+}
+
+// Replaces the pin table. Pins whose analog number and mode are still
+// valid keep their state; others are reset to their default mode. Must
+// be called with b.m held.
+func (b *Board) setPins(analog, digital map[byte][]Capability) {
+	// Size the reverse mapping for the highest analog number.
+	n := 0
+	for _, a := range b.analogMapping {
+		if a != 0x7F {
+			n = max(n, int(a)+1)
+		}
+	}
+	b.analogToNormal = make([]byte, n)
+
+	var change HardwareChange
+	pins := make(map[byte]*pin)
+	add := func(num, analogNum byte, modes []Capability) {
+		old, ok := b.pins[num]
+		if !ok {
+			change.Added = append(change.Added, num)
+		} else if old.analogNum != analogNum || !slices.Equal(old.caps, modes) {
+			change.Changed = append(change.Changed, num)
+		}
+		if ok && old.analogNum == analogNum && supportsMode(modes, old.mode) {
+			old.caps = modes
+			pins[num] = old
+			return
+		}
+		pins[num] = newPin(b.out, num, analogNum, modes)
+	}
 
 	// Initialize the analog pins.
 	for pin, modes := range analog {
-		if analogNum, ok := b.analogMapping[pin]; ok {
-			b.pins[pin] = newPin(b.serial, pin, analogNum, modes)
+		if analogNum, ok := b.analogMapping[pin]; ok && analogNum != 0x7F {
+			add(pin, analogNum, modes)
 			b.analogToNormal[analogNum] = pin
+			if analogNum > maxReportedAnalog && !b.pinsInitialized {
+				b.log().Warn("Analog pin cannot report, Firmata only reports A0-A15", "pin", pin, "analog", analogNum)
+			}
 		} else {
-			log.Printf("Error initializing analog pin %d", pin)
+			b.log().Warn("Analog pin missing from the analog mapping", "pin", pin)
 		}
 	}
 
@@ -234,25 +571,86 @@ func (b *Board) initPins(analog, digital map[byte][]byte) {
 	for pin, modes := range digital {
 		// 0x7F is passed directly as the analog pin number
 		// since it does not apply to digital pins.
-		b.pins[pin] = newPin(b.serial, pin, 0x7F, modes)
+		add(pin, 0x7F, modes)
+	}
+
+	for num := range b.pins {
+		if _, ok := pins[num]; !ok {
+			change.Removed = append(change.Removed, num)
+		}
+	}
+	if b.pinsInitialized && len(change.Added)+len(change.Removed)+len(change.Changed) > 0 {
+		slices.Sort(change.Added)
+		slices.Sort(change.Removed)
+		slices.Sort(change.Changed)
+		b.log().Warn("Board hardware changed", "added", change.Added, "removed", change.Removed, "changed", change.Changed)
+		b.bus.Publish(Event{Topic: TopicHardware, Data: change})
+	}
+	b.pins = pins
+	b.ports = [addressablePins / 8][8]*pin{}
+	for _, p := range b.pins {
+		if p.port != noPort {
+			b.ports[p.port][p.num%8] = p
+		}
 	}
+}
 
-	// Send the ready message to New() so it can return.
-	b.ready <- true
+// RefreshCapabilities queries the board's pins again and updates the
+// pin table, for firmware that can change its configuration at run
+// time. Pins that still support their current mode are left as they
+// are. It waits up to timeout for the board to answer.
+func (b *Board) RefreshCapabilities(timeout time.Duration) error {
+	done := make(chan bool)
+	b.m.Lock()
+	if b.refreshed != nil {
+		done = b.refreshed // Share the refresh already in flight.
+	} else {
+		b.refreshed = done
+	}
+	b.m.Unlock()
 
-	// Ignore any furthur calls from the capabilityResponse handler.
-	b.pinsInitialized = true
+	b.sendAnalogMappingQuery()
+	if _, err := b.sendCapabilityQuery(); err != nil {
+		return err
+	}
+	if err := b.out.Flush(); err != nil {
+		return err
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("Timed out waiting for the capability response")
+	}
 }
 
 func (b *Board) String() string {
 	return fmt.Sprintf("Arduino on device '%s'", b.cfg.Name)
 }
 
-// Close properly closes the serial connection to Board b.
+// Close properly closes the serial connection to Board b. Drivers added
+// with AddDriver are halted, pins given a value with SetSafeValue are
+// set to it and reporting is turned off, so the board stops streaming
+// into a closed port. Closing twice is a no-op.
 func (b *Board) Close() {
-	b.quit <- true
-	serial.Flush(b.fd, serial.TCIOFLUSH)
-	b.serial.Close()
+	b.closeOnce.Do(func() {
+		b.haltDrivers()
+		b.stopAnimations()
+		b.stopTasks()
+		b.WriteSafe()
+		b.stopReporting()
+		if b.resetOnClose {
+			b.out.Write([]byte{systemReset})
+		}
+		close(b.quit)
+		b.out.Flush()
+		b.queue.Close()
+		resetBuffers(b.serial)
+		b.serial.Close()
+		b.log().Info("Board closed", "device", b.cfg.Name)
+		b.bus.Publish(Event{Topic: TopicClosed})
+	})
 }
 
 // Version returns the Firmata protocol version.
@@ -282,38 +680,120 @@ func (b *Board) DigitalRead(pin byte) (s byte, err error) {
 // DigitalWrite sets the state of the digital pin.
 func (b *Board) DigitalWrite(pin byte, s byte) (err error) {
 	port := pinToPort(pin)
-	portVal := byte(0)
 
+	// Hold the lock while writing so concurrent writes to the port
+	// cannot be sent out of order.
 	b.m.Lock()
-	// Before looping, update the value of the pin DigitalWrite was called on.
-	if p, ok := b.pins[pin]; ok {
-		p.digitalVal = s
+	defer b.m.Unlock()
+
+	p, ok := b.pins[pin]
+	if !ok {
+		return fmt.Errorf("Invalid pin: %d", pin)
+	}
+	if port == noPort {
+		return fmt.Errorf("Pin %d has no port, only pins 0-%d can be written", pin, addressablePins-1)
+	}
+	if now := time.Now(); p.setDigital(s, now) {
+		b.publishPin(pin, KindDigital, int(s), now)
 	}
-	b.m.Unlock()
 
-	b.m.RLock()
-	defer b.m.RUnlock()
+	b.log().Debug("Digital write", "pin", pin, "port", port, "value", s)
+	return b.writePort(port)
+}
 
-	// Create the port bitmask.
-	for i := byte(0); i < 8; i++ {
-		n := 8*port + i // current pin
-		p, ok := b.pins[n]
-		if !ok {
-			err = fmt.Errorf("Error writing to port %d: pin %d unavailable", port, n)
+// WritePort sets the pins of a port whose bits are set in mask to the
+// matching bits of values, e.g. WritePort(1, 0x0F, 0x05) sets pins 8
+// and 10 high and 9 and 11 low. The pins change together, in a single
+// message, which parallel buses such as an LCD's data lines need. The
+// port's other pins are left as they are.
+func (b *Board) WritePort(port, mask, values byte) (err error) {
+	if int(port) >= len(b.ports) {
+		return fmt.Errorf("Invalid port: %d", port)
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if err = b.checkPort(port, mask); err != nil {
+		return err
+	}
+	return b.setPort(port, mask, values)
+}
+
+// DigitalWritePins sets several digital pins at once, e.g.
+// DigitalWritePins(map[byte]byte{8: HIGH, 9: LOW}). Pins in the same
+// port change together in a single message, and nothing is written if
+// any pin is invalid.
+func (b *Board) DigitalWritePins(states map[byte]byte) (err error) {
+	var masks, values [addressablePins / 8]byte
+	for pin, s := range states {
+		if pin >= addressablePins {
+			return fmt.Errorf("Invalid pin: %d", pin)
+		}
+		port, bit := pinToPort(pin), byte(1)<<(pin%8)
+		masks[port] |= bit
+		if s != LOW {
+			values[port] |= bit
+		}
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	for port, mask := range masks {
+		if err = b.checkPort(byte(port), mask); err != nil {
 			return err
 		}
-		if p.digitalVal != LOW {
-			portVal |= 1 << i
+	}
+	for port, mask := range masks {
+		if mask != 0 {
+			if err = b.setPort(byte(port), mask, values[port]); err != nil {
+				return err
+			}
+		}
+	}
+	return
+}
+
+// Returns an error if a pin in mask is missing. Must be called with
+// b.m held.
+func (b *Board) checkPort(port, mask byte) error {
+	for i, p := range b.ports[port] {
+		if mask&(1<<byte(i)) != 0 && p == nil {
+			return fmt.Errorf("Invalid pin: %d", int(port)*8+i)
 		}
 	}
+	return nil
+}
 
-	// Write the bitmask to the port.
-	msg := []byte{
-		digitalMessage | port,
-		portVal & 0x7F,
-		(portVal >> 7) & 0x7F,
+// Sets the pins in mask to values and sends the port. Must be called
+// with b.m held.
+func (b *Board) setPort(port, mask, values byte) error {
+	now := time.Now()
+	for i, p := range b.ports[port] {
+		if mask&(1<<byte(i)) == 0 {
+			continue
+		}
+		s := (values >> byte(i)) & 1
+		if p.setDigital(s, now) {
+			b.publishPin(p.num, KindDigital, int(s), now)
+		}
 	}
-	b.serial.Write(msg)
+
+	b.log().Debug("Port write", "port", port, "mask", mask, "value", values)
+	return b.writePort(port)
+}
+
+// Sends the port's output values. Slots without a pin are left low.
+// Must be called with b.m held, so writes to a port are sent in order.
+func (b *Board) writePort(port byte) (err error) {
+	var portVal byte
+	for i, p := range b.ports[port] {
+		if p != nil && p.digitalVal != LOW {
+			portVal |= 1 << byte(i)
+		}
+	}
+	_, err = b.out.Write(firmatawire.DigitalWrite(port, portVal))
 	return
 }
 
@@ -321,7 +801,7 @@ func (b *Board) DigitalWrite(pin byte, s byte) (err error) {
 //
 // If the pin is not in ANALOG or PWM mode, the value
 // is garbage.
-func (b *Board) AnalogRead(pin byte) (v byte, err error) {
+func (b *Board) AnalogRead(pin byte) (v int, err error) {
 	b.m.RLock()
 	defer b.m.RUnlock()
 
@@ -338,25 +818,93 @@ func (b *Board) AnalogWrite(pin, val byte) (err error) {
 	b.m.Lock()
 	defer b.m.Unlock()
 
-	p, ok := b.pins[pin]
-	if !ok {
-		return fmt.Errorf("Invalid pin: %d", pin)
+	p, err := b.sendablePin(pin)
+	if err != nil {
+		return err
 	}
 	// Only write to pins in PWM mode
 	if p.mode == PWM {
-		p.analogVal = val
-		msg := []byte{
-			analogMessage | p.num,
-			val & 0x7F,
-			(val >> 7) & 0x7F,
-		}
-		b.serial.Write(msg)
+		b.log().Debug("Analog write", "pin", pin, "value", val)
+		now := time.Now()
+		p.setAnalog(int(val), now)
+		b.out.Write(firmatawire.AnalogWrite(p.num, int(val)))
+		b.publishPin(pin, KindAnalog, int(val), now)
 	} else {
 		err = fmt.Errorf("Pin %d not in PWM mode, got %s", pin, PinModeString[p.mode])
 	}
 	return
 }
 
+// AnalogOut writes v to the pin's analog output at its full resolution,
+// e.g. 0-4095 on a 12-bit DAC. Firmata has no DAC mode: boards with
+// DACs, such as the Due and Zero, report them as PWM pins with more
+// than 8 bits, see AnalogOutResolution. The pin must be in PWM mode.
+func (b *Board) AnalogOut(pin byte, v int) (err error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	p, err := b.sendablePin(pin)
+	if err != nil {
+		return err
+	}
+	if p.mode != PWM {
+		return fmt.Errorf("Pin %d not in PWM mode, got %s", pin, PinModeString[p.mode])
+	}
+	if full := analogMax(p.resolution(PWM)); v < 0 || v > full {
+		return fmt.Errorf("Analog output value must be 0-%d, got %d", full, v)
+	}
+	b.log().Debug("Analog out", "pin", pin, "value", v)
+	now := time.Now()
+	p.setAnalog(v, now)
+	_, err = b.out.Write(firmatawire.AnalogWrite(p.num, v))
+	b.publishPin(pin, KindAnalog, v, now)
+	return
+}
+
+// AnalogOutResolution returns the resolution in bits of the pin's
+// analog output: 8 for PWM on most boards, more for a DAC.
+func (b *Board) AnalogOutResolution(pin byte) (bits byte, err error) {
+	b.m.RLock()
+	defer b.m.RUnlock()
+
+	p, ok := b.pins[pin]
+	if !ok {
+		return 0, fmt.Errorf("Invalid pin: %d", pin)
+	}
+	if bits = p.resolution(PWM); bits == 0 {
+		return 0, fmt.Errorf("Pin %d does not support PWM mode", pin)
+	}
+	return
+}
+
+// AnalogResolution returns the resolution in bits of the pin's
+// analog input, as reported by the board.
+func (b *Board) AnalogResolution(pin byte) (bits byte, err error) {
+	b.m.RLock()
+	defer b.m.RUnlock()
+
+	p, ok := b.pins[pin]
+	if !ok {
+		return 0, fmt.Errorf("Invalid pin: %d", pin)
+	}
+	if bits = p.resolution(ANALOG); bits == 0 {
+		return 0, fmt.Errorf("Pin %d does not support ANALOG mode", pin)
+	}
+	return
+}
+
+// PinMode returns the current mode of the pin.
+func (b *Board) PinMode(pin byte) (mode byte, err error) {
+	b.m.RLock()
+	defer b.m.RUnlock()
+
+	p, ok := b.pins[pin]
+	if !ok {
+		return 0, fmt.Errorf("Invalid pin: %d", pin)
+	}
+	return p.mode, nil
+}
+
 // SetPinMode set a pin to a given mode if it is supported.
 func (b *Board) SetPinMode(pin, mode byte) (err error) {
 	b.m.Lock()
@@ -366,7 +914,22 @@ func (b *Board) SetPinMode(pin, mode byte) (err error) {
 	if !ok {
 		return fmt.Errorf("Invalid pin: %d", pin)
 	}
-	return p.setMode(mode)
+	return b.setMode(p, mode)
+}
+
+// Sets the pin's mode only if it is not already in that mode.
+func (b *Board) ensurePinMode(pin, mode byte) (err error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	p, ok := b.pins[pin]
+	if !ok {
+		return fmt.Errorf("Invalid pin: %d", pin)
+	}
+	if p.mode == mode {
+		return nil
+	}
+	return b.setMode(p, mode)
 }
 
 // SetDigitalPinReporting toggles reporting of a digital pin. It must be enabled
@@ -382,7 +945,22 @@ func (b *Board) SetPinReporting(pin byte, report bool) (err error) {
 	if !ok {
 		return fmt.Errorf("Invalid pin: %d", pin)
 	}
-	return p.setReporting(report)
+	b.log().Debug("Set pin reporting", "pin", pin, "port", p.port, "report", report)
+	return b.setReporting(p, report)
+}
+
+// Capabilities returns the modes supported by each pin, keyed by pin
+// number.
+func (b *Board) Capabilities() (m map[byte][]Capability) {
+	m = make(map[byte][]Capability)
+
+	b.m.RLock()
+	defer b.m.RUnlock()
+
+	for n, p := range b.pins {
+		m[n] = append([]Capability(nil), p.caps...)
+	}
+	return
 }
 
 // PortToPinMapping returns a mapping of port numbers to it's pins.
@@ -397,6 +975,9 @@ func (b *Board) PortToPinMapping() (m map[byte][]byte) {
 
 	// Fill the response map.
 	for _, pin := range b.pins {
+		if pin.port == noPort {
+			continue
+		}
 		// Create the slice for this port if not already done.
 		if _, ok := m[pin.port]; !ok {
 			m[pin.port] = make([]byte, 0, 8)
@@ -411,6 +992,9 @@ func (b *Board) PortToPinMapping() (m map[byte][]byte) {
 // The key is the A0 style number printed on the board,
 // The value is it's normal pin number.
 func (b *Board) AnalogMapping() (m []byte) {
+	b.m.RLock()
+	defer b.m.RUnlock()
+
 	// Return a copy to avoid having the internal values changed.
 	m = make([]byte, len(b.analogToNormal))
 	copy(m, b.analogToNormal)
@@ -422,26 +1006,42 @@ func (b *Board) AnalogMapping() (m []byte) {
 // Wraps a message in sysex start/end bytes, and writes it
 // to the serial port.
 func (b *Board) sendSysex(msg []byte) (n int, err error) {
-	m := wrapInSysex(msg)
-	n, err = b.serial.Write(m)
+	n, err = b.out.Write(firmatawire.Sysex(msg[0], msg[1:]...))
 	return
 }
 
-func (b *Board) sendCapabilityQuery()    { b.sendSysex([]byte{capabilityQuery}) }
-func (b *Board) sendAnalogMappingQuery() { b.sendSysex([]byte{analogMappingQuery}) }
+func (b *Board) sendCapabilityQuery() (int, error) {
+	return b.sendSysex([]byte{capabilityQuery})
+}
+
+func (b *Board) sendAnalogMappingQuery() (int, error) {
+	return b.sendSysex([]byte{analogMappingQuery})
+}
 
 // -- Message Handling Functions -- //
 
 func (b *Board) handleAnalogMessage(m message) {
 	pinNum := m.data[0] & 0x0F
-	pinVal := m.data[1] | m.data[2]<<7
+	pinVal := int(m.data[1]) | int(m.data[2])<<7
 
 	if int(pinNum) < len(b.analogToNormal) {
 		b.m.Lock()
 		defer b.m.Unlock()
 
-		if pin, ok := b.pins[b.analogToNormal[pinNum]]; ok {
-			pin.analogVal = pinVal
+		pin, ok := b.pins[b.analogToNormal[pinNum]]
+		if !ok {
+			return
+		}
+		if pin.filter != nil {
+			pinVal = pin.filter.Next(pinVal)
+		}
+		publish := pin.setAnalog(pinVal, m.at)
+		pin.stream(Reading{m.at, pinVal})
+		if pin.decim != nil {
+			publish = pin.decim.allow(pinVal, m.at)
+		}
+		if publish {
+			b.publishPin(pin.num, KindAnalog, pinVal, m.at)
 		}
 	}
 }
@@ -453,13 +1053,12 @@ func (b *Board) handleDigitalMessage(m message) {
 	b.m.Lock()
 	defer b.m.Unlock()
 
-	// TODO: Instead of looping over all pins, find the first pin
-	//       of the port and loop over the next eight.
-	for _, pin := range b.pins {
-		if pin.port == portNum && pin.mode == INPUT {
-			i := pin.num % 8 // Find the pins number relative to the port
-			pinVal := (portVal >> (i & 0x07)) & 0x01
-			pin.digitalVal = pinVal
+	for i, pin := range b.ports[portNum] {
+		if pin != nil && pin.isInput() {
+			pinVal := (portVal >> byte(i)) & 0x01
+			if pin.setDigital(pinVal, m.at) {
+				b.publishPin(pin.num, KindDigital, int(pinVal), m.at)
+			}
 		}
 	}
 }
@@ -468,57 +1067,82 @@ func (b *Board) handleDigitalMessage(m message) {
 func (b *Board) handleReportVersion(m message) {
 	b.maj = m.data[1]
 	b.min = m.data[2]
+	b.heartbeatReply()
 }
 
 // Store the response from reportFirmware.
 func (b *Board) handleReportFirmware(m message) {
-	b.firmware = string(m.data[4 : len(m.data)-1])
+	// Major and minor version, then the name.
+	if body := m.body(); len(body) >= 2 {
+		b.fwMaj, b.fwMin = body[0], body[1]
+		b.firmware = string(firmatawire.Bytes7(body[2:]))
+	}
 
 	if !b.pinsInitialized {
 		// Let the init() func continue setting up the pins.
 		b.boardDoneReboot <- true
+	} else {
+		// Only sent unasked when the board boots, so it was reset.
+		b.restore()
 	}
 }
 
 // Parse the capability response and pass to initPins.
 func (b *Board) handleCapabilityResponse(m message) {
-	// Maps of pin# -> supported modes
-	analogPins := make(map[byte][]byte)
-	digitalPins := make(map[byte][]byte)
-
-	// Create a buffer containing just the pin mode (bytes 2 to END-1)
-	currentPin := byte(0)
-	buf := bytes.NewBuffer(m.data[2 : len(m.data)-1])
-	for buf.Len() > 0 {
+	analog, digital, truncated := parseCapabilityResponse(m.body())
+	if truncated {
+		b.log().Warn("Board reports too many pins, ignoring the rest", "max", maxPins)
+	}
+	b.initPins(analog, digital)
+}
+
+// Splits the body of a capabilityResponse into maps of pin# -> supported
+// modes. Pins past maxPins are dropped since pin numbers are bytes, which
+// is reported by truncated.
+func parseCapabilityResponse(data []byte) (analog, digital map[byte][]Capability, truncated bool) {
+	analog = make(map[byte][]Capability)
+	digital = make(map[byte][]Capability)
+
+	buf := bytes.NewBuffer(data)
+	for pin := 0; buf.Len() > 0; pin++ {
+		if pin >= maxPins {
+			truncated = true
+			break
+		}
 		d, _ := buf.ReadBytes(0x7F)
-		info := unpackPinModeDataSlice(d[:len(d)-1]) // drop the 0x7F delimiter
+		caps := parseCapabilities(d[:len(d)-1]) // drop the 0x7F delimiter
 
 		switch {
-		case bytes.Contains(info, []byte{ANALOG}):
-			analogPins[currentPin] = info
+		case supportsMode(caps, ANALOG):
+			analog[byte(pin)] = caps
 
-		case bytes.Contains(info, []byte{INPUT, OUTPUT}):
-			digitalPins[currentPin] = info
+		case supportsMode(caps, INPUT) && supportsMode(caps, OUTPUT):
+			digital[byte(pin)] = caps
 		}
-		currentPin++
 	}
-	b.initPins(analogPins, digitalPins)
+	return
 }
 
 // Sets the analogMapping values.
 func (b *Board) handleAnalogMappingResponse(m message) {
 	// For each key value pair, the key is the regular pin number, and
 	// the value is the analog pin number, or 0x7F (127) if the pin
-	// does not support analog.
-	for pin, num := range m.data[2 : len(m.data)-1] {
-		b.analogMapping[byte(pin)] = num
+	// does not support analog. A new map replaces the old one so pins
+	// dropped by a refresh do not linger.
+	mapping := make(map[byte]byte)
+	for pin, num := range m.body() {
+		if pin >= maxPins {
+			break
+		}
+		mapping[byte(pin)] = num
 
 		// Hack until I figure out why Firmata sends
 		// A13 (pin 67) as A10.
 		// TODO: FIXME
 		if pin == 67 {
-			b.analogMapping[byte(pin)] = 13
+			mapping[byte(pin)] = 13
 		}
 
 	}
+	b.analogMapping = mapping
 }
@@ -0,0 +1,508 @@
+package gadget
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+// Returns a board with the given pins, as New leaves it once the
+// capability query is answered, writing to out, or discarding if nil.
+// Analog pins are numbered A0 up in pin order.
+func newTestBoard(t *testing.T, out io.Writer, analog, digital map[byte][]Capability) *Board {
+	t.Helper()
+	if out == nil {
+		out = io.Discard
+	}
+	b := &Board{
+		pins:          make(map[byte]*pin),
+		analogMapping: map[byte]byte{0: 0x7F},
+		ready:         make(chan bool, 1),
+		i2cReplies:    make(chan i2cReplyData, 1),
+		pinStates:     make(chan []byte, 1),
+		msgHandlers:   make(cbMap),
+		bus:           NewEventBus(),
+		out:           newBatchWriter(out),
+		logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	var nums []byte
+	for n := range analog {
+		nums = append(nums, n)
+	}
+	slices.Sort(nums)
+	for i, n := range nums {
+		b.analogMapping[n] = byte(i)
+	}
+	b.initPins(analog, digital)
+	return b
+}
+
+func TestParseCapabilities(t *testing.T) {
+	caps := parseCapabilities([]byte{INPUT, 1, OUTPUT, 1, ANALOG, 10})
+	if len(caps) != 3 || caps[2] != (Capability{ANALOG, 10}) || !supportsMode(caps, OUTPUT) || supportsMode(caps, PWM) {
+		t.Fatalf("Unexpected capabilities: %v", caps)
+	}
+	if parseCapabilities([]byte{INPUT, 1, OUTPUT}) != nil {
+		t.Fatalf("Odd length capability data should be rejected")
+	}
+}
+
+func TestParseCapabilityResponse(t *testing.T) {
+	var data []byte
+	for i := 0; i < maxPins+2; i++ {
+		data = append(data, INPUT, 1, OUTPUT, 1, 0x7F)
+	}
+	data = append(data[:5], append([]byte{ANALOG, 10, 0x7F}, data[5:]...)...)
+
+	analog, digital, truncated := parseCapabilityResponse(data)
+	if !truncated {
+		t.Fatalf("Pins past %d should be reported as dropped", maxPins)
+	}
+	if len(analog) != 1 || analog[1] == nil {
+		t.Fatalf("Expected pin 1 to be analog, got %v", analog)
+	}
+	if len(digital) != maxPins-1 {
+		t.Fatalf("Expected %d digital pins, got %d", maxPins-1, len(digital))
+	}
+	if _, ok := digital[maxPins-1]; !ok {
+		t.Fatalf("Pin %d should be digital", maxPins-1)
+	}
+}
+
+func TestHighPins(t *testing.T) {
+	var out bytes.Buffer
+	caps := []Capability{{INPUT, 1}, {OUTPUT, 1}, {PWM, 8}, {SERVO, 14}}
+	b := newTestBoard(t, &out, nil, map[byte][]Capability{8: caps, 136: caps})
+	out.Reset()
+
+	// Every message carries a pin in one 7-bit byte, so pin 136 can't
+	// be sent without aliasing pin 8.
+	if _, ok := b.pins[136]; !ok {
+		t.Fatalf("Pin 136 should be in the pin table")
+	}
+	if err := b.DigitalWrite(136, HIGH); err == nil {
+		t.Fatalf("Pins above 127 have no port to write")
+	}
+	if err := b.SetPinMode(136, INPUT); err == nil {
+		t.Fatalf("Pins above 127 can't be set with SET_PIN_MODE")
+	}
+	if err := b.AnalogWrite(136, 200); err == nil {
+		t.Fatalf("AnalogWrite(136) should fail")
+	}
+	if err := b.ServoConfig(136, 544, 2400); err == nil {
+		t.Fatalf("ServoConfig(136) should fail")
+	}
+	if _, err := b.QueryPinState(136); err == nil {
+		t.Fatalf("QueryPinState(136) should fail")
+	}
+	if _, err := NewIRReceiver(b, 136, IRSysex); err == nil {
+		t.Fatalf("NewIRReceiver(136) should fail")
+	}
+	if _, err := NewQTRRC(b, []byte{8, 136}, QTRSysex); err == nil {
+		t.Fatalf("NewQTRRC with pin 136 should fail")
+	}
+	if m := b.PortToPinMapping(); len(m[1]) != 1 {
+		t.Fatalf("Port 1 should only hold pin 8, got %v", m[1])
+	}
+	b.out.Flush()
+	if out.Len() != 0 {
+		t.Fatalf("Sent % X for pin 136", out.Bytes())
+	}
+}
+
+func TestPortMessages(t *testing.T) {
+	var out bytes.Buffer
+	caps := []Capability{{INPUT, 1}, {OUTPUT, 1}}
+	b := newTestBoard(t, &out, nil, map[byte][]Capability{8: caps, 9: caps, 11: caps})
+	b.pins[9].mode = INPUT
+
+	// Pin 9 is the second pin of port 1. Output pins ignore reports.
+	sub := b.bus.Subscribe("pin/+/digital", 1)
+	at := time.Now().Add(-time.Second)
+	b.handleDigitalMessage(message{data: []byte{digitalMessage | 1, 0x03, 0x00}, at: at})
+	if b.pins[9].digitalVal != HIGH || b.pins[8].digitalVal != LOW {
+		t.Fatalf("Port report should only set input pins")
+	}
+	if e := <-sub.C; e.Pin != 9 || !e.Time.Equal(at) {
+		t.Fatalf("Event should carry the message time, got %+v", e)
+	}
+	if updated, _ := b.LastUpdated(9); !updated.Equal(at) {
+		t.Fatalf("LastUpdated = %s, want %s", updated, at)
+	}
+
+	// Missing pin 10 is left low.
+	out.Reset()
+	if err := b.DigitalWrite(11, HIGH); err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{digitalMessage | 1, 0x0A, 0x00}; !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("DigitalWrite sent % X, want % X", out.Bytes(), want)
+	}
+
+	// Pins 8 and 11 change in one message, pin 9 is left alone.
+	out.Reset()
+	if err := b.WritePort(1, 0x09, 0x01); err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{digitalMessage | 1, 0x03, 0x00}; !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("WritePort sent % X, want % X", out.Bytes(), want)
+	}
+	if b.pins[8].digitalVal != HIGH || b.pins[11].digitalVal != LOW {
+		t.Fatalf("WritePort should update the pins")
+	}
+	if err := b.WritePort(1, 0x04, 0x04); err == nil {
+		t.Fatalf("Writing missing pin 10 should fail")
+	}
+
+	// Nothing is written if any pin is invalid.
+	out.Reset()
+	if err := b.DigitalWritePins(map[byte]byte{8: LOW, 10: HIGH}); err == nil || out.Len() != 0 {
+		t.Fatalf("DigitalWritePins with missing pin 10 should fail and write nothing")
+	}
+	if err := b.DigitalWritePins(map[byte]byte{8: LOW, 11: HIGH}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{digitalMessage | 1, 0x0A, 0x00}; !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("DigitalWritePins sent % X, want % X", out.Bytes(), want)
+	}
+}
+
+func TestRefreshPins(t *testing.T) {
+	caps := []Capability{{INPUT, 1}, {OUTPUT, 1}}
+	b := newTestBoard(t, nil, nil, map[byte][]Capability{8: caps, 9: caps})
+	<-b.ready
+	b.pins[9].mode = INPUT
+	b.pins[9].digitalVal = HIGH
+	sub := b.bus.Subscribe(TopicHardware, 2)
+
+	// Pin 8 is gone, pin 9 can no longer be an input and pin 10 is new.
+	done := make(chan bool)
+	b.refreshed = done
+	b.initPins(nil, map[byte][]Capability{9: {{OUTPUT, 1}}, 10: caps})
+	select {
+	case <-done:
+	default:
+		t.Fatalf("Refresh should be signalled")
+	}
+	select {
+	case e := <-sub.C:
+		want := HardwareChange{Added: []byte{10}, Removed: []byte{8}, Changed: []byte{9}}
+		if !reflect.DeepEqual(e.Data, want) {
+			t.Fatalf("Hardware change %+v, want %+v", e.Data, want)
+		}
+	default:
+		t.Fatalf("Expected TopicHardware")
+	}
+	if len(b.ready) != 0 {
+		t.Fatalf("A refresh should not signal ready again")
+	}
+
+	if _, ok := b.pins[8]; ok || b.ports[1][0] != nil {
+		t.Fatalf("Pin 8 should be removed")
+	}
+	if p := b.pins[9]; p.mode != OUTPUT || p.digitalVal != LOW {
+		t.Fatalf("Pin 9 should be reset, got mode %s", PinModeString[p.mode])
+	}
+	if b.pins[10] == nil || b.ports[1][2] != b.pins[10] {
+		t.Fatalf("Pin 10 should be added")
+	}
+
+	// Pins whose mode is still supported keep their state.
+	b.pins[10].digitalVal = HIGH
+	p := b.pins[10]
+	b.initPins(nil, map[byte][]Capability{9: {{OUTPUT, 1}}, 10: caps})
+	if b.pins[10] != p || p.digitalVal != HIGH {
+		t.Fatalf("Pin 10 should be kept")
+	}
+	if len(sub.C) != 0 {
+		t.Fatalf("Unchanged pins should not publish TopicHardware")
+	}
+}
+
+func TestReadTimeout(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	b := &Board{serial: r, quit: make(chan bool), bus: NewEventBus()}
+	b.buf = bufio.NewReader(r)
+	b.dec = firmatawire.NewDecoder(b.buf)
+	sub := b.bus.Subscribe(TopicError, 4)
+
+	if err = b.SetReadTimeout(10 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	b.run()
+
+	expectTimeout := func() {
+		select {
+		case e := <-sub.C:
+			if e.Err != ErrReadTimeout {
+				t.Fatalf("Expected ErrReadTimeout, got %v", e.Err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected a timeout")
+		}
+	}
+	expectTimeout()
+
+	// Data from the board resets the timeout.
+	w.Write([]byte{reportVersion, 2, 5})
+	expectTimeout()
+	if s := b.Stats(); s.FramesIn["REPORT_VERSION"] != 1 || s.BytesIn != 3 {
+		t.Fatalf("Unexpected input stats: %+v", s)
+	}
+
+	close(b.quit)
+	r.Close()
+	select {
+	case e := <-sub.C:
+		t.Fatalf("Unexpected error after closing: %v", e.Err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestHandlerPanic(t *testing.T) {
+	b := &Board{
+		msgHandlers: make(cbMap),
+		bus:         NewEventBus(),
+		logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	sub := b.bus.Subscribe(TopicError, 1)
+	calls := 0
+	b.addHandler(reportVersion, func(m message) {
+		if calls++; calls == 1 {
+			panic("boom")
+		}
+	})
+
+	msg := message{t: midiMsg, data: []byte{reportVersion, 2, 5}}
+	b.handleCallback(msg)
+	if e := <-sub.C; e.Err == nil || !strings.Contains(e.Err.Error(), "boom") {
+		t.Fatalf("Expected the panic on TopicError, got %v", e.Err)
+	}
+	b.handleCallback(msg)
+	if calls != 2 {
+		t.Fatalf("Handler should still be called after a panic")
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	b := &Board{bus: NewEventBus()}
+	WithLogger(slog.New(slog.NewTextHandler(&buf, nil)))(b)
+
+	b.reportError(errors.New("Lost sync"))
+	if !strings.Contains(buf.String(), `level=ERROR msg="Lost sync"`) {
+		t.Fatalf("Error not logged to the logger: %q", buf.String())
+	}
+
+	// Messages are only logged at debug level.
+	msg := message{t: midiMsg, data: []byte{digitalMessage | 1, 0x01, 0x00}}
+	buf.Reset()
+	b.handleCallback(msg)
+	if buf.Len() != 0 {
+		t.Fatalf("Message logged at info level: %q", buf.String())
+	}
+	WithLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))(b)
+	b.handleCallback(msg)
+	if !strings.Contains(buf.String(), `level=DEBUG msg="Received message" cmd=DIGITAL_MESSAGE data="91 01 00"`) {
+		t.Fatalf("Message not logged at debug level: %q", buf.String())
+	}
+}
+
+func TestAnalogOut(t *testing.T) {
+	var out bytes.Buffer
+	b := newTestBoard(t, &out, nil, map[byte][]Capability{66: {{OUTPUT, 1}, {PWM, 12}}})
+	b.SetPinMode(66, PWM)
+
+	if bits, _ := b.AnalogOutResolution(66); bits != 12 {
+		t.Fatalf("AnalogOutResolution = %d, want 12", bits)
+	}
+	out.Reset()
+	if err := b.AnalogOut(66, 4095); err != nil {
+		t.Fatal(err)
+	}
+	if want := firmatawire.Sysex(extendedAnalog, 66, 0x7F, 0x1F); !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("AnalogOut sent % X, want % X", out.Bytes(), want)
+	}
+	if err := b.AnalogOut(66, 4096); err == nil {
+		t.Fatalf("4096 should not fit in 12 bits")
+	}
+}
+
+func TestWithBaud(t *testing.T) {
+	if _, err := New("/dev/null", WithBaud(0)); err == nil || !strings.Contains(err.Error(), "baud") {
+		t.Fatalf("Expected an invalid baud error, got %v", err)
+	}
+}
+
+// Answers the handshake like StandardFirmata on a board with digital
+// pins 2 and 3 and analog pin 14 (A0), until the connection closes.
+// Boards that reset on open announce their firmware.
+func fakeFirmata(conn net.Conn, reset bool) {
+	announce := func() {
+		conn.Write([]byte{reportVersion, 2, 5})
+		conn.Write(firmatawire.Sysex(reportFirmware, firmatawire.AppendBytes7([]byte{2, 5}, []byte("StandardFirmata"))...))
+	}
+	if reset {
+		announce()
+	}
+
+	d := firmatawire.NewDecoder(conn)
+	for {
+		f, err := d.Next()
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrClosedPipe) {
+			return
+		}
+		if err != nil || !f.Sysex() {
+			continue
+		}
+		switch f.Command() {
+		case reportFirmware:
+			announce()
+		case analogMappingQuery:
+			mapping := bytes.Repeat([]byte{0x7F}, 15)
+			mapping[14] = 0
+			conn.Write(firmatawire.Sysex(analogMappingResponse, mapping...))
+		case capabilityQuery:
+			var caps []byte
+			for pin := 0; pin < 15; pin++ {
+				switch pin {
+				case 2, 3:
+					caps = append(caps, INPUT, 1, OUTPUT, 1)
+				case 14:
+					caps = append(caps, ANALOG, 10)
+				}
+				caps = append(caps, 0x7F)
+			}
+			conn.Write(firmatawire.Sysex(capabilityResponse, caps...))
+		}
+	}
+}
+
+func TestWithTransport(t *testing.T) {
+	host, board := net.Pipe()
+	go fakeFirmata(board, true)
+
+	b, err := New("fake", WithTransport(host), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if b.Firmware() != "StandardFirmata 2.5" || len(b.Pins()) != 3 || b.String() != "Arduino on device 'fake'" {
+		t.Fatalf("Unexpected board %s with %d pins", b.Firmware(), len(b.Pins()))
+	}
+	if mode, _ := b.PinMode(14); mode != ANALOG {
+		t.Fatalf("Pin 14 should be analog, got %s", PinModeString[mode])
+	}
+}
+
+func TestWithoutReset(t *testing.T) {
+	host, board := net.Pipe()
+	go fakeFirmata(board, false)
+
+	b, err := New("fake", WithTransport(host), WithoutReset(), WithSettleDelay(time.Millisecond),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if b.Firmware() != "StandardFirmata 2.5" {
+		t.Fatalf("Unexpected firmware %s", b.Firmware())
+	}
+	if err = b.Reset(); err == nil {
+		t.Fatalf("A pipe has no DTR line")
+	}
+}
+
+func TestAnalogReportingLimit(t *testing.T) {
+	var out bytes.Buffer
+	analog := map[byte][]Capability{70: {{ANALOG, 10}}, 71: {{ANALOG, 10}}}
+	b := newTestBoard(t, &out, analog, nil)
+
+	// Renumber them A16 and A15, like a board with more analog inputs.
+	b.analogMapping = map[byte]byte{70: 16, 71: 15}
+	b.initPins(analog, nil)
+
+	// A16 would be sent as A0.
+	out.Reset()
+	if err := b.SetPinReporting(70, true); err == nil || out.Len() != 0 {
+		t.Fatalf("Reporting A16 should fail without sending anything")
+	}
+	if err := b.SetPinReporting(71, true); err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{reportAnalog | 15, 1}; !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("Reporting A15 sent % X, want % X", out.Bytes(), want)
+	}
+}
+
+func TestStopReporting(t *testing.T) {
+	var out bytes.Buffer
+	digital := []Capability{{INPUT, 1}, {OUTPUT, 1}}
+	b := newTestBoard(t, &out, map[byte][]Capability{14: {{ANALOG, 10}}}, map[byte][]Capability{2: digital, 3: digital})
+	for _, n := range []byte{2, 3} {
+		b.SetPinMode(n, INPUT)
+		b.SetPinReporting(n, true)
+	}
+	b.SetPinReporting(14, true)
+
+	out.Reset()
+	b.stopReporting()
+	got := out.Bytes()
+	for _, msg := range [][]byte{{reportDigital, 0}, {reportAnalog, 0}} {
+		if bytes.Count(got, msg) != 1 {
+			t.Fatalf("Expected % X once in % X", msg, got)
+		}
+	}
+	if len(got) != 4 || b.pins[2].reporting || b.pins[3].reporting || b.pins[14].reporting {
+		t.Fatalf("Every pin should stop reporting, sent % X", got)
+	}
+}
+
+func TestPortReporting(t *testing.T) {
+	var out bytes.Buffer
+	digital := []Capability{{INPUT, 1}, {OUTPUT, 1}}
+	b := newTestBoard(t, &out, nil, map[byte][]Capability{2: digital, 3: digital, 4: digital})
+	for _, n := range []byte{2, 3, 4} {
+		b.SetPinMode(n, INPUT)
+	}
+
+	expect := func(want []byte) {
+		t.Helper()
+		if !bytes.Equal(out.Bytes(), want) {
+			t.Fatalf("Sent % X, want % X", out.Bytes(), want)
+		}
+		out.Reset()
+	}
+	out.Reset()
+	b.SetPinReporting(2, true)
+	expect([]byte{reportDigital, 1})
+	b.SetPinReporting(3, true)
+	b.SetPinReporting(4, true)
+	expect(nil)
+
+	// The port keeps reporting until its last reporting pin stops or
+	// changes mode.
+	b.SetPinReporting(2, false)
+	b.SetPinMode(3, OUTPUT)
+	expect(firmatawire.SetMode(3, OUTPUT))
+	b.SetPinReporting(4, false)
+	expect([]byte{reportDigital, 0})
+}
@@ -0,0 +1,47 @@
+package gadget
+
+import (
+	"fmt"
+	"sort"
+)
+
+// CalibrationPoint pairs a raw reading with the value it represents.
+type CalibrationPoint struct {
+	Raw   int
+	Value float64
+}
+
+// Curve is a piecewise linear calibration curve. Readings between two
+// points are linearly interpolated, readings outside the curve are
+// clamped to the first or last point.
+type Curve []CalibrationPoint
+
+// Map converts a raw reading to its calibrated value.
+func (c Curve) Map(raw int) (v float64, err error) {
+	if len(c) == 0 {
+		return 0, fmt.Errorf("Calibration curve is empty")
+	}
+
+	pts := make(Curve, len(c))
+	copy(pts, c)
+	sort.Slice(pts, func(i, j int) bool { return pts[i].Raw < pts[j].Raw })
+
+	switch {
+	case raw <= pts[0].Raw:
+		return pts[0].Value, nil
+	case raw >= pts[len(pts)-1].Raw:
+		return pts[len(pts)-1].Value, nil
+	}
+
+	// Find the first point above raw and interpolate from the one before it.
+	i := sort.Search(len(pts), func(i int) bool { return pts[i].Raw > raw })
+	lo, hi := pts[i-1], pts[i]
+	t := float64(raw-lo.Raw) / float64(hi.Raw-lo.Raw)
+	return lo.Value + t*(hi.Value-lo.Value), nil
+}
+
+// Returns the largest raw value an analog input of the
+// given resolution can report.
+func analogMax(bits byte) int {
+	return 1<<bits - 1
+}
@@ -0,0 +1,38 @@
+package gadget
+
+import (
+	"testing"
+)
+
+func TestCurveMap(t *testing.T) {
+	c := Curve{
+		{Raw: 1000, Value: 10},
+		{Raw: 0, Value: 1000},
+		{Raw: 500, Value: 100},
+	}
+
+	tests := []struct {
+		raw  int
+		want float64
+	}{
+		{-5, 1000},
+		{0, 1000},
+		{250, 550},
+		{500, 100},
+		{750, 55},
+		{2000, 10},
+	}
+	for _, tt := range tests {
+		got, err := c.Map(tt.raw)
+		if err != nil {
+			t.Fatalf("Map(%d) returned error: %s", tt.raw, err)
+		}
+		if got != tt.want {
+			t.Errorf("Map(%d) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+
+	if _, err := (Curve{}).Map(10); err == nil {
+		t.Fatalf("Empty curve should return an error")
+	}
+}
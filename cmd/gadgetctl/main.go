@@ -0,0 +1,275 @@
+// Command gadgetctl is a quick way to inspect and drive a Firmata board
+// from the shell.
+//
+// Usage:
+//
+//	gadgetctl [-port device] command [args]
+//
+// The commands are:
+//
+//	info               Show the board's firmware and protocol version.
+//	snapshot           Print the board's full state as JSON, for bug reports.
+//	pins               List every pin with its mode and value.
+//	read pin           Print a pin's value, waiting for a report from inputs.
+//	write pin value    Write a value according to the pin's mode.
+//	mode pin mode      Set a pin's mode, e.g. OUTPUT or PWM.
+//	monitor [pin...]   Print pin changes until interrupted. Inputs are
+//	                   asked to report.
+//	i2c-scan           List the addresses of the devices on the I2C bus.
+//
+// Without -port the first board found by gadget.FindSerial is used.
+// With -flash, e.g. -board uno -flash StandardFirmata.ino.hex, the image
+// is uploaded to a board that does not answer as Firmata.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ZachMassia/GoGoGadget"
+	"github.com/ZachMassia/GoGoGadget/firmware"
+)
+
+type command struct {
+	args string // Argument usage, shown in errors.
+	min  int    // Minimum number of arguments.
+	run  func(b *gadget.Board, args []string) error
+}
+
+var commands = map[string]command{
+	"info":     {"", 0, info},
+	"snapshot": {"", 0, snapshot},
+	"pins":     {"", 0, pins},
+	"read":     {"pin", 1, read},
+	"write":    {"pin value", 2, write},
+	"mode":     {"pin mode", 2, mode},
+	"monitor":  {"[pin...]", 0, monitor},
+	"i2c-scan": {"", 0, i2cScan},
+}
+
+var (
+	port  = flag.String("port", "", "Serial device of the board.")
+	baud  = flag.Int("baud", 57600, "Baud rate of the board's firmware, 0 to detect it.")
+	flash = flag.String("flash", "", "StandardFirmata image to upload if the board has none, see -board.")
+	kind  = flag.String("board", "uno", "Kind of board for -flash: "+strings.Join(firmware.Names(), ", ")+".")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: gadgetctl [flags] info|snapshot|pins|read|write|mode|monitor|i2c-scan [args]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	name, args := flag.Arg(0), flag.Args()[1:]
+	cmd, ok := commands[name]
+	if !ok {
+		fatalf("Unknown command '%s'", name)
+	}
+	if len(args) < cmd.min {
+		fatalf("usage: gadgetctl %s %s", name, cmd.args)
+	}
+
+	device := *port
+	if device == "" {
+		found := gadget.FindSerial()
+		if len(found) == 0 {
+			fatalf("No board found, use -port")
+		}
+		device = found[0]
+	}
+
+	opts := []gadget.Option{gadget.WithBaud(*baud)}
+	if *baud == 0 {
+		opts = []gadget.Option{gadget.WithBaudDetection()}
+	}
+	if *flash != "" {
+		opts = append(opts, gadget.WithFirmwareUpload(func(ctx context.Context, device string) error {
+			return firmware.FlashFile(ctx, device, *kind, *flash)
+		}))
+	}
+	b, err := gadget.New(device, opts...)
+	if err != nil {
+		fatalf("Error connecting to %s: %s", device, err)
+	}
+	err = cmd.run(b, args)
+	b.Close()
+	if err != nil {
+		fatalf("%s", err)
+	}
+}
+
+func info(b *gadget.Board, args []string) error {
+	fmt.Printf("Firmware: %s\nProtocol: %s\nPins:     %d\nFeatures: %v\n",
+		b.Firmware(), b.Version(), len(b.Pins()), b.Features())
+	return nil
+}
+
+func snapshot(b *gadget.Board, args []string) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b.Snapshot())
+}
+
+func pins(b *gadget.Board, args []string) error {
+	for _, n := range pinNumbers(b) {
+		p, err := readPin(b, n)
+		if err != nil {
+			return err
+		}
+		printPin(p)
+	}
+	return nil
+}
+
+func read(b *gadget.Board, args []string) error {
+	n, err := parsePin(args[0])
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	reporting, err := report(b, n)
+	if err != nil {
+		return err
+	}
+	// A fresh connection knows nothing of inputs until they report.
+	for reporting {
+		if t, _ := b.LastUpdated(n); t.After(start) {
+			break
+		}
+		if time.Since(start) > time.Second {
+			return fmt.Errorf("Pin %d did not report", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_, v, err := b.ReadValue(n)
+	if err != nil {
+		return err
+	}
+	fmt.Println(v)
+	return nil
+}
+
+func write(b *gadget.Board, args []string) error {
+	n, err := parsePin(args[0])
+	if err != nil {
+		return err
+	}
+	v, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("Invalid value: %s", args[1])
+	}
+	return b.WriteValue(n, v)
+}
+
+func mode(b *gadget.Board, args []string) error {
+	n, err := parsePin(args[0])
+	if err != nil {
+		return err
+	}
+	m, ok := gadget.ParsePinMode(args[1])
+	if !ok {
+		return fmt.Errorf("Unknown pin mode '%s'", args[1])
+	}
+	if cur, _ := b.PinMode(n); cur == m {
+		return nil
+	}
+	return b.SetPinMode(n, m)
+}
+
+func monitor(b *gadget.Board, args []string) error {
+	var nums []byte
+	for _, a := range args {
+		n, err := parsePin(a)
+		if err != nil {
+			return err
+		}
+		nums = append(nums, n)
+	}
+	watched := nums
+	if watched == nil {
+		watched = pinNumbers(b)
+	}
+	for _, n := range watched {
+		if _, err := report(b, n); err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return b.WatchValues(ctx, nums, func(v gadget.PinValue) error {
+		fmt.Print(v.Time.Format("15:04:05.000 "))
+		printPin(v)
+		return nil
+	})
+}
+
+func i2cScan(b *gadget.Board, args []string) error {
+	if err := b.I2CConfig(0); err != nil {
+		return err
+	}
+	addrs := b.I2CScan()
+	for _, a := range addrs {
+		fmt.Printf("0x%02X\n", a)
+	}
+	if len(addrs) == 0 {
+		fmt.Println("No I2C devices found")
+	}
+	return nil
+}
+
+// Turns on reporting of pin n if it's an input, returning whether it
+// was.
+func report(b *gadget.Board, n byte) (bool, error) {
+	mode, err := b.PinMode(n)
+	if err != nil {
+		return false, err
+	}
+	switch mode {
+	case gadget.INPUT, gadget.PULLUP, gadget.ANALOG:
+		return true, b.SetPinReporting(n, true)
+	}
+	return false, nil
+}
+
+func readPin(b *gadget.Board, n byte) (p gadget.PinValue, err error) {
+	p.Pin = n
+	p.Mode, p.Value, err = b.ReadValue(n)
+	return
+}
+
+func printPin(p gadget.PinValue) {
+	fmt.Printf("%3d  %-8s %d\n", p.Pin, gadget.PinModeString[p.Mode], p.Value)
+}
+
+func pinNumbers(b *gadget.Board) (nums []byte) {
+	for _, p := range b.Pins() {
+		nums = append(nums, p.Pin)
+	}
+	return
+}
+
+func parsePin(s string) (byte, error) {
+	n, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid pin: %s", s)
+	}
+	return byte(n), nil
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "gadgetctl: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,214 @@
+package gadget
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// Fixed I2C addresses.
+	HMC5883LAddress byte = 0x1E
+	QMC5883LAddress byte = 0x0D
+
+	hmcRegConfigA byte  = 0x00
+	hmcRegConfigB byte  = 0x01
+	hmcRegMode    byte  = 0x02
+	hmcRegData    byte  = 0x03 // X, Z, Y; big endian.
+	hmcConfigA    byte  = 0x70 // 8 sample average, 15Hz output.
+	hmcOverflow   int16 = -4096
+
+	qmcRegData   byte = 0x00 // X, Y, Z; little endian.
+	qmcRegCtrl1  byte = 0x09
+	qmcRegPeriod byte = 0x0B
+	qmcCtrl1     byte = 0x05 // 512x oversampling, 50Hz, continuous.
+)
+
+// Vector is a three axis reading.
+type Vector struct {
+	X, Y, Z float64
+}
+
+// A selectable measurement range and its sensitivity.
+type compassRange struct {
+	code  byte    // Register bits selecting the range.
+	gauss float64 // Full scale, ±gauss.
+	lsb   float64 // Counts per gauss.
+}
+
+var (
+	hmcRanges = []compassRange{
+		{0, 0.88, 1370}, {1, 1.3, 1090}, {2, 1.9, 820}, {3, 2.5, 660},
+		{4, 4.0, 440}, {5, 4.7, 390}, {6, 5.6, 330}, {7, 8.1, 230},
+	}
+	qmcRanges = []compassRange{
+		{0, 2, 12000}, {1, 8, 3000},
+	}
+)
+
+// Compass is a Honeywell HMC5883L or QST QMC5883L three axis
+// magnetometer, run in continuous measurement mode.
+type Compass struct {
+	board  *Board
+	addr   byte
+	qmc    bool
+	ranges []compassRange
+
+	// Hard-iron offset in gauss, subtracted from every reading.
+	// Calibrate measures it.
+	Offset Vector
+
+	// Magnetic declination in degrees, added to Heading to
+	// give true north. East is positive.
+	Declination float64
+
+	m     sync.Mutex
+	scale compassRange
+}
+
+// NewHMC5883L returns the HMC5883L with a ±1.3 gauss range. I2CConfig
+// must already have been called.
+func NewHMC5883L(b *Board) (c *Compass, err error) {
+	c = &Compass{board: b, addr: HMC5883LAddress, ranges: hmcRanges}
+	if err = b.I2CWrite(c.addr, []byte{hmcRegConfigA, hmcConfigA}); err != nil {
+		return nil, err
+	}
+	if err = c.SetRange(1.3); err != nil {
+		return nil, err
+	}
+	if err = b.I2CWrite(c.addr, []byte{hmcRegMode, 0x00}); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// NewQMC5883L returns the QMC5883L with a ±2 gauss range. I2CConfig
+// must already have been called.
+func NewQMC5883L(b *Board) (c *Compass, err error) {
+	c = &Compass{board: b, addr: QMC5883LAddress, qmc: true, ranges: qmcRanges}
+	if err = b.I2CWrite(c.addr, []byte{qmcRegPeriod, 0x01}); err != nil {
+		return nil, err
+	}
+	if err = c.SetRange(2); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// SetRange selects the smallest measurement range covering ±gauss.
+// Smaller ranges have a higher resolution.
+func (c *Compass) SetRange(gauss float64) (err error) {
+	r := c.ranges[len(c.ranges)-1]
+	for _, cr := range c.ranges {
+		if cr.gauss >= gauss {
+			r = cr
+			break
+		}
+	}
+
+	if c.qmc {
+		err = c.board.I2CWrite(c.addr, []byte{qmcRegCtrl1, qmcCtrl1 | r.code<<4})
+	} else {
+		err = c.board.I2CWrite(c.addr, []byte{hmcRegConfigB, r.code << 5})
+	}
+	if err != nil {
+		return err
+	}
+
+	c.m.Lock()
+	c.scale = r
+	c.m.Unlock()
+	return
+}
+
+// Read returns the field strength in gauss, with the hard-iron
+// offset removed.
+func (c *Compass) Read() (v Vector, err error) {
+	v, err = c.readRaw()
+	if err != nil {
+		return v, err
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	v.X -= c.Offset.X
+	v.Y -= c.Offset.Y
+	v.Z -= c.Offset.Z
+	return
+}
+
+// Heading returns the compass heading in degrees (0-360), assuming
+// the sensor is level.
+func (c *Compass) Heading() (deg float64, err error) {
+	v, err := c.Read()
+	if err != nil {
+		return 0, err
+	}
+
+	c.m.Lock()
+	deg = math.Atan2(v.Y, v.X)*180/math.Pi + c.Declination
+	c.m.Unlock()
+
+	deg = math.Mod(deg, 360)
+	if deg < 0 {
+		deg += 360
+	}
+	return
+}
+
+// Calibrate measures the hard-iron offset by sampling for the given
+// duration while the sensor is rotated through every orientation. The
+// offset is the center of the smallest box holding every sample.
+func (c *Compass) Calibrate(d time.Duration) (err error) {
+	min := Vector{math.Inf(1), math.Inf(1), math.Inf(1)}
+	max := Vector{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+
+	n := 0
+	for end := time.Now().Add(d); time.Now().Before(end); time.Sleep(defaultSampleDelay) {
+		v, err := c.readRaw()
+		if err != nil {
+			continue // Overflows are expected while rotating.
+		}
+		min.X, max.X = math.Min(min.X, v.X), math.Max(max.X, v.X)
+		min.Y, max.Y = math.Min(min.Y, v.Y), math.Max(max.Y, v.Y)
+		min.Z, max.Z = math.Min(min.Z, v.Z), math.Max(max.Z, v.Z)
+		n++
+	}
+	if n == 0 {
+		return fmt.Errorf("Compass calibration took no samples")
+	}
+
+	c.m.Lock()
+	c.Offset = Vector{(min.X + max.X) / 2, (min.Y + max.Y) / 2, (min.Z + max.Z) / 2}
+	c.m.Unlock()
+	return
+}
+
+// Reads the data registers and scales them to gauss.
+func (c *Compass) readRaw() (v Vector, err error) {
+	var x, y, z int16
+	if c.qmc {
+		d, err := c.board.I2CReadRegister(c.addr, qmcRegData, 6)
+		if err != nil {
+			return v, err
+		}
+		x, y, z = int16(le16(d, 0)), int16(le16(d, 2)), int16(le16(d, 4))
+	} else {
+		d, err := c.board.I2CReadRegister(c.addr, hmcRegData, 6)
+		if err != nil {
+			return v, err
+		}
+		x, z, y = int16(be16(d, 0)), int16(be16(d, 2)), int16(be16(d, 4))
+		if x == hmcOverflow || y == hmcOverflow || z == hmcOverflow {
+			return v, fmt.Errorf("HMC5883L reading overflowed, use a larger range")
+		}
+	}
+
+	c.m.Lock()
+	lsb := c.scale.lsb
+	c.m.Unlock()
+
+	return Vector{float64(x) / lsb, float64(y) / lsb, float64(z) / lsb}, nil
+}
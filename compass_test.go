@@ -0,0 +1,72 @@
+package gadget
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestHMC5883L(t *testing.T) {
+	b, f := newI2CTestBoard(t, 1)
+	c, err := NewHMC5883L(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]byte{{hmcRegConfigA, hmcConfigA}, {hmcRegConfigB, 0x20}, {hmcRegMode, 0x00}}
+	for i := range want {
+		if !bytes.Equal(f.writes[i], want[i]) {
+			t.Fatalf("Write %d = % X, want % X", i, f.writes[i], want[i])
+		}
+	}
+
+	// X, Z, Y big endian, at 1090 counts per gauss.
+	copy(f.chip(HMC5883LAddress)[hmcRegData:], []byte{0x04, 0x42, 0xFD, 0xDF, 0x08, 0x84})
+	v, err := c.Read()
+	if err != nil || v != (Vector{1, 2, -0.5}) {
+		t.Fatalf("Read = %+v, %v, want {1 2 -0.5}", v, err)
+	}
+
+	c.Offset = Vector{1, 1, 0}
+	if deg, err := c.Heading(); err != nil || math.Abs(deg-90) > 1e-9 {
+		t.Fatalf("Heading = %f, %v, want 90", deg, err)
+	}
+
+	// 8.1 gauss is the largest range.
+	if err = c.SetRange(10); err != nil || !bytes.Equal(f.writes[len(f.writes)-1], []byte{hmcRegConfigB, 0xE0}) {
+		t.Fatalf("SetRange(10) wrote % X, %v", f.writes[len(f.writes)-1], err)
+	}
+
+	copy(f.chip(HMC5883LAddress)[hmcRegData:], []byte{0xF0, 0x00})
+	if _, err = c.Read(); err == nil {
+		t.Fatalf("Expected an overflow error")
+	}
+}
+
+func TestQMC5883L(t *testing.T) {
+	b, f := newI2CTestBoard(t, 1)
+	c, err := NewQMC5883L(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]byte{{qmcRegPeriod, 0x01}, {qmcRegCtrl1, qmcCtrl1}}
+	for i := range want {
+		if !bytes.Equal(f.writes[i], want[i]) {
+			t.Fatalf("Write %d = % X, want % X", i, f.writes[i], want[i])
+		}
+	}
+
+	// X, Y, Z little endian, at 3000 counts per gauss.
+	if err = c.SetRange(3); err != nil || !bytes.Equal(f.writes[2], []byte{qmcRegCtrl1, qmcCtrl1 | 0x10}) {
+		t.Fatalf("SetRange(3) wrote % X, %v", f.writes[2], err)
+	}
+	copy(f.chip(QMC5883LAddress)[qmcRegData:], []byte{0xB8, 0x0B, 0x24, 0xFA, 0x70, 0x17})
+	v, err := c.Read()
+	if err != nil || v != (Vector{1, -0.5, 2}) {
+		t.Fatalf("Read = %+v, %v, want {1 -0.5 2}", v, err)
+	}
+
+	c.Declination = 10
+	if deg, err := c.Heading(); err != nil || math.Abs(deg-(360-26.56505117707799+10)) > 1e-9 {
+		t.Fatalf("Heading = %f, %v, want 343.43", deg, err)
+	}
+}
@@ -0,0 +1,186 @@
+package gadget
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// ACS712Model is the current range of an ACS712 module.
+type ACS712Model struct {
+	Range       float64 // Amps either way.
+	Sensitivity float64 // Volts per amp.
+}
+
+var (
+	ACS712_5A  = ACS712Model{Range: 5, Sensitivity: 0.185}
+	ACS712_20A = ACS712Model{Range: 20, Sensitivity: 0.100}
+	ACS712_30A = ACS712Model{Range: 30, Sensitivity: 0.066}
+)
+
+// ACS712 is a hall effect current sensor module on an analog pin.
+// It outputs half its supply with no current, so the board's analog
+// reference must be that supply, usually 5V.
+type ACS712 struct {
+	board *Board
+	pin   byte // Normal (not A0 style) pin number.
+	model ACS712Model
+
+	// Output voltage with no current. Defaults to half the analog
+	// reference; use CalibrateZero to measure it.
+	Zero float64
+
+	// Number of readings averaged per measurement, defaults to 10.
+	// Readings are taken SampleDelay apart so each one is a fresh
+	// report from the board.
+	Samples     int
+	SampleDelay time.Duration
+}
+
+// NewACS712 returns an ACS712 of the given model on an analog pin, with
+// analog reporting turned on.
+func NewACS712(b *Board, pin byte, model ACS712Model) (s *ACS712, err error) {
+	if err = b.ensurePinMode(pin, ANALOG); err != nil {
+		return nil, err
+	}
+	if err = b.SetPinReporting(pin, true); err != nil {
+		return nil, err
+	}
+
+	s = &ACS712{
+		board:       b,
+		pin:         pin,
+		model:       model,
+		Zero:        b.AnalogReference() / 2,
+		Samples:     10,
+		SampleDelay: defaultSampleDelay,
+	}
+	return
+}
+
+// Voltage returns the (averaged) sensor output in volts.
+func (s *ACS712) Voltage() (v float64, err error) {
+	n := max(s.Samples, 1)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			time.Sleep(s.SampleDelay)
+		}
+		r, err := s.board.ReadVoltage(s.pin)
+		if err != nil {
+			return 0, err
+		}
+		v += r
+	}
+	return v / float64(n), nil
+}
+
+// CalibrateZero uses the current output as 0A, with nothing drawing
+// current through the sensor.
+func (s *ACS712) CalibrateZero() error {
+	v, err := s.Voltage()
+	if err != nil {
+		return err
+	}
+	s.Zero = v
+	return nil
+}
+
+// Amps returns the current, negative when flowing from IP- to IP+.
+func (s *ACS712) Amps() (float64, error) {
+	v, err := s.Voltage()
+	if err != nil {
+		return 0, err
+	}
+	return (v - s.Zero) / s.model.Sensitivity, nil
+}
+
+// Watts returns the power drawn from a supply of the given voltage.
+func (s *ACS712) Watts(supply float64) (float64, error) {
+	a, err := s.Amps()
+	return a * supply, err
+}
+
+const (
+	// Default I2C address, with A0 and A1 tied to GND.
+	INA219Address byte = 0x40
+
+	inaRegConfig  byte = 0x00
+	inaRegShunt   byte = 0x01
+	inaRegBus     byte = 0x02
+	inaRegPower   byte = 0x03
+	inaRegCurrent byte = 0x04
+	inaRegCalib   byte = 0x05
+
+	// 32V bus range, ±320mV shunt range, 12-bit conversions,
+	// continuous shunt and bus measurements.
+	inaConfig uint16 = 0x399F
+	inaReset  uint16 = 0x8000
+)
+
+// INA219 is a high side current and power monitor on the I2C bus,
+// measuring across a shunt resistor.
+type INA219 struct {
+	dev        *I2CDevice
+	currentLSB float64 // Amps per bit of the current register.
+}
+
+// NewINA219 resets the INA219 at addr and calibrates it for a shunt of
+// the given resistance, in ohms, and the largest current expected, in
+// amps. Most modules have a 0.1Ω shunt, good for up to 3.2A. I2CConfig
+// must already have been called.
+func NewINA219(b *Board, addr byte, shunt, maxAmps float64) (s *INA219, err error) {
+	if shunt <= 0 || maxAmps <= 0 {
+		return nil, fmt.Errorf("INA219 needs a shunt resistance and maximum current")
+	}
+	if maxAmps*shunt > 0.320001 {
+		return nil, fmt.Errorf("INA219 shunt voltage would exceed 320mV at %gA", maxAmps)
+	}
+
+	s = &INA219{dev: b.I2CDevice(addr), currentLSB: maxAmps / 32768}
+	if err = s.dev.WriteUint16(inaRegConfig, inaReset); err != nil {
+		return nil, err
+	}
+	if err = s.dev.WriteUint16(inaRegConfig, inaConfig); err != nil {
+		return nil, err
+	}
+	if err = s.dev.WriteUint16(inaRegCalib, ina219Calibration(s.currentLSB, shunt)); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// BusVoltage returns the voltage on the load side of the shunt.
+func (s *INA219) BusVoltage() (float64, error) {
+	v, err := s.dev.ReadUint16(inaRegBus)
+	if err != nil {
+		return 0, err
+	}
+	if v&0x01 != 0 {
+		return 0, fmt.Errorf("INA219 reading overflowed")
+	}
+	return float64(v>>3) * 0.004, nil
+}
+
+// ShuntVoltage returns the voltage across the shunt.
+func (s *INA219) ShuntVoltage() (float64, error) {
+	v, err := s.dev.ReadInt16(inaRegShunt)
+	return float64(v) * 0.00001, err
+}
+
+// Amps returns the current through the shunt.
+func (s *INA219) Amps() (float64, error) {
+	v, err := s.dev.ReadInt16(inaRegCurrent)
+	return float64(v) * s.currentLSB, err
+}
+
+// Watts returns the power delivered to the load.
+func (s *INA219) Watts() (float64, error) {
+	v, err := s.dev.ReadUint16(inaRegPower)
+	return float64(v) * 20 * s.currentLSB, err
+}
+
+// Returns the calibration register value giving currentLSB amps per bit
+// of the current register across a shunt of the given resistance.
+func ina219Calibration(currentLSB, shunt float64) uint16 {
+	return uint16(math.Trunc(0.04096 / (currentLSB * shunt)))
+}
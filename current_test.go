@@ -0,0 +1,57 @@
+package gadget
+
+import (
+	"math"
+	"testing"
+)
+
+func TestACS712(t *testing.T) {
+	b := newTestBoard(t, nil, map[byte][]Capability{14: {{ANALOG, 10}}}, nil)
+	s, err := NewACS712(b, 14, ACS712_5A)
+	if err != nil {
+		t.Fatalf("NewACS712: %s", err)
+	}
+	s.Samples = 1
+
+	b.pins[14].analogVal = 520 // A little above mid scale.
+	if err = s.CalibrateZero(); err != nil {
+		t.Fatalf("CalibrateZero: %s", err)
+	}
+	if a, _ := s.Amps(); a != 0 {
+		t.Fatalf("Amps at zero = %f", a)
+	}
+
+	// 0.37V above zero is 2A.
+	b.pins[14].analogVal = 520 + int(math.Round(0.37/5*1023))
+	if a, _ := s.Amps(); math.Abs(a-2) > 0.05 {
+		t.Fatalf("Amps = %f, want 2", a)
+	}
+	if w, _ := s.Watts(12); math.Abs(w-24) > 0.6 {
+		t.Fatalf("Watts = %f, want 24", w)
+	}
+}
+
+func TestINA219(t *testing.T) {
+	if c := ina219Calibration(3.2/32768, 0.1); c != 4194 {
+		t.Fatalf("Calibration = %d, want 4194", c)
+	}
+
+	f := &fakeI2CDevice{}
+	b := newTestBoard(t, f, nil, nil)
+	f.b = b
+	if _, err := NewINA219(b, INA219Address, 0.1, 5); err == nil {
+		t.Fatalf("Expected error for 500mV across the shunt")
+	}
+	s, err := NewINA219(b, INA219Address, 0.1, 3.2)
+	if err != nil {
+		t.Fatalf("NewINA219: %s", err)
+	}
+	if len(f.writes) != 3 || f.writes[2][0] != inaRegCalib || f.writes[2][1] != 4194>>8 || f.writes[2][2] != 4194&0xFF {
+		t.Fatalf("Setup wrote % X", f.writes)
+	}
+
+	// The fake's bus voltage register holds 0x0304.
+	if v, err := s.BusVoltage(); err != nil || math.Abs(v-0.384) > 1e-9 {
+		t.Fatalf("BusVoltage = %f, %v, want 0.384", v, err)
+	}
+}
@@ -0,0 +1,165 @@
+// Package datalog samples a gadget.Board's pins at a fixed rate and
+// writes the readings to one or more sinks.
+//
+// Samples are buffered per sink while it is failing, so a sink that is
+// briefly unavailable, such as a database being restarted, receives
+// everything once it recovers. The oldest samples are dropped once the
+// buffer is full.
+//
+//	f, _ := datalog.NewFileSink("pins.csv", datalog.CSV{}, 10<<20, 5)
+//	l := datalog.NewLogger(b, datalog.Config{Pins: []byte{14, 15}}, f)
+//	l.Start()
+package datalog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ZachMassia/GoGoGadget"
+)
+
+// Sample is a single pin reading.
+type Sample struct {
+	Time  time.Time
+	Pin   byte
+	Mode  string
+	Value int
+}
+
+// Sink stores samples. Write is given samples in time order and must
+// either store all of them or return an error, in which case they are
+// given again with the next write.
+type Sink interface {
+	Write(samples []Sample) error
+}
+
+// Config configures a Logger.
+type Config struct {
+	// The pins to sample.
+	Pins []byte
+
+	// How often the pins are sampled. Defaults to 1s.
+	Interval time.Duration
+
+	// The most samples held for a failing sink. Defaults to 10000.
+	BufferSize int
+
+	// Called with errors from reading pins and writing to sinks.
+	OnError func(error)
+}
+
+// Logger samples pins and writes them to its sinks.
+type Logger struct {
+	board *gadget.Board
+	cfg   Config
+	sinks []*bufferedSink
+
+	m    sync.Mutex
+	stop chan bool
+	done chan bool
+}
+
+// A sink and the samples it has yet to accept.
+type bufferedSink struct {
+	Sink
+	pending []Sample
+}
+
+// NewLogger returns a Logger sampling the board's pins to the sinks.
+func NewLogger(b *gadget.Board, cfg Config, sinks ...Sink) *Logger {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 10000
+	}
+	l := &Logger{board: b, cfg: cfg}
+	for _, s := range sinks {
+		l.sinks = append(l.sinks, &bufferedSink{Sink: s})
+	}
+	return l
+}
+
+// Start begins sampling in the background.
+func (l *Logger) Start() (err error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	if l.stop != nil {
+		return fmt.Errorf("Data logger already started")
+	}
+	l.stop = make(chan bool)
+	l.done = make(chan bool)
+	go l.run(l.stop, l.done)
+	return
+}
+
+// Halt stops sampling, making a final attempt to write any buffered
+// samples.
+func (l *Logger) Halt() (err error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	if l.stop != nil {
+		close(l.stop)
+		<-l.done
+		l.stop = nil
+		l.deliver(nil)
+	}
+	return
+}
+
+func (l *Logger) run(stop, done chan bool) {
+	defer close(done)
+
+	t := time.NewTicker(l.cfg.Interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-t.C:
+			l.deliver(l.sample(now))
+		}
+	}
+}
+
+// Reads every configured pin.
+func (l *Logger) sample(now time.Time) (samples []Sample) {
+	for _, n := range l.cfg.Pins {
+		mode, v, err := l.board.ReadValue(n)
+		if err != nil {
+			l.reportError(err)
+			continue
+		}
+		samples = append(samples, Sample{Time: now, Pin: n, Mode: gadget.PinModeString[mode], Value: v})
+	}
+	return
+}
+
+// Adds samples to each sink's buffer and tries to write it.
+func (l *Logger) deliver(samples []Sample) {
+	for _, s := range l.sinks {
+		s.pending = append(s.pending, samples...)
+		if over := len(s.pending) - l.cfg.BufferSize; over > 0 {
+			s.pending = append(s.pending[:0], s.pending[over:]...)
+		}
+		if len(s.pending) == 0 {
+			continue
+		}
+
+		if err := s.Write(s.pending); err != nil {
+			l.reportError(fmt.Errorf("Data logger sink failed, %d samples buffered: %s", len(s.pending), err))
+			continue
+		}
+		s.pending = s.pending[:0]
+	}
+}
+
+func (l *Logger) reportError(err error) {
+	if l.cfg.OnError != nil {
+		l.cfg.OnError(err)
+	}
+}
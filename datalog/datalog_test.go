@@ -0,0 +1,142 @@
+package datalog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var ts = time.Date(2024, 3, 1, 12, 0, 0, 500, time.UTC)
+
+func TestFormats(t *testing.T) {
+	s := Sample{Time: ts, Pin: 14, Mode: "ANALOG", Value: 512}
+	tests := []struct {
+		f    Format
+		want string
+	}{
+		{CSV{}, "2024-03-01T12:00:00.0000005Z,14,ANALOG,512\n"},
+		{JSONLines{}, `{"time":"2024-03-01T12:00:00.0000005Z","pin":14,"mode":"ANALOG","value":512}` + "\n"},
+		{Influx{}, "pin,mode=ANALOG,pin=14 value=512i 1709294400000000500\n"},
+		{Influx{Measurement: "bench", Tags: map[string]string{"board": "uno 1"}},
+			"bench,board=uno\\ 1,mode=ANALOG,pin=14 value=512i 1709294400000000500\n"},
+	}
+	for _, tt := range tests {
+		if got := string(tt.f.Append(nil, s)); got != tt.want {
+			t.Errorf("%T.Append =\n%s want\n%s", tt.f, got, tt.want)
+		}
+	}
+}
+
+func TestFileSinkRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pins.csv")
+	line := len(CSV{}.Append(nil, Sample{Time: ts, Mode: "INPUT"}))
+	header := len(CSV{}.Header())
+
+	// Room for two samples per file.
+	s, err := NewFileSink(path, CSV{}, int64(header+2*line), 2)
+	if err != nil {
+		t.Fatalf("NewFileSink returned error: %s", err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 7; i++ {
+		if err := s.Write([]Sample{{Time: ts, Mode: "INPUT"}}); err != nil {
+			t.Fatalf("Write returned error: %s", err)
+		}
+	}
+
+	for name, want := range map[string]int{path: 1, path + ".1": 2, path + ".2": 2} {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("Reading %s: %s", name, err)
+		}
+		if !strings.HasPrefix(string(b), CSV{}.Header()) {
+			t.Errorf("%s is missing its header", name)
+		}
+		if got := strings.Count(string(b), "\n") - 1; got != want {
+			t.Errorf("%s has %d samples, want %d", name, got, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); err == nil {
+		t.Errorf("Only 2 rotated files should be kept")
+	}
+}
+
+func TestFileSinkRotationFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pins.csv")
+	line := len(CSV{}.Append(nil, Sample{Time: ts, Mode: "INPUT"}))
+	s, err := NewFileSink(path, CSV{}, int64(len(CSV{}.Header())+line), 1)
+	if err != nil {
+		t.Fatalf("NewFileSink returned error: %s", err)
+	}
+	defer s.Close()
+	sample := []Sample{{Time: ts, Mode: "INPUT"}}
+	if err = s.Write(sample); err != nil {
+		t.Fatalf("Write returned error: %s", err)
+	}
+
+	// A directory in the way makes the rename fail.
+	if err = os.MkdirAll(filepath.Join(path+".1", "x"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Write(sample); err == nil {
+		t.Fatalf("Write should fail when the file cannot be rotated")
+	}
+	os.RemoveAll(path + ".1")
+	if err = s.Write(sample); err != nil {
+		t.Fatalf("Write after the rotation failure returned error: %s", err)
+	}
+	if b, _ := os.ReadFile(path); strings.Count(string(b), "\n") != 2 {
+		t.Fatalf("Expected a header and one sample after rotating, got %q", b)
+	}
+
+	s.Close()
+	if err = s.Write(sample); err == nil {
+		t.Fatalf("Write after Close should fail")
+	}
+}
+
+type flakySink struct {
+	fail    bool
+	written []Sample
+}
+
+func (s *flakySink) Write(samples []Sample) error {
+	if s.fail {
+		return fmt.Errorf("unavailable")
+	}
+	s.written = append(s.written, samples...)
+	return nil
+}
+
+func TestBufferDuringOutage(t *testing.T) {
+	sink := &flakySink{fail: true}
+	errs := 0
+	l := NewLogger(nil, Config{BufferSize: 3, OnError: func(error) { errs++ }}, sink)
+
+	for v := 0; v < 5; v++ {
+		l.deliver([]Sample{{Value: v}})
+	}
+	if errs != 5 || len(sink.written) != 0 {
+		t.Fatalf("Expected 5 failed writes, got %d errors and %d samples", errs, len(sink.written))
+	}
+
+	// The oldest samples beyond the buffer size are dropped.
+	sink.fail = false
+	l.deliver([]Sample{{Value: 5}})
+	var got []int
+	for _, s := range sink.written {
+		got = append(got, s.Value)
+	}
+	if fmt.Sprint(got) != "[3 4 5]" {
+		t.Fatalf("Delivered %v, want [3 4 5]", got)
+	}
+
+	l.deliver(nil)
+	if len(sink.written) != 3 {
+		t.Fatalf("Samples should not be delivered twice")
+	}
+}
@@ -0,0 +1,99 @@
+package datalog
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format encodes samples as text, one line per sample.
+type Format interface {
+	// Header returns the text starting every file, or "".
+	Header() string
+
+	// Append appends the line for s, including its newline, to buf.
+	Append(buf []byte, s Sample) []byte
+}
+
+// CSV writes samples as comma separated values with an RFC 3339 time.
+type CSV struct{}
+
+func (CSV) Header() string { return "time,pin,mode,value\n" }
+
+func (CSV) Append(buf []byte, s Sample) []byte {
+	buf = s.Time.AppendFormat(buf, time.RFC3339Nano)
+	buf = append(buf, ',')
+	buf = strconv.AppendUint(buf, uint64(s.Pin), 10)
+	buf = append(buf, ',')
+	buf = append(buf, s.Mode...)
+	buf = append(buf, ',')
+	buf = strconv.AppendInt(buf, int64(s.Value), 10)
+	return append(buf, '\n')
+}
+
+// JSONLines writes each sample as a JSON object on its own line.
+type JSONLines struct{}
+
+func (JSONLines) Header() string { return "" }
+
+func (JSONLines) Append(buf []byte, s Sample) []byte {
+	b, _ := json.Marshal(struct {
+		Time  time.Time `json:"time"`
+		Pin   byte      `json:"pin"`
+		Mode  string    `json:"mode"`
+		Value int       `json:"value"`
+	}{s.Time, s.Pin, s.Mode, s.Value})
+	buf = append(buf, b...)
+	return append(buf, '\n')
+}
+
+// Influx writes samples in the InfluxDB line protocol, with the pin
+// number and mode as tags and a nanosecond timestamp.
+type Influx struct {
+	// The measurement name. Defaults to "pin".
+	Measurement string
+
+	// Extra tags added to every line, such as the board's name.
+	Tags map[string]string
+}
+
+func (Influx) Header() string { return "" }
+
+func (f Influx) Append(buf []byte, s Sample) []byte {
+	m := f.Measurement
+	if m == "" {
+		m = "pin"
+	}
+	buf = append(buf, influxEscape(m)...)
+
+	keys := make([]string, 0, len(f.Tags))
+	for k := range f.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		buf = append(buf, ',')
+		buf = append(buf, influxEscape(k)...)
+		buf = append(buf, '=')
+		buf = append(buf, influxEscape(f.Tags[k])...)
+	}
+
+	buf = append(buf, ",mode="...)
+	buf = append(buf, influxEscape(s.Mode)...)
+	buf = append(buf, ",pin="...)
+	buf = strconv.AppendUint(buf, uint64(s.Pin), 10)
+	buf = append(buf, " value="...)
+	buf = strconv.AppendInt(buf, int64(s.Value), 10)
+	buf = append(buf, 'i', ' ')
+	buf = strconv.AppendInt(buf, s.Time.UnixNano(), 10)
+	return append(buf, '\n')
+}
+
+var influxEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// Escapes a measurement, tag key or tag value.
+func influxEscape(s string) string {
+	return influxEscaper.Replace(s)
+}
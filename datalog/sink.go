@@ -0,0 +1,172 @@
+package datalog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileSink appends samples to a file, rotating it once it grows past
+// a maximum size. Rotated files are renamed with a numeric suffix,
+// path.1 being the most recent.
+type FileSink struct {
+	path     string
+	format   Format
+	maxSize  int64
+	maxFiles int
+
+	m      sync.Mutex
+	f      *os.File // Nil after a failed rotation, until reopened.
+	size   int64
+	closed bool
+}
+
+// NewFileSink opens path for appending. A maxSize of 0 disables
+// rotation, otherwise up to maxFiles rotated files are kept.
+func NewFileSink(path string, format Format, maxSize int64, maxFiles int) (*FileSink, error) {
+	s := &FileSink{path: path, format: format, maxSize: maxSize, maxFiles: maxFiles}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) open() (err error) {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.f, s.size = f, info.Size()
+	if s.size == 0 && s.format.Header() != "" {
+		n, err := io.WriteString(s.f, s.format.Header())
+		s.size += int64(n)
+		return err
+	}
+	return nil
+}
+
+// Write appends the samples to the file.
+func (s *FileSink) Write(samples []Sample) (err error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.closed {
+		return fmt.Errorf("File sink %s is closed", s.path)
+	}
+	if s.f == nil {
+		if err = s.open(); err != nil {
+			return err
+		}
+	}
+
+	var buf []byte
+	for _, sample := range samples {
+		buf = s.format.Append(buf, sample)
+	}
+	if s.maxSize > 0 && s.size > int64(len(s.format.Header())) && s.size+int64(len(buf)) > s.maxSize {
+		if err = s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.f.Write(buf)
+	s.size += int64(n)
+	return err
+}
+
+// Moves each file up a suffix, dropping the oldest, and starts a new
+// file. If that fails the file is left closed, and reopened by the next
+// Write.
+func (s *FileSink) rotate() (err error) {
+	err = s.f.Close()
+	s.f = nil
+	if err != nil {
+		return err
+	}
+
+	if s.maxFiles > 0 {
+		for i := s.maxFiles - 1; i > 0; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", s.path, i), fmt.Sprintf("%s.%d", s.path, i+1))
+		}
+		err = os.Rename(s.path, s.path+".1")
+	} else {
+		err = os.Remove(s.path)
+	}
+	if err != nil {
+		return err
+	}
+	return s.open()
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
+
+// InfluxSink posts samples to an InfluxDB write endpoint using the line
+// protocol, e.g. "http://localhost:8086/write?db=gadget" for InfluxDB 1
+// or "http://localhost:8086/api/v2/write?org=o&bucket=b" for InfluxDB 2.
+type InfluxSink struct {
+	URL    string
+	Format Influx
+
+	// Sent as "Authorization: Token <Token>" when set.
+	Token string
+
+	Client *http.Client
+}
+
+// NewInfluxSink returns an InfluxSink posting to url.
+func NewInfluxSink(url string) *InfluxSink {
+	return &InfluxSink{URL: url, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Write posts the samples in a single request.
+func (s *InfluxSink) Write(samples []Sample) error {
+	var buf []byte
+	for _, sample := range samples {
+		buf = s.Format.Append(buf, sample)
+	}
+
+	req, err := http.NewRequest("POST", s.URL, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Token "+s.Token)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("InfluxDB write failed: %s %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
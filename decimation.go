@@ -0,0 +1,80 @@
+package gadget
+
+import (
+	"fmt"
+	"time"
+)
+
+// Decimation limits how often a pin's analog reports are published on
+// the event bus, so slow subscribers are not flooded by a fast sampling
+// interval. AnalogRead still returns the latest report.
+type Decimation struct {
+	// Publish only every Nth report. 0 or 1 publishes every report.
+	Every int
+
+	// Publish at most this many reports per second. 0 is unlimited.
+	MaxRate float64
+
+	// Publish only reports differing from the last published value by
+	// more than Delta, so ADC jitter is ignored.
+	Delta int
+}
+
+// Tracks a pin's reports against its Decimation.
+type decimator struct {
+	Decimation
+	n         int
+	last      time.Time
+	published int
+	sent      bool
+}
+
+// Returns true if report v, received at t, should be published.
+// Reports equal to the last published value are never published, even
+// with a zero Delta.
+func (d *decimator) allow(v int, t time.Time) bool {
+	d.n++
+	if d.Every > 1 && d.n%d.Every != 0 {
+		return false
+	}
+	if d.sent && abs(v-d.published) <= d.Delta {
+		return false
+	}
+	if d.MaxRate > 0 && t.Sub(d.last) < time.Duration(float64(time.Second)/d.MaxRate) {
+		return false
+	}
+	d.last, d.published, d.sent = t, v, true
+	return true
+}
+
+// SetDecimation limits how often the analog pin's reports are published:
+//
+//	b.SetDecimation(14, Decimation{Delta: 4}) // Ignore changes of 4 or less.
+//
+// The zero Decimation publishes every change, the default.
+func (b *Board) SetDecimation(pin byte, d Decimation) error {
+	if d.Every < 0 || d.MaxRate < 0 || d.Delta < 0 {
+		return fmt.Errorf("Invalid decimation: %+v", d)
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	p, ok := b.pins[pin]
+	if !ok {
+		return fmt.Errorf("Invalid pin: %d", pin)
+	}
+	if d == (Decimation{}) {
+		p.decim = nil
+	} else {
+		p.decim = &decimator{Decimation: d}
+	}
+	return nil
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,41 @@
+package gadget
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecimator(t *testing.T) {
+	var published []int
+	report := func(d *decimator, vals ...int) {
+		at := time.Unix(0, 0)
+		published = published[:0]
+		for _, v := range vals {
+			if d.allow(v, at) {
+				published = append(published, v)
+			}
+			at = at.Add(10 * time.Millisecond)
+		}
+	}
+
+	report(&decimator{Decimation: Decimation{Every: 3}}, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+	if len(published) != 3 || published[2] != 9 {
+		t.Errorf("Every 3rd report should be published, got %v", published)
+	}
+
+	// Reports are 10ms apart, so 20 Hz publishes every 5th.
+	report(&decimator{Decimation: Decimation{MaxRate: 20}}, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11)
+	if len(published) != 3 || published[1] != 6 {
+		t.Errorf("At most 20 Hz should be published, got %v", published)
+	}
+
+	report(&decimator{Decimation: Decimation{Delta: 3}}, 100, 102, 97, 96, 99, 100)
+	if len(published) != 3 || published[1] != 96 || published[2] != 100 {
+		t.Errorf("Changes of 3 or less should not be published, got %v", published)
+	}
+
+	report(&decimator{Decimation: Decimation{Every: 2}}, 5, 5, 5, 5, 6, 6)
+	if len(published) != 2 {
+		t.Errorf("Unchanged values should not be published, got %v", published)
+	}
+}
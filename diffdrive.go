@@ -0,0 +1,205 @@
+package gadget
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Encoder is a wheel encoder, counting ticks as the wheel turns.
+// Ticks increase when the wheel drives forward.
+type Encoder interface {
+	Ticks() (int64, error)
+}
+
+// Pose is a position and heading on the plane, starting at the origin
+// facing along the X axis.
+type Pose struct {
+	X, Y    float64 // Meters.
+	Heading float64 // Radians, counter clockwise.
+}
+
+// Moves the pose by the distance travelled by each wheel, using the
+// midpoint heading of the arc.
+func (p *Pose) integrate(left, right, trackWidth float64) {
+	d := (left + right) / 2
+	dh := (right - left) / trackWidth
+
+	mid := p.Heading + dh/2
+	p.X += d * math.Cos(mid)
+	p.Y += d * math.Sin(mid)
+	p.Heading = math.Remainder(p.Heading+dh, 2*math.Pi)
+}
+
+// DiffDrive is a differential drive robot, steered by running its left
+// and right wheels at different speeds. Its position is tracked by
+// integrating the wheel encoders, or if there are none, the commanded
+// wheel speeds.
+type DiffDrive struct {
+	Left, Right *Motor
+
+	// Optional. Both must be set for encoder odometry.
+	LeftEncoder, RightEncoder Encoder
+
+	TrackWidth    float64 // Distance between the wheels, in meters.
+	MaxSpeed      float64 // Wheel speed at full power, in m/s.
+	WheelDiameter float64 // In meters, needed with encoders.
+	TicksPerRev   float64 // Encoder ticks per wheel revolution.
+
+	// How often the pose is updated while started.
+	Interval time.Duration
+
+	m          sync.Mutex
+	pose       Pose
+	distance   float64 // Total distance travelled.
+	lastTicks  [2]int64
+	lastUpdate time.Time
+	stop       func()
+}
+
+// NewDiffDrive returns a DiffDrive with the given motors and geometry.
+func NewDiffDrive(left, right *Motor, trackWidth, maxSpeed float64) *DiffDrive {
+	return &DiffDrive{
+		Left:       left,
+		Right:      right,
+		TrackWidth: trackWidth,
+		MaxSpeed:   maxSpeed,
+		Interval:   50 * time.Millisecond,
+	}
+}
+
+// Drive moves at linear m/s while turning at angular rad/s (counter
+// clockwise positive). If a wheel would need to exceed MaxSpeed both
+// are slowed, keeping the turn radius.
+func (d *DiffDrive) Drive(linear, angular float64) (err error) {
+	if d.MaxSpeed <= 0 {
+		return fmt.Errorf("DiffDrive MaxSpeed must be set")
+	}
+
+	l := (linear - angular*d.TrackWidth/2) / d.MaxSpeed
+	r := (linear + angular*d.TrackWidth/2) / d.MaxSpeed
+	if m := math.Max(math.Abs(l), math.Abs(r)); m > 1 {
+		l, r = l/m, r/m
+	}
+
+	d.update() // Integrate the old speeds up to now.
+	if err = d.Left.SetSpeed(l); err != nil {
+		return err
+	}
+	return d.Right.SetSpeed(r)
+}
+
+// Stop brakes both wheels.
+func (d *DiffDrive) Stop() (err error) {
+	d.update()
+	if err = d.Left.Brake(); err != nil {
+		return err
+	}
+	return d.Right.Brake()
+}
+
+// Start tracks the pose in the background.
+func (d *DiffDrive) Start() (err error) {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	if d.stop != nil {
+		return fmt.Errorf("DiffDrive already started")
+	}
+	if err = d.resetLocked(); err != nil {
+		return err
+	}
+	d.stop = poll(d.Interval, d.update)
+	return
+}
+
+// Halt stops tracking the pose and brakes.
+func (d *DiffDrive) Halt() (err error) {
+	d.m.Lock()
+	if d.stop != nil {
+		d.stop()
+		d.stop = nil
+	}
+	d.m.Unlock()
+
+	return d.Stop()
+}
+
+// Pose returns the current pose estimate.
+func (d *DiffDrive) Pose() Pose {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	return d.pose
+}
+
+// Distance returns the total distance travelled in meters, counting
+// reversing as positive.
+func (d *DiffDrive) Distance() float64 {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	return d.distance
+}
+
+// ResetPose moves the origin to the current position and heading.
+func (d *DiffDrive) ResetPose() error {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	return d.resetLocked()
+}
+
+func (d *DiffDrive) resetLocked() (err error) {
+	d.pose, d.distance = Pose{}, 0
+	d.lastUpdate = time.Now()
+	if d.hasEncoders() {
+		d.lastTicks, err = d.readTicks()
+	}
+	return
+}
+
+// Integrates wheel travel since the last update.
+func (d *DiffDrive) update() {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	now := time.Now()
+	if d.lastUpdate.IsZero() {
+		d.lastUpdate = now
+		return
+	}
+
+	var left, right float64
+	if d.hasEncoders() {
+		ticks, err := d.readTicks()
+		if err != nil {
+			return
+		}
+		perTick := math.Pi * d.WheelDiameter / d.TicksPerRev
+		left = float64(ticks[0]-d.lastTicks[0]) * perTick
+		right = float64(ticks[1]-d.lastTicks[1]) * perTick
+		d.lastTicks = ticks
+	} else {
+		dt := now.Sub(d.lastUpdate).Seconds()
+		left = d.Left.Speed() * d.MaxSpeed * dt
+		right = d.Right.Speed() * d.MaxSpeed * dt
+	}
+	d.lastUpdate = now
+
+	d.pose.integrate(left, right, d.TrackWidth)
+	d.distance += math.Abs(left+right) / 2
+}
+
+func (d *DiffDrive) hasEncoders() bool {
+	return d.LeftEncoder != nil && d.RightEncoder != nil && d.TicksPerRev > 0
+}
+
+func (d *DiffDrive) readTicks() (t [2]int64, err error) {
+	if t[0], err = d.LeftEncoder.Ticks(); err != nil {
+		return t, err
+	}
+	t[1], err = d.RightEncoder.Ticks()
+	return
+}
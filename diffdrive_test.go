@@ -0,0 +1,28 @@
+package gadget
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPoseIntegrate(t *testing.T) {
+	var p Pose
+
+	// Straight ahead.
+	p.integrate(1, 1, 0.5)
+	if p.X != 1 || p.Y != 0 || p.Heading != 0 {
+		t.Fatalf("Straight move gave %+v", p)
+	}
+
+	// A quarter turn on the spot, counter clockwise.
+	p.integrate(-math.Pi/8, math.Pi/8, 0.5)
+	if math.Abs(p.Heading-math.Pi/2) > 1e-9 || math.Abs(p.X-1) > 1e-9 {
+		t.Fatalf("Turn on the spot gave %+v", p)
+	}
+
+	// Driving now moves along Y.
+	p.integrate(2, 2, 0.5)
+	if math.Abs(p.X-1) > 1e-9 || math.Abs(p.Y-2) > 1e-9 {
+		t.Fatalf("Move after turn gave %+v", p)
+	}
+}
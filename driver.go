@@ -0,0 +1,261 @@
+package gadget
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Driver is a device attached to a board. Drivers added with AddDriver
+// are started straight away and halted when the board is closed.
+type Driver interface {
+	Name() string
+	Start() error
+	Halt() error
+}
+
+// Runner is a driver without a name, such as a Keypad or Joystick. See
+// Named.
+type Runner interface {
+	Start() error
+	Halt() error
+}
+
+type namedDriver struct {
+	Runner
+	name string
+}
+
+func (d namedDriver) Name() string { return d.name }
+
+func (d namedDriver) Requires() Requirements { return requirementsOf(d.Runner) }
+
+// Named makes a Driver of r, e.g. b.AddDriver(Named("keys", keypad)).
+// The Requirements of r, if any, are kept.
+func Named(name string, r Runner) Driver {
+	return namedDriver{r, name}
+}
+
+// A device without a background task of its own, see AsDriver.
+type idleRunner struct {
+	v interface{}
+}
+
+func (idleRunner) Start() error { return nil }
+func (idleRunner) Halt() error  { return nil }
+
+func (r idleRunner) Requires() Requirements { return requirementsOf(r.v) }
+
+// AsDriver makes a Driver of any device, so every device can be added
+// with AddDriver. Runners such as a Keypad are started and halted with
+// the board, other devices such as a BME280 only have their
+// Requirements checked and their pins claimed.
+func AsDriver(name string, v interface{}) Driver {
+	if r, ok := v.(Runner); ok {
+		return Named(name, r)
+	}
+	return Named(name, idleRunner{v})
+}
+
+// Requirements are what a driver needs from the board, checked by
+// AddDriver before the driver is started.
+type Requirements struct {
+	Features []Feature // Firmware features, e.g. FeatureI2C.
+	Pins     []byte    // Pins no other driver may use.
+	Shared   []byte    // Bus pins, such as I2C's, shared with other drivers on the bus.
+}
+
+// Returns v's Requirements, if it declares any.
+func requirementsOf(v interface{}) Requirements {
+	if r, ok := v.(Requirer); ok {
+		return r.Requires()
+	}
+	return Requirements{}
+}
+
+// Returns the requirements of a device on the I2C bus.
+func (b *Board) i2cRequirements() Requirements {
+	b.m.RLock()
+	defer b.m.RUnlock()
+
+	req := Requirements{Features: []Feature{FeatureI2C}}
+	for n, p := range b.pins {
+		if p.supports(I2C) {
+			req.Shared = append(req.Shared, n)
+		}
+	}
+	sort.Slice(req.Shared, func(i, j int) bool { return req.Shared[i] < req.Shared[j] })
+	return req
+}
+
+// Requirer is implemented by drivers that declare their Requirements.
+type Requirer interface {
+	Requires() Requirements
+}
+
+type requiringDriver struct {
+	Driver
+	req Requirements
+}
+
+func (d requiringDriver) Requires() Requirements { return d.req }
+
+// Requiring attaches requirements to a driver that does not declare
+// its own, e.g.
+//
+//	b.AddDriver(Requiring(Named("keys", keypad), Requirements{Pins: pins}))
+func Requiring(d Driver, req Requirements) Driver {
+	return requiringDriver{d, req}
+}
+
+// AddDriver starts the driver and adds it to the board, which halts it
+// on Close. Names must be unique. A driver declaring Requirements is
+// only started if the firmware has its features and its pins exist and
+// are not used by another driver. Shared pins may only be used by other
+// drivers sharing them.
+func (b *Board) AddDriver(d Driver) error {
+	b.dm.Lock()
+	defer b.dm.Unlock()
+
+	name := d.Name()
+	for _, o := range b.drivers {
+		if o.Name() == name {
+			return fmt.Errorf("Driver '%s' already added", name)
+		}
+	}
+	req := requirementsOf(d)
+	if err := b.checkRequirements(req); err != nil {
+		return fmt.Errorf("Driver '%s': %s", name, err)
+	}
+	if err := d.Start(); err != nil {
+		return fmt.Errorf("Driver '%s': %s", name, err)
+	}
+
+	b.drivers = append(b.drivers, d)
+	if b.claims == nil {
+		b.claims = make(map[byte]string)
+	}
+	for _, pin := range req.Pins {
+		b.claims[pin] = name
+	}
+	if b.shared == nil {
+		b.shared = make(map[byte][]string)
+	}
+	for _, pin := range req.Shared {
+		b.shared[pin] = append(b.shared[pin], name)
+	}
+	return nil
+}
+
+// Must be called with b.dm held.
+func (b *Board) checkRequirements(req Requirements) error {
+	for _, f := range req.Features {
+		if err := b.require(f); err != nil {
+			return err
+		}
+	}
+
+	b.m.RLock()
+	defer b.m.RUnlock()
+	for _, pin := range req.Pins {
+		if _, ok := b.pins[pin]; !ok {
+			return fmt.Errorf("Invalid pin: %d", pin)
+		}
+		if owner, ok := b.claims[pin]; ok {
+			return fmt.Errorf("Pin %d already used by driver '%s'", pin, owner)
+		}
+		if owners := b.shared[pin]; len(owners) > 0 {
+			return fmt.Errorf("Pin %d already used by driver '%s'", pin, owners[0])
+		}
+	}
+	for _, pin := range req.Shared {
+		if _, ok := b.pins[pin]; !ok {
+			return fmt.Errorf("Invalid pin: %d", pin)
+		}
+		if owner, ok := b.claims[pin]; ok {
+			return fmt.Errorf("Pin %d already used by driver '%s'", pin, owner)
+		}
+	}
+	return nil
+}
+
+// PinOwner returns the name of the driver using the pin, if any. For a
+// shared pin it is the first driver added.
+func (b *Board) PinOwner(pin byte) (name string, ok bool) {
+	b.dm.Lock()
+	defer b.dm.Unlock()
+	if name, ok = b.claims[pin]; ok {
+		return
+	}
+	if owners := b.shared[pin]; len(owners) > 0 {
+		return owners[0], true
+	}
+	return
+}
+
+// Releases the driver's pins. Must be called with b.dm held.
+func (b *Board) release(name string) {
+	for pin, owner := range b.claims {
+		if owner == name {
+			delete(b.claims, pin)
+		}
+	}
+	for pin, owners := range b.shared {
+		for i, owner := range owners {
+			if owner == name {
+				b.shared[pin] = append(owners[:i:i], owners[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// RemoveDriver halts the named driver and removes it from the board.
+func (b *Board) RemoveDriver(name string) error {
+	b.dm.Lock()
+	defer b.dm.Unlock()
+
+	for i, d := range b.drivers {
+		if d.Name() == name {
+			b.drivers = append(b.drivers[:i], b.drivers[i+1:]...)
+			b.release(name)
+			return d.Halt()
+		}
+	}
+	return fmt.Errorf("No driver named '%s'", name)
+}
+
+// Driver returns the named driver, or nil.
+func (b *Board) Driver(name string) Driver {
+	b.dm.Lock()
+	defer b.dm.Unlock()
+
+	for _, d := range b.drivers {
+		if d.Name() == name {
+			return d
+		}
+	}
+	return nil
+}
+
+// Drivers returns the board's drivers in the order they were added.
+func (b *Board) Drivers() []Driver {
+	b.dm.Lock()
+	defer b.dm.Unlock()
+	return append([]Driver(nil), b.drivers...)
+}
+
+// Halts every driver, last added first, and removes them.
+func (b *Board) haltDrivers() {
+	b.dm.Lock()
+	drivers := b.drivers
+	b.drivers = nil
+	b.claims = nil
+	b.shared = nil
+	b.dm.Unlock()
+
+	for i := len(drivers) - 1; i >= 0; i-- {
+		if err := drivers[i].Halt(); err != nil {
+			b.log().Warn("Could not halt driver", "driver", drivers[i].Name(), "err", err)
+		}
+	}
+}
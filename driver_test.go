@@ -0,0 +1,139 @@
+package gadget
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+type fakeDriver struct {
+	name     string
+	startErr error
+	log      *[]string
+}
+
+func (d *fakeDriver) Name() string { return d.name }
+
+func (d *fakeDriver) Start() error {
+	if d.startErr == nil {
+		*d.log = append(*d.log, "start "+d.name)
+	}
+	return d.startErr
+}
+
+func (d *fakeDriver) Halt() error {
+	*d.log = append(*d.log, "halt "+d.name)
+	return nil
+}
+
+func TestDrivers(t *testing.T) {
+	b := &Board{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	var log []string
+
+	for _, name := range []string{"a", "b", "c"} {
+		if err := b.AddDriver(&fakeDriver{name: name, log: &log}); err != nil {
+			t.Fatalf("AddDriver(%s): %s", name, err)
+		}
+	}
+	if err := b.AddDriver(&fakeDriver{name: "a", log: &log}); err == nil {
+		t.Fatalf("Expected error for duplicate name")
+	}
+	if err := b.AddDriver(&fakeDriver{name: "d", startErr: errors.New("no"), log: &log}); err == nil || b.Driver("d") != nil {
+		t.Fatalf("A driver failing to start should not be added")
+	}
+	if b.Driver("b") == nil || len(b.Drivers()) != 3 {
+		t.Fatalf("Expected drivers a, b and c, got %d", len(b.Drivers()))
+	}
+
+	if err := b.RemoveDriver("b"); err != nil {
+		t.Fatalf("RemoveDriver: %s", err)
+	}
+	if err := b.RemoveDriver("b"); err == nil {
+		t.Fatalf("Expected error removing a missing driver")
+	}
+	b.haltDrivers()
+
+	want := []string{"start a", "start b", "start c", "halt b", "halt c", "halt a"}
+	if len(log) != len(want) {
+		t.Fatalf("Got %v, want %v", log, want)
+	}
+	for i := range want {
+		if log[i] != want[i] {
+			t.Fatalf("Got %v, want %v", log, want)
+		}
+	}
+}
+
+func TestDriverRequirements(t *testing.T) {
+	caps := []Capability{{INPUT, 1}, {OUTPUT, 1}}
+	b := newTestBoard(t, nil, nil, map[byte][]Capability{4: caps, 5: caps, 6: caps})
+	var log []string
+
+	a := Requiring(&fakeDriver{name: "a", log: &log}, Requirements{Pins: []byte{4, 5}})
+	if err := b.AddDriver(a); err != nil {
+		t.Fatalf("AddDriver: %s", err)
+	}
+	if owner, ok := b.PinOwner(5); !ok || owner != "a" {
+		t.Fatalf("PinOwner(5) = %s, %t, want a", owner, ok)
+	}
+
+	for _, req := range []Requirements{
+		{Pins: []byte{5, 6}},              // Claimed by a.
+		{Pins: []byte{7}},                 // No such pin.
+		{Features: []Feature{FeatureI2C}}, // Unsupported.
+	} {
+		d := Requiring(&fakeDriver{name: "b", log: &log}, req)
+		if err := b.AddDriver(d); err == nil {
+			t.Fatalf("Expected error for %+v", req)
+		}
+	}
+	if len(log) != 1 {
+		t.Fatalf("Drivers failing their requirements should not start, got %v", log)
+	}
+
+	b.RemoveDriver("a")
+	if err := b.AddDriver(Requiring(&fakeDriver{name: "b", log: &log}, Requirements{Pins: []byte{5, 6}})); err != nil {
+		t.Fatalf("Pins should be released by RemoveDriver: %s", err)
+	}
+}
+
+func TestDeviceRequirements(t *testing.T) {
+	caps := []Capability{{INPUT, 1}, {OUTPUT, 1}}
+	i2c := []Capability{{INPUT, 1}, {OUTPUT, 1}, {I2C, 1}}
+	b := newTestBoard(t, nil, nil, map[byte][]Capability{4: caps, 5: caps, 6: caps, 18: i2c, 19: i2c})
+
+	// Devices on the same bus share its pins.
+	lcd := &LCD{board: b}
+	if err := b.AddDriver(AsDriver("lcd", lcd)); err != nil {
+		t.Fatalf("AddDriver(lcd): %s", err)
+	}
+	if err := b.AddDriver(AsDriver("adc", NewADS1115(b, 0x48))); err != nil {
+		t.Fatalf("AddDriver(adc): %s", err)
+	}
+	if owner, ok := b.PinOwner(18); !ok || owner != "lcd" {
+		t.Fatalf("PinOwner(18) = %s, %t, want lcd", owner, ok)
+	}
+	k, err := NewKeypad(b, []byte{4, 18}, []byte{5}, []string{"1", "2"})
+	if err != nil {
+		t.Fatalf("NewKeypad: %s", err)
+	}
+	if err = b.AddDriver(AsDriver("keys", k)); err == nil {
+		t.Fatalf("A keypad on the I2C pins should fail")
+	}
+
+	// Named keeps the requirements of what it wraps.
+	k.rows = []byte{4, 6}
+	if err := b.AddDriver(Named("keys", k)); err != nil {
+		t.Fatalf("AddDriver(keys): %s", err)
+	}
+	if owner, _ := b.PinOwner(6); owner != "keys" {
+		t.Fatalf("PinOwner(6) = %s, want keys", owner)
+	}
+
+	b.RemoveDriver("lcd")
+	if owner, _ := b.PinOwner(19); owner != "adc" {
+		t.Fatalf("PinOwner(19) = %s after removing lcd, want adc", owner)
+	}
+	b.haltDrivers()
+}
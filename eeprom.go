@@ -0,0 +1,136 @@
+package gadget
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// Default I2C address, with A0-A2 tied low.
+	EEPROMAddress byte = 0x50
+
+	// Largest transfer that fits in StandardFirmata's sysex buffer
+	// and the Wire library's 32 byte buffer.
+	eepromChunk = 16
+
+	// Max write cycle time of the AT24C family.
+	eepromWriteDelay = 5 * time.Millisecond
+)
+
+// EEPROMModel describes the geometry of an AT24Cxx part.
+type EEPROMModel struct {
+	Size      int // Total size in bytes.
+	PageSize  int // Largest write that does not wrap within a page.
+	AddrBytes int // Memory address length, 1 or 2 bytes.
+}
+
+var (
+	// Parts up to 2KB use a single address byte, with the upper
+	// address bits taking the place of the A0-A2 pins.
+	AT24C02  = EEPROMModel{Size: 256, PageSize: 8, AddrBytes: 1}
+	AT24C04  = EEPROMModel{Size: 512, PageSize: 16, AddrBytes: 1}
+	AT24C08  = EEPROMModel{Size: 1024, PageSize: 16, AddrBytes: 1}
+	AT24C16  = EEPROMModel{Size: 2048, PageSize: 16, AddrBytes: 1}
+	AT24C32  = EEPROMModel{Size: 4096, PageSize: 32, AddrBytes: 2}
+	AT24C64  = EEPROMModel{Size: 8192, PageSize: 32, AddrBytes: 2}
+	AT24C128 = EEPROMModel{Size: 16384, PageSize: 64, AddrBytes: 2}
+	AT24C256 = EEPROMModel{Size: 32768, PageSize: 64, AddrBytes: 2}
+	AT24C512 = EEPROMModel{Size: 65536, PageSize: 128, AddrBytes: 2}
+)
+
+// EEPROM is an AT24Cxx I2C EEPROM. It implements io.ReaderAt and
+// io.WriterAt.
+type EEPROM struct {
+	board *Board
+	addr  byte
+	model EEPROMModel
+
+	// How long to wait after each page write for the write cycle
+	// to finish. Defaults to 5ms.
+	WriteDelay time.Duration
+
+	m sync.Mutex // Reads and writes move the chip's address pointer.
+}
+
+// NewEEPROM returns the EEPROM of the given model at addr. I2CConfig
+// must already have been called.
+func NewEEPROM(b *Board, addr byte, model EEPROMModel) *EEPROM {
+	return &EEPROM{
+		board:      b,
+		addr:       addr,
+		model:      model,
+		WriteDelay: eepromWriteDelay,
+	}
+}
+
+// Size returns the capacity in bytes.
+func (e *EEPROM) Size() int64 {
+	return int64(e.model.Size)
+}
+
+// ReadAt reads len(p) bytes starting at off.
+func (e *EEPROM) ReadAt(p []byte, off int64) (n int, err error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	if off < 0 || off >= e.Size() {
+		return 0, fmt.Errorf("EEPROM offset %d out of range", off)
+	}
+
+	for n < len(p) {
+		pos := int(off) + n
+		if pos >= e.model.Size {
+			return n, io.EOF
+		}
+
+		// Don't let a chunk cross into the next 256 byte block, since
+		// single address byte parts select it by device address.
+		size := min(eepromChunk, len(p)-n, e.model.Size-pos, 256-pos%256)
+
+		dev, addr := e.address(pos)
+		if err = e.board.I2CWrite(dev, addr); err != nil {
+			return n, err
+		}
+		d, err := e.board.I2CRead(dev, size)
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], d)
+	}
+	return
+}
+
+// WriteAt writes p starting at off, split into page writes.
+func (e *EEPROM) WriteAt(p []byte, off int64) (n int, err error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	if off < 0 || off+int64(len(p)) > e.Size() {
+		return 0, fmt.Errorf("EEPROM write of %d bytes at %d out of range", len(p), off)
+	}
+
+	for n < len(p) {
+		pos := int(off) + n
+
+		// Writes wrap around within a page, so never cross one.
+		size := min(eepromChunk, len(p)-n, e.model.PageSize-pos%e.model.PageSize)
+
+		dev, addr := e.address(pos)
+		if err = e.board.I2CWrite(dev, append(addr, p[n:n+size]...)); err != nil {
+			return n, err
+		}
+		n += size
+		time.Sleep(e.WriteDelay)
+	}
+	return
+}
+
+// Returns the device address and memory address bytes of pos.
+func (e *EEPROM) address(pos int) (dev byte, addr []byte) {
+	if e.model.AddrBytes == 1 {
+		return e.addr | byte(pos>>8)&0x07, []byte{byte(pos)}
+	}
+	return e.addr, []byte{byte(pos >> 8), byte(pos)}
+}
@@ -0,0 +1,70 @@
+package gadget
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestEEPROM(t *testing.T) {
+	b, f := newI2CTestBoard(t, 2)
+	e := NewEEPROM(b, EEPROMAddress, AT24C32)
+	e.WriteDelay = 0
+
+	// 40 bytes from 0x1A are split at the page boundaries at 0x20
+	// and 0x40, and into chunks the firmware can buffer.
+	data := make([]byte, 40)
+	for i := range data {
+		data[i] = byte(i + 1)
+	}
+	if n, err := e.WriteAt(data, 0x1A); err != nil || n != len(data) {
+		t.Fatalf("WriteAt = %d, %v", n, err)
+	}
+	want := []int{6, 16, 16, 2}
+	if len(f.writes) != len(want) {
+		t.Fatalf("Wrote %d pages, want %d", len(f.writes), len(want))
+	}
+	for i, w := range f.writes {
+		if len(w)-2 != want[i] {
+			t.Fatalf("Write %d was %d bytes, want %d", i, len(w)-2, want[i])
+		}
+	}
+	if !bytes.Equal(f.writes[1][:2], []byte{0x00, 0x20}) {
+		t.Fatalf("Second write at % X, want 00 20", f.writes[1][:2])
+	}
+
+	got := make([]byte, len(data))
+	if n, err := e.ReadAt(got, 0x1A); err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("ReadAt = %d, %v, % X, want % X", n, err, got, data)
+	}
+
+	if _, err := e.WriteAt(data, e.Size()-10); err == nil {
+		t.Fatalf("Expected an error writing past the end")
+	}
+	if n, err := e.ReadAt(got, e.Size()-10); err != io.EOF || n != 10 {
+		t.Fatalf("ReadAt past the end = %d, %v, want 10, EOF", n, err)
+	}
+}
+
+func TestEEPROMBlocks(t *testing.T) {
+	b, f := newI2CTestBoard(t, 1)
+	e := NewEEPROM(b, EEPROMAddress, AT24C16)
+	e.WriteDelay = 0
+
+	// Single address byte parts select each 256 byte block by device
+	// address.
+	if _, err := e.WriteAt([]byte{1, 2, 3, 4}, 0x3FE); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.chip(EEPROMAddress | 3)[0xFE:]; !bytes.Equal(got, []byte{1, 2}) {
+		t.Fatalf("Block 3 holds % X, want 01 02", got)
+	}
+	if got := f.chip(EEPROMAddress | 4)[:2]; !bytes.Equal(got, []byte{3, 4}) {
+		t.Fatalf("Block 4 holds % X, want 03 04", got)
+	}
+
+	got := make([]byte, 4)
+	if _, err := e.ReadAt(got, 0x3FE); err != nil || !bytes.Equal(got, []byte{1, 2, 3, 4}) {
+		t.Fatalf("ReadAt = % X, %v, want 01 02 03 04", got, err)
+	}
+}
@@ -0,0 +1,115 @@
+package gadget
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	// Encoder sub commands.
+	encoderAttach     byte = 0x00 // encoder, pin A, pin B
+	encoderReset      byte = 0x03 // encoder
+	encoderReportAuto byte = 0x04 // enable
+	encoderDetach     byte = 0x05 // encoder
+
+	// EncoderFirmata handles at most 5 encoders.
+	maxEncoders = 5
+)
+
+// QuadratureEncoder is a quadrature encoder counted by the board's
+// EncoderFirmata extension, which reports every encoder's position
+// each sampling interval. It implements Encoder.
+type QuadratureEncoder struct {
+	board *Board
+	num   byte
+
+	m   sync.Mutex
+	pos int64
+}
+
+// NewQuadratureEncoder attaches an encoder to pins a and b, which
+// should be interrupt capable, and turns on position reports. Swap the
+// pins if the count runs backwards.
+func NewQuadratureEncoder(b *Board, pinA, pinB byte) (e *QuadratureEncoder, err error) {
+	if err = b.require(FeatureEncoder); err != nil {
+		return nil, err
+	}
+
+	b.m.Lock()
+	for _, pin := range []byte{pinA, pinB} {
+		p, err := b.sendablePin(pin)
+		if err != nil {
+			b.m.Unlock()
+			return nil, err
+		}
+		if !p.supports(ENCODER) {
+			b.m.Unlock()
+			return nil, fmt.Errorf("Pin %d does not support ENCODER mode", pin)
+		}
+	}
+	if b.encoders == maxEncoders {
+		b.m.Unlock()
+		return nil, fmt.Errorf("Too many encoders, the maximum is %d", maxEncoders)
+	}
+	num := b.encoders
+	b.encoders++
+	b.m.Unlock()
+
+	e = &QuadratureEncoder{board: b, num: num}
+	b.addHandler(encoderData, e.handleReport)
+
+	if _, err = b.sendSysex([]byte{encoderData, encoderAttach, num, pinA, pinB}); err != nil {
+		return nil, err
+	}
+	if _, err = b.sendSysex([]byte{encoderData, encoderReportAuto, 1}); err != nil {
+		return nil, err
+	}
+	return e, b.out.Flush()
+}
+
+// Ticks returns the last reported position.
+func (e *QuadratureEncoder) Ticks() (int64, error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.pos, nil
+}
+
+// Reset zeroes the position.
+func (e *QuadratureEncoder) Reset() (err error) {
+	e.m.Lock()
+	e.pos = 0
+	e.m.Unlock()
+
+	_, err = e.board.sendSysex([]byte{encoderData, encoderReset, e.num})
+	return
+}
+
+// Detach stops counting. The encoder's number is not reused.
+func (e *QuadratureEncoder) Detach() (err error) {
+	_, err = e.board.sendSysex([]byte{encoderData, encoderDetach, e.num})
+	return
+}
+
+// Records this encoder's position from a report of one or all of the
+// encoders, 5 bytes each: the encoder number with the sign in bit 6,
+// then the magnitude as four 7-bit bytes.
+func (e *QuadratureEncoder) handleReport(m message) {
+	if len(m.data) < 3 {
+		return
+	}
+	body := m.data[2 : len(m.data)-1]
+	for ; len(body) >= 5; body = body[5:] {
+		if body[0]&0x3F != e.num {
+			continue
+		}
+		pos := int64(body[1]) | int64(body[2])<<7 | int64(body[3])<<14 | int64(body[4])<<21
+		if body[0]&0x40 != 0 {
+			pos = -pos
+		}
+
+		e.m.Lock()
+		e.pos = pos
+		e.m.Unlock()
+		return
+	}
+}
@@ -0,0 +1,17 @@
+package gadget
+
+import (
+	"testing"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+func TestQuadratureEncoderReport(t *testing.T) {
+	e := &QuadratureEncoder{num: 1}
+
+	// Encoder 0 at 5, encoder 1 at -300.
+	e.handleReport(message{t: sysexMsg, data: firmatawire.Sysex(encoderData, 0x00, 5, 0, 0, 0, 0x41, 300&0x7F, 300>>7, 0, 0)})
+	if ticks, _ := e.Ticks(); ticks != -300 {
+		t.Fatalf("Ticks = %d, want -300", ticks)
+	}
+}
@@ -0,0 +1,18 @@
+package gadget
+
+// Environment is a reading from an environmental sensor.
+type Environment struct {
+	Temperature float64 // °C
+	Pressure    float64 // Pa
+	Humidity    float64 // %RH, always 0 if the sensor has no humidity sensor.
+}
+
+// Reads a little endian uint16 from buf at i.
+func le16(buf []byte, i int) uint16 {
+	return uint16(buf[i]) | uint16(buf[i+1])<<8
+}
+
+// Reads a big endian uint16 from buf at i.
+func be16(buf []byte, i int) uint16 {
+	return uint16(buf[i])<<8 | uint16(buf[i+1])
+}
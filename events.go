@@ -0,0 +1,162 @@
+package gadget
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Topics published by the Board. Pin events are published on
+// "pin/{n}/{kind}" topics, see PinTopic.
+const (
+	TopicReady     = "board/ready"     // The board finished configuring.
+	TopicClosed    = "board/closed"    // Close was called.
+	TopicError     = "board/error"     // Err holds an error talking to the board.
+	TopicUnhealthy = "board/unhealthy" // The board stopped answering a Heartbeat.
+	TopicHealthy   = "board/healthy"   // The board answered a Heartbeat again.
+	TopicReset     = "board/reset"     // The board rebooted and was reconfigured.
+	TopicHardware  = "board/hardware"  // The board's pins changed, Data holds a HardwareChange.
+
+	// Driver events. Data holds the driver's event type.
+	TopicKeypad   = "driver/keypad"   // KeyEvent
+	TopicJoystick = "driver/joystick" // JoystickEvent
+	TopicExpander = "driver/expander" // ExpanderEvent
+	TopicIR       = "driver/ir"       // IREvent
+	TopicSoil     = "driver/soil"     // SoilEvent
+	TopicOdometry = "driver/odometry" // OdometryEvent
+	TopicBattery  = "driver/battery"  // BatteryEvent
+)
+
+// Kinds of pin event.
+const (
+	KindDigital = "digital" // A digital value was reported or written.
+	KindAnalog  = "analog"  // An analog value was reported or written.
+	KindMode    = "mode"    // The pin's mode changed, Value holds the new mode.
+)
+
+// Event is a message published on an EventBus.
+type Event struct {
+	Topic string
+	Time  time.Time
+
+	// The pin and its new value for pin events.
+	Pin   byte
+	Value int
+
+	// The driver's own event type for driver events, e.g. a KeyEvent
+	// on "driver/keypad".
+	Data interface{}
+
+	Err error
+}
+
+// PinTopic returns the topic of pin events of a kind, e.g. "pin/13/digital".
+func PinTopic(pin byte, kind string) string {
+	return "pin/" + strconv.Itoa(int(pin)) + "/" + kind
+}
+
+// EventBus delivers published events to every subscriber with a
+// matching topic pattern. Publishing never blocks: events are dropped
+// for subscribers whose buffer is full.
+type EventBus struct {
+	m    sync.RWMutex
+	subs map[*Subscription]bool
+
+	dropped atomic.Int64
+}
+
+// Subscription receives the events matching its pattern on C.
+type Subscription struct {
+	C <-chan Event
+
+	c       chan Event
+	pattern []string
+	bus     *EventBus
+}
+
+// NewEventBus returns an EventBus with no subscribers.
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[*Subscription]bool)}
+}
+
+// Subscribe returns a Subscription to the topics matching pattern.
+// Topic levels are separated by '/'. In a pattern '+' matches any one
+// level and a final '#' any number of levels, so "pin/+/digital"
+// matches digital events of every pin and "#" matches everything.
+// Buffer is the number of events held for the subscriber.
+func (bus *EventBus) Subscribe(pattern string, buffer int) *Subscription {
+	c := make(chan Event, buffer)
+	s := &Subscription{C: c, c: c, pattern: strings.Split(pattern, "/"), bus: bus}
+
+	bus.m.Lock()
+	bus.subs[s] = true
+	bus.m.Unlock()
+	return s
+}
+
+// Unsubscribe stops delivery and closes C.
+func (s *Subscription) Unsubscribe() {
+	s.bus.m.Lock()
+	defer s.bus.m.Unlock()
+
+	if s.bus.subs[s] {
+		delete(s.bus.subs, s)
+		close(s.c)
+	}
+}
+
+// Publish delivers e to the matching subscribers, setting its time if
+// it is zero.
+func (bus *EventBus) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	topic := strings.Split(e.Topic, "/")
+
+	bus.m.RLock()
+	defer bus.m.RUnlock()
+
+	for s := range bus.subs {
+		if !topicMatches(s.pattern, topic) {
+			continue
+		}
+		select {
+		case s.c <- e:
+		default:
+			bus.dropped.Add(1)
+		}
+	}
+}
+
+// Dropped returns how many events were not delivered because a
+// subscriber's buffer was full.
+func (bus *EventBus) Dropped() int64 {
+	return bus.dropped.Load()
+}
+
+// Reports whether the topic levels match the pattern levels.
+func topicMatches(pattern, topic []string) bool {
+	for i, p := range pattern {
+		switch {
+		case p == "#":
+			return true
+		case i >= len(topic):
+			return false
+		case p != "+" && p != topic[i]:
+			return false
+		}
+	}
+	return len(pattern) == len(topic)
+}
+
+// Events returns the board's event bus.
+func (b *Board) Events() *EventBus {
+	return b.bus
+}
+
+// Publishes a pin event that happened at the given time.
+func (b *Board) publishPin(pin byte, kind string, v int, at time.Time) {
+	b.bus.Publish(Event{Topic: PinTopic(pin, kind), Time: at, Pin: pin, Value: v})
+}
@@ -0,0 +1,55 @@
+package gadget
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTopicMatches(t *testing.T) {
+	tests := []struct {
+		pattern, topic string
+		want           bool
+	}{
+		{"pin/13/digital", "pin/13/digital", true},
+		{"pin/+/digital", "pin/2/digital", true},
+		{"pin/+/digital", "pin/2/analog", false},
+		{"pin/#", "pin/2/mode", true},
+		{"#", "board/ready", true},
+		{"pin/+", "pin/2/digital", false},
+		{"pin/2/digital/x", "pin/2/digital", false},
+		{"board/ready", "board/closed", false},
+	}
+	for _, tt := range tests {
+		got := topicMatches(strings.Split(tt.pattern, "/"), strings.Split(tt.topic, "/"))
+		if got != tt.want {
+			t.Errorf("topicMatches(%s, %s) = %v, want %v", tt.pattern, tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestEventBus(t *testing.T) {
+	bus := NewEventBus()
+	pins := bus.Subscribe("pin/+/digital", 1)
+	all := bus.Subscribe("#", 8)
+
+	bus.Publish(Event{Topic: PinTopic(13, KindDigital), Pin: 13, Value: 1})
+	bus.Publish(Event{Topic: PinTopic(12, KindDigital), Pin: 12, Value: 1}) // Dropped, buffer full.
+	bus.Publish(Event{Topic: TopicReady})
+
+	if e := <-pins.C; e.Pin != 13 || e.Time.IsZero() {
+		t.Fatalf("Unexpected event %+v", e)
+	}
+	if len(pins.C) != 0 {
+		t.Fatalf("Events beyond the buffer should be dropped")
+	}
+	if len(all.C) != 3 {
+		t.Fatalf("Expected 3 events for '#', got %d", len(all.C))
+	}
+
+	pins.Unsubscribe()
+	pins.Unsubscribe()
+	bus.Publish(Event{Topic: PinTopic(13, KindDigital)})
+	if _, ok := <-pins.C; ok {
+		t.Fatalf("Channel should be closed after Unsubscribe")
+	}
+}
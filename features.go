@@ -0,0 +1,84 @@
+package gadget
+
+import "fmt"
+
+// Feature is an optional part of the firmware. ConfigurableFirmata
+// builds can leave any of them out.
+type Feature string
+
+// Features that can be detected, named like Firmata's pin modes.
+const (
+	FeatureAnalog  Feature = "ANALOG"
+	FeaturePWM     Feature = "PWM"
+	FeatureServo   Feature = "SERVO"
+	FeatureShift   Feature = "SHIFT"
+	FeatureI2C     Feature = "I2C"
+	FeatureOneWire Feature = "ONEWIRE"
+	FeatureStepper Feature = "STEPPER"
+	FeatureEncoder Feature = "ENCODER"
+	FeatureSerial  Feature = "SERIAL"
+	FeaturePullup  Feature = "PULLUP"
+	FeatureSPI     Feature = "SPI"
+)
+
+// The pin mode a feature adds to the capability response.
+var featureModes = map[Feature]byte{
+	FeatureAnalog:  ANALOG,
+	FeaturePWM:     PWM,
+	FeatureServo:   SERVO,
+	FeatureShift:   SHIFT,
+	FeatureI2C:     I2C,
+	FeatureOneWire: ONEWIRE,
+	FeatureStepper: STEPPER,
+	FeatureEncoder: ENCODER,
+	FeatureSerial:  SERIAL,
+	FeaturePullup:  PULLUP,
+	FeatureSPI:     SPI,
+}
+
+// Supports reports whether the firmware has feature f. Firmata has no
+// query for its features, and ignores sysex commands it does not know,
+// so a feature counts as present when at least one pin offers its mode
+// in the capability response.
+func (b *Board) Supports(f Feature) bool {
+	mode, ok := featureModes[f]
+	if !ok {
+		return false
+	}
+
+	b.m.RLock()
+	defer b.m.RUnlock()
+
+	for _, p := range b.pins {
+		if p.supports(mode) {
+			return true
+		}
+	}
+	return false
+}
+
+// Features returns the features the firmware supports.
+func (b *Board) Features() (fs []Feature) {
+	for _, f := range []Feature{FeatureAnalog, FeaturePWM, FeatureServo, FeatureShift, FeatureI2C,
+		FeatureOneWire, FeatureStepper, FeatureEncoder, FeatureSerial, FeaturePullup, FeatureSPI} {
+		if b.Supports(f) {
+			fs = append(fs, f)
+		}
+	}
+	return
+}
+
+// Returns an error naming f if the firmware does not support it.
+func (b *Board) require(f Feature) error {
+	if !b.Supports(f) {
+		return fmt.Errorf("Firmware '%s' does not support %s", b.firmware, f)
+	}
+	return nil
+}
+
+// FirmwareVersion returns the version of the sketch uploaded to the
+// board, e.g. "2.5" for StandardFirmata 2.5, as opposed to Version's
+// protocol version.
+func (b *Board) FirmwareVersion() string {
+	return fmt.Sprintf("%d.%d", b.fwMaj, b.fwMin)
+}
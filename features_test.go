@@ -0,0 +1,48 @@
+package gadget
+
+import (
+	"io"
+	"testing"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+func TestFeatures(t *testing.T) {
+	b := &Board{
+		pins:            make(map[byte]*pin),
+		analogMapping:   map[byte]byte{14: 0},
+		ready:           make(chan bool, 1),
+		boardDoneReboot: make(chan bool, 1),
+		out:             newBatchWriter(io.Discard),
+	}
+	b.handleReportFirmware(message{t: sysexMsg,
+		data: firmatawire.Sysex(reportFirmware, firmatawire.AppendBytes7([]byte{2, 5}, []byte("Configurable"))...)})
+	if b.firmware != "Configurable" || b.FirmwareVersion() != "2.5" {
+		t.Fatalf("Unexpected firmware '%s' %s", b.firmware, b.FirmwareVersion())
+	}
+
+	b.initPins(map[byte][]Capability{14: {{ANALOG, 10}}},
+		map[byte][]Capability{3: {{INPUT, 1}, {OUTPUT, 1}, {PWM, 8}, {PULLUP, 1}}})
+
+	fs := b.Features()
+	if len(fs) != 3 || fs[0] != FeatureAnalog || fs[1] != FeaturePWM || fs[2] != FeaturePullup {
+		t.Fatalf("Unexpected features %v", fs)
+	}
+	if b.Supports(FeatureI2C) || b.Supports("MAGIC") {
+		t.Fatalf("I2C should not be supported")
+	}
+	if err := b.I2CConfig(0); err == nil {
+		t.Fatalf("I2CConfig should fail without I2C")
+	}
+}
+
+func TestLooksLikeFirmata(t *testing.T) {
+	for name, want := range map[string]bool{
+		"StandardFirmata.ino": true, "ConfigurableFirmata": true, "StandardFirmataPlus.ino": true,
+		"": false, "Blink.ino": false,
+	} {
+		if looksLikeFirmata(name) != want {
+			t.Errorf("looksLikeFirmata(%q) = %v", name, !want)
+		}
+	}
+}
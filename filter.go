@@ -0,0 +1,97 @@
+package gadget
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Filter smooths a pin's analog reports before they are stored and
+// published. Filters keep state, so each pin needs its own.
+type Filter interface {
+	// Next takes a raw report and returns the filtered value.
+	Next(v int) int
+}
+
+// MovingAverage returns a filter averaging the last n reports.
+func MovingAverage(n int) Filter {
+	return &movingAverage{window: make([]int, 0, max(n, 1))}
+}
+
+type movingAverage struct {
+	window []int
+	next   int // Oldest slot, overwritten once the window is full.
+	sum    int
+}
+
+func (f *movingAverage) Next(v int) int {
+	if len(f.window) < cap(f.window) {
+		f.window = append(f.window, v)
+	} else {
+		f.sum -= f.window[f.next]
+		f.window[f.next] = v
+		f.next = (f.next + 1) % len(f.window)
+	}
+	f.sum += v
+	return int(math.Round(float64(f.sum) / float64(len(f.window))))
+}
+
+// Exponential returns an exponential smoothing filter. Alpha, from 0 up
+// to 1, is the weight given to each new report: smaller values smooth
+// more but respond more slowly.
+func Exponential(alpha float64) Filter {
+	return &exponential{alpha: math.Max(0, math.Min(alpha, 1))}
+}
+
+type exponential struct {
+	alpha float64
+	v     float64
+	init  bool
+}
+
+func (f *exponential) Next(v int) int {
+	if !f.init {
+		f.v, f.init = float64(v), true
+	} else {
+		f.v += f.alpha * (float64(v) - f.v)
+	}
+	return int(math.Round(f.v))
+}
+
+// Median returns a filter giving the median of the last n reports,
+// which removes single-sample spikes without blurring steps.
+func Median(n int) Filter {
+	return &median{window: make([]int, 0, max(n, 1))}
+}
+
+type median struct {
+	window []int
+	next   int
+	sorted []int
+}
+
+func (f *median) Next(v int) int {
+	if len(f.window) < cap(f.window) {
+		f.window = append(f.window, v)
+	} else {
+		f.window[f.next] = v
+		f.next = (f.next + 1) % len(f.window)
+	}
+	f.sorted = append(f.sorted[:0], f.window...)
+	slices.Sort(f.sorted)
+	return f.sorted[len(f.sorted)/2]
+}
+
+// SetFilter smooths the analog pin's reports with f before AnalogRead
+// and events see them. A nil filter removes it.
+func (b *Board) SetFilter(pin byte, f Filter) error {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	p, ok := b.pins[pin]
+	if !ok {
+		return fmt.Errorf("Invalid pin: %d", pin)
+	}
+	p.filter = f
+	return nil
+}
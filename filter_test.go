@@ -0,0 +1,23 @@
+package gadget
+
+import "testing"
+
+func TestFilters(t *testing.T) {
+	tests := []struct {
+		name string
+		f    Filter
+		in   []int
+		want []int
+	}{
+		{"MovingAverage", MovingAverage(3), []int{3, 6, 9, 30, 0}, []int{3, 5, 6, 15, 13}},
+		{"Exponential", Exponential(0.5), []int{100, 0, 0, 100}, []int{100, 50, 25, 63}},
+		{"Median", Median(3), []int{10, 900, 12, 11, 0, 13}, []int{10, 900, 12, 12, 11, 11}},
+	}
+	for _, tt := range tests {
+		for i, v := range tt.in {
+			if got := tt.f.Next(v); got != tt.want[i] {
+				t.Errorf("%s: sample %d = %d, want %d", tt.name, i, got, tt.want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package firmatawire
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// SyncError is returned by Decoder.Next when the stream was found out
+// of step, e.g. with a data byte where a command was expected because a
+// byte was dropped.
+type SyncError struct {
+	Discarded int // Bytes thrown away to find the next command.
+}
+
+func (e *SyncError) Error() string {
+	return fmt.Sprintf("Lost sync with the board, discarded %d bytes", e.Discarded)
+}
+
+// Decoder reads frames from a stream. The data of every frame shares one
+// buffer, so reading a frame does not allocate once the buffer has grown
+// to the longest frame seen.
+//
+// After a SyncError the decoder picks up again at the next command byte
+// (>= 0x80), so a dropped byte costs one message rather than the rest
+// of the stream. A read deadline passing mid-frame keeps the frame read
+// so far, which the next call completes.
+type Decoder struct {
+	r   *bufio.Reader
+	buf []byte
+
+	// Set when buf holds the start of a frame cut short by a deadline.
+	partial bool
+	at      time.Time
+
+	resyncs atomic.Int64
+}
+
+// NewDecoder returns a Decoder reading from r, which is buffered unless
+// it is already a *bufio.Reader.
+func NewDecoder(r io.Reader) *Decoder {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &Decoder{r: br, buf: make([]byte, 0, 64)}
+}
+
+// Next returns the next frame. Its data is only valid until the next
+// call, so callers must copy any data they keep. Errors from the reader
+// are wrapped.
+func (d *Decoder) Next() (f Frame, err error) {
+	if !d.partial {
+		header, err := d.r.ReadByte()
+		if err != nil {
+			return f, fmt.Errorf("Error reading message header: %w", err)
+		}
+		if header < 0x80 {
+			return f, d.resync(1)
+		}
+		d.buf = append(d.buf[:0], header)
+		d.at = time.Now()
+	}
+	d.partial = false
+	at := d.at
+
+	// Sysex commands have their own header so check for that first.
+	if d.buf[0] == StartSysex {
+		// Read until EndSysex.
+		for {
+			c, err := d.r.ReadByte()
+			if err != nil {
+				return f, d.readError("Error reading sysex data", err)
+			}
+			if c >= 0x80 && c != EndSysex {
+				d.r.UnreadByte()
+				return f, d.resync(len(d.buf))
+			}
+			d.buf = append(d.buf, c)
+			if c == EndSysex {
+				return Frame{Data: d.buf, At: at}, nil
+			}
+		}
+	}
+
+	// Read the two MIDI data bytes
+	for len(d.buf) < 3 {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			return f, d.readError("Error reading MIDI data", err)
+		}
+		if c >= 0x80 {
+			d.r.UnreadByte()
+			return f, d.resync(len(d.buf))
+		}
+		d.buf = append(d.buf, c)
+	}
+	return Frame{Data: d.buf, At: at}, nil
+}
+
+// Wraps an error reading the rest of a frame, keeping the frame if the
+// read deadline passed.
+func (d *Decoder) readError(msg string, err error) error {
+	d.partial = errors.Is(err, os.ErrDeadlineExceeded)
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// Resyncs returns how many SyncErrors Next has returned.
+func (d *Decoder) Resyncs() int64 {
+	return d.resyncs.Load()
+}
+
+// Discards data bytes up to the next command byte, which is left to be
+// read by the next call. n bytes have already been thrown away.
+func (d *Decoder) resync(n int) error {
+	for {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			break
+		}
+		if c >= 0x80 {
+			d.r.UnreadByte()
+			break
+		}
+		n++
+	}
+	d.resyncs.Add(1)
+	return &SyncError{Discarded: n}
+}
@@ -0,0 +1,131 @@
+package firmatawire
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDecoder(t *testing.T) {
+	long := strings.Repeat("x", 5000) // Longer than the bufio.Reader's buffer.
+	in := []byte{AnalogMessage | 2, 0x7F, 0x03, StartSysex, ReportFirmware, 2, 5}
+	in = append(in, long...)
+	in = append(in, EndSysex)
+
+	d := NewDecoder(bytes.NewReader(in))
+	f, err := d.Next()
+	if err != nil || f.Sysex() || !bytes.Equal(f.Data, in[:3]) {
+		t.Fatalf("Expected analog message, got %v % X", err, f.Data)
+	}
+	f, err = d.Next()
+	if err != nil || !f.Sysex() || f.Command() != ReportFirmware || !bytes.Equal(f.Data, in[3:]) {
+		t.Fatalf("Expected %d byte sysex message, got %v, %d bytes", len(in)-3, err, len(f.Data))
+	}
+	if len(f.Body()) != len(long)+2 {
+		t.Fatalf("Unexpected body length %d", len(f.Body()))
+	}
+	if _, err = d.Next(); err == nil {
+		t.Fatalf("Expected an error at the end of input")
+	}
+}
+
+func TestDecoderResync(t *testing.T) {
+	in := []byte{
+		0x12, 0x34, // Stray data bytes.
+		AnalogMessage | 1, 0x10, // Missing its msb.
+		StartSysex, ReportFirmware, 2, // Missing its end.
+		DigitalMessage | 1, 0x01, 0x00,
+	}
+	d := NewDecoder(bytes.NewReader(in))
+
+	for i, want := range []int{2, 2, 3} {
+		var serr *SyncError
+		if _, err := d.Next(); !errors.As(err, &serr) || serr.Discarded != want {
+			t.Fatalf("Error %d = %v, want %d bytes discarded", i, err, want)
+		}
+	}
+	f, err := d.Next()
+	if err != nil || !bytes.Equal(f.Data, in[len(in)-3:]) {
+		t.Fatalf("Expected to resume at the digital message, got %v % X", err, f.Data)
+	}
+	if n := d.Resyncs(); n != 3 {
+		t.Fatalf("Expected 3 resyncs, got %d", n)
+	}
+}
+
+// Repeats its data forever.
+type loopReader struct {
+	data []byte
+	off  int
+}
+
+func (l *loopReader) Read(p []byte) (n int, err error) {
+	for n < len(p) {
+		c := copy(p[n:], l.data[l.off:])
+		n += c
+		l.off = (l.off + c) % len(l.data)
+	}
+	return
+}
+
+// Six analog pins reporting, as at a high sampling rate.
+func BenchmarkDecoder(b *testing.B) {
+	var frame []byte
+	for pin := byte(0); pin < 6; pin++ {
+		frame = append(frame, AnalogMessage|pin, 0x12, 0x04)
+	}
+	d := NewDecoder(&loopReader{data: frame})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := d.Next(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Returns its chunks one per Read, with a deadline error between them.
+type deadlineReader struct {
+	chunks [][]byte
+	late   bool
+}
+
+func (r *deadlineReader) Read(p []byte) (int, error) {
+	if r.late = !r.late; r.late {
+		return 0, os.ErrDeadlineExceeded
+	}
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.chunks[0])
+	r.chunks = r.chunks[1:]
+	return n, nil
+}
+
+func TestDecoderDeadline(t *testing.T) {
+	sysex := []byte{StartSysex, ReportFirmware, 2, 5, 'F', 0, EndSysex}
+	analog := []byte{AnalogMessage | 2, 0x7F, 0x03}
+	r := &deadlineReader{chunks: [][]byte{sysex[:3], sysex[3:], analog[:1], analog[1:]}}
+	d := NewDecoder(r)
+
+	var frames [][]byte
+	for len(frames) < 2 {
+		f, err := d.Next()
+		switch {
+		case errors.Is(err, os.ErrDeadlineExceeded):
+			continue
+		case err != nil:
+			t.Fatalf("Next returned %v after %d frames", err, len(frames))
+		}
+		frames = append(frames, append([]byte(nil), f.Data...))
+	}
+	if !bytes.Equal(frames[0], sysex) || !bytes.Equal(frames[1], analog) {
+		t.Fatalf("Got % X, want % X and % X", frames, sysex, analog)
+	}
+	if d.Resyncs() != 0 {
+		t.Fatalf("Deadlines mid-frame should not resync, got %d", d.Resyncs())
+	}
+}
@@ -0,0 +1,104 @@
+package firmatawire
+
+// Sysex wraps a sysex command and its body in start and end bytes.
+func Sysex(cmd byte, body ...byte) []byte {
+	msg := make([]byte, 0, len(body)+3)
+	msg = append(msg, StartSysex, cmd)
+	msg = append(msg, body...)
+	return append(msg, EndSysex)
+}
+
+// DigitalWrite returns the message setting the outputs of a port, one
+// bit per pin.
+func DigitalWrite(port, mask byte) []byte {
+	return []byte{DigitalMessage | port&0x0F, mask & 0x7F, mask >> 7}
+}
+
+// AnalogWrite returns the message writing v to an analog (PWM, Servo)
+// output. Pins above 15 and values above 14 bits need the extended
+// message, which carries the pin in one 7-bit byte like every other
+// message, so only pins 0-127 can be written.
+func AnalogWrite(pin byte, v int) []byte {
+	if pin <= 0x0F && v <= 0x3FFF {
+		return []byte{AnalogMessage | pin, byte(v) & 0x7F, byte(v>>7) & 0x7F}
+	}
+
+	body := []byte{pin & 0x7F}
+	for v > 0 || len(body) == 1 {
+		body = append(body, byte(v)&0x7F)
+		v >>= 7
+	}
+	return Sysex(ExtendedAnalog, body...)
+}
+
+// QueryPinState returns the message asking for a pin's mode and state.
+// Only pins 0-127 can be queried.
+func QueryPinState(pin byte) []byte {
+	return Sysex(PinStateQuery, pin&0x7F)
+}
+
+// PinStateReply decodes the body of the pin state response answering
+// the query for pin. ok is false if it answers another pin.
+func PinStateReply(body []byte, pin byte) (mode byte, state int, ok bool) {
+	if len(body) < 3 || body[0] != pin {
+		return 0, 0, false
+	}
+	mode = body[1]
+	for i, v := range body[2:] {
+		state |= int(v&0x7F) << (7 * uint(i))
+	}
+	return mode, state, true
+}
+
+// SetMode returns the message setting a pin's mode.
+func SetMode(pin, mode byte) []byte {
+	return []byte{SetPinMode, pin & 0x7F, mode & 0x7F}
+}
+
+// ReportAnalogPin returns the message turning reporting of an analog pin,
+// by its A0 style number, on or off. Only A0-A15 can be addressed.
+func ReportAnalogPin(analogPin byte, on bool) []byte {
+	return []byte{ReportAnalog | analogPin&0x0F, boolByte(on)}
+}
+
+// ReportDigitalPort returns the message turning reporting of a digital
+// port on or off.
+func ReportDigitalPort(port byte, on bool) []byte {
+	return []byte{ReportDigital | port&0x0F, boolByte(on)}
+}
+
+// AppendUint14 appends v as two 7-bit bytes, least significant first.
+func AppendUint14(b []byte, v int) []byte {
+	return append(b, byte(v)&0x7F, byte(v>>7)&0x7F)
+}
+
+// Uint14 decodes a value sent as two 7-bit bytes.
+func Uint14(lsb, msb byte) int {
+	return int(lsb&0x7F) | int(msb&0x7F)<<7
+}
+
+// AppendBytes7 appends every byte of data as two 7-bit bytes, as used
+// by I2C and other sysex bodies carrying 8-bit data.
+func AppendBytes7(b, data []byte) []byte {
+	for _, d := range data {
+		b = append(b, d&0x7F, d>>7)
+	}
+	return b
+}
+
+// Bytes7 decodes data sent with AppendBytes7. A trailing odd byte is
+// ignored.
+func Bytes7(data []byte) []byte {
+	out := make([]byte, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		out = append(out, data[i]|data[i+1]<<7)
+	}
+	return out
+}
+
+func boolByte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,90 @@
+package firmatawire
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAnalogWrite(t *testing.T) {
+	tests := []struct {
+		pin  byte
+		v    int
+		want []byte
+	}{
+		{3, 255, []byte{AnalogMessage | 3, 0x7F, 0x01}},
+		{15, 1500, []byte{AnalogMessage | 15, 0x5C, 0x0B}},
+		{44, 0, []byte{StartSysex, ExtendedAnalog, 44, 0x00, EndSysex}},
+		{44, 200, []byte{StartSysex, ExtendedAnalog, 44, 0x48, 0x01, EndSysex}},
+		{2, 0x4000, []byte{StartSysex, ExtendedAnalog, 2, 0x00, 0x00, 0x01, EndSysex}},
+	}
+	for _, tt := range tests {
+		if got := AnalogWrite(tt.pin, tt.v); !bytes.Equal(got, tt.want) {
+			t.Errorf("AnalogWrite(%d, %d) = % X, want % X", tt.pin, tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestEncoders(t *testing.T) {
+	tests := []struct {
+		got, want []byte
+	}{
+		{DigitalWrite(1, 0x8A), []byte{DigitalMessage | 1, 0x0A, 0x01}},
+		{SetMode(13, 1), []byte{SetPinMode, 13, 1}},
+		{ReportAnalogPin(2, true), []byte{ReportAnalog | 2, 1}},
+		{ReportDigitalPort(1, false), []byte{ReportDigital | 1, 0}},
+		{Sysex(SamplingInterval, AppendUint14(nil, 1000)...), []byte{StartSysex, SamplingInterval, 0x68, 0x07, EndSysex}},
+		{AppendBytes7(nil, []byte{0xFF, 0x01}), []byte{0x7F, 0x01, 0x01, 0x00}},
+	}
+	for i, tt := range tests {
+		if !bytes.Equal(tt.got, tt.want) {
+			t.Errorf("Encoder %d = % X, want % X", i, tt.got, tt.want)
+		}
+	}
+	if v := Uint14(0x68, 0x07); v != 1000 {
+		t.Errorf("Uint14 = %d, want 1000", v)
+	}
+	if b := Bytes7([]byte{0x7F, 0x01, 0x01, 0x00, 0x05}); !bytes.Equal(b, []byte{0xFF, 0x01}) {
+		t.Errorf("Bytes7 = % X", b)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	data := []byte{
+		SetPinMode, 13, 1,
+		StartSysex, SamplingInterval, 0x13, EndSysex,
+		DigitalMessage | 1, 0x20, 0,
+		0x55, // A stray data byte.
+	}
+	msgs := Split(data)
+	if len(msgs) != 3 || !bytes.Equal(msgs[1], data[3:7]) || !bytes.Equal(msgs[2], data[7:]) {
+		t.Fatalf("Unexpected messages: % X", msgs)
+	}
+	if Command(msgs[2]) != DigitalMessage || CommandName(Command(msgs[1])) != "SAMPLING_INTERVAL" {
+		t.Fatalf("Unexpected commands")
+	}
+}
+
+func TestPinStateReply(t *testing.T) {
+	if !bytes.Equal(QueryPinState(44), []byte{StartSysex, PinStateQuery, 44, EndSysex}) {
+		t.Errorf("QueryPinState(44) = % X", QueryPinState(44))
+	}
+	tests := []struct {
+		body  []byte
+		pin   byte
+		mode  byte
+		state int
+		ok    bool
+	}{
+		{[]byte{2, 0, 1}, 2, 0, 1, true},
+		{[]byte{2, 3, 0x7F, 0x01}, 2, 3, 0xFF, true},
+		{[]byte{8, 3, 0x10}, 9, 0, 0, false}, // Pin 8's reply.
+		{[]byte{2, 0}, 2, 0, 0, false},       // No state.
+	}
+	for _, tt := range tests {
+		mode, state, ok := PinStateReply(tt.body, tt.pin)
+		if mode != tt.mode || state != tt.state || ok != tt.ok {
+			t.Errorf("PinStateReply(% X, %d) = %d, %d, %t; want %d, %d, %t",
+				tt.body, tt.pin, mode, state, ok, tt.mode, tt.state, tt.ok)
+		}
+	}
+}
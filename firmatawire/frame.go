@@ -0,0 +1,75 @@
+package firmatawire
+
+import "time"
+
+// Frame is a message received from the board.
+type Frame struct {
+	// The message bytes, including the command and, for sysex, the
+	// start and end bytes.
+	Data []byte
+
+	// When the frame started to arrive.
+	At time.Time
+}
+
+// Sysex reports whether the frame is a sysex message.
+func (f Frame) Sysex() bool {
+	return len(f.Data) > 0 && f.Data[0] == StartSysex
+}
+
+// Command returns the frame's command, see Command.
+func (f Frame) Command() byte {
+	return Command(f.Data)
+}
+
+// Body returns the bytes between a sysex frame's command and end byte,
+// or the data bytes of a MIDI message. It is empty, never an error, for
+// short frames.
+func (f Frame) Body() []byte {
+	switch {
+	case !f.Sysex():
+		if len(f.Data) < 1 {
+			return nil
+		}
+		return f.Data[1:]
+	case len(f.Data) < 3:
+		return nil
+	}
+	return f.Data[2 : len(f.Data)-1]
+}
+
+// Command returns the command of a message: the second byte of sysex
+// messages, and the first, without its pin or port, for MIDI messages.
+func Command(msg []byte) byte {
+	switch {
+	case len(msg) == 0:
+		return 0
+	case msg[0] == StartSysex && len(msg) > 1:
+		return msg[1]
+	case msg[0] < 0xF0:
+		return msg[0] & 0xF0
+	}
+	return msg[0]
+}
+
+// Split splits a stream of messages, each starting at a command byte.
+// Sysex messages run to their end byte.
+func Split(data []byte) (msgs [][]byte) {
+	start, inSysex := 0, false
+	for i, d := range data {
+		switch {
+		case d == EndSysex && inSysex:
+			msgs = append(msgs, data[start:i+1])
+			start, inSysex = i+1, false
+		case d >= 0x80 && !inSysex:
+			if i > start {
+				msgs = append(msgs, data[start:i])
+			}
+			start, inSysex = i, d == StartSysex
+		}
+	}
+	if start < len(data) {
+		msgs = append(msgs, data[start:])
+	}
+	return
+}
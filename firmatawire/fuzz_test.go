@@ -0,0 +1,47 @@
+package firmatawire
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func FuzzDecoder(f *testing.F) {
+	f.Add([]byte{AnalogMessage | 2, 0x7F, 0x03, StartSysex, ReportFirmware, 2, 5, 'x', EndSysex})
+	f.Add([]byte{0x12, AnalogMessage, 0x10, StartSysex, 2, DigitalMessage, 1, 0})
+	f.Add([]byte{StartSysex, EndSysex, EndSysex, 0xFF})
+
+	f.Fuzz(func(t *testing.T, in []byte) {
+		d := NewDecoder(bytes.NewReader(in))
+		read := 0
+		for {
+			fr, err := d.Next()
+			var serr *SyncError
+			switch {
+			case errors.Is(err, io.EOF):
+				return
+			case errors.As(err, &serr):
+				read += serr.Discarded
+				continue
+			case err != nil:
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			read += len(fr.Data)
+
+			if fr.Data[0] < 0x80 {
+				t.Fatalf("Frame starts with a data byte: % X", fr.Data)
+			}
+			if fr.Sysex() && fr.Data[len(fr.Data)-1] != EndSysex {
+				t.Fatalf("Sysex frame without an end: % X", fr.Data)
+			}
+			if !fr.Sysex() && len(fr.Data) != 3 {
+				t.Fatalf("MIDI frame of %d bytes: % X", len(fr.Data), fr.Data)
+			}
+			fr.Body()
+			if read > len(in) {
+				t.Fatalf("Decoded %d bytes from %d", read, len(in))
+			}
+		}
+	})
+}
@@ -0,0 +1,89 @@
+// Package firmatawire encodes and decodes the Firmata wire format: the
+// MIDI style messages and sysex frames exchanged with a board. It has no
+// state beyond the Decoder, so it can be used and tested without a
+// board.
+package firmatawire
+
+import "fmt"
+
+// Message command bytes (128-255 / 0x80-0xFF). Commands below 0xF0
+// carry a pin or port number in their low nibble.
+const (
+	DigitalMessage byte = 0x90 // Send data for a digital port.
+	AnalogMessage  byte = 0xE0 // Send data for an analog pin (or PWM).
+	ReportDigital  byte = 0xD0 // Enable digital input by port.
+	ReportAnalog   byte = 0xC0 // Enable analog input by pin #.
+	SetPinMode     byte = 0xF4 // Set the pin mode.
+	ReportVersion  byte = 0xF9 // Report protocol version.
+	SystemReset    byte = 0xFF // Reset from MIDI.
+	StartSysex     byte = 0xF0 // Start a MIDI Sysex message.
+	EndSysex       byte = 0xF7 // End a MIDI Sysex message.
+)
+
+// Extended command set using sysex (0-127 / 0x00-0x7F). 0x00-0x0F are
+// reserved for user-defined commands.
+const (
+	ServoConfig           byte = 0x70 // Set max angle, minPulse, maxPulse, freq.
+	StringData            byte = 0x71 // A string message with 14-bits per char.
+	ShiftData             byte = 0x75 // A bitstream to/from a shift register.
+	I2CRequest            byte = 0x76 // Send an I2C read/write request.
+	I2CReply              byte = 0x77 // A reply to an I2C read request.
+	I2CConfig             byte = 0x78 // Config I2C read request.
+	SerialMessage         byte = 0x60 // Serial port passthrough, see SerialFirmata.
+	EncoderData           byte = 0x61 // Quadrature encoders, see EncoderFirmata.
+	SPIData               byte = 0x68 // SPI transfers, see SpiFirmata.
+	ExtendedAnalog        byte = 0x6F // Analog write (PWM, Servo, etc) to any pin.
+	PinStateQuery         byte = 0x6D // Ask for a pin's current mode and value.
+	PinStateResponse      byte = 0x6E // Reply with pin's current mode and value.
+	CapabilityQuery       byte = 0x6B // Ask for supported modes and resolution of all pins.
+	CapabilityResponse    byte = 0x6C // Reply with supported modes and resolution.
+	AnalogMappingQuery    byte = 0x69 // Ask for mapping of analog to pin numbers.
+	AnalogMappingResponse byte = 0x6A // Reply with mapping info.
+	ReportFirmware        byte = 0x79 // Report name and version of the firmware.
+	SamplingInterval      byte = 0x7A // Set the poll rate of the main loop.
+	SysexNonRealtime      byte = 0x7E // MIDI reserved for non-realtime messages.
+	SysexRealtime         byte = 0x7F // MIDI reserved for realtime messages.
+)
+
+// Firmata's names for the commands. MIDI commands are 0x80 and above and
+// sysex commands below, so one map holds both.
+var commandNames = map[byte]string{
+	DigitalMessage:        "DIGITAL_MESSAGE",
+	AnalogMessage:         "ANALOG_MESSAGE",
+	ReportDigital:         "REPORT_DIGITAL",
+	ReportAnalog:          "REPORT_ANALOG",
+	SetPinMode:            "SET_PIN_MODE",
+	ReportVersion:         "REPORT_VERSION",
+	SystemReset:           "SYSTEM_RESET",
+	StartSysex:            "START_SYSEX",
+	EndSysex:              "END_SYSEX",
+	ServoConfig:           "SERVO_CONFIG",
+	StringData:            "STRING_DATA",
+	ShiftData:             "SHIFT_DATA",
+	I2CRequest:            "I2C_REQUEST",
+	I2CReply:              "I2C_REPLY",
+	I2CConfig:             "I2C_CONFIG",
+	SerialMessage:         "SERIAL_MESSAGE",
+	EncoderData:           "ENCODER_DATA",
+	SPIData:               "SPI_DATA",
+	ExtendedAnalog:        "EXTENDED_ANALOG",
+	PinStateQuery:         "PIN_STATE_QUERY",
+	PinStateResponse:      "PIN_STATE_RESPONSE",
+	CapabilityQuery:       "CAPABILITY_QUERY",
+	CapabilityResponse:    "CAPABILITY_RESPONSE",
+	AnalogMappingQuery:    "ANALOG_MAPPING_QUERY",
+	AnalogMappingResponse: "ANALOG_MAPPING_RESPONSE",
+	ReportFirmware:        "REPORT_FIRMWARE",
+	SamplingInterval:      "SAMPLING_INTERVAL",
+	SysexNonRealtime:      "SYSEX_NON_REALTIME",
+	SysexRealtime:         "SYSEX_REALTIME",
+}
+
+// CommandName returns Firmata's name for command cmd, or its hex value
+// if unknown.
+func CommandName(cmd byte) string {
+	if name, ok := commandNames[cmd]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%02X", cmd)
+}
@@ -0,0 +1,134 @@
+// Package firmware uploads a StandardFirmata build to a board over its
+// serial port.
+//
+// Uploads are done by avrdude (AVR boards) or bossac (SAM boards),
+// which must be on the PATH. No images are bundled: StandardFirmata is
+// LGPL, and a prebuilt image would have to ship with its exact sources
+// and build for every board. Build one for the board with the Arduino
+// IDE or arduino-cli instead, e.g.
+//
+//	arduino-cli compile -b arduino:avr:uno -e StandardFirmata
+//
+// and upload the resulting StandardFirmata.ino.hex:
+//
+//	err := firmware.FlashFile(ctx, "/dev/ttyACM0", "uno", "StandardFirmata.ino.hex")
+package firmware
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ZachMassia/GoGoGadget"
+)
+
+// Profile describes how to upload to a kind of board.
+type Profile struct {
+	Name  string // e.g. "uno".
+	Tool  string // "avrdude" or "bossac".
+	Image string // Kind of image, "hex" or "bin".
+
+	// avrdude's part (-p) and programmer (-c), and the bootloader's
+	// baud rate.
+	Part       string
+	Programmer string
+	Baud       int
+
+	// Reset the board into its bootloader by opening the port at 1200
+	// baud first, as needed by the Due.
+	Touch1200 bool
+}
+
+// Profiles are the supported boards, by name.
+var Profiles = map[string]Profile{
+	"uno":      {Name: "uno", Tool: "avrdude", Image: "hex", Part: "atmega328p", Programmer: "arduino", Baud: 115200},
+	"nano":     {Name: "nano", Tool: "avrdude", Image: "hex", Part: "atmega328p", Programmer: "arduino", Baud: 115200},
+	"nano-old": {Name: "nano-old", Tool: "avrdude", Image: "hex", Part: "atmega328p", Programmer: "arduino", Baud: 57600},
+	"mega":     {Name: "mega", Tool: "avrdude", Image: "hex", Part: "atmega2560", Programmer: "wiring", Baud: 115200},
+	"due":      {Name: "due", Tool: "bossac", Image: "bin", Touch1200: true},
+}
+
+// Names returns the names of the supported boards in order.
+func Names() (names []string) {
+	for n := range Profiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return
+}
+
+// FlashFile uploads the image in file to the board on port, using the
+// profile named board.
+func FlashFile(ctx context.Context, port, board, file string) error {
+	p, ok := Profiles[board]
+	if !ok {
+		return fmt.Errorf("Unknown board '%s'", board)
+	}
+	img, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	return Flash(ctx, port, p, img)
+}
+
+// Flash uploads image, an Intel hex file for avrdude or a raw binary for
+// bossac, to the board on port. The port must not be open. The tool's
+// output is included in the error if it fails.
+func Flash(ctx context.Context, port string, p Profile, image []byte) error {
+	if _, err := exec.LookPath(p.Tool); err != nil {
+		return fmt.Errorf("Uploading to %s needs %s: %s", p.Name, p.Tool, err)
+	}
+
+	dir, err := os.MkdirTemp("", "gadget-firmware")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "firmware."+p.Image)
+	if err = os.WriteFile(file, image, 0o644); err != nil {
+		return err
+	}
+
+	if p.Touch1200 {
+		if err = touch1200(port); err != nil {
+			return err
+		}
+	}
+
+	name, args := p.command(port, file)
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err = cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %s\n%s", name, err, out.Bytes())
+	}
+	return nil
+}
+
+// Returns the upload command for p.
+func (p Profile) command(port, file string) (name string, args []string) {
+	switch p.Tool {
+	case "bossac":
+		return "bossac", []string{"--port=" + filepath.Base(port), "-U", "false", "-e", "-w", "-v", "-b", file, "-R"}
+	default:
+		return "avrdude", []string{"-p", p.Part, "-c", p.Programmer, "-P", port,
+			"-b", fmt.Sprint(p.Baud), "-D", "-U", "flash:w:" + file + ":i"}
+	}
+}
+
+// Opens and closes the port at 1200 baud, which tells boards with a
+// native USB port to erase and start their bootloader.
+func touch1200(port string) error {
+	s, err := gadget.OpenSerial(port, 1200)
+	if err != nil {
+		return fmt.Errorf("Error resetting %s: %s", port, err)
+	}
+	s.Close()
+	time.Sleep(500 * time.Millisecond) // Give the bootloader time to start.
+	return nil
+}
@@ -0,0 +1,31 @@
+package firmware
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCommand(t *testing.T) {
+	name, args := Profiles["uno"].command("/dev/ttyACM0", "/tmp/uno.hex")
+	if name != "avrdude" || strings.Join(args, " ") !=
+		"-p atmega328p -c arduino -P /dev/ttyACM0 -b 115200 -D -U flash:w:/tmp/uno.hex:i" {
+		t.Errorf("Unexpected avrdude command: %s %v", name, args)
+	}
+
+	name, args = Profiles["due"].command("/dev/ttyACM0", "/tmp/due.bin")
+	if name != "bossac" || args[0] != "--port=ttyACM0" || args[len(args)-2] != "/tmp/due.bin" {
+		t.Errorf("Unexpected bossac command: %s %v", name, args)
+	}
+}
+
+func TestFlashFile(t *testing.T) {
+	if err := FlashFile(context.Background(), "/dev/null", "abacus", "firmware.hex"); err == nil {
+		t.Errorf("Unknown board should fail")
+	}
+	for _, n := range Names() {
+		if p := Profiles[n]; p.Name != n {
+			t.Errorf("Profile %s is named %s", n, p.Name)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package gadget
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Pulses per liter of a YF-S201, whose frequency in Hz is 7.5 times
+// the flow in L/min.
+const YFS201PulsesPerLiter = 450
+
+// FlowSensor is a hall effect water flow sensor, such as the YF-S201,
+// whose pulses are counted from the pin's digital reports. Every edge
+// costs a serial message, so fast flows on sensors with many pulses
+// per liter can outrun the link; Firmata reports changes once per
+// loop, a few hundred per second at most.
+type FlowSensor struct {
+	board *Board
+	pin   byte
+
+	// Pulses per liter. Defaults to YFS201PulsesPerLiter.
+	PulsesPerLiter float64
+
+	// The time Rate averages over. Defaults to one second.
+	Window time.Duration
+
+	m      sync.Mutex
+	total  int64       // Pulses since the last Reset.
+	recent []time.Time // Pulses within Window, oldest first.
+	sub    *Subscription
+}
+
+// NewFlowSensor returns a FlowSensor on the given digital pin, which is
+// put in PULLUP mode, as the sensors have open collector outputs, with
+// reporting turned on.
+func NewFlowSensor(b *Board, pin byte) (f *FlowSensor, err error) {
+	if err = b.ensurePinMode(pin, PULLUP); err != nil {
+		return nil, err
+	}
+	if err = b.SetPinReporting(pin, true); err != nil {
+		return nil, err
+	}
+
+	f = &FlowSensor{
+		board:          b,
+		pin:            pin,
+		PulsesPerLiter: YFS201PulsesPerLiter,
+		Window:         time.Second,
+	}
+	return
+}
+
+// Start counts pulses in the background.
+func (f *FlowSensor) Start() (err error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if f.sub != nil {
+		return fmt.Errorf("Flow sensor on pin %d already started", f.pin)
+	}
+	f.sub = f.board.onEdge(f.pin, HIGH, f.pulse)
+	return
+}
+
+// Halt stops counting.
+func (f *FlowSensor) Halt() (err error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if f.sub != nil {
+		f.sub.Unsubscribe()
+		f.sub = nil
+	}
+	return
+}
+
+// Rate returns the flow in liters per minute, averaged over Window.
+func (f *FlowSensor) Rate() float64 {
+	return f.rate(time.Now())
+}
+
+// Volume returns the liters that have flowed since the last Reset.
+func (f *FlowSensor) Volume() float64 {
+	f.m.Lock()
+	defer f.m.Unlock()
+	return float64(f.total) / f.PulsesPerLiter
+}
+
+// Pulses returns the pulses counted since the last Reset.
+func (f *FlowSensor) Pulses() int64 {
+	f.m.Lock()
+	defer f.m.Unlock()
+	return f.total
+}
+
+// Reset zeroes the volume.
+func (f *FlowSensor) Reset() {
+	f.m.Lock()
+	defer f.m.Unlock()
+	f.total = 0
+}
+
+func (f *FlowSensor) pulse(at time.Time) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	f.total++
+	f.recent = append(f.recent, at)
+	f.prune(at)
+}
+
+func (f *FlowSensor) rate(now time.Time) float64 {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	f.prune(now)
+	perSecond := float64(len(f.recent)) / f.Window.Seconds()
+	return 60 * perSecond / f.PulsesPerLiter
+}
+
+// Drops pulses older than Window. Must be called with f.m held.
+func (f *FlowSensor) prune(now time.Time) {
+	i := 0
+	for i < len(f.recent) && now.Sub(f.recent[i]) >= f.Window {
+		i++
+	}
+	f.recent = append(f.recent[:0], f.recent[i:]...)
+}
@@ -0,0 +1,44 @@
+package gadget
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFlowSensor(t *testing.T) {
+	b := newTestBoard(t, nil, nil, map[byte][]Capability{2: {{INPUT, 1}, {OUTPUT, 1}, {PULLUP, 1}}})
+	f, err := NewFlowSensor(b, 2)
+	if err != nil {
+		t.Fatalf("NewFlowSensor: %s", err)
+	}
+
+	// 75 pulses a second is 10 L/min on a YF-S201.
+	start := time.Now()
+	for i := 0; i < 150; i++ {
+		f.pulse(start.Add(time.Duration(i) * time.Second / 75))
+	}
+	if r := f.rate(start.Add(2 * time.Second)); math.Abs(r-10) > 0.2 {
+		t.Fatalf("Rate = %f L/min, want 10", r)
+	}
+	if v := f.Volume(); v != 150.0/450 {
+		t.Fatalf("Volume = %f L, want %f", v, 150.0/450)
+	}
+	if r := f.rate(start.Add(10 * time.Second)); r != 0 {
+		t.Fatalf("Rate after flow stopped = %f, want 0", r)
+	}
+
+	// Pulses are counted from rising edges.
+	f.Reset()
+	f.Start()
+	defer f.Halt()
+	for i := 0; i < 3; i++ {
+		b.handleDigitalMessage(message{data: []byte{digitalMessage, 0x04, 0}, at: time.Now()})
+		b.handleDigitalMessage(message{data: []byte{digitalMessage, 0x00, 0}, at: time.Now()})
+	}
+	for deadline := time.Now().Add(time.Second); f.Pulses() != 3; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Counted %d pulses, want 3", f.Pulses())
+		}
+	}
+}
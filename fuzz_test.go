@@ -0,0 +1,56 @@
+package gadget
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+// Feeds arbitrary input from the board through the core handlers.
+func FuzzHandlers(f *testing.F) {
+	f.Add([]byte{startSysex, analogMappingResponse, 0x7F, 0x7F, 0, 1, endSysex,
+		startSysex, capabilityResponse, INPUT, 1, OUTPUT, 1, 0x7F, ANALOG, 10, 0x7F, endSysex,
+		analogMessage | 1, 0x10, 0x02, digitalMessage, 0x03, 0x00})
+	f.Add([]byte{startSysex, reportFirmware, 2, 5, 'S', 0, endSysex, reportVersion, 2, 5})
+	f.Add([]byte{startSysex, i2cReply, 0x48, 0, 0, 0, 0x10, 0x01, endSysex})
+	f.Add([]byte{startSysex, capabilityResponse, endSysex, startSysex, endSysex})
+
+	f.Fuzz(func(t *testing.T, in []byte) {
+		b := &Board{
+			pins:            make(map[byte]*pin),
+			analogMapping:   make(map[byte]byte),
+			ready:           make(chan bool, 1),
+			boardDoneReboot: make(chan bool, 1),
+			i2cReplies:      make(chan i2cReplyData, 1),
+			bus:             NewEventBus(),
+			out:             newBatchWriter(io.Discard),
+			logger:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+		}
+		b.msgHandlers = b.coreHandlers()
+
+		d := firmatawire.NewDecoder(bytes.NewReader(in))
+		for {
+			f, err := d.Next()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				continue
+			}
+			msg := message{t: midiMsg, data: f.Data, at: f.At}
+			if f.Sysex() {
+				msg.t = sysexMsg
+			}
+			b.handleCallback(msg)
+
+			select {
+			case <-b.boardDoneReboot:
+			default:
+			}
+		}
+	})
+}
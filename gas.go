@@ -0,0 +1,207 @@
+package gadget
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// ErrGasWarmingUp is returned by GasSensor readings taken before the
+// heater has warmed up.
+var ErrGasWarmingUp = errors.New("Gas sensor still warming up")
+
+// GasCurve fits a gas's line on an MQ sensor's sensitivity chart:
+// ppm = A * (Rs/R0)^B.
+type GasCurve struct {
+	A, B float64
+}
+
+// GasModel describes an MQ series sensor.
+type GasModel struct {
+	Name string
+
+	// Rs/R0 in clean air, from the datasheet's chart.
+	CleanAirRatio float64
+
+	// How long the heater needs after power up before readings are
+	// stable. Sensors stored for a while need a day or two the first
+	// time.
+	WarmUp time.Duration
+
+	Curves map[string]GasCurve
+}
+
+var (
+	// MQ-2 flammable gas and smoke sensor.
+	MQ2 = GasModel{
+		Name:          "MQ-2",
+		CleanAirRatio: 9.83,
+		WarmUp:        3 * time.Minute,
+		Curves: map[string]GasCurve{
+			"H2":      {987.99, -2.162},
+			"LPG":     {574.25, -2.222},
+			"CO":      {36974, -3.109},
+			"Alcohol": {3616.1, -2.675},
+			"Propane": {658.71, -2.168},
+		},
+	}
+
+	// MQ-135 air quality sensor.
+	MQ135 = GasModel{
+		Name:          "MQ-135",
+		CleanAirRatio: 3.6,
+		WarmUp:        3 * time.Minute,
+		Curves: map[string]GasCurve{
+			"CO":      {605.18, -3.937},
+			"Alcohol": {77.255, -3.18},
+			"CO2":     {110.47, -2.862},
+			"Toluene": {44.947, -3.445},
+			"NH4":     {102.2, -2.473},
+			"Acetone": {34.668, -3.369},
+		},
+	}
+)
+
+// GasSensor is an MQ series gas sensor module on an analog pin. Its
+// readings are estimates: the curves are fitted to the datasheet's
+// charts at 20°C and 65% humidity, and each sensor needs its baseline,
+// R0, measured in clean air with Calibrate.
+type GasSensor struct {
+	board   *Board
+	pin     byte // Normal (not A0 style) pin number.
+	max     int  // Full scale raw reading.
+	model   GasModel
+	started time.Time
+
+	// The module's load resistor in kΩ. Defaults to 10, though some
+	// modules fit 1 or 5.
+	LoadResistance float64
+
+	// The sensor's resistance in clean air in kΩ, set by Calibrate or
+	// from an earlier calibration.
+	R0 float64
+
+	// Number of readings averaged per measurement, defaults to 5.
+	Samples     int
+	SampleDelay time.Duration
+}
+
+// NewGasSensor returns a GasSensor of the given model on an analog
+// pin, with analog reporting turned on. The warm up time is counted
+// from now.
+func NewGasSensor(b *Board, pin byte, model GasModel) (s *GasSensor, err error) {
+	bits, err := b.AnalogResolution(pin)
+	if err != nil {
+		return nil, err
+	}
+	if err = b.ensurePinMode(pin, ANALOG); err != nil {
+		return nil, err
+	}
+	if err = b.SetPinReporting(pin, true); err != nil {
+		return nil, err
+	}
+
+	s = &GasSensor{
+		board:          b,
+		pin:            pin,
+		max:            analogMax(bits),
+		model:          model,
+		started:        time.Now(),
+		LoadResistance: 10,
+		Samples:        5,
+		SampleDelay:    defaultSampleDelay,
+	}
+	return
+}
+
+// Ready reports whether the heater has warmed up.
+func (s *GasSensor) Ready() bool {
+	return time.Since(s.started) >= s.model.WarmUp
+}
+
+// WarmUpLeft returns how long until the sensor is ready.
+func (s *GasSensor) WarmUpLeft() time.Duration {
+	return max(0, s.model.WarmUp-time.Since(s.started))
+}
+
+// Resistance returns the sensor's resistance, Rs, in kΩ.
+func (s *GasSensor) Resistance() (rs float64, err error) {
+	if !s.Ready() {
+		return 0, ErrGasWarmingUp
+	}
+
+	n := max(s.Samples, 1)
+	sum := 0
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			time.Sleep(s.SampleDelay)
+		}
+		raw, err := s.board.AnalogRead(s.pin)
+		if err != nil {
+			return 0, err
+		}
+		sum += raw
+	}
+	return gasResistance(float64(sum)/float64(n), s.max, s.LoadResistance)
+}
+
+// Calibrate measures R0 with the sensor in clean air.
+func (s *GasSensor) Calibrate() error {
+	rs, err := s.Resistance()
+	if err != nil {
+		return err
+	}
+	s.R0 = rs / s.model.CleanAirRatio
+	return nil
+}
+
+// Ratio returns Rs/R0, which falls as the gas concentration rises.
+func (s *GasSensor) Ratio() (float64, error) {
+	if s.R0 <= 0 {
+		return 0, fmt.Errorf("%s on pin %d is not calibrated", s.model.Name, s.pin)
+	}
+	rs, err := s.Resistance()
+	if err != nil {
+		return 0, err
+	}
+	return rs / s.R0, nil
+}
+
+// PPM estimates the concentration of gas, one of the model's Gases, in
+// parts per million.
+func (s *GasSensor) PPM(gas string) (float64, error) {
+	c, ok := s.model.Curves[gas]
+	if !ok {
+		return 0, fmt.Errorf("%s has no curve for '%s'", s.model.Name, gas)
+	}
+	ratio, err := s.Ratio()
+	if err != nil {
+		return 0, err
+	}
+	return c.PPM(ratio), nil
+}
+
+// Gases returns the names of the gases the model has curves for.
+func (m GasModel) Gases() (names []string) {
+	for name := range m.Curves {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// PPM returns the concentration for an Rs/R0 ratio.
+func (c GasCurve) PPM(ratio float64) float64 {
+	return c.A * math.Pow(ratio, c.B)
+}
+
+// Returns the sensor resistance from the reading across the load
+// resistor: Rs = RL * (Vcc - Vout) / Vout.
+func gasResistance(raw float64, full int, load float64) (float64, error) {
+	if raw <= 0 {
+		return 0, fmt.Errorf("Gas sensor reads 0, check its wiring")
+	}
+	return load * (float64(full) - raw) / raw, nil
+}
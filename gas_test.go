@@ -0,0 +1,43 @@
+package gadget
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestGasSensor(t *testing.T) {
+	b := newTestBoard(t, nil, map[byte][]Capability{14: {{ANALOG, 10}}}, nil)
+	s, err := NewGasSensor(b, 14, MQ135)
+	if err != nil {
+		t.Fatalf("NewGasSensor: %s", err)
+	}
+	s.Samples = 1
+
+	if _, err = s.Resistance(); err != ErrGasWarmingUp {
+		t.Fatalf("Expected ErrGasWarmingUp, got %v", err)
+	}
+	s.started = time.Now().Add(-MQ135.WarmUp)
+
+	if _, err = s.PPM("CO2"); err == nil {
+		t.Fatalf("Expected error before calibration")
+	}
+
+	// 1023/5 across the load resistor: Rs = 4 * RL.
+	b.pins[14].analogVal = 1023 / 5
+	if err = s.Calibrate(); err != nil {
+		t.Fatalf("Calibrate: %s", err)
+	}
+	if want := 40 / MQ135.CleanAirRatio; math.Abs(s.R0-want) > 0.1 {
+		t.Fatalf("R0 = %f, want %f", s.R0, want)
+	}
+
+	// Clean air reads the curve at the clean air ratio.
+	ppm, err := s.PPM("CO2")
+	if want := MQ135.Curves["CO2"].PPM(MQ135.CleanAirRatio); err != nil || math.Abs(ppm-want) > 0.5 {
+		t.Fatalf("PPM = %f, %v, want %f", ppm, err, want)
+	}
+	if _, err = s.PPM("Radon"); err == nil {
+		t.Fatalf("Expected error for an unknown gas")
+	}
+}
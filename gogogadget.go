@@ -2,44 +2,51 @@
 package gadget
 
 import (
-	"path/filepath"
+	"fmt"
+	"time"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
 )
 
+// The Firmata commands, from firmatawire.
 const (
-	// The following constants are all from Firmata.h
-	//
 	// Message command bytes (128-255 / 0x80-0xFF)
-	digitalMessage byte = 0x90 // Send data for a digital pin.
-	analogMessage  byte = 0xE0 // Send data for an analog pin (or PWM).
-	reportDigital  byte = 0xD0 // Enable digital input by port pair.
-	reportAnalog   byte = 0xC0 // Enable analog input by pin #.
-	setPinMode     byte = 0xF4 // Set the pin mode.
-	reportVersion  byte = 0xF9 // Report protocol version.
-	unknown        byte = 0xFF // TODO: Change to system reset as per firmata.h
-	startSysex     byte = 0xF0 // Start a MIDI Sysex message
-	endSysex       byte = 0xF7 // End a MIDI Sysex message.
-
-	// Extended command set using systex. (0-127 / 0x00-0x7F)
+	digitalMessage = firmatawire.DigitalMessage
+	analogMessage  = firmatawire.AnalogMessage
+	reportDigital  = firmatawire.ReportDigital
+	reportAnalog   = firmatawire.ReportAnalog
+	setPinMode     = firmatawire.SetPinMode
+	reportVersion  = firmatawire.ReportVersion
+	systemReset    = firmatawire.SystemReset
+	startSysex     = firmatawire.StartSysex
+	endSysex       = firmatawire.EndSysex
+
+	// Extended command set using sysex. (0-127 / 0x00-0x7F)
 	// 0x00-0x0F reserved for user-defined commands.
-	servoConfig           byte = 0x70 // Set max angle, minPulse, maxPulse, freq.
-	stringData            byte = 0x71 // A string message with 14-bits per char.
-	shiftData             byte = 0x75 // A bitstream to/from a shift register.
-	i2cRequest            byte = 0x76 // Send an I2C read/write request.
-	i2cReply              byte = 0x77 // A reply to an I2C read request.
-	i2cConfig             byte = 0x78 // Config I2C read request.
-	extendedAnalog        byte = 0x6F // Analog write (PWM, Servo, etc) to any pin.
-	pinStateQuery         byte = 0x6D // Ask for a pin's current mode and value.
-	pinStateResponse      byte = 0x6E // Reply with pin's current move and value.
-	capabilityQuery       byte = 0x6B // Ask for supported modes and resolution of all pins.
-	capabilityResponse    byte = 0x6C // Reply with supported modes and resolution.
-	analogMappingQuery    byte = 0x69 // Ask for mapping of analog to pin numbers.
-	analogMappingResponse byte = 0x6A // Reply with mapping info.
-	reportFirmware        byte = 0x79 // Report name and version of the firmware.
-	samplingInterval      byte = 0x7A // Set the poll rate of the main loop.
-	sysexNonRealtime      byte = 0x7E // MIDI reserved for non-realtime messages.
-	sysexRealtime         byte = 0x7F // MIDI reserved for realtime messages.
-
-	// The baud rate the Arduino expects.
+	servoConfig           = firmatawire.ServoConfig
+	stringData            = firmatawire.StringData
+	shiftData             = firmatawire.ShiftData
+	i2cRequest            = firmatawire.I2CRequest
+	i2cReply              = firmatawire.I2CReply
+	i2cConfig             = firmatawire.I2CConfig
+	serialMessage         = firmatawire.SerialMessage
+	encoderData           = firmatawire.EncoderData
+	spiData               = firmatawire.SPIData
+	extendedAnalog        = firmatawire.ExtendedAnalog
+	pinStateQuery         = firmatawire.PinStateQuery
+	pinStateResponse      = firmatawire.PinStateResponse
+	capabilityQuery       = firmatawire.CapabilityQuery
+	capabilityResponse    = firmatawire.CapabilityResponse
+	analogMappingQuery    = firmatawire.AnalogMappingQuery
+	analogMappingResponse = firmatawire.AnalogMappingResponse
+	reportFirmware        = firmatawire.ReportFirmware
+	samplingInterval      = firmatawire.SamplingInterval
+	sysexNonRealtime      = firmatawire.SysexNonRealtime
+	sysexRealtime         = firmatawire.SysexRealtime
+)
+
+const (
+	// The baud rate StandardFirmata uses, see WithBaud.
 	defaultBaud = 57600
 
 	// Message types
@@ -54,8 +61,18 @@ var midiHeaders = []byte{
 }
 
 type message struct {
-	t    byte   // MIDI or Sysex.
-	data []byte // The message data including any start/end bytes.
+	t    byte      // MIDI or Sysex.
+	data []byte    // The message data including any start/end bytes.
+	at   time.Time // When the message started to arrive.
+}
+
+// Returns the bytes between a sysex message's command and end byte, or
+// nil if the message is too short to have any.
+func (m message) body() []byte {
+	if len(m.data) < 3 {
+		return nil
+	}
+	return m.data[2 : len(m.data)-1]
 }
 
 // A message handler.
@@ -67,18 +84,6 @@ type cbMap map[byte]callback
 //
 // -- Utility functions -- //
 
-// FindSerial checks if any serial connections exist at the standard
-// locations ('/dev/ttyACM*', '/dev/ttyUSB*').
-// Returns nil if no connections were found.
-func FindSerial() (s []string) {
-	acm, _ := filepath.Glob("/dev/ttyACM*")
-	usb, _ := filepath.Glob("/dev/ttyUSB*")
-
-	s = append(s, acm...)
-	s = append(s, usb...)
-	return s
-}
-
 func boolToByte(b bool) byte {
 	if b {
 		return 1
@@ -87,12 +92,84 @@ func boolToByte(b bool) byte {
 	}
 }
 
+// Pin numbers are bytes, so a board can report up to 256 pins. Every
+// message sends a pin as a single 7-bit byte, or a port as the low
+// nibble of the command, so only pins 0-127 can be configured, written
+// or queried. Higher pins are kept in the pin table but never sent.
+const (
+	maxPins         = 256
+	addressablePins = 128
+
+	// The port of pins above 127.
+	noPort byte = 0xFF
+)
+
 func pinToPort(n byte) byte {
-	return (n >> 3) & 0x0F
+	if n >= addressablePins {
+		return noPort
+	}
+	return n >> 3
+}
+
+// Looks up a pin to send to the board, which must exist and fit in a
+// single 7-bit byte. The lock must be held.
+func (b *Board) sendablePin(n byte) (*pin, error) {
+	if n >= addressablePins {
+		return nil, fmt.Errorf("Pin %d can't be sent to the board, Firmata only addresses pins 0-%d", n, addressablePins-1)
+	}
+	p, ok := b.pins[n]
+	if !ok {
+		return nil, fmt.Errorf("Invalid pin: %d", n)
+	}
+	return p, nil
+}
+
+// Checks pins about to be sent in a message, see sendablePin.
+func (b *Board) checkPins(pins ...byte) error {
+	b.m.RLock()
+	defer b.m.RUnlock()
+
+	for _, n := range pins {
+		if _, err := b.sendablePin(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Calls fn every interval in its own goroutine until the returned
+// stop func is called.
+func poll(interval time.Duration, fn func()) (stop func()) {
+	quit := make(chan bool)
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-quit:
+				return
+			case <-t.C:
+				fn()
+			}
+		}
+	}()
+	return func() { close(quit) }
 }
 
-func wrapInSysex(msg []byte) (sysex []byte) {
-	sysex = append([]byte{startSysex}, msg...)
-	sysex = append(sysex, endSysex)
-	return
+// Sends v on ch without blocking. If ch is full, the stale value is
+// dropped so slow readers always get the latest one.
+func sendLatest[T any](ch chan T, v T) {
+	select {
+	case ch <- v:
+		return
+	default:
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	select {
+	case ch <- v:
+	default:
+	}
 }
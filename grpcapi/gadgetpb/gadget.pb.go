@@ -0,0 +1,360 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: gadget.proto
+
+package gadgetpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type PinRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pin           uint32                 `protobuf:"varint,1,opt,name=pin,proto3" json:"pin,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PinRequest) Reset() {
+	*x = PinRequest{}
+	mi := &file_gadget_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PinRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PinRequest) ProtoMessage() {}
+
+func (x *PinRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gadget_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PinRequest.ProtoReflect.Descriptor instead.
+func (*PinRequest) Descriptor() ([]byte, []int) {
+	return file_gadget_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *PinRequest) GetPin() uint32 {
+	if x != nil {
+		return x.Pin
+	}
+	return 0
+}
+
+type WriteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pin           uint32                 `protobuf:"varint,1,opt,name=pin,proto3" json:"pin,omitempty"`
+	Value         int32                  `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WriteRequest) Reset() {
+	*x = WriteRequest{}
+	mi := &file_gadget_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WriteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WriteRequest) ProtoMessage() {}
+
+func (x *WriteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gadget_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WriteRequest.ProtoReflect.Descriptor instead.
+func (*WriteRequest) Descriptor() ([]byte, []int) {
+	return file_gadget_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *WriteRequest) GetPin() uint32 {
+	if x != nil {
+		return x.Pin
+	}
+	return 0
+}
+
+func (x *WriteRequest) GetValue() int32 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+type SetModeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pin           uint32                 `protobuf:"varint,1,opt,name=pin,proto3" json:"pin,omitempty"`
+	Mode          string                 `protobuf:"bytes,2,opt,name=mode,proto3" json:"mode,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetModeRequest) Reset() {
+	*x = SetModeRequest{}
+	mi := &file_gadget_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetModeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetModeRequest) ProtoMessage() {}
+
+func (x *SetModeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gadget_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetModeRequest.ProtoReflect.Descriptor instead.
+func (*SetModeRequest) Descriptor() ([]byte, []int) {
+	return file_gadget_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SetModeRequest) GetPin() uint32 {
+	if x != nil {
+		return x.Pin
+	}
+	return 0
+}
+
+func (x *SetModeRequest) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+type StreamRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pins          []uint32               `protobuf:"varint,1,rep,packed,name=pins,proto3" json:"pins,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamRequest) Reset() {
+	*x = StreamRequest{}
+	mi := &file_gadget_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamRequest) ProtoMessage() {}
+
+func (x *StreamRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gadget_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamRequest.ProtoReflect.Descriptor instead.
+func (*StreamRequest) Descriptor() ([]byte, []int) {
+	return file_gadget_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *StreamRequest) GetPins() []uint32 {
+	if x != nil {
+		return x.Pins
+	}
+	return nil
+}
+
+type Pin struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pin           uint32                 `protobuf:"varint,1,opt,name=pin,proto3" json:"pin,omitempty"`
+	Mode          string                 `protobuf:"bytes,2,opt,name=mode,proto3" json:"mode,omitempty"`
+	Value         int32                  `protobuf:"varint,3,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Pin) Reset() {
+	*x = Pin{}
+	mi := &file_gadget_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Pin) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Pin) ProtoMessage() {}
+
+func (x *Pin) ProtoReflect() protoreflect.Message {
+	mi := &file_gadget_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Pin.ProtoReflect.Descriptor instead.
+func (*Pin) Descriptor() ([]byte, []int) {
+	return file_gadget_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Pin) GetPin() uint32 {
+	if x != nil {
+		return x.Pin
+	}
+	return 0
+}
+
+func (x *Pin) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *Pin) GetValue() int32 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+var File_gadget_proto protoreflect.FileDescriptor
+
+const file_gadget_proto_rawDesc = "" +
+	"\n" +
+	"\fgadget.proto\x12\x06gadget\"\x1e\n" +
+	"\n" +
+	"PinRequest\x12\x10\n" +
+	"\x03pin\x18\x01 \x01(\rR\x03pin\"6\n" +
+	"\fWriteRequest\x12\x10\n" +
+	"\x03pin\x18\x01 \x01(\rR\x03pin\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value\"6\n" +
+	"\x0eSetModeRequest\x12\x10\n" +
+	"\x03pin\x18\x01 \x01(\rR\x03pin\x12\x12\n" +
+	"\x04mode\x18\x02 \x01(\tR\x04mode\"#\n" +
+	"\rStreamRequest\x12\x12\n" +
+	"\x04pins\x18\x01 \x03(\rR\x04pins\"A\n" +
+	"\x03Pin\x12\x10\n" +
+	"\x03pin\x18\x01 \x01(\rR\x03pin\x12\x12\n" +
+	"\x04mode\x18\x02 \x01(\tR\x04mode\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\x05R\x05value2\xc3\x01\n" +
+	"\x06Gadget\x12'\n" +
+	"\x04Read\x12\x12.gadget.PinRequest\x1a\v.gadget.Pin\x12*\n" +
+	"\x05Write\x12\x14.gadget.WriteRequest\x1a\v.gadget.Pin\x12.\n" +
+	"\aSetMode\x12\x16.gadget.SetModeRequest\x1a\v.gadget.Pin\x124\n" +
+	"\fStreamEvents\x12\x15.gadget.StreamRequest\x1a\v.gadget.Pin0\x01B3Z1github.com/ZachMassia/GoGoGadget/grpcapi/gadgetpbb\x06proto3"
+
+var (
+	file_gadget_proto_rawDescOnce sync.Once
+	file_gadget_proto_rawDescData []byte
+)
+
+func file_gadget_proto_rawDescGZIP() []byte {
+	file_gadget_proto_rawDescOnce.Do(func() {
+		file_gadget_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_gadget_proto_rawDesc), len(file_gadget_proto_rawDesc)))
+	})
+	return file_gadget_proto_rawDescData
+}
+
+var file_gadget_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_gadget_proto_goTypes = []any{
+	(*PinRequest)(nil),     // 0: gadget.PinRequest
+	(*WriteRequest)(nil),   // 1: gadget.WriteRequest
+	(*SetModeRequest)(nil), // 2: gadget.SetModeRequest
+	(*StreamRequest)(nil),  // 3: gadget.StreamRequest
+	(*Pin)(nil),            // 4: gadget.Pin
+}
+var file_gadget_proto_depIdxs = []int32{
+	0, // 0: gadget.Gadget.Read:input_type -> gadget.PinRequest
+	1, // 1: gadget.Gadget.Write:input_type -> gadget.WriteRequest
+	2, // 2: gadget.Gadget.SetMode:input_type -> gadget.SetModeRequest
+	3, // 3: gadget.Gadget.StreamEvents:input_type -> gadget.StreamRequest
+	4, // 4: gadget.Gadget.Read:output_type -> gadget.Pin
+	4, // 5: gadget.Gadget.Write:output_type -> gadget.Pin
+	4, // 6: gadget.Gadget.SetMode:output_type -> gadget.Pin
+	4, // 7: gadget.Gadget.StreamEvents:output_type -> gadget.Pin
+	4, // [4:8] is the sub-list for method output_type
+	0, // [0:4] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_gadget_proto_init() }
+func file_gadget_proto_init() {
+	if File_gadget_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_gadget_proto_rawDesc), len(file_gadget_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_gadget_proto_goTypes,
+		DependencyIndexes: file_gadget_proto_depIdxs,
+		MessageInfos:      file_gadget_proto_msgTypes,
+	}.Build()
+	File_gadget_proto = out.File
+	file_gadget_proto_goTypes = nil
+	file_gadget_proto_depIdxs = nil
+}
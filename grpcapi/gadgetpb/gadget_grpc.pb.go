@@ -0,0 +1,255 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: gadget.proto
+
+package gadgetpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Gadget_Read_FullMethodName         = "/gadget.Gadget/Read"
+	Gadget_Write_FullMethodName        = "/gadget.Gadget/Write"
+	Gadget_SetMode_FullMethodName      = "/gadget.Gadget/SetMode"
+	Gadget_StreamEvents_FullMethodName = "/gadget.Gadget/StreamEvents"
+)
+
+// GadgetClient is the client API for Gadget service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Gadget controls a single Firmata board.
+type GadgetClient interface {
+	// Read returns the state of a pin.
+	Read(ctx context.Context, in *PinRequest, opts ...grpc.CallOption) (*Pin, error)
+	// Write sets a pin's value according to its mode: OUTPUT pins take
+	// 0 or 1, PWM pins 0-255 and SERVO pins an angle or pulse width.
+	Write(ctx context.Context, in *WriteRequest, opts ...grpc.CallOption) (*Pin, error)
+	// SetMode changes a pin's mode, e.g. "OUTPUT" or "PWM".
+	SetMode(ctx context.Context, in *SetModeRequest, opts ...grpc.CallOption) (*Pin, error)
+	// StreamEvents sends the state of the requested pins, or every pin
+	// if none are given, then again each time one changes.
+	StreamEvents(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Pin], error)
+}
+
+type gadgetClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGadgetClient(cc grpc.ClientConnInterface) GadgetClient {
+	return &gadgetClient{cc}
+}
+
+func (c *gadgetClient) Read(ctx context.Context, in *PinRequest, opts ...grpc.CallOption) (*Pin, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Pin)
+	err := c.cc.Invoke(ctx, Gadget_Read_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gadgetClient) Write(ctx context.Context, in *WriteRequest, opts ...grpc.CallOption) (*Pin, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Pin)
+	err := c.cc.Invoke(ctx, Gadget_Write_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gadgetClient) SetMode(ctx context.Context, in *SetModeRequest, opts ...grpc.CallOption) (*Pin, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Pin)
+	err := c.cc.Invoke(ctx, Gadget_SetMode_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gadgetClient) StreamEvents(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Pin], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Gadget_ServiceDesc.Streams[0], Gadget_StreamEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamRequest, Pin]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Gadget_StreamEventsClient = grpc.ServerStreamingClient[Pin]
+
+// GadgetServer is the server API for Gadget service.
+// All implementations must embed UnimplementedGadgetServer
+// for forward compatibility.
+//
+// Gadget controls a single Firmata board.
+type GadgetServer interface {
+	// Read returns the state of a pin.
+	Read(context.Context, *PinRequest) (*Pin, error)
+	// Write sets a pin's value according to its mode: OUTPUT pins take
+	// 0 or 1, PWM pins 0-255 and SERVO pins an angle or pulse width.
+	Write(context.Context, *WriteRequest) (*Pin, error)
+	// SetMode changes a pin's mode, e.g. "OUTPUT" or "PWM".
+	SetMode(context.Context, *SetModeRequest) (*Pin, error)
+	// StreamEvents sends the state of the requested pins, or every pin
+	// if none are given, then again each time one changes.
+	StreamEvents(*StreamRequest, grpc.ServerStreamingServer[Pin]) error
+	mustEmbedUnimplementedGadgetServer()
+}
+
+// UnimplementedGadgetServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedGadgetServer struct{}
+
+func (UnimplementedGadgetServer) Read(context.Context, *PinRequest) (*Pin, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Read not implemented")
+}
+func (UnimplementedGadgetServer) Write(context.Context, *WriteRequest) (*Pin, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Write not implemented")
+}
+func (UnimplementedGadgetServer) SetMode(context.Context, *SetModeRequest) (*Pin, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetMode not implemented")
+}
+func (UnimplementedGadgetServer) StreamEvents(*StreamRequest, grpc.ServerStreamingServer[Pin]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEvents not implemented")
+}
+func (UnimplementedGadgetServer) mustEmbedUnimplementedGadgetServer() {}
+func (UnimplementedGadgetServer) testEmbeddedByValue()                {}
+
+// UnsafeGadgetServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GadgetServer will
+// result in compilation errors.
+type UnsafeGadgetServer interface {
+	mustEmbedUnimplementedGadgetServer()
+}
+
+func RegisterGadgetServer(s grpc.ServiceRegistrar, srv GadgetServer) {
+	// If the following call pancis, it indicates UnimplementedGadgetServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Gadget_ServiceDesc, srv)
+}
+
+func _Gadget_Read_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PinRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GadgetServer).Read(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gadget_Read_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GadgetServer).Read(ctx, req.(*PinRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gadget_Write_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WriteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GadgetServer).Write(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gadget_Write_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GadgetServer).Write(ctx, req.(*WriteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gadget_SetMode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetModeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GadgetServer).SetMode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gadget_SetMode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GadgetServer).SetMode(ctx, req.(*SetModeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gadget_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GadgetServer).StreamEvents(m, &grpc.GenericServerStream[StreamRequest, Pin]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Gadget_StreamEventsServer = grpc.ServerStreamingServer[Pin]
+
+// Gadget_ServiceDesc is the grpc.ServiceDesc for Gadget service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Gadget_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gadget.Gadget",
+	HandlerType: (*GadgetServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Read",
+			Handler:    _Gadget_Read_Handler,
+		},
+		{
+			MethodName: "Write",
+			Handler:    _Gadget_Write_Handler,
+		},
+		{
+			MethodName: "SetMode",
+			Handler:    _Gadget_SetMode_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _Gadget_StreamEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "gadget.proto",
+}
@@ -0,0 +1,119 @@
+// Package grpcapi serves a board over gRPC, allowing it to be
+// controlled from any language with gRPC support.
+//
+// The service is defined in gadget.proto. The gadgetpb package holds
+// the generated messages, server interface and client; regenerate it
+// with go generate after changing the definition, which needs protoc,
+// protoc-gen-go and protoc-gen-go-grpc.
+//
+// The package depends on gRPC and protobuf, built against
+// google.golang.org/grpc v1.84.0 and google.golang.org/protobuf v1.36.11:
+//
+//	go get google.golang.org/grpc google.golang.org/protobuf
+//
+// To serve a board:
+//
+//	s := grpc.NewServer()
+//	gadgetpb.RegisterGadgetServer(s, grpcapi.NewServer(b))
+//	s.Serve(lis)
+//
+// Clients use gadgetpb.NewGadgetClient.
+package grpcapi
+
+//go:generate protoc --go_out=gadgetpb --go_opt=paths=source_relative --go-grpc_out=gadgetpb --go-grpc_opt=paths=source_relative gadget.proto
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ZachMassia/GoGoGadget"
+	"github.com/ZachMassia/GoGoGadget/grpcapi/gadgetpb"
+)
+
+// Server implements gadgetpb.GadgetServer for a board.
+type Server struct {
+	gadgetpb.UnimplementedGadgetServer
+	board *gadget.Board
+}
+
+// NewServer returns a Server for the board.
+func NewServer(b *gadget.Board) *Server {
+	return &Server{board: b}
+}
+
+// Read returns the state of a pin.
+func (s *Server) Read(ctx context.Context, req *gadgetpb.PinRequest) (*gadgetpb.Pin, error) {
+	n, err := pinNumber(req.GetPin())
+	if err != nil {
+		return nil, err
+	}
+	return s.read(n)
+}
+
+// Write sets a pin's value.
+func (s *Server) Write(ctx context.Context, req *gadgetpb.WriteRequest) (*gadgetpb.Pin, error) {
+	n, err := pinNumber(req.GetPin())
+	if err != nil {
+		return nil, err
+	}
+	if err = s.board.WriteValue(n, int(req.GetValue())); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return s.read(n)
+}
+
+// SetMode changes a pin's mode.
+func (s *Server) SetMode(ctx context.Context, req *gadgetpb.SetModeRequest) (*gadgetpb.Pin, error) {
+	n, err := pinNumber(req.GetPin())
+	if err != nil {
+		return nil, err
+	}
+	mode, ok := gadget.ParsePinMode(req.GetMode())
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "Unknown pin mode '%s'", req.GetMode())
+	}
+	if cur, _ := s.board.PinMode(n); cur != mode {
+		if err = s.board.SetPinMode(n, mode); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	return s.read(n)
+}
+
+// StreamEvents sends pin states as they change until the client
+// cancels the stream.
+func (s *Server) StreamEvents(req *gadgetpb.StreamRequest, stream gadgetpb.Gadget_StreamEventsServer) error {
+	var pins []byte
+	for _, p := range req.GetPins() {
+		n, err := pinNumber(p)
+		if err != nil {
+			return err
+		}
+		pins = append(pins, n)
+	}
+
+	err := s.board.WatchValues(stream.Context(), pins, func(v gadget.PinValue) error {
+		return stream.Send(&gadgetpb.Pin{Pin: uint32(v.Pin), Mode: gadget.PinModeString[v.Mode], Value: int32(v.Value)})
+	})
+	if _, ok := status.FromError(err); !ok {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return err
+}
+
+func (s *Server) read(n byte) (*gadgetpb.Pin, error) {
+	mode, v, err := s.board.ReadValue(n)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return &gadgetpb.Pin{Pin: uint32(n), Mode: gadget.PinModeString[mode], Value: int32(v)}, nil
+}
+
+func pinNumber(p uint32) (byte, error) {
+	if p > 255 {
+		return 0, status.Errorf(codes.InvalidArgument, "Invalid pin: %d", p)
+	}
+	return byte(p), nil
+}
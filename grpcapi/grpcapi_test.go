@@ -0,0 +1,148 @@
+package grpcapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/ZachMassia/GoGoGadget"
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+	"github.com/ZachMassia/GoGoGadget/grpcapi/gadgetpb"
+)
+
+// Returns a client of a Server for a board with digital pins 2 and 3
+// and analog pin 14 (A0), answered by a fake StandardFirmata.
+func newTestClient(t *testing.T) gadgetpb.GadgetClient {
+	host, board := net.Pipe()
+	go fakeFirmata(board)
+	b, err := gadget.New("fake", gadget.WithTransport(host),
+		gadget.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+
+	lis := bufconn.Listen(1 << 16)
+	s := grpc.NewServer()
+	gadgetpb.RegisterGadgetServer(s, NewServer(b))
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return gadgetpb.NewGadgetClient(conn)
+}
+
+// Answers the handshake until the connection closes.
+func fakeFirmata(conn net.Conn) {
+	announce := func() {
+		conn.Write([]byte{firmatawire.ReportVersion, 2, 5})
+		conn.Write(firmatawire.Sysex(firmatawire.ReportFirmware,
+			firmatawire.AppendBytes7([]byte{2, 5}, []byte("StandardFirmata"))...))
+	}
+	announce()
+
+	d := firmatawire.NewDecoder(conn)
+	for {
+		f, err := d.Next()
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrClosedPipe) {
+			return
+		}
+		if err != nil || !f.Sysex() {
+			continue
+		}
+		switch f.Command() {
+		case firmatawire.ReportFirmware:
+			announce()
+		case firmatawire.AnalogMappingQuery:
+			mapping := bytes.Repeat([]byte{0x7F}, 15)
+			mapping[14] = 0
+			conn.Write(firmatawire.Sysex(firmatawire.AnalogMappingResponse, mapping...))
+		case firmatawire.CapabilityQuery:
+			var caps []byte
+			for pin := 0; pin < 15; pin++ {
+				switch pin {
+				case 2, 3:
+					caps = append(caps, gadget.INPUT, 1, gadget.OUTPUT, 1)
+				case 14:
+					caps = append(caps, gadget.ANALOG, 10)
+				}
+				caps = append(caps, 0x7F)
+			}
+			conn.Write(firmatawire.Sysex(firmatawire.CapabilityResponse, caps...))
+		}
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	p, err := c.Read(ctx, &gadgetpb.PinRequest{Pin: 14})
+	if err != nil || p.GetMode() != "ANALOG" {
+		t.Fatalf("Read(14) = %v, %v", p, err)
+	}
+	if _, err = c.Read(ctx, &gadgetpb.PinRequest{Pin: 9}); status.Code(err) != codes.NotFound {
+		t.Fatalf("Read(9) should be NotFound, got %v", err)
+	}
+
+	if p, err = c.SetMode(ctx, &gadgetpb.SetModeRequest{Pin: 3, Mode: "OUTPUT"}); err != nil || p.GetMode() != "OUTPUT" {
+		t.Fatalf("SetMode(3, OUTPUT) = %v, %v", p, err)
+	}
+	if _, err = c.SetMode(ctx, &gadgetpb.SetModeRequest{Pin: 3, Mode: "BOGUS"}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("SetMode(3, BOGUS) should be InvalidArgument, got %v", err)
+	}
+	if p, err = c.Write(ctx, &gadgetpb.WriteRequest{Pin: 3, Value: 1}); err != nil || p.GetValue() != 1 {
+		t.Fatalf("Write(3, 1) = %v, %v", p, err)
+	}
+	if _, err = c.Write(ctx, &gadgetpb.WriteRequest{Pin: 3, Value: 2}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Write(3, 2) should be InvalidArgument, got %v", err)
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	c := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c.SetMode(ctx, &gadgetpb.SetModeRequest{Pin: 3, Mode: "OUTPUT"})
+	stream, err := c.StreamEvents(ctx, &gadgetpb.StreamRequest{Pins: []uint32{3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := stream.Recv()
+	if err != nil || p.GetPin() != 3 || p.GetMode() != "OUTPUT" || p.GetValue() != 0 {
+		t.Fatalf("Expected pin 3's current state first, got %v, %v", p, err)
+	}
+
+	// Changes to other pins aren't sent.
+	c.SetMode(ctx, &gadgetpb.SetModeRequest{Pin: 2, Mode: "OUTPUT"})
+	c.Write(ctx, &gadgetpb.WriteRequest{Pin: 3, Value: 1})
+	if p, err = stream.Recv(); err != nil || p.GetPin() != 3 || p.GetValue() != 1 {
+		t.Fatalf("Expected pin 3 to go to 1, got %v, %v", p, err)
+	}
+
+	// Unknown pins end the stream.
+	stream, err = c.StreamEvents(ctx, &gadgetpb.StreamRequest{Pins: []uint32{9}})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("Streaming pin 9 should be NotFound, got %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package gadget
+
+import (
+	"fmt"
+	"sync"
+)
+
+// OutputExpander drives one or more daisy chained 74HC595 shift
+// registers, exposing their outputs as virtual pins.
+//
+// Virtual pin n is output Q(n%8) of chip n/8, with chip 0 being the
+// one wired directly to the board.
+type OutputExpander struct {
+	board              *Board
+	data, clock, latch byte
+
+	m     sync.Mutex
+	state []byte // Output state of each chip.
+}
+
+// NewOutputExpander returns an OutputExpander for the given number of
+// chained chips, with all outputs cleared.
+func NewOutputExpander(b *Board, dataPin, clockPin, latchPin byte, chips int) (e *OutputExpander, err error) {
+	if chips < 1 {
+		return nil, fmt.Errorf("Output expander needs at least one chip, got %d", chips)
+	}
+
+	for _, pin := range []byte{dataPin, clockPin, latchPin} {
+		if err = b.ensurePinMode(pin, OUTPUT); err != nil {
+			return nil, err
+		}
+		if err = b.DigitalWrite(pin, LOW); err != nil {
+			return nil, err
+		}
+	}
+
+	e = &OutputExpander{
+		board: b,
+		data:  dataPin,
+		clock: clockPin,
+		latch: latchPin,
+		state: make([]byte, chips),
+	}
+	if err = e.update(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Pins returns the number of virtual pins.
+func (e *OutputExpander) Pins() int {
+	return 8 * len(e.state)
+}
+
+// Set drives virtual pin n HIGH.
+func (e *OutputExpander) Set(n int) error {
+	return e.DigitalWrite(n, HIGH)
+}
+
+// Clear drives virtual pin n LOW.
+func (e *OutputExpander) Clear(n int) error {
+	return e.DigitalWrite(n, LOW)
+}
+
+// DigitalWrite sets the state of virtual pin n.
+func (e *OutputExpander) DigitalWrite(n int, s byte) (err error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	if n < 0 || n >= 8*len(e.state) {
+		return fmt.Errorf("Invalid expander pin: %d", n)
+	}
+	if s != LOW {
+		e.state[n/8] |= 1 << uint(n%8)
+	} else {
+		e.state[n/8] &^= 1 << uint(n%8)
+	}
+	return e.update()
+}
+
+// DigitalRead returns the last state written to virtual pin n.
+func (e *OutputExpander) DigitalRead(n int) (s byte, err error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	if n < 0 || n >= 8*len(e.state) {
+		return 0, fmt.Errorf("Invalid expander pin: %d", n)
+	}
+	return (e.state[n/8] >> uint(n%8)) & 0x01, nil
+}
+
+// Write sets the outputs of every chip at once, state[i] holding the
+// outputs of chip i.
+func (e *OutputExpander) Write(state []byte) (err error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	if len(state) != len(e.state) {
+		return fmt.Errorf("Expected state for %d chips, got %d", len(e.state), len(state))
+	}
+	copy(e.state, state)
+	return e.update()
+}
+
+// Shifts the state out to the chain and latches it. The last chip's
+// byte goes first so it ends up furthest down the chain.
+func (e *OutputExpander) update() (err error) {
+	for i := len(e.state) - 1; i >= 0; i-- {
+		if err = e.board.ShiftOut(e.data, e.clock, MSBFIRST, e.state[i]); err != nil {
+			return err
+		}
+	}
+	if err = e.board.DigitalWrite(e.latch, HIGH); err != nil {
+		return err
+	}
+	return e.board.DigitalWrite(e.latch, LOW)
+}
@@ -0,0 +1,79 @@
+package gadget
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOutputExpander(t *testing.T) {
+	f := &fakeShiftChain{data: 2, clock: 3, cs: 4}
+	caps := []Capability{{OUTPUT, 1}}
+	b := newTestBoard(t, f, nil, map[byte][]Capability{2: caps, 3: caps, 4: caps})
+	e, err := NewOutputExpander(b, 2, 3, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.frames) != 1 || !bytes.Equal(f.frames[0], []byte{0, 0}) {
+		t.Fatalf("Expected both chips cleared, got % X", f.frames)
+	}
+
+	// Chip 1's byte is shifted out first, to pass through chip 0.
+	f.frames = nil
+	if err = e.Set(9); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.Set(0); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.Clear(9); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]byte{{0x02, 0x00}, {0x02, 0x01}, {0x00, 0x01}}
+	if len(f.frames) != len(want) {
+		t.Fatalf("Sent frames % X, want % X", f.frames, want)
+	}
+	for i := range want {
+		if !bytes.Equal(f.frames[i], want[i]) {
+			t.Fatalf("Frame %d = % X, want % X", i, f.frames[i], want[i])
+		}
+	}
+	if s, _ := e.DigitalRead(0); s != HIGH {
+		t.Fatalf("Virtual pin 0 should read back HIGH")
+	}
+
+	f.frames = nil
+	if err = e.Write([]byte{0xA5, 0x3C}); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.frames) != 1 || !bytes.Equal(f.frames[0], []byte{0x3C, 0xA5}) {
+		t.Fatalf("Write sent % X, want 3C A5", f.frames)
+	}
+
+	for _, n := range []int{-1, 16} {
+		if e.Set(n) == nil {
+			t.Fatalf("Virtual pin %d should be refused", n)
+		}
+	}
+	if e.Write([]byte{1}) == nil {
+		t.Fatalf("Write should need a byte per chip")
+	}
+	if _, err = NewOutputExpander(b, 2, 3, 4, 0); err == nil {
+		t.Fatalf("An expander needs a chip")
+	}
+}
+
+func TestShiftOutOrder(t *testing.T) {
+	f := &fakeShiftChain{data: 2, clock: 3, cs: 4}
+	caps := []Capability{{OUTPUT, 1}}
+	b := newTestBoard(t, f, nil, map[byte][]Capability{2: caps, 3: caps, 4: caps})
+	for _, n := range []byte{2, 3} {
+		b.SetPinMode(n, OUTPUT)
+	}
+
+	b.ShiftOut(2, 3, LSBFIRST, 0x06)
+	b.ShiftOut(2, 3, MSBFIRST, 0x06)
+	want := []byte{0, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 0}
+	if !bytes.Equal(f.bits, want) {
+		t.Fatalf("Shifted bits %v, want %v", f.bits, want)
+	}
+}
@@ -0,0 +1,121 @@
+package gadget
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A heartbeat's state, shared by the task and the version handler.
+type heartbeat struct {
+	m         sync.Mutex
+	lastReply time.Time
+	unhealthy bool
+
+	// When the port was last reopened, see WithReconnect. Only used
+	// by the task.
+	reopened time.Time
+
+	task *Task
+}
+
+// Heartbeat checks that the board is still answering by sending a
+// version query every interval. If no reply arrives within misses
+// intervals the board is declared unhealthy and TopicUnhealthy is
+// published, followed by TopicHealthy if it starts answering again.
+//
+// Boards opened WithReconnect have their port reopened while unhealthy,
+// at most once every misses intervals. Otherwise, to recover, subscribe
+// to TopicUnhealthy and reopen the board with Close and New. Stop the
+// returned task to stop checking. Calling Heartbeat again stops the
+// previous one.
+func (b *Board) Heartbeat(interval time.Duration, misses int) *Task {
+	if misses < 1 {
+		misses = 1
+	}
+	hb := &heartbeat{lastReply: time.Now()}
+
+	b.hbm.Lock()
+	defer b.hbm.Unlock()
+	if b.hb != nil {
+		b.hb.task.Stop()
+	}
+	b.hb = hb
+
+	hb.task = b.Every(interval, func() {
+		silence := time.Since(hb.lastSeen())
+		ok := silence <= time.Duration(misses)*interval
+		switch {
+		case !hb.setHealthy(ok):
+		case ok:
+			b.bus.Publish(Event{Topic: TopicHealthy})
+		default:
+			b.bus.Publish(Event{Topic: TopicUnhealthy,
+				Err: fmt.Errorf("Board has not answered for %s", silence.Round(time.Millisecond))})
+		}
+		if !ok && time.Since(hb.reopened) > time.Duration(misses)*interval && b.reopen() {
+			hb.reopened = time.Now()
+		}
+		b.out.Write([]byte{reportVersion})
+	})
+	return hb.task
+}
+
+// Reopens the port if the board was opened WithReconnect, returning
+// false if it wasn't. Boards that reset when the port opens are restored
+// once they announce themselves, others straight away.
+func (b *Board) reopen() bool {
+	p, ok := b.serial.(*reopenablePort)
+	if !ok {
+		return false
+	}
+	b.log().Warn("Reopening port", "device", b.cfg.Name)
+	if err := p.reopen(b.settle); err != nil {
+		b.reportError(fmt.Errorf("Error reopening '%s': %s", b.cfg.Name, err))
+	} else if b.noReset {
+		b.restore()
+	}
+	return true
+}
+
+// Healthy returns false while a Heartbeat has found the board not
+// answering. Boards without a heartbeat are always healthy.
+func (b *Board) Healthy() bool {
+	b.hbm.Lock()
+	hb := b.hb
+	b.hbm.Unlock()
+	return hb == nil || !hb.isUnhealthy()
+}
+
+// Called by the version handler.
+func (b *Board) heartbeatReply() {
+	b.hbm.Lock()
+	hb := b.hb
+	b.hbm.Unlock()
+	if hb != nil {
+		hb.m.Lock()
+		hb.lastReply = time.Now()
+		hb.m.Unlock()
+	}
+}
+
+func (hb *heartbeat) lastSeen() time.Time {
+	hb.m.Lock()
+	defer hb.m.Unlock()
+	return hb.lastReply
+}
+
+func (hb *heartbeat) isUnhealthy() bool {
+	hb.m.Lock()
+	defer hb.m.Unlock()
+	return hb.unhealthy
+}
+
+// Records the board's health, returning true if it changed.
+func (hb *heartbeat) setHealthy(ok bool) (changed bool) {
+	hb.m.Lock()
+	defer hb.m.Unlock()
+	changed = hb.unhealthy == ok
+	hb.unhealthy = !ok
+	return
+}
@@ -0,0 +1,132 @@
+package gadget
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+func TestHeartbeat(t *testing.T) {
+	var l writeLog
+	b := &Board{bus: NewEventBus(), out: newBatchWriter(&l)}
+	sub := b.bus.Subscribe("board/+", 4)
+
+	task := b.Heartbeat(5*time.Millisecond, 2)
+	defer task.Stop()
+
+	expect := func(topic string) {
+		select {
+		case e := <-sub.C:
+			if e.Topic != topic {
+				t.Fatalf("Expected %s, got %s", topic, e.Topic)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected %s", topic)
+		}
+	}
+
+	// Nothing answers the queries.
+	expect(TopicUnhealthy)
+	l.m.Lock()
+	query := l.writes[0]
+	l.m.Unlock()
+	if b.Healthy() || query[0] != reportVersion {
+		t.Fatalf("Board should be unhealthy after unanswered version queries")
+	}
+
+	b.handleReportVersion(message{data: []byte{reportVersion, 2, 5}})
+	expect(TopicHealthy)
+	if !b.Healthy() {
+		t.Fatalf("Board should be healthy after a reply")
+	}
+}
+
+func TestHeartbeatReplaced(t *testing.T) {
+	b := &Board{bus: NewEventBus(), out: newBatchWriter(io.Discard)}
+	first := b.Heartbeat(time.Hour, 1)
+	second := b.Heartbeat(time.Hour, 1)
+	defer second.Stop()
+
+	select {
+	case <-first.quit:
+	default:
+		t.Fatalf("A second Heartbeat should stop the first")
+	}
+	b.tm.Lock()
+	n := len(b.tasks)
+	b.tm.Unlock()
+	if n != 1 {
+		t.Fatalf("Expected 1 task, got %d", n)
+	}
+}
+
+// Runs a fake board on one end of a pipe, returning the other. The
+// board announces itself and answers version queries if alive. Answers
+// are written while reading on, as a serial port buffers them.
+func fakeBoardConn(t *testing.T, alive bool) net.Conn {
+	host, board := net.Pipe()
+	t.Cleanup(func() { board.Close() })
+	go func() {
+		if alive {
+			board.Write(firmatawire.Sysex(reportFirmware, 2, 5))
+		}
+		buf := make([]byte, 64)
+		for {
+			n, err := board.Read(buf)
+			if err != nil {
+				return
+			}
+			if alive && bytes.IndexByte(buf[:n], reportVersion) >= 0 {
+				go board.Write([]byte{reportVersion, 2, 5})
+			}
+		}
+	}()
+	return host
+}
+
+func TestHeartbeatReconnect(t *testing.T) {
+	b := newTestBoard(t, nil, nil, map[byte][]Capability{13: {{OUTPUT, 1}}})
+	<-b.ready
+	b.msgHandlers = b.coreHandlers()
+	b.cfg = &portConfig{Name: "fake"}
+	b.quit = make(chan bool)
+
+	opened := 0
+	p := newReopenablePort(fakeBoardConn(t, false), func() (io.ReadWriteCloser, error) {
+		opened++
+		return fakeBoardConn(t, true), nil
+	})
+	b.serial = p
+	b.buf = bufio.NewReader(p)
+	b.dec = firmatawire.NewDecoder(b.buf)
+	b.out = newBatchWriter(p)
+	b.run()
+	defer func() {
+		close(b.quit)
+		p.Close()
+	}()
+
+	sub := b.bus.Subscribe("board/+", 4)
+	task := b.Heartbeat(10*time.Millisecond, 2)
+	defer task.Stop()
+
+	// The dead board is reopened, announces itself and is restored.
+	for _, topic := range []string{TopicUnhealthy, TopicReset, TopicHealthy} {
+		select {
+		case e := <-sub.C:
+			if e.Topic != topic {
+				t.Fatalf("Expected %s, got %s: %v", topic, e.Topic, e.Err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected %s", topic)
+		}
+	}
+	if opened != 1 || !b.Healthy() {
+		t.Fatalf("Port opened %d times, want 1", opened)
+	}
+}
@@ -0,0 +1,76 @@
+package gadget
+
+import (
+	"fmt"
+	"time"
+)
+
+// Reading is a pin value and when it was reported or written.
+type Reading struct {
+	Time  time.Time `json:"time"`
+	Value int       `json:"value"`
+}
+
+// A fixed size ring of a pin's latest readings.
+type history struct {
+	buf  []Reading
+	next int // Where the next reading goes.
+	full bool
+}
+
+func newHistory(n int) *history {
+	return &history{buf: make([]Reading, n)}
+}
+
+func (h *history) add(r Reading) {
+	h.buf[h.next] = r
+	h.next = (h.next + 1) % len(h.buf)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// Returns a copy of the readings, oldest first.
+func (h *history) readings() []Reading {
+	if !h.full {
+		return append([]Reading(nil), h.buf[:h.next]...)
+	}
+	return append(append([]Reading(nil), h.buf[h.next:]...), h.buf[:h.next]...)
+}
+
+// KeepHistory keeps the last n readings of the pin, every report from
+// the board and every write, for History. Zero stops keeping them.
+func (b *Board) KeepHistory(pin byte, n int) error {
+	if n < 0 {
+		return fmt.Errorf("Invalid history length: %d", n)
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	p, ok := b.pins[pin]
+	if !ok {
+		return fmt.Errorf("Invalid pin: %d", pin)
+	}
+	p.history = nil
+	if n > 0 {
+		p.history = newHistory(n)
+	}
+	return nil
+}
+
+// History returns the pin's kept readings, oldest first. It is empty
+// unless KeepHistory was called for the pin.
+func (b *Board) History(pin byte) ([]Reading, error) {
+	b.m.RLock()
+	defer b.m.RUnlock()
+
+	p, ok := b.pins[pin]
+	if !ok {
+		return nil, fmt.Errorf("Invalid pin: %d", pin)
+	}
+	if p.history == nil {
+		return nil, nil
+	}
+	return p.history.readings(), nil
+}
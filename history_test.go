@@ -0,0 +1,28 @@
+package gadget
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	var l writeLog
+	b := &Board{pins: map[byte]*pin{14: newPin(&l, 14, 0, []Capability{{ANALOG, 10}})}}
+
+	if h, _ := b.History(14); h != nil {
+		t.Fatalf("History should be empty until kept, got %v", h)
+	}
+	b.KeepHistory(14, 3)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		b.pins[14].setAnalog(i*10, start.Add(time.Duration(i)*time.Second))
+	}
+	h, err := b.History(14)
+	if err != nil || len(h) != 3 || h[0].Value != 20 || h[2].Value != 40 || !h[2].Time.Equal(start.Add(4*time.Second)) {
+		t.Fatalf("Expected the last 3 readings, got %v, %v", h, err)
+	}
+	if _, err = b.History(2); err == nil {
+		t.Fatalf("History of a missing pin should fail")
+	}
+}
@@ -0,0 +1,308 @@
+// Package httpapi serves a JSON HTTP API for a gadget.Board, so boards
+// can be driven from other languages or curl.
+//
+// Endpoints:
+//
+//	GET  /info       Firmware name and protocol version.
+//	GET  /snapshot   The board's full state, see gadget.Snapshot.
+//	GET  /pins       Every pin's number, mode and value.
+//	GET  /pins/{pin} A single pin.
+//	POST /pins/{pin} Set a pin's mode, value and/or reporting, e.g.
+//	                 {"value": 1}, {"mode": "PWM", "value": 128} or
+//	                 {"report": false}. Switching a pin to INPUT,
+//	                 PULLUP or ANALOG turns on its reporting unless
+//	                 "report" says otherwise. The body must
+//	                 be sent as application/json, which browsers
+//	                 won't do cross-origin without asking first.
+//	GET  /events     A WebSocket streaming an Event every time a pin's
+//	                 mode or value changes. The state of every pin is
+//	                 sent when the stream opens. Commands sent by the
+//	                 client, e.g. {"pin": 13, "value": 1}, are applied
+//	                 like a POST and failures answered with
+//	                 {"pin": 13, "error": "..."}. Browser pages
+//	                 from other origins are refused unless listed in
+//	                 the Server's AllowedOrigins field.
+//	GET  /           A web dashboard showing live pin values, with
+//	                 controls for outputs and a reporting toggle for
+//	                 inputs. Only served when the Server's Dashboard
+//	                 field is set.
+//
+// Values are written according to the pin's mode: OUTPUT pins take 0
+// or 1, PWM pins 0-255 and SERVO pins an angle or pulse width.
+package httpapi
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ZachMassia/GoGoGadget"
+)
+
+// Info describes the connected board.
+type Info struct {
+	Board    string `json:"board"`
+	Firmware string `json:"firmware"`
+	Version  string `json:"version"`
+}
+
+// Pin is a pin's state.
+type Pin struct {
+	Pin       byte   `json:"pin"`
+	Mode      string `json:"mode"`
+	Value     int    `json:"value"`
+	Reporting bool   `json:"reporting"`
+}
+
+// PinUpdate is the body of a POST to a pin. Any field may be left out.
+type PinUpdate struct {
+	Mode   *string `json:"mode,omitempty"`
+	Value  *int    `json:"value,omitempty"`
+	Report *bool   `json:"report,omitempty"`
+}
+
+// Event is sent on the event stream when a pin changes.
+type Event struct {
+	Pin
+	Time time.Time `json:"time"`
+}
+
+// Command is a PinUpdate sent on the event stream.
+type Command struct {
+	Pin byte `json:"pin"`
+	PinUpdate
+}
+
+// Server is an http.Handler serving the API for a board.
+type Server struct {
+	board *gadget.Board
+	mux   *http.ServeMux
+
+	// Serve the web dashboard at the root.
+	Dashboard bool
+
+	// Origins, e.g. "http://localhost:3000", whose pages may open the
+	// event stream. Pages served by the Server itself always may.
+	AllowedOrigins []string
+}
+
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+// NewServer returns a Server for the board.
+func NewServer(b *gadget.Board) *Server {
+	s := &Server{board: b, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /info", s.handleInfo)
+	s.mux.HandleFunc("GET /snapshot", s.handleSnapshot)
+	s.mux.HandleFunc("GET /pins", s.handlePins)
+	s.mux.HandleFunc("GET /pins/{pin}", s.handlePin)
+	s.mux.HandleFunc("POST /pins/{pin}", s.handleUpdate)
+	s.mux.HandleFunc("GET /events", s.handleEvents)
+	s.mux.HandleFunc("GET /{$}", s.handleDashboard)
+	return s
+}
+
+// ListenAndServe serves the API for the board on addr.
+func ListenAndServe(addr string, b *gadget.Board) error {
+	return http.ListenAndServe(addr, NewServer(b))
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Info{
+		Board:    s.board.String(),
+		Firmware: s.board.Firmware(),
+		Version:  s.board.Version(),
+	})
+}
+
+func (s *Server) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.board.Snapshot())
+}
+
+func (s *Server) handlePins(w http.ResponseWriter, r *http.Request) {
+	nums := PinNumbers(s.board)
+	pins := make([]Pin, 0, len(nums))
+	for _, n := range nums {
+		p, err := ReadPin(s.board, n)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		pins = append(pins, p)
+	}
+	writeJSON(w, http.StatusOK, pins)
+}
+
+func (s *Server) handlePin(w http.ResponseWriter, r *http.Request) {
+	n, err := pinParam(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	p, err := ReadPin(s.board, n)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (s *Server) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	n, err := pinParam(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, err = s.board.PinMode(n); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, fmt.Errorf("Expected an application/json body"))
+		return
+	}
+	var u PinUpdate
+	if err = json.NewDecoder(r.Body).Decode(&u); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("Invalid body: %s", err))
+		return
+	}
+	if err = UpdatePin(s.board, n, u); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	p, err := ReadPin(s.board, n)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if !s.Dashboard {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardHTML)
+}
+
+// Streams pin changes over a WebSocket until the client goes away.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	c, err := wsUpgrade(w, r, s.AllowedOrigins)
+	if err != nil {
+		return
+	}
+	defer c.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		c.readLoop(func(msg []byte) { s.handleCommand(c, msg) })
+		cancel()
+	}()
+
+	s.board.WatchValues(ctx, nil, func(v gadget.PinValue) error {
+		info, _ := s.board.Pin(v.Pin)
+		msg, _ := json.Marshal(Event{
+			Pin:  Pin{Pin: v.Pin, Mode: gadget.PinModeString[v.Mode], Value: v.Value, Reporting: info.Reporting},
+			Time: v.Time,
+		})
+		return c.WriteText(msg)
+	})
+}
+
+// Applies a Command received on the event stream. The resulting change
+// is reported by the stream itself.
+func (s *Server) handleCommand(c *wsConn, msg []byte) {
+	var cmd Command
+	err := json.Unmarshal(msg, &cmd)
+	if err != nil {
+		err = fmt.Errorf("Invalid command: %s", err)
+	} else {
+		err = UpdatePin(s.board, cmd.Pin, cmd.PinUpdate)
+	}
+	if err != nil {
+		reply, _ := json.Marshal(map[string]interface{}{"pin": cmd.Pin, "error": err.Error()})
+		c.WriteText(reply)
+	}
+}
+
+// PinNumbers returns the board's pin numbers in order.
+func PinNumbers(b *gadget.Board) (nums []byte) {
+	for _, p := range b.Pins() {
+		nums = append(nums, p.Pin)
+	}
+	return
+}
+
+// ReadPin returns the state of pin n, see Board.ReadValue.
+func ReadPin(b *gadget.Board, n byte) (p Pin, err error) {
+	mode, v, err := b.ReadValue(n)
+	if err != nil {
+		return p, err
+	}
+	info, err := b.Pin(n)
+	if err != nil {
+		return p, err
+	}
+	return Pin{Pin: n, Mode: gadget.PinModeString[mode], Value: v, Reporting: info.Reporting}, nil
+}
+
+// UpdatePin applies an update to pin n, setting the mode first. The
+// value is written with Board.WriteValue. A pin switched to an input
+// mode starts reporting unless the update says otherwise.
+func UpdatePin(b *gadget.Board, n byte, u PinUpdate) (err error) {
+	report := u.Report
+	if u.Mode != nil {
+		mode, ok := gadget.ParsePinMode(*u.Mode)
+		if !ok {
+			return fmt.Errorf("Unknown pin mode '%s'", *u.Mode)
+		}
+		if cur, _ := b.PinMode(n); cur != mode {
+			if err = b.SetPinMode(n, mode); err != nil {
+				return err
+			}
+			if report == nil && (mode == gadget.INPUT || mode == gadget.PULLUP || mode == gadget.ANALOG) {
+				on := true
+				report = &on
+			}
+		}
+	}
+	if report != nil {
+		if err = b.SetPinReporting(n, *report); err != nil {
+			return err
+		}
+	}
+	if u.Value == nil {
+		return
+	}
+	return b.WriteValue(n, *u.Value)
+}
+
+func pinParam(r *http.Request) (byte, error) {
+	n, err := strconv.ParseUint(r.PathValue("pin"), 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid pin: %s", r.PathValue("pin"))
+	}
+	return byte(n), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
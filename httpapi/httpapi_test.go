@@ -0,0 +1,166 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ZachMassia/GoGoGadget"
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+// Returns a board with digital pins 2 and 3 and analog pin 14 (A0),
+// answered by a fake StandardFirmata.
+func newTestBoard(t *testing.T) *gadget.Board {
+	host, board := net.Pipe()
+	go fakeFirmata(board)
+
+	b, err := gadget.New("fake", gadget.WithTransport(host),
+		gadget.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return b
+}
+
+// Answers the handshake until the connection closes.
+func fakeFirmata(conn net.Conn) {
+	announce := func() {
+		conn.Write([]byte{firmatawire.ReportVersion, 2, 5})
+		conn.Write(firmatawire.Sysex(firmatawire.ReportFirmware,
+			firmatawire.AppendBytes7([]byte{2, 5}, []byte("StandardFirmata"))...))
+	}
+	announce()
+
+	d := firmatawire.NewDecoder(conn)
+	for {
+		f, err := d.Next()
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrClosedPipe) {
+			return
+		}
+		if err != nil || !f.Sysex() {
+			continue
+		}
+		switch f.Command() {
+		case firmatawire.ReportFirmware:
+			announce()
+		case firmatawire.AnalogMappingQuery:
+			mapping := bytes.Repeat([]byte{0x7F}, 15)
+			mapping[14] = 0
+			conn.Write(firmatawire.Sysex(firmatawire.AnalogMappingResponse, mapping...))
+		case firmatawire.CapabilityQuery:
+			var caps []byte
+			for pin := 0; pin < 15; pin++ {
+				switch pin {
+				case 2, 3:
+					caps = append(caps, gadget.INPUT, 1, gadget.OUTPUT, 1)
+				case 14:
+					caps = append(caps, gadget.ANALOG, 10)
+				}
+				caps = append(caps, 0x7F)
+			}
+			conn.Write(firmatawire.Sysex(firmatawire.CapabilityResponse, caps...))
+		}
+	}
+}
+
+func TestGetPins(t *testing.T) {
+	srv := httptest.NewServer(NewServer(newTestBoard(t)))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/pins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var pins []Pin
+	if err = json.NewDecoder(resp.Body).Decode(&pins); err != nil {
+		t.Fatal(err)
+	}
+	if len(pins) != 3 || pins[0].Pin != 2 || pins[2].Pin != 14 || pins[2].Mode != "ANALOG" {
+		t.Fatalf("Unexpected pins %+v", pins)
+	}
+
+	for path, status := range map[string]int{"/pins/14": 200, "/pins/9": 404, "/pins/x": 400} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("GET %s: got %s, want %d", path, resp.Status, status)
+		}
+	}
+}
+
+func TestPostPin(t *testing.T) {
+	b := newTestBoard(t)
+	srv := httptest.NewServer(NewServer(b))
+	defer srv.Close()
+
+	tests := []struct {
+		path, contentType, body string
+		status                  int
+	}{
+		{"/pins/3", "application/json", `{"mode": "OUTPUT", "value": 1}`, http.StatusOK},
+		{"/pins/3", "application/json; charset=utf-8", `{"value": 0}`, http.StatusOK},
+		{"/pins/3", "text/plain", `{"value": 1}`, http.StatusUnsupportedMediaType},
+		{"/pins/3", "", `{"value": 1}`, http.StatusUnsupportedMediaType},
+		{"/pins/3", "application/json", `{"value": `, http.StatusBadRequest},
+		{"/pins/3", "application/json", `{"mode": "BOGUS"}`, http.StatusBadRequest},
+		{"/pins/9", "application/json", `{"value": 1}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("POST", srv.URL+tt.path, strings.NewReader(tt.body))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("POST %s %s '%s': got %s, want %d", tt.path, tt.contentType, tt.body, resp.Status, tt.status)
+		}
+	}
+
+	// Only the two JSON posts were applied.
+	if mode, v, _ := b.ReadValue(3); mode != gadget.OUTPUT || v != 0 {
+		t.Fatalf("Pin 3 is %s %d, want OUTPUT 0", gadget.PinModeString[mode], v)
+	}
+}
+
+func TestPostReporting(t *testing.T) {
+	srv := httptest.NewServer(NewServer(newTestBoard(t)))
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		body      string
+		reporting bool
+	}{
+		{`{"mode": "INPUT"}`, true},
+		{`{"report": false}`, false},
+		{`{"mode": "OUTPUT"}`, false},
+		{`{"mode": "INPUT", "report": false}`, false},
+	} {
+		resp, err := http.Post(srv.URL+"/pins/2", "application/json", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var p Pin
+		err = json.NewDecoder(resp.Body).Decode(&p)
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK || p.Reporting != tt.reporting {
+			t.Fatalf("POST '%s': %s %+v, want reporting %v", tt.body, resp.Status, p, tt.reporting)
+		}
+	}
+}
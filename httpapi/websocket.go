@@ -0,0 +1,237 @@
+package httpapi
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Appended to the client's key to form the accept hash, from RFC 6455.
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket frame opcodes.
+const (
+	wsContinuation byte = 0x0
+	wsText         byte = 0x1
+	wsClose        byte = 0x8
+	wsPing         byte = 0x9
+	wsPong         byte = 0xA
+)
+
+// Close status codes sent when a client breaks the limits below.
+const (
+	wsUnsupported uint16 = 1003
+	wsTooBig      uint16 = 1009
+)
+
+// The largest frame accepted from a client. Clients send pin commands,
+// small JSON objects, and control frames, limited to 125 bytes.
+const wsMaxPayload = 4096
+
+// A frame the connection does not accept, closed with code.
+type wsError struct {
+	code uint16
+	msg  string
+}
+
+func (e *wsError) Error() string {
+	return e.msg
+}
+
+// A server side WebSocket connection. Only what the event stream needs
+// is implemented: sending and receiving unfragmented text frames,
+// answering pings and closing. Fragmented client messages are refused.
+type wsConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	m    sync.Mutex // Serializes frame writes.
+}
+
+// Completes the WebSocket opening handshake and takes over the
+// connection. Browsers send an Origin with the handshake, which must be
+// the server's own host or one of allowed.
+func wsUpgrade(w http.ResponseWriter, r *http.Request, allowed []string) (c *wsConn, err error) {
+	if !originAllowed(r, allowed) {
+		http.Error(w, "Cross-origin WebSocket not allowed", http.StatusForbidden)
+		return nil, fmt.Errorf("Origin '%s' not allowed", r.Header.Get("Origin"))
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" || key == "" {
+		http.Error(w, "Expected a WebSocket upgrade", http.StatusBadRequest)
+		return nil, fmt.Errorf("Not a WebSocket handshake")
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSockets not supported", http.StatusInternalServerError)
+		return nil, fmt.Errorf("ResponseWriter cannot be hijacked")
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha1.Sum([]byte(key + wsGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: %s\r\n\r\n", base64.StdEncoding.EncodeToString(sum[:]))
+	if err = rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, rw: rw}, nil
+}
+
+// Reports whether the request's Origin may open a WebSocket. Requests
+// without one don't come from a browser page and are allowed.
+func originAllowed(r *http.Request, allowed []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(a, origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// WriteText sends a text frame.
+func (c *wsConn) WriteText(p []byte) error {
+	return c.writeFrame(wsText, p)
+}
+
+// Close sends a close frame and closes the connection.
+func (c *wsConn) Close() error {
+	c.writeFrame(wsClose, nil)
+	return c.conn.Close()
+}
+
+// Reads frames until the client closes the connection or it fails,
+// answering pings. Text frames are passed to onText, binary frames are
+// discarded.
+func (c *wsConn) readLoop(onText func([]byte)) {
+	defer c.conn.Close()
+	for {
+		op, payload, err := readFrame(c.rw)
+		var we *wsError
+		if errors.As(err, &we) {
+			c.writeFrame(wsClose, append(binary.BigEndian.AppendUint16(nil, we.code), we.msg...))
+		}
+		if err != nil {
+			return
+		}
+		switch op {
+		case wsClose:
+			c.writeFrame(wsClose, nil)
+			return
+		case wsPing:
+			c.writeFrame(wsPong, payload)
+		case wsText:
+			onText(payload)
+		}
+	}
+}
+
+func (c *wsConn) writeFrame(op byte, p []byte) (err error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if _, err = c.rw.Write(frameHeader(op, len(p))); err != nil {
+		return err
+	}
+	if _, err = c.rw.Write(p); err != nil {
+		return err
+	}
+	return c.rw.Flush()
+}
+
+// Returns the header of an unmasked, final frame.
+func frameHeader(op byte, n int) (h []byte) {
+	h = []byte{0x80 | op}
+	switch {
+	case n < 126:
+		h = append(h, byte(n))
+	case n <= 0xFFFF:
+		h = append(h, 126)
+		h = binary.BigEndian.AppendUint16(h, uint16(n))
+	default:
+		h = append(h, 127)
+		h = binary.BigEndian.AppendUint64(h, uint64(n))
+	}
+	return
+}
+
+// Reads a single client frame, unmasking its payload.
+func readFrame(r io.Reader) (op byte, payload []byte, err error) {
+	var h [2]byte
+	if _, err = io.ReadFull(r, h[:]); err != nil {
+		return 0, nil, err
+	}
+	op = h[0] & 0x0F
+	if h[1]&0x80 == 0 {
+		return 0, nil, fmt.Errorf("Client frame is not masked")
+	}
+	if h[0]&0x80 == 0 || op == wsContinuation {
+		return 0, nil, &wsError{wsUnsupported, "Fragmented messages are not supported"}
+	}
+
+	n := uint64(h[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > wsMaxPayload {
+		return 0, nil, &wsError{wsTooBig, fmt.Sprintf("WebSocket frame of %d bytes too large", n)}
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(r, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return
+}
+
+// Reports whether a comma separated header contains token, ignoring case.
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
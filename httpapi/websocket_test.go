@@ -0,0 +1,150 @@
+package httpapi
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFrameHeader(t *testing.T) {
+	tests := []struct {
+		n    int
+		want []byte
+	}{
+		{5, []byte{0x81, 5}},
+		{200, []byte{0x81, 126, 0x00, 0xC8}},
+		{70000, []byte{0x81, 127, 0, 0, 0, 0, 0x00, 0x01, 0x11, 0x70}},
+	}
+	for _, tt := range tests {
+		if got := frameHeader(wsText, tt.n); !bytes.Equal(got, tt.want) {
+			t.Errorf("frameHeader(%d) = % X, want % X", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	// A masked "Hello" text frame, from RFC 6455 section 5.7.
+	frame := []byte{0x81, 0x85, 0x37, 0xfa, 0x21, 0x3d, 0x7f, 0x9f, 0x4d, 0x51, 0x58}
+	op, payload, err := readFrame(bytes.NewReader(frame))
+	if err != nil {
+		t.Fatalf("readFrame returned error: %s", err)
+	}
+	if op != wsText || string(payload) != "Hello" {
+		t.Fatalf("readFrame = %X '%s'", op, payload)
+	}
+
+	// Unmasked client frames are rejected.
+	if _, _, err := readFrame(bytes.NewReader([]byte{0x81, 0x01, 'a'})); err == nil {
+		t.Fatalf("Unmasked frame should be rejected")
+	}
+
+	// So are fragments, from RFC 6455 section 5.7, and large frames.
+	for _, tt := range []struct {
+		frame []byte
+		code  uint16
+	}{
+		{[]byte{0x01, 0x83, 0, 0, 0, 0, 'H', 'e', 'l'}, wsUnsupported},
+		{[]byte{0x80, 0x82, 0, 0, 0, 0, 'l', 'o'}, wsUnsupported},
+		{[]byte{0x81, 0xFE, 0x20, 0x00}, wsTooBig},
+	} {
+		_, _, err := readFrame(bytes.NewReader(tt.frame))
+		if we, ok := err.(*wsError); !ok || we.code != tt.code {
+			t.Errorf("readFrame(% X) = %v, want close code %d", tt.frame, err, tt.code)
+		}
+	}
+}
+
+func TestReadLoopFragmented(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	c := &wsConn{conn: server, rw: bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server))}
+	go c.readLoop(func(msg []byte) { t.Errorf("Fragment passed on as '%s'", msg) })
+
+	go client.Write([]byte{0x01, 0x83, 0, 0, 0, 0, 'H', 'e', 'l'})
+	h := make([]byte, 4)
+	if _, err := io.ReadFull(client, h); err != nil {
+		t.Fatalf("Reading close frame: %s", err)
+	}
+	if h[0] != 0x80|wsClose || h[2] != 0x03 || h[3] != 0xEB {
+		t.Fatalf("Expected close frame with code 1003, got % X", h)
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := wsUpgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		c.WriteText([]byte("hi"))
+		c.Close()
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Handshake failed: %s", err)
+	}
+	defer resp.Body.Close()
+
+	// The accept value for this key is given in RFC 6455 section 1.3.
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Bad handshake response: %s %v", resp.Status, resp.Header)
+	}
+
+	frame := make([]byte, 4)
+	if _, err := io.ReadFull(resp.Body, frame); err != nil || !bytes.Equal(frame, []byte{0x81, 2, 'h', 'i'}) {
+		t.Fatalf("Expected 'hi' text frame, got % X (%v)", frame, err)
+	}
+}
+
+func TestUpgradeOrigin(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := wsUpgrade(w, r, []string{"http://allowed.example"})
+		if err != nil {
+			return
+		}
+		c.Close()
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		origin string
+		status int
+	}{
+		{"", http.StatusSwitchingProtocols},
+		{srv.URL, http.StatusSwitchingProtocols},
+		{"http://allowed.example", http.StatusSwitchingProtocols},
+		{"http://evil.example", http.StatusForbidden},
+		{"null", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", srv.URL, nil)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Origin '%s': %s", tt.origin, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("Origin '%s': got %s, want %d", tt.origin, resp.Status, tt.status)
+		}
+	}
+}
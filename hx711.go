@@ -0,0 +1,170 @@
+package gadget
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// Default sysex command of the HX711 firmware extension, from the
+	// range Firmata reserves for user-defined commands.
+	//
+	// The HX711 powers down if its clock is held high for more than
+	// 60µs, so it cannot be bit-banged from the host.
+	HX711Sysex byte = 0x0E
+
+	// Sub commands.
+	hx711Config byte = 0x01 // dataPin, clockPin, gain pulses
+	hx711Read   byte = 0x02 // dataPin
+	hx711Data   byte = 0x03 // dataPin, 24-bit value as four 7-bit bytes
+
+	// A conversion takes 100ms at the HX711's 10Hz rate.
+	hx711Timeout = 500 * time.Millisecond
+)
+
+// HX711Gain selects the HX711's input channel and gain.
+type HX711Gain byte
+
+const (
+	HX711GainA128 HX711Gain = 1 // Channel A, gain 128.
+	HX711GainB32  HX711Gain = 2 // Channel B, gain 32.
+	HX711GainA64  HX711Gain = 3 // Channel A, gain 64.
+)
+
+// HX711 is a load cell amplifier read through an HX711 Firmata
+// extension.
+type HX711 struct {
+	board       *Board
+	cmd         byte
+	data, clock byte
+	replies     chan int32
+
+	m      sync.Mutex
+	offset float64 // Raw reading with no load, set by Tare.
+
+	// Raw counts per unit of weight, set by Calibrate. Defaults to 1.
+	Scale float64
+}
+
+// NewHX711 configures an HX711 on the given pins, using the firmware
+// extension's sysex command cmd (usually HX711Sysex).
+func NewHX711(b *Board, dataPin, clockPin byte, gain HX711Gain, cmd byte) (h *HX711, err error) {
+	if err = b.checkPins(dataPin, clockPin); err != nil {
+		return nil, err
+	}
+	h = &HX711{
+		board:   b,
+		cmd:     cmd,
+		data:    dataPin,
+		clock:   clockPin,
+		replies: make(chan int32, 1),
+		Scale:   1,
+	}
+	b.addHandler(cmd, h.handleReply)
+
+	_, err = b.sendSysex([]byte{cmd, hx711Config, dataPin, clockPin, byte(gain)})
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Raw returns a single raw conversion.
+func (h *HX711) Raw() (v int32, err error) {
+	// Drop a stale reply from a read that timed out.
+	select {
+	case <-h.replies:
+	default:
+	}
+
+	if _, err = h.board.sendSysex([]byte{h.cmd, hx711Read, h.data}); err != nil {
+		return 0, err
+	}
+	select {
+	case v = <-h.replies:
+		return v, nil
+	case <-time.After(hx711Timeout):
+		return 0, fmt.Errorf("Timed out reading HX711 on pin %d", h.data)
+	}
+}
+
+// Average returns the mean of n raw conversions.
+func (h *HX711) Average(n int) (v float64, err error) {
+	if n < 1 {
+		n = 1
+	}
+
+	var sum float64
+	for i := 0; i < n; i++ {
+		raw, err := h.Raw()
+		if err != nil {
+			return 0, err
+		}
+		sum += float64(raw)
+	}
+	return sum / float64(n), nil
+}
+
+// Tare averages n conversions with the scale empty and uses it as the
+// zero point.
+func (h *HX711) Tare(n int) (err error) {
+	avg, err := h.Average(n)
+	if err != nil {
+		return err
+	}
+
+	h.m.Lock()
+	h.offset = avg
+	h.m.Unlock()
+	return
+}
+
+// Calibrate sets Scale by averaging n conversions with a known weight
+// on the (already tared) scale.
+func (h *HX711) Calibrate(known float64, n int) (err error) {
+	if known == 0 {
+		return fmt.Errorf("HX711 calibration weight must not be 0")
+	}
+	avg, err := h.Average(n)
+	if err != nil {
+		return err
+	}
+
+	h.m.Lock()
+	h.Scale = (avg - h.offset) / known
+	h.m.Unlock()
+	return
+}
+
+// Weight returns the average of n conversions, in the units of the
+// calibration weight.
+func (h *HX711) Weight(n int) (w float64, err error) {
+	avg, err := h.Average(n)
+	if err != nil {
+		return 0, err
+	}
+
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	return (avg - h.offset) / h.Scale, nil
+}
+
+// Passes a conversion for this HX711 to a waiting Raw.
+func (h *HX711) handleReply(m message) {
+	// Sysex start, cmd, sub cmd, pin, 4 value bytes, end.
+	if len(m.data) != 9 || m.data[2] != hx711Data || m.data[3] != h.data {
+		return
+	}
+	d := m.data[4:8]
+	raw := uint32(d[0]) | uint32(d[1])<<7 | uint32(d[2])<<14 | uint32(d[3])<<21
+
+	// Sign extend the 24-bit two's complement value.
+	v := int32(raw<<8) >> 8
+
+	select {
+	case h.replies <- v:
+	default:
+	}
+}
@@ -0,0 +1,72 @@
+package gadget
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Returns an HX711 data reply for pin holding the 24-bit value raw.
+func hx711Reply(pin byte, raw uint32) message {
+	return message{t: sysexMsg, data: []byte{
+		startSysex, HX711Sysex, hx711Data, pin,
+		byte(raw) & 0x7F, byte(raw>>7) & 0x7F, byte(raw>>14) & 0x7F, byte(raw>>21) & 0x7F,
+		endSysex,
+	}}
+}
+
+// An HX711 extension answering each read with the next raw value.
+type fakeHX711 struct {
+	h   *HX711
+	raw []uint32
+}
+
+func (f *fakeHX711) Write(p []byte) (int, error) {
+	if bytes.Equal(p, []byte{startSysex, HX711Sysex, hx711Read, f.h.data, endSysex}) {
+		go f.h.handleReply(hx711Reply(f.h.data, f.raw[0]))
+		f.raw = f.raw[1:]
+	}
+	return len(p), nil
+}
+
+func TestHX711SignExtend(t *testing.T) {
+	h := &HX711{data: 5, replies: make(chan int32, 1)}
+	for raw, want := range map[uint32]int32{0x000001: 1, 0x7FFFFF: 8388607, 0x800000: -8388608, 0xFFFFFF: -1} {
+		h.handleReply(hx711Reply(5, raw))
+		if v := <-h.replies; v != want {
+			t.Fatalf("Raw %#06x = %d, want %d", raw, v, want)
+		}
+	}
+
+	// Replies for other pins are ignored.
+	h.handleReply(hx711Reply(6, 1))
+	if len(h.replies) != 0 {
+		t.Fatalf("Reply for pin 6 was delivered")
+	}
+}
+
+func TestHX711Weight(t *testing.T) {
+	var out bytes.Buffer
+	caps := []Capability{{INPUT, 1}, {OUTPUT, 1}}
+	b := newTestBoard(t, &out, nil, map[byte][]Capability{5: caps, 6: caps})
+	out.Reset()
+	h, err := NewHX711(b, 5, 6, HX711GainA64, HX711Sysex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{startSysex, HX711Sysex, hx711Config, 5, 6, 3, endSysex}; !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("NewHX711 sent % X, want % X", out.Bytes(), want)
+	}
+
+	// Tare at -1000, 500 counts per gram.
+	f := &fakeHX711{h: h, raw: []uint32{0xFFFC18, 0xFFFC18, 24000, 24000, 11500}}
+	b.out = newBatchWriter(f)
+	if err = h.Tare(2); err != nil {
+		t.Fatal(err)
+	}
+	if err = h.Calibrate(50, 2); err != nil || h.Scale != 500 {
+		t.Fatalf("Calibrate = %v, Scale %f, want 500", err, h.Scale)
+	}
+	if w, err := h.Weight(1); err != nil || w != 25 {
+		t.Fatalf("Weight = %f, %v, want 25", w, err)
+	}
+}
@@ -0,0 +1,205 @@
+package gadget
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+const (
+	// I2C read/write modes, bits 3-4 of the second request byte.
+	i2cModeWrite       byte = 0x00
+	i2cModeRead        byte = 0x01
+	i2cModeReadCont    byte = 0x02
+	i2cModeStopReading byte = 0x03
+
+	// Set in the second request byte for 10-bit addresses.
+	i2cTenBit byte = 0x20
+
+	// Set in the second request byte to end a register write with a
+	// repeated start rather than a stop.
+	i2cRestart byte = 0x40
+
+	// How long I2CRead waits for the board to reply.
+	i2cReplyTimeout = time.Second
+
+	// How long I2CScan waits for each address. Absent devices never
+	// reply so this bounds the length of a scan.
+	i2cScanTimeout = 50 * time.Millisecond
+
+	// The range of 7-bit addresses not reserved by the I2C spec.
+	i2cFirstAddr byte = 0x08
+	i2cLastAddr  byte = 0x77
+)
+
+// I2CTarget is a device's address and how to reach it, for devices the
+// plain 7-bit methods cannot address or talk to.
+type I2CTarget struct {
+	Addr   uint16
+	TenBit bool // Addr is a 10-bit address.
+
+	// Read registers with a repeated start between writing the
+	// register and reading it, rather than a stop, as some devices
+	// require. Needs Firmata 2.5 or later.
+	Restart bool
+}
+
+func (t I2CTarget) String() string {
+	if t.TenBit {
+		return fmt.Sprintf("0x%03X", t.Addr)
+	}
+	return fmt.Sprintf("0x%02X", t.Addr)
+}
+
+// Reports an address out of range for its mode.
+func (t I2CTarget) check() error {
+	if (t.TenBit && t.Addr > 0x3FF) || (!t.TenBit && t.Addr > 0x7F) {
+		return fmt.Errorf("Invalid I2C address: %s", t)
+	}
+	return nil
+}
+
+// A decoded i2cReply message.
+type i2cReplyData struct {
+	addr uint16
+	reg  uint16
+	data []byte
+}
+
+// I2CConfig enables I2C on the board. Delay is the time the firmware
+// waits between writing a register and reading it back, needed by some
+// devices. It must be called before any other I2C method.
+func (b *Board) I2CConfig(delay time.Duration) (err error) {
+	if err = b.require(FeatureI2C); err != nil {
+		return err
+	}
+	us := delay / time.Microsecond
+	_, err = b.sendSysex(firmatawire.AppendUint14([]byte{i2cConfig}, int(us)))
+	return
+}
+
+// I2CWrite writes data to the device at the 7-bit address addr.
+func (b *Board) I2CWrite(addr byte, data []byte) (err error) {
+	return b.I2CWriteTo(I2CTarget{Addr: uint16(addr)}, data)
+}
+
+// I2CRead reads n bytes from the device at the 7-bit address addr.
+func (b *Board) I2CRead(addr byte, n int) (data []byte, err error) {
+	return b.i2cRead(I2CTarget{Addr: uint16(addr)}, nil, n, i2cReplyTimeout)
+}
+
+// I2CReadRegister reads n bytes from the device at the 7-bit address
+// addr, starting at register reg.
+func (b *Board) I2CReadRegister(addr, reg byte, n int) (data []byte, err error) {
+	return b.i2cRead(I2CTarget{Addr: uint16(addr)}, []byte{reg}, n, i2cReplyTimeout)
+}
+
+// I2CWriteTo writes data to the target device.
+func (b *Board) I2CWriteTo(t I2CTarget, data []byte) (err error) {
+	if err = t.check(); err != nil {
+		return err
+	}
+	_, err = b.sendSysex(t.requestMsg(i2cModeWrite, data))
+	return
+}
+
+// I2CReadFrom reads n bytes from the target device, starting at
+// register reg if it is not empty.
+func (b *Board) I2CReadFrom(t I2CTarget, reg []byte, n int) (data []byte, err error) {
+	if err = t.check(); err != nil {
+		return nil, err
+	}
+	return b.i2cRead(t, reg, n, i2cReplyTimeout)
+}
+
+// I2CScan returns the addresses of the devices on the bus, found by
+// reading a byte from every non-reserved address. I2CConfig must be
+// called first.
+func (b *Board) I2CScan() (addrs []byte) {
+	for addr := i2cFirstAddr; addr <= i2cLastAddr; addr++ {
+		if _, err := b.i2cRead(I2CTarget{Addr: uint16(addr)}, nil, 1, i2cScanTimeout); err == nil {
+			addrs = append(addrs, addr)
+		}
+	}
+	return
+}
+
+// Sends a read request and waits up to timeout for the matching reply.
+// Reads are serialized since replies only identify the device and
+// register.
+func (b *Board) i2cRead(t I2CTarget, reg []byte, n int, timeout time.Duration) (data []byte, err error) {
+	b.i2cMutex.Lock()
+	defer b.i2cMutex.Unlock()
+
+	// Drop any stale reply left by a previous timed out read.
+	select {
+	case <-b.i2cReplies:
+	default:
+	}
+
+	payload := append(append([]byte(nil), reg...), byte(n))
+	if _, err = b.sendSysex(t.requestMsg(i2cModeRead, payload)); err != nil {
+		return nil, err
+	}
+
+	expired := time.After(timeout)
+	for {
+		select {
+		case r := <-b.i2cReplies:
+			if r.addr != t.Addr {
+				continue // Reply to a continuous read of another device.
+			}
+			if len(r.data) != n {
+				return nil, fmt.Errorf("I2C device %s: expected %d bytes, got %d", t, n, len(r.data))
+			}
+			return r.data, nil
+
+		case <-expired:
+			return nil, fmt.Errorf("Timed out waiting for I2C reply from %s", t)
+		}
+	}
+}
+
+// Builds an i2cRequest message body for a 7-bit address.
+func i2cRequestMsg(addr, mode byte, data []byte) []byte {
+	return I2CTarget{Addr: uint16(addr)}.requestMsg(mode, data)
+}
+
+// Builds an i2cRequest message body. The top 3 bits of a 10-bit
+// address share the second byte with the mode. Every data byte is sent
+// as two 7-bit bytes.
+func (t I2CTarget) requestMsg(mode byte, data []byte) (msg []byte) {
+	msg = []byte{
+		i2cRequest,
+		byte(t.Addr) & 0x7F,
+		mode << 3,
+	}
+	if t.TenBit {
+		msg[2] |= i2cTenBit | byte(t.Addr>>7)&0x07
+	}
+	if t.Restart {
+		msg[2] |= i2cRestart
+	}
+	return firmatawire.AppendBytes7(msg, data)
+}
+
+// Passes an i2cReply to a waiting I2CRead.
+func (b *Board) handleI2CReply(m message) {
+	// Sysex start, cmd, addr (2), register (2), end.
+	if len(m.data) < 7 {
+		return
+	}
+	body := m.data[2 : len(m.data)-1]
+
+	r := i2cReplyData{
+		addr: uint16(firmatawire.Uint14(body[0], body[1])),
+		reg:  uint16(firmatawire.Uint14(body[2], body[3])),
+		data: firmatawire.Bytes7(body[4:]),
+	}
+
+	select {
+	case b.i2cReplies <- r:
+	default:
+	}
+}
@@ -0,0 +1,132 @@
+package gadget
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+func TestI2CRequestMsg(t *testing.T) {
+	got := i2cRequestMsg(0x20, i2cModeRead, []byte{0x12, 0xFF})
+	want := []byte{i2cRequest, 0x20, i2cModeRead << 3, 0x12, 0x00, 0x7F, 0x01}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("i2cRequestMsg = % X, want % X", got, want)
+	}
+}
+
+func TestHandleI2CReply(t *testing.T) {
+	b := &Board{i2cReplies: make(chan i2cReplyData, 1)}
+	b.handleI2CReply(message{
+		t:    sysexMsg,
+		data: []byte{startSysex, i2cReply, 0x20, 0x00, 0x12, 0x00, 0x7F, 0x01, 0x05, 0x00, endSysex},
+	})
+
+	select {
+	case r := <-b.i2cReplies:
+		if r.addr != 0x20 || r.reg != 0x12 || !bytes.Equal(r.data, []byte{0xFF, 0x05}) {
+			t.Fatalf("Unexpected reply: %+v", r)
+		}
+	default:
+		t.Fatalf("Reply was not delivered")
+	}
+
+	// Truncated replies are ignored.
+	b.handleI2CReply(message{t: sysexMsg, data: []byte{startSysex, i2cReply, 0x20, endSysex}})
+	if len(b.i2cReplies) != 0 {
+		t.Fatalf("Truncated reply should be dropped")
+	}
+}
+
+func TestI2CTargetRequest(t *testing.T) {
+	got := I2CTarget{Addr: 0x2A5, TenBit: true}.requestMsg(i2cModeWrite, []byte{0x01})
+	want := []byte{i2cRequest, 0x25, i2cTenBit | 0x05, 0x01, 0x00}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("requestMsg = % X, want % X", got, want)
+	}
+
+	got = I2CTarget{Addr: 0x68, Restart: true}.requestMsg(i2cModeRead, []byte{0x3B, 6})
+	want = []byte{i2cRequest, 0x68, i2cRestart | i2cModeRead<<3, 0x3B, 0x00, 0x06, 0x00}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("requestMsg with restart = % X, want % X", got, want)
+	}
+
+	if err := (I2CTarget{Addr: 0x80}).check(); err == nil {
+		t.Fatalf("Expected error for 7-bit address 0x80")
+	}
+	if err := (I2CTarget{Addr: 0x400, TenBit: true}).check(); err == nil {
+		t.Fatalf("Expected error for 10-bit address 0x400")
+	}
+}
+
+// A fake bus of I2C chips, each with a byte addressed memory. A write's
+// first addrBytes bytes move the chip's pointer and the rest are stored
+// from there. Reads return bytes from the pointer, which advances past
+// them. Every write is recorded.
+type fakeI2CBus struct {
+	b         *Board
+	addrBytes int
+	mem       map[byte][]byte
+	ptr       map[byte]int
+	writes    [][]byte
+}
+
+func newFakeI2CBus(addrBytes int) *fakeI2CBus {
+	return &fakeI2CBus{addrBytes: addrBytes, mem: make(map[byte][]byte), ptr: make(map[byte]int)}
+}
+
+// Returns the memory of the chip at addr.
+func (f *fakeI2CBus) chip(addr byte) []byte {
+	if f.mem[addr] == nil {
+		f.mem[addr] = make([]byte, 1<<(8*f.addrBytes))
+	}
+	return f.mem[addr]
+}
+
+// Moves the pointer of the chip at addr to the address in data,
+// returning the bytes after it.
+func (f *fakeI2CBus) seek(addr byte, data []byte) []byte {
+	if len(data) < f.addrBytes {
+		return nil
+	}
+	p := 0
+	for _, d := range data[:f.addrBytes] {
+		p = p<<8 | int(d)
+	}
+	f.ptr[addr] = p
+	return data[f.addrBytes:]
+}
+
+func (f *fakeI2CBus) Write(p []byte) (int, error) {
+	if len(p) < 4 || p[1] != i2cRequest {
+		return len(p), nil
+	}
+	body := p[2 : len(p)-1]
+	addr, data := body[0], firmatawire.Bytes7(body[2:])
+	mem := f.chip(addr)
+
+	switch body[1] >> 3 & 0x03 {
+	case i2cModeWrite:
+		f.writes = append(f.writes, data)
+		for _, d := range f.seek(addr, data) {
+			mem[f.ptr[addr]] = d
+			f.ptr[addr] = (f.ptr[addr] + 1) % len(mem)
+		}
+	case i2cModeRead:
+		f.seek(addr, data[:len(data)-1])
+		reply := firmatawire.AppendUint14([]byte{i2cReply, addr, 0}, 0)
+		for i := 0; i < int(data[len(data)-1]); i++ {
+			reply = firmatawire.AppendBytes7(reply, []byte{mem[f.ptr[addr]]})
+			f.ptr[addr] = (f.ptr[addr] + 1) % len(mem)
+		}
+		go f.b.handleI2CReply(message{t: sysexMsg, data: firmatawire.Sysex(reply[0], reply[1:]...)})
+	}
+	return len(p), nil
+}
+
+// Returns a board on a fake I2C bus.
+func newI2CTestBoard(t *testing.T, addrBytes int) (*Board, *fakeI2CBus) {
+	f := newFakeI2CBus(addrBytes)
+	f.b = newTestBoard(t, f, nil, nil)
+	return f.b, f
+}
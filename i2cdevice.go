@@ -0,0 +1,106 @@
+package gadget
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// I2CDevice is a handle on one device on the I2C bus, taking care of
+// the register addressing and byte order drivers would otherwise each
+// repeat. I2CConfig must be called before it is used.
+type I2CDevice struct {
+	board  *Board
+	target I2CTarget
+
+	// Byte order of the 16-bit register helpers. Defaults to big
+	// endian, which most devices use.
+	ByteOrder binary.ByteOrder
+}
+
+// I2CDevice returns a handle on the device at the 7-bit address addr.
+func (b *Board) I2CDevice(addr byte) *I2CDevice {
+	return b.I2CDeviceAt(I2CTarget{Addr: uint16(addr)})
+}
+
+// I2CDeviceAt returns a handle on the target device, for 10-bit
+// addresses or devices needing a repeated start.
+func (b *Board) I2CDeviceAt(t I2CTarget) *I2CDevice {
+	return &I2CDevice{board: b, target: t, ByteOrder: binary.BigEndian}
+}
+
+// Target returns the device's address.
+func (d *I2CDevice) Target() I2CTarget {
+	return d.target
+}
+
+func (d *I2CDevice) String() string {
+	return "I2C device " + d.target.String()
+}
+
+// Read reads n bytes without selecting a register first.
+func (d *I2CDevice) Read(n int) ([]byte, error) {
+	return d.board.I2CReadFrom(d.target, nil, n)
+}
+
+// Write writes data as is.
+func (d *I2CDevice) Write(data []byte) error {
+	return d.board.I2CWriteTo(d.target, data)
+}
+
+// ReadBlock reads n bytes starting at register reg.
+func (d *I2CDevice) ReadBlock(reg byte, n int) ([]byte, error) {
+	return d.board.I2CReadFrom(d.target, []byte{reg}, n)
+}
+
+// WriteBlock writes data starting at register reg.
+func (d *I2CDevice) WriteBlock(reg byte, data []byte) error {
+	return d.Write(append([]byte{reg}, data...))
+}
+
+// ReadRegister reads the 8-bit register reg.
+func (d *I2CDevice) ReadRegister(reg byte) (byte, error) {
+	data, err := d.ReadBlock(reg, 1)
+	if err != nil {
+		return 0, err
+	}
+	return data[0], nil
+}
+
+// WriteRegister writes v to the 8-bit register reg.
+func (d *I2CDevice) WriteRegister(reg, v byte) error {
+	return d.WriteBlock(reg, []byte{v})
+}
+
+// ReadUint16 reads the 16-bit register reg in the device's byte order.
+func (d *I2CDevice) ReadUint16(reg byte) (uint16, error) {
+	data, err := d.ReadBlock(reg, 2)
+	if err != nil {
+		return 0, err
+	}
+	return d.ByteOrder.Uint16(data), nil
+}
+
+// ReadInt16 reads the signed 16-bit register reg in the device's byte
+// order.
+func (d *I2CDevice) ReadInt16(reg byte) (int16, error) {
+	v, err := d.ReadUint16(reg)
+	return int16(v), err
+}
+
+// WriteUint16 writes v to the 16-bit register reg in the device's byte
+// order.
+func (d *I2CDevice) WriteUint16(reg byte, v uint16) error {
+	data := make([]byte, 2)
+	d.ByteOrder.PutUint16(data, v)
+	return d.WriteBlock(reg, data)
+}
+
+// UpdateRegister sets the bits of register reg selected by mask to
+// those of v, leaving the others as they are.
+func (d *I2CDevice) UpdateRegister(reg, mask, v byte) error {
+	old, err := d.ReadRegister(reg)
+	if err != nil {
+		return fmt.Errorf("%s: %s", d, err)
+	}
+	return d.WriteRegister(reg, old&^mask|v&mask)
+}
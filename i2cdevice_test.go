@@ -0,0 +1,63 @@
+package gadget
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+// A device at 0x40 whose registers hold their own number plus one.
+// Reads are answered and writes recorded.
+type fakeI2CDevice struct {
+	b      *Board
+	writes [][]byte
+}
+
+func (f *fakeI2CDevice) Write(p []byte) (int, error) {
+	body := p[2 : len(p)-1]
+	data := firmatawire.Bytes7(body[2:])
+	if body[1]>>3&0x03 == i2cModeWrite {
+		f.writes = append(f.writes, data)
+		return len(p), nil
+	}
+
+	reg, n := data[0], int(data[1])
+	reply := firmatawire.AppendUint14([]byte{i2cReply, body[0], 0}, int(reg))
+	for i := 0; i < n; i++ {
+		reply = firmatawire.AppendBytes7(reply, []byte{reg + byte(i) + 1})
+	}
+	go f.b.handleI2CReply(message{t: sysexMsg, data: firmatawire.Sysex(reply[0], reply[1:]...)})
+	return len(p), nil
+}
+
+func TestI2CDevice(t *testing.T) {
+	b := &Board{i2cReplies: make(chan i2cReplyData, 1)}
+	f := &fakeI2CDevice{b: b}
+	b.out = newBatchWriter(f)
+	d := b.I2CDevice(0x40)
+
+	if v, err := d.ReadRegister(0x10); err != nil || v != 0x11 {
+		t.Fatalf("ReadRegister = %#x, %v, want 0x11", v, err)
+	}
+	if v, err := d.ReadUint16(0x10); err != nil || v != 0x1112 {
+		t.Fatalf("ReadUint16 = %#x, %v, want 0x1112", v, err)
+	}
+	d.ByteOrder = binary.LittleEndian
+	if v, err := d.ReadUint16(0x10); err != nil || v != 0x1211 {
+		t.Fatalf("Little endian ReadUint16 = %#x, %v, want 0x1211", v, err)
+	}
+
+	d.WriteUint16(0x20, 0xABCD)
+	d.UpdateRegister(0x30, 0x0F, 0x05) // Holds 0x31.
+	want := [][]byte{{0x20, 0xCD, 0xAB}, {0x30, 0x35}}
+	if len(f.writes) != len(want) {
+		t.Fatalf("Wrote %X, want %X", f.writes, want)
+	}
+	for i := range want {
+		if !bytes.Equal(f.writes[i], want[i]) {
+			t.Fatalf("Write %d = % X, want % X", i, f.writes[i], want[i])
+		}
+	}
+}
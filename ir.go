@@ -0,0 +1,145 @@
+package gadget
+
+import (
+	"sync"
+)
+
+const (
+	// Default sysex command of the IRremote firmware extension, from
+	// the range Firmata reserves for user-defined commands.
+	IRSysex byte = 0x0D
+
+	// Sub commands.
+	irConfig byte = 0x01 // pin
+	irCode   byte = 0x02 // pin, protocol, flags, 32-bit code as five 7-bit bytes
+
+	irFlagRepeat byte = 0x01
+)
+
+// IRProtocol is the encoding of a received infrared code.
+type IRProtocol byte
+
+const (
+	IRUnknown IRProtocol = iota
+	IRNEC
+	IRRC5
+)
+
+// IREvent is a code received from a remote.
+type IREvent struct {
+	Protocol IRProtocol
+	Code     uint32
+
+	// Set if the button is being held. NEC remotes send a repeat
+	// code instead of the button's code, so Code and Button are
+	// those of the last press.
+	Repeat bool
+
+	// The name given to the code with MapButton, if any.
+	Button string
+}
+
+// A code without the repeat flag, used to look up button names.
+type irKey struct {
+	p    IRProtocol
+	code uint32
+}
+
+// IRReceiver is an infrared receiver whose codes are decoded by an
+// IRremote Firmata extension.
+type IRReceiver struct {
+	board *Board
+	cmd   byte
+	pin   byte
+
+	m        sync.Mutex
+	buttons  map[irKey]string
+	last     IREvent
+	onCode   []func(IREvent)
+	onButton map[string][]func(IREvent)
+}
+
+// NewIRReceiver starts decoding on the given pin, using the firmware
+// extension's sysex command cmd (usually IRSysex).
+func NewIRReceiver(b *Board, pin, cmd byte) (r *IRReceiver, err error) {
+	if err = b.checkPins(pin); err != nil {
+		return nil, err
+	}
+	r = &IRReceiver{
+		board:    b,
+		cmd:      cmd,
+		pin:      pin,
+		buttons:  make(map[irKey]string),
+		onButton: make(map[string][]func(IREvent)),
+	}
+	b.addHandler(cmd, r.handleCode)
+
+	if _, err = b.sendSysex([]byte{cmd, irConfig, pin}); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// MapButton names a code, so events for it carry the name and
+// OnButton callbacks can be attached.
+func (r *IRReceiver) MapButton(p IRProtocol, code uint32, name string) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.buttons[irKey{p, code}] = name
+}
+
+// OnCode adds a callback run for every code received.
+//
+// Callbacks run on the board's message loop and must return quickly.
+func (r *IRReceiver) OnCode(cb func(IREvent)) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.onCode = append(r.onCode, cb)
+}
+
+// OnButton adds a callback run when the named button is pressed
+// or held.
+//
+// Callbacks run on the board's message loop and must return quickly.
+func (r *IRReceiver) OnButton(name string, cb func(IREvent)) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.onButton[name] = append(r.onButton[name], cb)
+}
+
+// Decodes a code reported for this receiver's pin and runs the callbacks.
+func (r *IRReceiver) handleCode(m message) {
+	// Sysex start, cmd, sub cmd, pin, protocol, flags, 5 code bytes, end.
+	if len(m.data) != 12 || m.data[2] != irCode || m.data[3] != r.pin {
+		return
+	}
+	d := m.data[6:11]
+
+	e := IREvent{
+		Protocol: IRProtocol(m.data[4]),
+		Code: uint32(d[0]) | uint32(d[1])<<7 | uint32(d[2])<<14 |
+			uint32(d[3])<<21 | uint32(d[4])<<28,
+		Repeat: m.data[5]&irFlagRepeat != 0,
+	}
+
+	r.m.Lock()
+	if e.Repeat && e.Protocol == IRNEC {
+		e.Code = r.last.Code
+	}
+	e.Button = r.buttons[irKey{e.Protocol, e.Code}]
+	r.last = e
+
+	cbs := append([]func(IREvent){}, r.onCode...)
+	if e.Button != "" {
+		cbs = append(cbs, r.onButton[e.Button]...)
+	}
+	r.m.Unlock()
+
+	r.board.bus.Publish(Event{Topic: TopicIR, Data: e})
+	for _, cb := range cbs {
+		cb(e)
+	}
+}
@@ -0,0 +1,57 @@
+package gadget
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Returns an IR code report for pin.
+func irReport(pin byte, p IRProtocol, flags byte, code uint32) message {
+	return message{t: sysexMsg, data: []byte{
+		startSysex, IRSysex, irCode, pin, byte(p), flags,
+		byte(code) & 0x7F, byte(code>>7) & 0x7F, byte(code>>14) & 0x7F,
+		byte(code>>21) & 0x7F, byte(code>>28) & 0x7F,
+		endSysex,
+	}}
+}
+
+func TestIRReceiver(t *testing.T) {
+	var out bytes.Buffer
+	b := newTestBoard(t, &out, nil, map[byte][]Capability{11: {{INPUT, 1}}})
+	out.Reset()
+	r, err := NewIRReceiver(b, 11, IRSysex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{startSysex, IRSysex, irConfig, 11, endSysex}; !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("NewIRReceiver sent % X, want % X", out.Bytes(), want)
+	}
+
+	var codes []IREvent
+	presses := 0
+	r.MapButton(IRNEC, 0x20DF10EF, "power")
+	r.OnCode(func(e IREvent) { codes = append(codes, e) })
+	r.OnButton("power", func(IREvent) { presses++ })
+	sub := b.bus.Subscribe(TopicIR, 1)
+
+	r.handleCode(irReport(11, IRNEC, 0, 0x20DF10EF))
+	if e := (<-sub.C).Data.(IREvent); e != (IREvent{Protocol: IRNEC, Code: 0x20DF10EF, Button: "power"}) {
+		t.Fatalf("Published %+v", e)
+	}
+
+	// An NEC repeat code stands for the last press.
+	r.handleCode(irReport(11, IRNEC, irFlagRepeat, 0xFFFFFFFF))
+	if e := codes[1]; !e.Repeat || e.Code != 0x20DF10EF || e.Button != "power" {
+		t.Fatalf("Repeat = %+v, want a repeat of power", e)
+	}
+
+	// RC5 codes keep their own code, and other pins are ignored.
+	r.handleCode(irReport(11, IRRC5, irFlagRepeat, 0x080C))
+	r.handleCode(irReport(12, IRNEC, 0, 0x20DF10EF))
+	if len(codes) != 3 || codes[2].Code != 0x080C || codes[2].Button != "" {
+		t.Fatalf("Got codes %+v", codes)
+	}
+	if presses != 2 {
+		t.Fatalf("power pressed %d times, want 2", presses)
+	}
+}
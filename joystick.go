@@ -0,0 +1,216 @@
+package gadget
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// JoystickEvent is the state of a Joystick when it changed.
+type JoystickEvent struct {
+	X, Y    float64 // Normalized axes, -1 to 1.
+	Pressed bool
+}
+
+// Joystick is a two axis analog joystick, optionally with a push button.
+type Joystick struct {
+	board  *Board
+	x, y   byte // Normal (not A0 style) pin numbers of the axes.
+	button byte
+	hasBtn bool
+	max    int // Full scale raw reading.
+
+	// Raw reading of each axis at rest. Defaults to mid scale,
+	// use Calibrate to measure it.
+	CenterX, CenterY int
+
+	// Fraction of travel around the center that reads as 0.
+	DeadZone float64
+
+	// Minimum axis movement needed to fire an event. Defaults to 0.02.
+	Threshold float64
+
+	// Set if the button reads HIGH when pressed. Most joystick
+	// modules pull the button to ground, which needs a pull-up.
+	ButtonActiveHigh bool
+
+	// How often the pins are sampled while started.
+	Interval time.Duration
+
+	m      sync.Mutex
+	last   JoystickEvent
+	events chan JoystickEvent
+	stop   func()
+}
+
+// NewJoystick returns a Joystick on the given analog pins, with
+// analog reporting turned on.
+func NewJoystick(b *Board, xPin, yPin byte) (j *Joystick, err error) {
+	bits, err := b.AnalogResolution(xPin)
+	if err != nil {
+		return nil, err
+	}
+	for _, pin := range []byte{xPin, yPin} {
+		if err = b.ensurePinMode(pin, ANALOG); err != nil {
+			return nil, err
+		}
+		if err = b.SetPinReporting(pin, true); err != nil {
+			return nil, err
+		}
+	}
+
+	max := analogMax(bits)
+	j = &Joystick{
+		board:     b,
+		x:         xPin,
+		y:         yPin,
+		max:       max,
+		CenterX:   max / 2,
+		CenterY:   max / 2,
+		Threshold: 0.02,
+		Interval:  defaultSampleDelay,
+		events:    make(chan JoystickEvent, 1),
+	}
+	return
+}
+
+// AttachButton uses a digital pin as the joystick's push button.
+func (j *Joystick) AttachButton(pin byte) (err error) {
+	if err = j.board.ensurePinMode(pin, INPUT); err != nil {
+		return err
+	}
+	if err = j.board.SetPinReporting(pin, true); err != nil {
+		return err
+	}
+
+	j.m.Lock()
+	j.button, j.hasBtn = pin, true
+	j.m.Unlock()
+	return
+}
+
+// Calibrate stores the current position as the center. The stick
+// must be at rest when it is called.
+func (j *Joystick) Calibrate() (err error) {
+	x, err := j.board.AnalogRead(j.x)
+	if err != nil {
+		return err
+	}
+	y, err := j.board.AnalogRead(j.y)
+	if err != nil {
+		return err
+	}
+
+	j.m.Lock()
+	j.CenterX, j.CenterY = x, y
+	j.m.Unlock()
+	return
+}
+
+// Read returns the current joystick state.
+func (j *Joystick) Read() (e JoystickEvent, err error) {
+	x, err := j.board.AnalogRead(j.x)
+	if err != nil {
+		return e, err
+	}
+	y, err := j.board.AnalogRead(j.y)
+	if err != nil {
+		return e, err
+	}
+
+	j.m.Lock()
+	defer j.m.Unlock()
+
+	e.X = j.normalize(x, j.CenterX)
+	e.Y = j.normalize(y, j.CenterY)
+	if j.hasBtn {
+		s, err := j.board.DigitalRead(j.button)
+		if err != nil {
+			return e, err
+		}
+		e.Pressed = (s == HIGH) == j.ButtonActiveHigh
+	}
+	return
+}
+
+// Requires reports the joystick's pins, see AddDriver.
+func (j *Joystick) Requires() Requirements {
+	j.m.Lock()
+	defer j.m.Unlock()
+
+	req := Requirements{Pins: []byte{j.x, j.y}}
+	if j.hasBtn {
+		req.Pins = append(req.Pins, j.button)
+	}
+	return req
+}
+
+// Start samples the joystick in the background, sending its state on
+// Events whenever an axis moves by more than Threshold or the button
+// changes.
+func (j *Joystick) Start() (err error) {
+	j.m.Lock()
+	defer j.m.Unlock()
+
+	if j.stop != nil {
+		return fmt.Errorf("Joystick on pins %d,%d already started", j.x, j.y)
+	}
+	j.stop = poll(j.Interval, func() {
+		e, err := j.Read()
+		if err != nil {
+			return
+		}
+
+		j.m.Lock()
+		changed := e.Pressed != j.last.Pressed ||
+			math.Abs(e.X-j.last.X) >= j.Threshold ||
+			math.Abs(e.Y-j.last.Y) >= j.Threshold
+		if changed {
+			j.last = e
+		}
+		j.m.Unlock()
+
+		if changed {
+			sendLatest(j.events, e)
+			j.board.bus.Publish(Event{Topic: TopicJoystick, Data: e})
+		}
+	})
+	return
+}
+
+// Halt stops background sampling.
+func (j *Joystick) Halt() (err error) {
+	j.m.Lock()
+	defer j.m.Unlock()
+
+	if j.stop != nil {
+		j.stop()
+		j.stop = nil
+	}
+	return
+}
+
+// Events returns the channel joystick events are delivered on. Only
+// the latest event is kept if the reader falls behind.
+func (j *Joystick) Events() <-chan JoystickEvent {
+	return j.events
+}
+
+// Maps a raw axis reading to -1..1 around center, applying the dead zone.
+func (j *Joystick) normalize(raw, center int) (v float64) {
+	switch {
+	case raw > center && center < j.max:
+		v = float64(raw-center) / float64(j.max-center)
+	case raw < center && center > 0:
+		v = float64(raw-center) / float64(center)
+	}
+
+	if dz := j.DeadZone; dz > 0 && dz < 1 {
+		if math.Abs(v) < dz {
+			return 0
+		}
+		v = math.Copysign((math.Abs(v)-dz)/(1-dz), v)
+	}
+	return math.Max(-1, math.Min(1, v))
+}
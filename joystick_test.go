@@ -0,0 +1,41 @@
+package gadget
+
+import (
+	"math"
+	"testing"
+)
+
+func TestJoystickRead(t *testing.T) {
+	analog := []Capability{{ANALOG, 10}}
+	b := newTestBoard(t, nil, map[byte][]Capability{14: analog, 15: analog},
+		map[byte][]Capability{2: {{INPUT, 1}, {OUTPUT, 1}}})
+	j, err := NewJoystick(b, 14, 15)
+	if err != nil {
+		t.Fatalf("NewJoystick: %s", err)
+	}
+	if err = j.AttachButton(2); err != nil {
+		t.Fatalf("AttachButton: %s", err)
+	}
+	j.CenterX, j.CenterY = 400, 423
+
+	for _, tc := range []struct {
+		x, y     int
+		deadZone float64
+		button   byte
+		want     JoystickEvent
+	}{
+		{400, 423, 0, HIGH, JoystickEvent{0, 0, false}},
+		{1023, 0, 0, LOW, JoystickEvent{1, -1, true}}, // Pulled low when pressed.
+		{200, 723, 0, HIGH, JoystickEvent{-0.5, 0.5, false}},
+		{200, 463, 0.2, HIGH, JoystickEvent{-0.375, 0, false}},
+	} {
+		b.pins[14].analogVal, b.pins[15].analogVal = tc.x, tc.y
+		b.pins[2].digitalVal = tc.button
+		j.DeadZone = tc.deadZone
+
+		e, err := j.Read()
+		if err != nil || math.Abs(e.X-tc.want.X) > 1e-9 || math.Abs(e.Y-tc.want.Y) > 1e-9 || e.Pressed != tc.want.Pressed {
+			t.Errorf("Read at %d,%d = %+v, %v, want %+v", tc.x, tc.y, e, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package gadget
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// Key layout of a 4x4 membrane keypad.
+	Keypad4x4 = []string{"123A", "456B", "789C", "*0#D"}
+
+	// Key layout of a 4x3 membrane keypad.
+	Keypad4x3 = []string{"123", "456", "789", "*0#"}
+)
+
+// KeyEvent is a key being pressed or released.
+type KeyEvent struct {
+	Key     rune
+	Pressed bool
+}
+
+// Keypad is a matrix keypad scanned by driving one row pin HIGH at a
+// time and reading the column pins.
+//
+// The column pins need pull-down resistors so they read LOW when no
+// key in the driven row is pressed.
+type Keypad struct {
+	board      *Board
+	rows, cols []byte
+	layout     []string
+
+	// How long a key must stay in its new state before the change
+	// is reported. Defaults to 50ms.
+	Debounce time.Duration
+
+	// How long to wait after driving a row before reading the
+	// columns, giving the board time to report the new values.
+	ScanDelay time.Duration
+
+	m       sync.Mutex
+	state   [][]bool      // Debounced key state.
+	pending [][]time.Time // When an undebounced change was first seen.
+	events  chan KeyEvent
+	stop    func()
+}
+
+// NewKeypad returns a Keypad using the given row and column pins. Each
+// string in layout is a row, each rune the key at that column.
+func NewKeypad(b *Board, rows, cols []byte, layout []string) (k *Keypad, err error) {
+	if len(layout) != len(rows) {
+		return nil, fmt.Errorf("Keypad layout has %d rows, got %d row pins", len(layout), len(rows))
+	}
+	for _, r := range layout {
+		if len([]rune(r)) != len(cols) {
+			return nil, fmt.Errorf("Keypad layout row '%s' does not match %d column pins", r, len(cols))
+		}
+	}
+
+	for _, pin := range rows {
+		if err = b.ensurePinMode(pin, OUTPUT); err != nil {
+			return nil, err
+		}
+		if err = b.DigitalWrite(pin, LOW); err != nil {
+			return nil, err
+		}
+	}
+	for _, pin := range cols {
+		if err = b.ensurePinMode(pin, INPUT); err != nil {
+			return nil, err
+		}
+		if err = b.SetPinReporting(pin, true); err != nil {
+			return nil, err
+		}
+	}
+
+	k = &Keypad{
+		board:     b,
+		rows:      rows,
+		cols:      cols,
+		layout:    layout,
+		Debounce:  50 * time.Millisecond,
+		ScanDelay: defaultSampleDelay,
+		state:     make([][]bool, len(rows)),
+		pending:   make([][]time.Time, len(rows)),
+		events:    make(chan KeyEvent, 16),
+	}
+	for i := range rows {
+		k.state[i] = make([]bool, len(cols))
+		k.pending[i] = make([]time.Time, len(cols))
+	}
+	return
+}
+
+// Requires reports the keypad's row and column pins, see AddDriver.
+func (k *Keypad) Requires() Requirements {
+	return Requirements{Pins: append(append([]byte(nil), k.rows...), k.cols...)}
+}
+
+// Start scans the keypad in the background, sending key presses and
+// releases on Events.
+func (k *Keypad) Start() (err error) {
+	k.m.Lock()
+	defer k.m.Unlock()
+
+	if k.stop != nil {
+		return fmt.Errorf("Keypad already started")
+	}
+	k.stop = poll(time.Millisecond, k.scan)
+	return
+}
+
+// Halt stops scanning.
+func (k *Keypad) Halt() (err error) {
+	k.m.Lock()
+	defer k.m.Unlock()
+
+	if k.stop != nil {
+		k.stop()
+		k.stop = nil
+	}
+	return
+}
+
+// Events returns the channel key events are delivered on. Events are
+// dropped if more than 16 are waiting to be read.
+func (k *Keypad) Events() <-chan KeyEvent {
+	return k.events
+}
+
+// Pressed returns the keys currently held down.
+func (k *Keypad) Pressed() (keys []rune) {
+	k.m.Lock()
+	defer k.m.Unlock()
+
+	for r, row := range k.state {
+		for c, down := range row {
+			if down {
+				keys = append(keys, []rune(k.layout[r])[c])
+			}
+		}
+	}
+	return
+}
+
+// Drives each row in turn and debounces the column readings.
+func (k *Keypad) scan() {
+	for r, rowPin := range k.rows {
+		if err := k.board.DigitalWrite(rowPin, HIGH); err != nil {
+			return
+		}
+		time.Sleep(k.ScanDelay)
+
+		now := time.Now()
+		for c, colPin := range k.cols {
+			s, err := k.board.DigitalRead(colPin)
+			if err != nil {
+				continue
+			}
+			k.update(r, c, s == HIGH, now)
+		}
+
+		if err := k.board.DigitalWrite(rowPin, LOW); err != nil {
+			return
+		}
+	}
+}
+
+// Records a raw key reading, firing an event once a change has
+// outlasted the debounce time.
+func (k *Keypad) update(r, c int, down bool, now time.Time) {
+	k.m.Lock()
+	defer k.m.Unlock()
+
+	if down == k.state[r][c] {
+		k.pending[r][c] = time.Time{}
+		return
+	}
+	if k.pending[r][c].IsZero() {
+		k.pending[r][c] = now
+	}
+	if now.Sub(k.pending[r][c]) < k.Debounce {
+		return
+	}
+
+	k.state[r][c] = down
+	k.pending[r][c] = time.Time{}
+	e := KeyEvent{Key: []rune(k.layout[r])[c], Pressed: down}
+	select {
+	case k.events <- e:
+	default:
+	}
+	k.board.bus.Publish(Event{Topic: TopicKeypad, Data: e})
+}
@@ -0,0 +1,43 @@
+package gadget
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeypadDebounce(t *testing.T) {
+	k := &Keypad{
+		board:    &Board{bus: NewEventBus()},
+		layout:   Keypad4x3,
+		Debounce: 50 * time.Millisecond,
+		state:    [][]bool{{false, false, false}},
+		pending:  [][]time.Time{{{}, {}, {}}},
+		events:   make(chan KeyEvent, 16),
+	}
+	sub := k.board.Events().Subscribe(TopicKeypad, 4)
+	start := time.Now()
+
+	// A bounce shorter than the debounce time is ignored.
+	k.update(0, 1, true, start)
+	k.update(0, 1, false, start.Add(10*time.Millisecond))
+	k.update(0, 1, true, start.Add(20*time.Millisecond))
+	if len(k.events) != 0 {
+		t.Fatalf("Bounce should not fire an event")
+	}
+
+	k.update(0, 1, true, start.Add(80*time.Millisecond))
+	select {
+	case e := <-k.events:
+		if e.Key != '2' || !e.Pressed {
+			t.Fatalf("Expected '2' pressed, got %+v", e)
+		}
+	default:
+		t.Fatalf("Stable press should fire an event")
+	}
+	if e := <-sub.C; e.Data != (KeyEvent{Key: '2', Pressed: true}) {
+		t.Fatalf("Expected key event on the bus, got %+v", e)
+	}
+	if keys := k.Pressed(); len(keys) != 1 || keys[0] != '2' {
+		t.Fatalf("Pressed() = %q, want ['2']", keys)
+	}
+}
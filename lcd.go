@@ -0,0 +1,247 @@
+package gadget
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// Default I2C address of PCF8574 backpacks. PCF8574A based
+	// ones use 0x3F.
+	LCDAddress byte = 0x27
+
+	// PCF8574 pin to LCD signal mapping used by the common backpacks.
+	lcdRS        byte = 0x01
+	lcdEnable    byte = 0x04
+	lcdBacklight byte = 0x08
+
+	// HD44780 instructions.
+	lcdClear       byte = 0x01
+	lcdHome        byte = 0x02
+	lcdEntryMode   byte = 0x04
+	lcdDisplayCtrl byte = 0x08
+	lcdShift       byte = 0x10
+	lcdFunctionSet byte = 0x20
+	lcdSetCGRAM    byte = 0x40
+	lcdSetDDRAM    byte = 0x80
+
+	// Instruction flags.
+	lcdEntryLeft    byte = 0x02
+	lcdDisplayOn    byte = 0x04
+	lcdCursorOn     byte = 0x02
+	lcdBlinkOn      byte = 0x01
+	lcdShiftDisplay byte = 0x08
+	lcdShiftRight   byte = 0x04
+	lcdTwoLines     byte = 0x08
+
+	// Bytes sent per I2C write, each LCD byte takes four.
+	lcdChunk = 16
+)
+
+// LCD is an HD44780 compatible character LCD driven through a PCF8574
+// I2C backpack, in 4-bit mode.
+type LCD struct {
+	board      *Board
+	addr       byte
+	cols, rows int
+
+	m         sync.Mutex
+	backlight byte // lcdBacklight or 0.
+	control   byte // Display control flags.
+}
+
+// NewLCD initializes the LCD and returns it cleared, with the
+// backlight on. I2CConfig must already have been called.
+func NewLCD(b *Board, addr byte, cols, rows int) (l *LCD, err error) {
+	if rows < 1 || rows > 4 {
+		return nil, fmt.Errorf("LCD must have 1-4 rows, got %d", rows)
+	}
+
+	l = &LCD{
+		board:     b,
+		addr:      addr,
+		cols:      cols,
+		rows:      rows,
+		backlight: lcdBacklight,
+		control:   lcdDisplayOn,
+	}
+
+	// The reset sequence from the HD44780 datasheet, figure 24. The
+	// controller may be in either 8 or 4-bit mode, so send 0x3 three
+	// times to get into 8-bit mode, then switch to 4-bit.
+	time.Sleep(50 * time.Millisecond)
+	for _, d := range []time.Duration{5 * time.Millisecond, 5 * time.Millisecond, time.Millisecond} {
+		if err = l.send(l.nibble(0x30, 0)); err != nil {
+			return nil, err
+		}
+		time.Sleep(d)
+	}
+	if err = l.send(l.nibble(0x20, 0)); err != nil {
+		return nil, err
+	}
+
+	function := lcdFunctionSet
+	if rows > 1 {
+		function |= lcdTwoLines
+	}
+	for _, cmd := range []byte{function, lcdDisplayCtrl | l.control, lcdEntryMode | lcdEntryLeft} {
+		if err = l.command(cmd); err != nil {
+			return nil, err
+		}
+	}
+	if err = l.Clear(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Requires reports that the LCD needs I2C, see AddDriver.
+func (l *LCD) Requires() Requirements {
+	return l.board.i2cRequirements()
+}
+
+// Clear blanks the display and returns the cursor home.
+func (l *LCD) Clear() (err error) {
+	if err = l.command(lcdClear); err != nil {
+		return err
+	}
+	time.Sleep(2 * time.Millisecond)
+	return
+}
+
+// Home returns the cursor and any display shift to the top left.
+func (l *LCD) Home() (err error) {
+	if err = l.command(lcdHome); err != nil {
+		return err
+	}
+	time.Sleep(2 * time.Millisecond)
+	return
+}
+
+// SetCursor moves the cursor to the given column and row.
+func (l *LCD) SetCursor(col, row int) (err error) {
+	if col < 0 || col >= l.cols || row < 0 || row >= l.rows {
+		return fmt.Errorf("LCD position %d,%d out of range", col, row)
+	}
+
+	// Rows 2 and 3 continue on from rows 0 and 1 in DDRAM.
+	offsets := []int{0x00, 0x40, l.cols, 0x40 + l.cols}
+	return l.command(lcdSetDDRAM | byte(offsets[row]+col))
+}
+
+// Print writes s at the cursor position.
+func (l *LCD) Print(s string) (err error) {
+	_, err = l.Write([]byte(s))
+	return
+}
+
+// Write writes raw character codes at the cursor position. Codes 0-7
+// are the custom characters.
+func (l *LCD) Write(p []byte) (n int, err error) {
+	var buf []byte
+	for _, c := range p {
+		buf = append(buf, l.byteBits(c, lcdRS)...)
+	}
+	if err = l.send(buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Display turns the display on or off without losing its contents.
+func (l *LCD) Display(on bool) error {
+	return l.setControl(lcdDisplayOn, on)
+}
+
+// Cursor shows or hides the underline cursor.
+func (l *LCD) Cursor(on bool) error {
+	return l.setControl(lcdCursorOn, on)
+}
+
+// Blink turns the blinking block cursor on or off.
+func (l *LCD) Blink(on bool) error {
+	return l.setControl(lcdBlinkOn, on)
+}
+
+// Backlight turns the backlight on or off.
+func (l *LCD) Backlight(on bool) error {
+	l.m.Lock()
+	if on {
+		l.backlight = lcdBacklight
+	} else {
+		l.backlight = 0
+	}
+	l.m.Unlock()
+
+	return l.board.I2CWrite(l.addr, []byte{l.backlight})
+}
+
+// ScrollLeft shifts the whole display one position to the left.
+func (l *LCD) ScrollLeft() error {
+	return l.command(lcdShift | lcdShiftDisplay)
+}
+
+// ScrollRight shifts the whole display one position to the right.
+func (l *LCD) ScrollRight() error {
+	return l.command(lcdShift | lcdShiftDisplay | lcdShiftRight)
+}
+
+// CreateChar defines custom character slot (0-7) from 8 rows of 5
+// pixels, the low bits of each byte. The cursor must be positioned
+// again afterwards.
+func (l *LCD) CreateChar(slot int, bitmap [8]byte) (err error) {
+	if slot < 0 || slot > 7 {
+		return fmt.Errorf("Invalid LCD custom character slot: %d", slot)
+	}
+	if err = l.command(lcdSetCGRAM | byte(slot)<<3); err != nil {
+		return err
+	}
+	_, err = l.Write(bitmap[:])
+	return
+}
+
+func (l *LCD) setControl(flag byte, on bool) error {
+	l.m.Lock()
+	if on {
+		l.control |= flag
+	} else {
+		l.control &^= flag
+	}
+	ctrl := l.control
+	l.m.Unlock()
+
+	return l.command(lcdDisplayCtrl | ctrl)
+}
+
+// Sends an instruction.
+func (l *LCD) command(cmd byte) error {
+	return l.send(l.byteBits(cmd, 0))
+}
+
+// Returns the expander writes clocking a byte in as two nibbles.
+func (l *LCD) byteBits(b, mode byte) []byte {
+	return append(l.nibble(b&0xF0, mode), l.nibble(b<<4, mode)...)
+}
+
+// Returns the expander writes clocking in the high nibble of n.
+func (l *LCD) nibble(n, mode byte) []byte {
+	l.m.Lock()
+	bits := n&0xF0 | mode | l.backlight
+	l.m.Unlock()
+
+	return []byte{bits | lcdEnable, bits}
+}
+
+// Writes expander states, split into I2C writes that fit the
+// firmware's buffers.
+func (l *LCD) send(data []byte) (err error) {
+	for len(data) > 0 {
+		n := min(len(data), lcdChunk)
+		if err = l.board.I2CWrite(l.addr, data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return
+}
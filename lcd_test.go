@@ -0,0 +1,57 @@
+package gadget
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLCDFrames(t *testing.T) {
+	l := &LCD{backlight: lcdBacklight}
+
+	// Each nibble is clocked in on the enable's falling edge.
+	if got, want := l.byteBits('A', lcdRS), []byte{0x4D, 0x49, 0x1D, 0x19}; !bytes.Equal(got, want) {
+		t.Fatalf("byteBits('A') = % X, want % X", got, want)
+	}
+	l.backlight = 0
+	if got, want := l.byteBits(lcdClear, 0), []byte{0x04, 0x00, 0x14, 0x10}; !bytes.Equal(got, want) {
+		t.Fatalf("byteBits(lcdClear) = % X, want % X", got, want)
+	}
+}
+
+func TestLCD(t *testing.T) {
+	b, f := newI2CTestBoard(t, 1)
+	l, err := NewLCD(b, LCDAddress, 20, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Three 0x3 nibbles, the switch to 4-bit mode and a two line
+	// function set.
+	want := [][]byte{{0x3C, 0x38}, {0x3C, 0x38}, {0x3C, 0x38}, {0x2C, 0x28}, {0x2C, 0x28, 0x8C, 0x88}}
+	for i := range want {
+		if !bytes.Equal(f.writes[i], want[i]) {
+			t.Fatalf("Write %d = % X, want % X", i, f.writes[i], want[i])
+		}
+	}
+
+	// Row 2 continues on from row 0 in DDRAM.
+	f.writes = nil
+	if err = l.SetCursor(3, 2); err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0x9C, 0x98, 0x7C, 0x78}; !bytes.Equal(f.writes[0], want) {
+		t.Fatalf("SetCursor(3, 2) wrote % X, want % X", f.writes[0], want)
+	}
+	if err = l.SetCursor(20, 0); err == nil {
+		t.Fatalf("Expected an error for column 20")
+	}
+
+	// Five characters take 20 expander writes, sent as 16 and 4.
+	f.writes = nil
+	if err = l.Print("Hello"); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.writes) != 2 || len(f.writes[0]) != lcdChunk || len(f.writes[1]) != 4 {
+		t.Fatalf("Print sent % X, want writes of 16 and 4 bytes", f.writes)
+	}
+}
@@ -0,0 +1,74 @@
+package gadget
+
+import (
+	"fmt"
+)
+
+// LightSensor is a photoresistor (LDR) wired as a voltage divider
+// to an analog pin.
+type LightSensor struct {
+	board *Board
+	pin   byte // Normal (not A0 style) pin number.
+	max   int  // Full scale raw reading.
+
+	// Calibration maps raw readings to lux. It is left empty by
+	// default, since every LDR and divider resistor differs.
+	Calibration Curve
+
+	// Set if the LDR is on the low side of the divider, where
+	// more light gives a lower reading.
+	Inverted bool
+}
+
+// NewLightSensor returns a LightSensor on the given analog pin, with
+// analog reporting turned on.
+func NewLightSensor(b *Board, pin byte) (l *LightSensor, err error) {
+	bits, err := b.AnalogResolution(pin)
+	if err != nil {
+		return nil, err
+	}
+	if err = b.ensurePinMode(pin, ANALOG); err != nil {
+		return nil, err
+	}
+	if err = b.SetPinReporting(pin, true); err != nil {
+		return nil, err
+	}
+
+	l = &LightSensor{
+		board: b,
+		pin:   pin,
+		max:   analogMax(bits),
+	}
+	return
+}
+
+// Raw returns the last analog reading.
+func (l *LightSensor) Raw() (v int, err error) {
+	v, err = l.board.AnalogRead(l.pin)
+	if err == nil && l.Inverted {
+		v = l.max - v
+	}
+	return
+}
+
+// Brightness returns the light level as a percentage (0-100) of the
+// full scale reading.
+func (l *LightSensor) Brightness() (pct float64, err error) {
+	v, err := l.Raw()
+	if err != nil {
+		return 0, err
+	}
+	return 100 * float64(v) / float64(l.max), nil
+}
+
+// Lux returns the approximate illuminance using the Calibration curve.
+func (l *LightSensor) Lux() (lux float64, err error) {
+	if len(l.Calibration) == 0 {
+		return 0, fmt.Errorf("Light sensor on pin %d is not calibrated", l.pin)
+	}
+	v, err := l.Raw()
+	if err != nil {
+		return 0, err
+	}
+	return l.Calibration.Map(v)
+}
@@ -0,0 +1,46 @@
+package gadget
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLightSensor(t *testing.T) {
+	var out bytes.Buffer
+	b := newTestBoard(t, &out, map[byte][]Capability{14: {{ANALOG, 10}}}, map[byte][]Capability{2: {{INPUT, 1}}})
+
+	if _, err := NewLightSensor(b, 2); err == nil {
+		t.Fatalf("A pin without ANALOG mode should be refused")
+	}
+	out.Reset()
+	l, err := NewLightSensor(b, 14)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{reportAnalog, 1}; !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("NewLightSensor sent % X, want % X", out.Bytes(), want)
+	}
+
+	// A0 reports 767.
+	b.handleAnalogMessage(message{data: []byte{analogMessage, 0x7F, 0x05}, at: time.Now()})
+	if v, err := l.Raw(); err != nil || v != 767 {
+		t.Fatalf("Raw = %d, %v, want 767", v, err)
+	}
+	if pct, _ := l.Brightness(); math.Abs(pct-100*767.0/1023) > 1e-9 {
+		t.Fatalf("Brightness = %f", pct)
+	}
+	l.Inverted = true
+	if v, _ := l.Raw(); v != 256 {
+		t.Fatalf("Inverted Raw = %d, want 256", v)
+	}
+
+	if _, err = l.Lux(); err == nil {
+		t.Fatalf("Lux should fail without a calibration")
+	}
+	l.Calibration = Curve{{0, 0}, {512, 1000}}
+	if lux, err := l.Lux(); err != nil || math.Abs(lux-500) > 1e-9 {
+		t.Fatalf("Lux = %f, %v, want 500", lux, err)
+	}
+}
@@ -0,0 +1,191 @@
+package gadget
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// LEDStep is one fade of an LED pattern.
+type LEDStep struct {
+	Level    float64       // Brightness faded to, 0-1.
+	Duration time.Duration // 0 jumps straight to Level.
+	Ease     Easing        // Linear if nil.
+}
+
+// LED is an LED on a PWM pin. Its brightness can be faded or animated
+// with a pattern in the background, stepped by the board's animation
+// ticker along with its other animations.
+type LED struct {
+	board *Board
+	pin   byte
+
+	// Brightness is raised to Gamma before being written, so fades
+	// look even to the eye. Defaults to 2.2; 1 writes it linearly.
+	Gamma float64
+
+	m          sync.Mutex
+	brightness float64
+
+	anim *Animation // The last animation started.
+	am   sync.Mutex
+}
+
+// NewLED puts the pin in PWM mode and turns the LED off.
+func NewLED(b *Board, pin byte) (l *LED, err error) {
+	if err = b.ensurePinMode(pin, PWM); err != nil {
+		return nil, err
+	}
+
+	l = &LED{board: b, pin: pin, Gamma: 2.2}
+	if err = l.set(0); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// SetBrightness stops any animation and sets the brightness, 0-1.
+func (l *LED) SetBrightness(v float64) error {
+	if math.IsNaN(v) || v < 0 || v > 1 {
+		return fmt.Errorf("Brightness must be 0-1, got %g", v)
+	}
+	l.Stop()
+	return l.set(v)
+}
+
+// Brightness returns the current brightness, 0-1.
+func (l *LED) Brightness() float64 {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return l.brightness
+}
+
+// On stops any animation and turns the LED fully on.
+func (l *LED) On() error {
+	return l.SetBrightness(1)
+}
+
+// Off stops any animation and turns the LED off.
+func (l *LED) Off() error {
+	return l.SetBrightness(0)
+}
+
+// FadeTo fades to level over d, following ease, or Linear if nil.
+func (l *LED) FadeTo(level float64, d time.Duration, ease Easing) *Animation {
+	return l.Pattern([]LEDStep{{Level: level, Duration: d, Ease: ease}}, 1)
+}
+
+// FadeIn fades the LED fully on over d.
+func (l *LED) FadeIn(d time.Duration) *Animation {
+	return l.FadeTo(1, d, nil)
+}
+
+// FadeOut fades the LED off over d.
+func (l *LED) FadeOut(d time.Duration) *Animation {
+	return l.FadeTo(0, d, nil)
+}
+
+// Breathe fades the LED smoothly on and off, once every period, until
+// stopped.
+func (l *LED) Breathe(period time.Duration) *Animation {
+	return l.Pattern([]LEDStep{
+		{Level: 1, Duration: period / 2, Ease: EaseInOutQuad},
+		{Level: 0, Duration: period / 2, Ease: EaseInOutQuad},
+	}, 0)
+}
+
+// Pulse flashes the LED n times, once every period, with a quick rise
+// and a slow decay like a heartbeat. An n of 0 pulses until stopped.
+func (l *LED) Pulse(period time.Duration, n int) *Animation {
+	return l.Pattern([]LEDStep{
+		{Level: 1, Duration: period / 5, Ease: EaseOutQuad},
+		{Level: 0, Duration: period - period/5, Ease: EaseInQuad},
+	}, n)
+}
+
+// Pattern runs the steps in order, repeat times, or until stopped if
+// repeat is 0. Each step fades from the previous one's level, the first
+// from the current brightness. A running animation is interrupted.
+func (l *LED) Pattern(steps []LEDStep, repeat int) *Animation {
+	l.am.Lock()
+	defer l.am.Unlock()
+
+	if l.anim != nil {
+		l.anim.Stop()
+	}
+	from := l.Brightness()
+	l.anim = l.board.animate(func(elapsed time.Duration) (bool, error) {
+		if len(steps) == 0 {
+			return false, nil
+		}
+		v, more := patternLevel(steps, from, repeat, elapsed)
+		return more, l.set(v)
+	})
+	return l.anim
+}
+
+// Stop stops the running animation, if any, leaving the LED at its
+// current brightness.
+func (l *LED) Stop() {
+	l.am.Lock()
+	defer l.am.Unlock()
+
+	if l.anim != nil {
+		l.anim.Stop()
+		l.anim = nil
+	}
+}
+
+// Writes the brightness v, clamped to 0-1.
+func (l *LED) set(v float64) (err error) {
+	v = math.Max(0, math.Min(1, v))
+
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	gamma := l.Gamma
+	if gamma <= 0 {
+		gamma = 1
+	}
+	if err = l.board.AnalogWrite(l.pin, byte(math.Round(255*math.Pow(v, gamma)))); err != nil {
+		return err
+	}
+	l.brightness = v
+	return
+}
+
+// Returns the level a pattern is at after elapsed, starting from from,
+// and whether it is still running.
+func patternLevel(steps []LEDStep, from float64, repeat int, elapsed time.Duration) (float64, bool) {
+	var cycle time.Duration
+	for _, s := range steps {
+		cycle += s.Duration
+	}
+	last := steps[len(steps)-1].Level
+
+	n, at := 0, elapsed
+	if cycle > 0 {
+		n, at = int(elapsed/cycle), elapsed%cycle
+	}
+	if cycle == 0 || (repeat > 0 && n >= repeat) {
+		return last, false
+	}
+	if n > 0 {
+		from = last
+	}
+
+	for _, s := range steps {
+		if at < s.Duration {
+			ease := s.Ease
+			if ease == nil {
+				ease = Linear
+			}
+			f := ease(at.Seconds() / s.Duration.Seconds())
+			return from + f*(s.Level-from), true
+		}
+		at -= s.Duration
+		from = s.Level
+	}
+	return last, true
+}
@@ -0,0 +1,70 @@
+package gadget
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPatternLevel(t *testing.T) {
+	steps := []LEDStep{
+		{Level: 1, Duration: 100 * time.Millisecond},
+		{Level: 0.5, Duration: 0}, // Jump.
+		{Level: 0, Duration: 100 * time.Millisecond},
+	}
+	for _, tc := range []struct {
+		repeat  int
+		elapsed time.Duration
+		want    float64
+		more    bool
+	}{
+		{1, 0, 0.2, true}, // The first cycle starts from the current brightness.
+		{1, 50 * time.Millisecond, 0.6, true},
+		{1, 150 * time.Millisecond, 0.25, true},
+		{1, 200 * time.Millisecond, 0, false},
+		{2, 250 * time.Millisecond, 0.5, true}, // Later cycles from the last level.
+		{0, time.Hour + 50*time.Millisecond, 0.5, true},
+	} {
+		got, more := patternLevel(steps, 0.2, tc.repeat, tc.elapsed)
+		if math.Abs(got-tc.want) > 1e-9 || more != tc.more {
+			t.Fatalf("patternLevel(%d, %s) = %f, %v, want %f, %v", tc.repeat, tc.elapsed, got, more, tc.want, tc.more)
+		}
+	}
+}
+
+func TestLED(t *testing.T) {
+	b := newTestBoard(t, nil, nil, map[byte][]Capability{3: {{PWM, 8}}})
+
+	l, err := NewLED(b, 3)
+	if err != nil {
+		t.Fatalf("NewLED: %s", err)
+	}
+
+	if err = l.FadeIn(60 * time.Millisecond).Wait(); err != nil {
+		t.Fatalf("FadeIn = %v", err)
+	}
+	if v, _ := b.AnalogRead(3); l.Brightness() != 1 || v != 255 {
+		t.Fatalf("Brightness %f, PWM %d after FadeIn, want 1, 255", l.Brightness(), v)
+	}
+
+	l.Gamma = 1
+	if err = l.SetBrightness(0.5); err != nil {
+		t.Fatalf("SetBrightness: %s", err)
+	}
+	if v, _ := b.AnalogRead(3); v != 128 {
+		t.Fatalf("PWM %d at half brightness, want 128", v)
+	}
+
+	// Setting the brightness stops an animation.
+	breathe := l.Breathe(time.Second)
+	time.Sleep(3 * animationInterval)
+	if err = l.Off(); err != nil {
+		t.Fatalf("Off: %s", err)
+	}
+	if err = breathe.Wait(); err != ErrInterrupted {
+		t.Fatalf("Breathe = %v, want ErrInterrupted", err)
+	}
+	if v, _ := b.AnalogRead(3); v != 0 {
+		t.Fatalf("PWM %d after Off, want 0", v)
+	}
+}
@@ -0,0 +1,252 @@
+package gadget
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MAX7219 registers. Registers 0x01-0x08 are the digits/rows.
+	maxRegDecode    byte = 0x09
+	maxRegIntensity byte = 0x0A
+	maxRegScanLimit byte = 0x0B
+	maxRegShutdown  byte = 0x0C
+	maxRegTest      byte = 0x0F
+)
+
+// Segment patterns for 7-segment displays, bit 7 is the decimal point,
+// then segments A-G.
+var sevenSegFont = map[rune]byte{
+	'0': 0x7E, '1': 0x30, '2': 0x6D, '3': 0x79, '4': 0x33,
+	'5': 0x5B, '6': 0x5F, '7': 0x70, '8': 0x7F, '9': 0x7B,
+	'A': 0x77, 'b': 0x1F, 'C': 0x4E, 'c': 0x0D, 'd': 0x3D,
+	'E': 0x4F, 'F': 0x47, 'H': 0x37, 'h': 0x17, 'L': 0x0E,
+	'n': 0x15, 'o': 0x1D, 'P': 0x67, 'r': 0x05, 'U': 0x3E,
+	'u': 0x1C, '-': 0x01, '_': 0x08, ' ': 0x00,
+}
+
+// MAX7219 drives a chain of MAX7219 LED drivers, wired to 8x8 LED
+// matrices or 8 digit 7-segment displays. Data is clocked out with
+// ShiftOut, so any three digital pins can be used.
+//
+// Device 0 is the one wired to the board. On matrices, it shows the
+// leftmost 8 columns.
+type MAX7219 struct {
+	board           *Board
+	data, clock, cs byte
+	devices         int
+
+	m   sync.Mutex
+	buf [][8]byte // Row registers of each device.
+}
+
+// NewMAX7219 returns a chain of MAX7219s, initialized and cleared at
+// half brightness.
+func NewMAX7219(b *Board, dataPin, clockPin, csPin byte, devices int) (d *MAX7219, err error) {
+	if devices < 1 {
+		return nil, fmt.Errorf("MAX7219 chain needs at least one device, got %d", devices)
+	}
+
+	for _, pin := range []byte{dataPin, clockPin, csPin} {
+		if err = b.ensurePinMode(pin, OUTPUT); err != nil {
+			return nil, err
+		}
+	}
+	if err = b.DigitalWrite(csPin, HIGH); err != nil {
+		return nil, err
+	}
+
+	d = &MAX7219{
+		board:   b,
+		data:    dataPin,
+		clock:   clockPin,
+		cs:      csPin,
+		devices: devices,
+		buf:     make([][8]byte, devices),
+	}
+
+	setup := []struct{ reg, val byte }{
+		{maxRegTest, 0x00},
+		{maxRegDecode, 0x00}, // Raw segments, no BCD decoding.
+		{maxRegScanLimit, 0x07},
+		{maxRegIntensity, 0x07},
+		{maxRegShutdown, 0x01},
+	}
+	for _, i := range setup {
+		if err = d.writeAll(i.reg, i.val); err != nil {
+			return nil, err
+		}
+	}
+	if err = d.Show(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// SetBrightness sets the brightness of every device, 0 (dim) to 15.
+func (d *MAX7219) SetBrightness(level byte) error {
+	if level > 15 {
+		return fmt.Errorf("MAX7219 brightness must be 0-15, got %d", level)
+	}
+	return d.writeAll(maxRegIntensity, level)
+}
+
+// Shutdown blanks (true) or restores (false) every display, keeping
+// its contents.
+func (d *MAX7219) Shutdown(off bool) error {
+	return d.writeAll(maxRegShutdown, boolToByte(!off))
+}
+
+// Clear blanks the buffer. Call Show to update the displays.
+func (d *MAX7219) Clear() {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	for i := range d.buf {
+		d.buf[i] = [8]byte{}
+	}
+}
+
+// SetRow sets a row of a matrix (or digit of a 7-segment display) in
+// the buffer, bit 7 being the leftmost LED. Call Show to update the
+// displays.
+func (d *MAX7219) SetRow(device, row int, bits byte) error {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	if device < 0 || device >= d.devices || row < 0 || row > 7 {
+		return fmt.Errorf("MAX7219 row %d of device %d out of range", row, device)
+	}
+	d.buf[device][row] = bits
+	return nil
+}
+
+// SetPixel sets a pixel in the buffer. x runs across the whole chain,
+// 0 being the leftmost column of device 0. Call Show to update the
+// displays.
+func (d *MAX7219) SetPixel(x, y int, on bool) error {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	if x < 0 || x >= 8*d.devices || y < 0 || y > 7 {
+		return fmt.Errorf("MAX7219 pixel %d,%d out of range", x, y)
+	}
+	bit := byte(0x80) >> uint(x%8)
+	if on {
+		d.buf[x/8][y] |= bit
+	} else {
+		d.buf[x/8][y] &^= bit
+	}
+	return nil
+}
+
+// Show writes the buffer to the displays.
+func (d *MAX7219) Show() (err error) {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	for row := 0; row < 8; row++ {
+		vals := make([]byte, d.devices)
+		for i := range vals {
+			vals[i] = d.buf[i][row]
+		}
+		if err = d.write(byte(row+1), vals); err != nil {
+			return err
+		}
+	}
+	return
+}
+
+// PrintDigits shows s right aligned on a 7-segment display. A '.'
+// lights the decimal point of the character before it. Characters
+// without a 7-segment form are shown blank.
+func (d *MAX7219) PrintDigits(device int, s string) (err error) {
+	var digits []byte
+	for _, r := range s {
+		if r == '.' && len(digits) > 0 {
+			digits[len(digits)-1] |= 0x80
+			continue
+		}
+		seg, ok := sevenSegFont[r]
+		if !ok {
+			seg = sevenSegFont[rune(strings.ToUpper(string(r))[0])]
+		}
+		digits = append(digits, seg)
+	}
+	if len(digits) > 8 {
+		return fmt.Errorf("'%s' does not fit on 8 digits", s)
+	}
+
+	// Digit register 1 is the rightmost digit.
+	for i := 0; i < 8; i++ {
+		var seg byte
+		if j := len(digits) - 1 - i; j >= 0 {
+			seg = digits[j]
+		}
+		if err = d.SetRow(device, i, seg); err != nil {
+			return err
+		}
+	}
+	return d.Show()
+}
+
+// ScrollText scrolls text across the whole chain of matrices, from
+// right to left, moving one column every delay. It returns once the
+// text has scrolled off the left edge.
+func (d *MAX7219) ScrollText(text string, delay time.Duration) (err error) {
+	width := 8 * d.devices
+	cols := renderText(text)
+
+	// Pad both sides so the text enters and leaves a blank display.
+	cols = append(make([]byte, width), append(cols, make([]byte, width)...)...)
+
+	for off := 0; off+width <= len(cols); off++ {
+		d.m.Lock()
+		for x := 0; x < width; x++ {
+			for y := 0; y < 8; y++ {
+				bit := byte(0x80) >> uint(x%8)
+				if cols[off+x]&(1<<uint(y)) != 0 {
+					d.buf[x/8][y] |= bit
+				} else {
+					d.buf[x/8][y] &^= bit
+				}
+			}
+		}
+		d.m.Unlock()
+
+		if err = d.Show(); err != nil {
+			return err
+		}
+		time.Sleep(delay)
+	}
+	return
+}
+
+// Writes the same value to a register of every device.
+func (d *MAX7219) writeAll(reg, val byte) error {
+	vals := make([]byte, d.devices)
+	for i := range vals {
+		vals[i] = val
+	}
+	return d.write(reg, vals)
+}
+
+// Writes vals[i] to register reg of device i. The last device's frame
+// is shifted out first, so every frame lands in its device before CS
+// latches them.
+func (d *MAX7219) write(reg byte, vals []byte) (err error) {
+	if err = d.board.DigitalWrite(d.cs, LOW); err != nil {
+		return err
+	}
+	for i := len(vals) - 1; i >= 0; i-- {
+		if err = d.board.ShiftOut(d.data, d.clock, MSBFIRST, reg); err != nil {
+			return err
+		}
+		if err = d.board.ShiftOut(d.data, d.clock, MSBFIRST, vals[i]); err != nil {
+			return err
+		}
+	}
+	return d.board.DigitalWrite(d.cs, HIGH)
+}
@@ -0,0 +1,89 @@
+package gadget
+
+import (
+	"bytes"
+	"testing"
+)
+
+// A chain of shift registers on port 0, clocked by DigitalWrites.
+// Each frame holds the bytes shifted in before CS rose.
+type fakeShiftChain struct {
+	data, clock, cs byte
+	levels          byte
+	bits            []byte
+	frames          [][]byte
+}
+
+func (f *fakeShiftChain) Write(p []byte) (int, error) {
+	for i := 0; i+2 < len(p); i += 3 {
+		if p[i] != digitalMessage {
+			continue
+		}
+		prev := f.levels
+		f.levels = p[i+1] | p[i+2]<<7
+		rose := func(pin byte) bool { return prev>>pin&1 == 0 && f.levels>>pin&1 == 1 }
+		switch {
+		case rose(f.clock):
+			f.bits = append(f.bits, f.levels>>f.data&1)
+		case rose(f.cs) && len(f.bits) > 0:
+			frame := make([]byte, len(f.bits)/8)
+			for j, bit := range f.bits {
+				frame[j/8] |= bit << (7 - j%8)
+			}
+			f.frames = append(f.frames, frame)
+			f.bits = nil
+		}
+	}
+	return len(p), nil
+}
+
+func TestMAX7219(t *testing.T) {
+	f := &fakeShiftChain{data: 2, clock: 3, cs: 4}
+	caps := []Capability{{OUTPUT, 1}}
+	b := newTestBoard(t, f, nil, map[byte][]Capability{2: caps, 3: caps, 4: caps})
+	d, err := NewMAX7219(b, 2, 3, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Setup frames, then the 8 blank rows. Every frame holds a
+	// register and value for each device.
+	want := [][]byte{
+		{maxRegTest, 0, maxRegTest, 0},
+		{maxRegDecode, 0, maxRegDecode, 0},
+		{maxRegScanLimit, 7, maxRegScanLimit, 7},
+		{maxRegIntensity, 7, maxRegIntensity, 7},
+		{maxRegShutdown, 1, maxRegShutdown, 1},
+	}
+	if len(f.frames) != len(want)+8 {
+		t.Fatalf("Sent %d frames, want %d", len(f.frames), len(want)+8)
+	}
+	for i := range want {
+		if !bytes.Equal(f.frames[i], want[i]) {
+			t.Fatalf("Frame %d = % X, want % X", i, f.frames[i], want[i])
+		}
+	}
+
+	// Device 1's frame is shifted out first, so it comes first.
+	f.frames = nil
+	d.SetPixel(9, 2, true)
+	if err = d.Show(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{3, 0x40, 3, 0x00}; !bytes.Equal(f.frames[2], want) {
+		t.Fatalf("Row 3 frame = % X, want % X", f.frames[2], want)
+	}
+
+	// Digit 1 is the rightmost, the '.' lights the point of the '1'.
+	f.frames = nil
+	if err = d.PrintDigits(0, "1.5"); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(f.frames[0], []byte{1, 0x00, 1, 0x5B}) || !bytes.Equal(f.frames[1], []byte{2, 0x00, 2, 0xB0}) {
+		t.Fatalf("PrintDigits sent % X", f.frames[:2])
+	}
+
+	if err = d.SetBrightness(16); err == nil {
+		t.Fatalf("Expected an error for brightness 16")
+	}
+}
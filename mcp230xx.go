@@ -0,0 +1,299 @@
+package gadget
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MCP230xx register indexes, as laid out on the MCP23008. The MCP23017
+// (with IOCON.BANK = 0) interleaves its A and B ports, so its register
+// address is index*2 + port.
+const (
+	mcpIODIR byte = iota
+	mcpIPOL
+	mcpGPINTEN
+	mcpDEFVAL
+	mcpINTCON
+	mcpIOCON
+	mcpGPPU
+	mcpINTF
+	mcpINTCAP
+	mcpGPIO
+	mcpOLAT
+
+	// Default I2C address, with A0-A2 tied low.
+	MCP230xxAddress byte = 0x20
+)
+
+// ExpanderEvent is an input pin of a GPIO expander changing state.
+type ExpanderEvent struct {
+	Pin   int
+	State byte
+}
+
+// MCP230xx is a Microchip MCP23017 (16 GPIOs) or MCP23008 (8 GPIOs)
+// I2C GPIO expander. Pins are numbered 0-15, with GPA0 being pin 0
+// and GPB0 pin 8.
+type MCP230xx struct {
+	board *Board
+	addr  byte
+	ports int // 1 on the MCP23008, 2 on the MCP23017.
+
+	// How often the interrupt flags are polled while started.
+	Interval time.Duration
+
+	m      sync.Mutex
+	iodir  []byte // Cached direction registers, 1 = input.
+	olat   []byte // Cached output latches.
+	gpio   []byte // Last polled input state.
+	events chan ExpanderEvent
+	stop   func()
+}
+
+// NewMCP23017 returns the MCP23017 at the given address. I2CConfig
+// must already have been called.
+func NewMCP23017(b *Board, addr byte) (*MCP230xx, error) {
+	return newMCP230xx(b, addr, 2)
+}
+
+// NewMCP23008 returns the MCP23008 at the given address. I2CConfig
+// must already have been called.
+func NewMCP23008(b *Board, addr byte) (*MCP230xx, error) {
+	return newMCP230xx(b, addr, 1)
+}
+
+func newMCP230xx(b *Board, addr byte, ports int) (e *MCP230xx, err error) {
+	e = &MCP230xx{
+		board:    b,
+		addr:     addr,
+		ports:    ports,
+		Interval: defaultSampleDelay,
+		iodir:    make([]byte, ports),
+		olat:     make([]byte, ports),
+		gpio:     make([]byte, ports),
+		events:   make(chan ExpanderEvent, 16),
+	}
+
+	// Read back the current configuration so the caches match the chip.
+	if e.iodir, err = e.readReg(mcpIODIR); err != nil {
+		return nil, err
+	}
+	if e.olat, err = e.readReg(mcpOLAT); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Requires reports that the expander needs I2C, see AddDriver.
+func (e *MCP230xx) Requires() Requirements {
+	return e.board.i2cRequirements()
+}
+
+// Pins returns the number of GPIOs.
+func (e *MCP230xx) Pins() int {
+	return 8 * e.ports
+}
+
+// SetPinMode sets a pin to INPUT or OUTPUT mode.
+func (e *MCP230xx) SetPinMode(pin int, mode byte) (err error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	port, bit, err := e.locate(pin)
+	if err != nil {
+		return err
+	}
+
+	switch mode {
+	case INPUT:
+		e.iodir[port] |= bit
+	case OUTPUT:
+		e.iodir[port] &^= bit
+	default:
+		return fmt.Errorf("Pin mode %s not supported by MCP230xx pin %d", PinModeString[mode], pin)
+	}
+	return e.writeReg(mcpIODIR, port, e.iodir[port])
+}
+
+// SetPullUp toggles the internal 100k pull-up of an input pin.
+func (e *MCP230xx) SetPullUp(pin int, on bool) (err error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	port, bit, err := e.locate(pin)
+	if err != nil {
+		return err
+	}
+
+	gppu, err := e.readReg(mcpGPPU)
+	if err != nil {
+		return err
+	}
+	if on {
+		gppu[port] |= bit
+	} else {
+		gppu[port] &^= bit
+	}
+	return e.writeReg(mcpGPPU, port, gppu[port])
+}
+
+// DigitalWrite sets the state of an output pin.
+func (e *MCP230xx) DigitalWrite(pin int, s byte) (err error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	port, bit, err := e.locate(pin)
+	if err != nil {
+		return err
+	}
+	if e.iodir[port]&bit != 0 {
+		return fmt.Errorf("MCP230xx pin %d not in OUTPUT mode", pin)
+	}
+
+	if s != LOW {
+		e.olat[port] |= bit
+	} else {
+		e.olat[port] &^= bit
+	}
+	return e.writeReg(mcpOLAT, port, e.olat[port])
+}
+
+// DigitalRead returns the current state of a pin, read from the chip.
+func (e *MCP230xx) DigitalRead(pin int) (s byte, err error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	port, bit, err := e.locate(pin)
+	if err != nil {
+		return 0, err
+	}
+	gpio, err := e.readReg(mcpGPIO)
+	if err != nil {
+		return 0, err
+	}
+	return boolToByte(gpio[port]&bit != 0), nil
+}
+
+// Start enables interrupt-on-change for every input pin and polls the
+// interrupt flags in the background, sending an event on Events for
+// each input that changed.
+//
+// The INTA/INTB pins are also driven, for boards that want to watch
+// them directly.
+func (e *MCP230xx) Start() (err error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	if e.stop != nil {
+		return fmt.Errorf("MCP230xx at 0x%02X already started", e.addr)
+	}
+
+	for port := 0; port < e.ports; port++ {
+		// Compare against the previous value, not DEFVAL.
+		if err = e.writeReg(mcpINTCON, port, 0); err != nil {
+			return err
+		}
+		if err = e.writeReg(mcpGPINTEN, port, e.iodir[port]); err != nil {
+			return err
+		}
+	}
+	if e.gpio, err = e.readReg(mcpGPIO); err != nil {
+		return err
+	}
+
+	e.stop = poll(e.Interval, e.checkInterrupts)
+	return
+}
+
+// Halt stops polling and disables interrupt-on-change.
+func (e *MCP230xx) Halt() (err error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	if e.stop == nil {
+		return
+	}
+	e.stop()
+	e.stop = nil
+
+	for port := 0; port < e.ports; port++ {
+		if err = e.writeReg(mcpGPINTEN, port, 0); err != nil {
+			return err
+		}
+	}
+	return
+}
+
+// Events returns the channel input changes are delivered on. Events
+// are dropped if more than 16 are waiting to be read.
+func (e *MCP230xx) Events() <-chan ExpanderEvent {
+	return e.events
+}
+
+// Reads the interrupt flags, and if any are set reads the inputs
+// (clearing the interrupt) and reports the changed pins.
+func (e *MCP230xx) checkInterrupts() {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	intf, err := e.readReg(mcpINTF)
+	if err != nil {
+		return
+	}
+	pending := false
+	for _, f := range intf {
+		pending = pending || f != 0
+	}
+	if !pending {
+		return
+	}
+
+	gpio, err := e.readReg(mcpGPIO)
+	if err != nil {
+		return
+	}
+	for port := range gpio {
+		changed := (gpio[port] ^ e.gpio[port]) & e.iodir[port]
+		for i := uint(0); i < 8; i++ {
+			if changed&(1<<i) == 0 {
+				continue
+			}
+			ev := ExpanderEvent{
+				Pin:   8*port + int(i),
+				State: (gpio[port] >> i) & 0x01,
+			}
+			select {
+			case e.events <- ev:
+			default:
+			}
+			e.board.bus.Publish(Event{Topic: TopicExpander, Data: ev})
+		}
+	}
+	e.gpio = gpio
+}
+
+// Returns the port and bit mask of a pin.
+func (e *MCP230xx) locate(pin int) (port int, bit byte, err error) {
+	if pin < 0 || pin >= 8*e.ports {
+		return 0, 0, fmt.Errorf("Invalid MCP230xx pin: %d", pin)
+	}
+	return pin / 8, 1 << uint(pin%8), nil
+}
+
+// Returns the address of register reg for the given port.
+func (e *MCP230xx) regAddr(reg byte, port int) byte {
+	if e.ports == 1 {
+		return reg
+	}
+	return reg*2 + byte(port)
+}
+
+// Reads register reg of every port.
+func (e *MCP230xx) readReg(reg byte) ([]byte, error) {
+	return e.board.I2CReadRegister(e.addr, e.regAddr(reg, 0), e.ports)
+}
+
+func (e *MCP230xx) writeReg(reg byte, port int, val byte) error {
+	return e.board.I2CWrite(e.addr, []byte{e.regAddr(reg, port), val})
+}
@@ -0,0 +1,93 @@
+package gadget
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestMCP23017(t *testing.T) {
+	b, f := newI2CTestBoard(t, 1)
+	mem := f.chip(MCP230xxAddress)
+	// Every pin powers up an input.
+	mem[0x00], mem[0x01] = 0xFF, 0xFF
+	e, err := NewMCP23017(b, MCP230xxAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Interval = time.Hour
+
+	lastWrite := func() []byte { return f.writes[len(f.writes)-1] }
+
+	// GPB1 is IODIRB bit 1, its latch OLATB.
+	if err = e.SetPinMode(9, OUTPUT); err != nil || !bytes.Equal(lastWrite(), []byte{0x01, 0xFD}) {
+		t.Fatalf("SetPinMode(9, OUTPUT) wrote % X, %v", lastWrite(), err)
+	}
+	if err = e.DigitalWrite(9, HIGH); err != nil || !bytes.Equal(lastWrite(), []byte{0x15, 0x02}) {
+		t.Fatalf("DigitalWrite(9, HIGH) wrote % X, %v", lastWrite(), err)
+	}
+	if err = e.DigitalWrite(0, HIGH); err == nil {
+		t.Fatalf("Writing an input should fail")
+	}
+	if err = e.SetPinMode(16, INPUT); err == nil {
+		t.Fatalf("Pin 16 should be refused")
+	}
+	mem[0x0C] = 0x01
+	if err = e.SetPullUp(3, true); err != nil || !bytes.Equal(lastWrite(), []byte{0x0C, 0x09}) {
+		t.Fatalf("SetPullUp(3) wrote % X, %v", lastWrite(), err)
+	}
+	mem[0x12] = 0x10
+	if s, err := e.DigitalRead(4); err != nil || s != HIGH {
+		t.Fatalf("DigitalRead(4) = %d, %v, want HIGH", s, err)
+	}
+
+	// Interrupts on change are enabled for the inputs only.
+	f.writes = nil
+	if err = e.Start(); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]byte{{0x08, 0}, {0x04, 0xFF}, {0x09, 0}, {0x05, 0xFD}}
+	for i := range want {
+		if !bytes.Equal(f.writes[i], want[i]) {
+			t.Fatalf("Start write %d = % X, want % X", i, f.writes[i], want[i])
+		}
+	}
+
+	// GPA0 rises; GPB1's change is its own output.
+	mem[0x0E], mem[0x12], mem[0x13] = 0x01, 0x11, 0x02
+	e.checkInterrupts()
+	select {
+	case ev := <-e.Events():
+		if ev != (ExpanderEvent{Pin: 0, State: 1}) {
+			t.Fatalf("Unexpected event %+v", ev)
+		}
+	default:
+		t.Fatalf("Expected an event for pin 0")
+	}
+	if len(e.Events()) != 0 {
+		t.Fatalf("Only pin 0 should have changed")
+	}
+
+	f.writes = nil
+	if err = e.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.writes) != 2 || !bytes.Equal(f.writes[0], []byte{0x04, 0}) || !bytes.Equal(f.writes[1], []byte{0x05, 0}) {
+		t.Fatalf("Halt wrote % X, want interrupts disabled", f.writes)
+	}
+}
+
+func TestMCP23008Registers(t *testing.T) {
+	b, f := newI2CTestBoard(t, 1)
+	e, err := NewMCP23008(b, 0x21)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Pins() != 8 || e.SetPinMode(8, OUTPUT) == nil {
+		t.Fatalf("The MCP23008 has 8 pins")
+	}
+	// Registers aren't interleaved, OLAT is 0x0A.
+	if err = e.DigitalWrite(7, HIGH); err != nil || !bytes.Equal(f.writes[len(f.writes)-1], []byte{0x0A, 0x80}) {
+		t.Fatalf("DigitalWrite(7, HIGH) wrote % X, %v", f.writes, err)
+	}
+}
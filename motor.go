@@ -0,0 +1,157 @@
+package gadget
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// Motor is a DC motor on one channel of an H-bridge such as the L298N
+// or L293D, using two direction pins (IN1, IN2) and a PWM enable pin.
+type Motor struct {
+	board    *Board
+	in1, in2 byte
+	enable   byte
+
+	// Swaps forward and backward, for motors wired the other way round.
+	Inverted bool
+
+	m     sync.Mutex
+	speed float64 // Last speed set, -1 to 1.
+}
+
+// NewMotor returns a stopped (coasting) Motor. The enable pin must
+// support PWM.
+func NewMotor(b *Board, in1, in2, enable byte) (m *Motor, err error) {
+	for _, pin := range []byte{in1, in2} {
+		if err = b.ensurePinMode(pin, OUTPUT); err != nil {
+			return nil, err
+		}
+	}
+	if err = b.ensurePinMode(enable, PWM); err != nil {
+		return nil, err
+	}
+
+	m = &Motor{board: b, in1: in1, in2: in2, enable: enable}
+	if err = m.Coast(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Forward runs the motor forward at speed (0-1).
+func (m *Motor) Forward(speed float64) error {
+	return m.SetSpeed(math.Abs(speed))
+}
+
+// Backward runs the motor backward at speed (0-1).
+func (m *Motor) Backward(speed float64) error {
+	return m.SetSpeed(-math.Abs(speed))
+}
+
+// SetSpeed runs the motor at speed, from -1 (full backward) to 1 (full
+// forward). A speed of 0 coasts.
+func (m *Motor) SetSpeed(speed float64) (err error) {
+	if speed < -1 || speed > 1 || math.IsNaN(speed) {
+		return fmt.Errorf("Motor speed must be -1 to 1, got %v", speed)
+	}
+	if speed == 0 {
+		return m.Coast()
+	}
+
+	forward := (speed > 0) != m.Inverted
+	if err = m.setDirection(boolToByte(forward), boolToByte(!forward)); err != nil {
+		return err
+	}
+	if err = m.board.AnalogWrite(m.enable, byte(math.Round(math.Abs(speed)*255))); err != nil {
+		return err
+	}
+
+	m.m.Lock()
+	m.speed = speed
+	m.m.Unlock()
+	return
+}
+
+// Speed returns the last speed set, -1 to 1.
+func (m *Motor) Speed() float64 {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	return m.speed
+}
+
+// Brake stops the motor quickly by shorting its terminals.
+func (m *Motor) Brake() (err error) {
+	if err = m.setDirection(HIGH, HIGH); err != nil {
+		return err
+	}
+	return m.stopped(255)
+}
+
+// Coast cuts power and lets the motor spin down freely.
+func (m *Motor) Coast() (err error) {
+	if err = m.setDirection(LOW, LOW); err != nil {
+		return err
+	}
+	return m.stopped(0)
+}
+
+func (m *Motor) setDirection(in1, in2 byte) (err error) {
+	if err = m.board.DigitalWrite(m.in1, in1); err != nil {
+		return err
+	}
+	return m.board.DigitalWrite(m.in2, in2)
+}
+
+// Sets the enable pin and records the motor as stopped.
+func (m *Motor) stopped(enable byte) (err error) {
+	if err = m.board.AnalogWrite(m.enable, enable); err != nil {
+		return err
+	}
+
+	m.m.Lock()
+	m.speed = 0
+	m.m.Unlock()
+	return
+}
+
+// DualMotor is the pair of motors on a typical two channel robot
+// shield or H-bridge board.
+type DualMotor struct {
+	Left, Right *Motor
+}
+
+// Drive sets the speed of each motor, -1 to 1.
+func (d *DualMotor) Drive(left, right float64) (err error) {
+	if err = d.Left.SetSpeed(left); err != nil {
+		return err
+	}
+	return d.Right.SetSpeed(right)
+}
+
+// Forward runs both motors forward at speed (0-1).
+func (d *DualMotor) Forward(speed float64) error {
+	return d.Drive(math.Abs(speed), math.Abs(speed))
+}
+
+// Backward runs both motors backward at speed (0-1).
+func (d *DualMotor) Backward(speed float64) error {
+	return d.Drive(-math.Abs(speed), -math.Abs(speed))
+}
+
+// Brake brakes both motors.
+func (d *DualMotor) Brake() (err error) {
+	if err = d.Left.Brake(); err != nil {
+		return err
+	}
+	return d.Right.Brake()
+}
+
+// Coast lets both motors spin down freely.
+func (d *DualMotor) Coast() (err error) {
+	if err = d.Left.Coast(); err != nil {
+		return err
+	}
+	return d.Right.Coast()
+}
@@ -0,0 +1,40 @@
+package gadget
+
+import "testing"
+
+func TestMotor(t *testing.T) {
+	out := []Capability{{OUTPUT, 1}}
+	b := newTestBoard(t, nil, nil, map[byte][]Capability{7: out, 8: out, 9: {{OUTPUT, 1}, {PWM, 8}}})
+	m, err := NewMotor(b, 7, 8, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := func(name string, in1, in2 byte, enable int, speed float64) {
+		t.Helper()
+		if b.pins[7].digitalVal != in1 || b.pins[8].digitalVal != in2 || b.pins[9].analogVal != enable {
+			t.Fatalf("%s: IN1 %d, IN2 %d, enable %d, want %d, %d, %d", name,
+				b.pins[7].digitalVal, b.pins[8].digitalVal, b.pins[9].analogVal, in1, in2, enable)
+		}
+		if m.Speed() != speed {
+			t.Fatalf("%s: Speed = %v, want %v", name, m.Speed(), speed)
+		}
+	}
+	expect("NewMotor", LOW, LOW, 0, 0)
+
+	m.Forward(0.5)
+	expect("Forward", HIGH, LOW, 128, 0.5)
+	m.Backward(-1)
+	expect("Backward", LOW, HIGH, 255, -1)
+	m.Inverted = true
+	m.SetSpeed(0.2)
+	expect("Inverted", LOW, HIGH, 51, 0.2)
+	m.Brake()
+	expect("Brake", HIGH, HIGH, 255, 0)
+	m.SetSpeed(0)
+	expect("SetSpeed(0)", LOW, LOW, 0, 0)
+
+	if err = m.SetSpeed(1.5); err == nil {
+		t.Fatalf("Expected an error for speed 1.5")
+	}
+}
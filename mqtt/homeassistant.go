@@ -0,0 +1,189 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// Home Assistant entity components.
+const (
+	ComponentSensor       = "sensor"
+	ComponentBinarySensor = "binary_sensor"
+	ComponentSwitch       = "switch"
+	ComponentLight        = "light"
+)
+
+// DefaultDiscoveryPrefix is Home Assistant's default discovery prefix.
+const DefaultDiscoveryPrefix = "homeassistant"
+
+// Discovery announces the bridge's pins to Home Assistant using MQTT
+// discovery, so they appear as entities without any configuration.
+type Discovery struct {
+	// The discovery prefix. Defaults to DefaultDiscoveryPrefix.
+	Prefix string
+
+	// The entities to announce. When empty, every analog pin is
+	// announced as a sensor and every digital pin as a binary sensor.
+	Entities []Entity
+}
+
+// Entity describes a pin as a Home Assistant entity. Entities reading a
+// pin must have it listed in the bridge's Analog or Digital pins for
+// its state to be published.
+type Entity struct {
+	Name      string
+	Component string
+	Kind      string // KindAnalog, KindDigital or KindPWM.
+	Pin       byte
+
+	DeviceClass string // e.g. "temperature", "door" or "outlet".
+	Unit        string // Unit of measurement, e.g. "°C".
+
+	// Converts the raw reading, e.g. "{{ (value | int) * 0.488 }}".
+	// With JSON payloads the reading is value_json.value, which is
+	// used when no template is given.
+	ValueTemplate string
+
+	// Treat LOW as on, for buttons and switches wired to ground.
+	Invert bool
+}
+
+// Sensor returns an entity for an analog input. Class, unit and tmpl
+// may be empty.
+func Sensor(name string, analogPin byte, class, unit, tmpl string) Entity {
+	return Entity{
+		Name:          name,
+		Component:     ComponentSensor,
+		Kind:          KindAnalog,
+		Pin:           analogPin,
+		DeviceClass:   class,
+		Unit:          unit,
+		ValueTemplate: tmpl,
+	}
+}
+
+// TemperatureSensor returns an entity for an analog temperature sensor
+// such as a TMP36. Tmpl converts the raw reading to degrees Celsius.
+func TemperatureSensor(name string, analogPin byte, tmpl string) Entity {
+	return Sensor(name, analogPin, "temperature", "°C", tmpl)
+}
+
+// Button returns a binary sensor entity for a push button wired to
+// ground with a pull-up.
+func Button(name string, pin byte) Entity {
+	return Entity{Name: name, Component: ComponentBinarySensor, Kind: KindDigital, Pin: pin, Invert: true}
+}
+
+// Relay returns a switch entity for a relay or other digital output.
+func Relay(name string, pin byte) Entity {
+	return Entity{Name: name, Component: ComponentSwitch, Kind: KindDigital, Pin: pin, DeviceClass: "outlet"}
+}
+
+// Dimmer returns a light entity with brightness for a PWM output.
+func Dimmer(name string, pin byte) Entity {
+	return Entity{Name: name, Component: ComponentLight, Kind: KindPWM, Pin: pin}
+}
+
+// A discovery config topic and payload.
+type discoveryMessage struct {
+	topic   string
+	payload []byte
+}
+
+// Builds the discovery messages for the configured entities.
+func (br *Bridge) discoveryMessages(firmware, version string) (msgs []discoveryMessage) {
+	d := br.cfg.Discovery
+	prefix := d.Prefix
+	if prefix == "" {
+		prefix = DefaultDiscoveryPrefix
+	}
+
+	entities := d.Entities
+	if len(entities) == 0 {
+		for _, a := range br.cfg.Analog {
+			entities = append(entities, Sensor("A"+strconv.Itoa(int(a)), a, "", "", ""))
+		}
+		for _, p := range br.cfg.Digital {
+			entities = append(entities, Entity{
+				Name:      "Pin " + strconv.Itoa(int(p)),
+				Component: ComponentBinarySensor,
+				Kind:      KindDigital,
+				Pin:       p,
+			})
+		}
+	}
+
+	device := map[string]interface{}{
+		"identifiers":  []string{"gogogadget_" + br.cfg.Name},
+		"name":         br.cfg.Name,
+		"manufacturer": "Arduino",
+		"model":        firmware,
+		"sw_version":   version,
+	}
+
+	for _, e := range entities {
+		pin := strconv.Itoa(int(e.Pin))
+		id := br.cfg.Name + "_" + e.Kind + "_" + pin
+
+		c := map[string]interface{}{
+			"name":      e.Name,
+			"unique_id": id,
+			"device":    device,
+		}
+		if e.DeviceClass != "" {
+			c["device_class"] = e.DeviceClass
+		}
+		if e.Unit != "" {
+			c["unit_of_measurement"] = e.Unit
+		}
+		if e.ValueTemplate != "" {
+			c["value_template"] = e.ValueTemplate
+		} else if br.cfg.JSON && e.Component != ComponentLight {
+			c["value_template"] = "{{ value_json.value }}"
+		}
+
+		state := expandTopic(br.cfg.StateTopic, br.cfg.Name, e.Kind, pin)
+		command := expandTopic(br.cfg.CommandTopic, br.cfg.Name, e.Kind, pin)
+		on, off := "1", "0"
+		if e.Invert {
+			on, off = off, on
+		}
+
+		switch e.Component {
+		case ComponentSensor:
+			c["state_topic"] = state
+			c["state_class"] = "measurement"
+		case ComponentBinarySensor:
+			c["state_topic"] = state
+			c["payload_on"], c["payload_off"] = on, off
+		case ComponentSwitch:
+			c["state_topic"] = state
+			c["command_topic"] = command
+			c["payload_on"], c["payload_off"] = on, off
+			c["state_on"], c["state_off"] = on, off
+		case ComponentLight:
+			// PWM pins are write only, so the light is optimistic.
+			c["command_topic"] = command
+			c["brightness_command_topic"] = command
+			c["payload_on"], c["payload_off"] = "255", "0"
+			c["optimistic"] = true
+		}
+
+		payload, _ := json.Marshal(c)
+		msgs = append(msgs, discoveryMessage{
+			topic:   prefix + "/" + e.Component + "/" + br.cfg.Name + "/" + e.Kind + "_" + pin + "/config",
+			payload: payload,
+		})
+	}
+	return
+}
+
+// Publishes the retained discovery messages.
+func (br *Bridge) publishDiscovery() (err error) {
+	for _, m := range br.discoveryMessages(br.board.Firmware(), br.board.Version()) {
+		if err = br.client.Publish(m.topic, br.cfg.QoS, true, m.payload); err != nil {
+			return err
+		}
+	}
+	return
+}
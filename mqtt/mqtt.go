@@ -0,0 +1,387 @@
+// Package mqtt bridges a gadget.Board to an MQTT broker, publishing pin
+// readings and accepting writes on command topics.
+//
+// The bridge is written against the small Client interface rather than
+// a specific MQTT library. Wrapping the Eclipse Paho client takes a few
+// lines:
+//
+//	type paho struct{ c pahomqtt.Client }
+//
+//	func (p paho) Publish(topic string, qos byte, retain bool, payload []byte) error {
+//		t := p.c.Publish(topic, qos, retain, payload)
+//		t.Wait()
+//		return t.Error()
+//	}
+//
+//	func (p paho) Subscribe(topic string, qos byte, h func(topic string, payload []byte)) error {
+//		t := p.c.Subscribe(topic, qos, func(_ pahomqtt.Client, m pahomqtt.Message) {
+//			h(m.Topic(), m.Payload())
+//		})
+//		t.Wait()
+//		return t.Error()
+//	}
+package mqtt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ZachMassia/GoGoGadget"
+)
+
+// Reading is the payload of a state topic in JSON mode.
+type Reading struct {
+	Board string    `json:"board"`
+	Kind  string    `json:"kind"`
+	Pin   byte      `json:"pin"`
+	Value int       `json:"value"`
+	Time  time.Time `json:"time"`
+}
+
+// Client is the subset of an MQTT client used by the bridge.
+type Client interface {
+	Publish(topic string, qos byte, retain bool, payload []byte) error
+	Subscribe(topic string, qos byte, handler func(topic string, payload []byte)) error
+}
+
+// Pin kinds used in topics.
+const (
+	KindAnalog  = "analog"  // Analog inputs, by A0 style number, state only.
+	KindDigital = "digital" // Digital pins.
+	KindPWM     = "pwm"     // PWM outputs, command only.
+)
+
+const (
+	// Default topic templates. {board}, {kind} and {pin} must each be
+	// a whole topic level.
+	DefaultStateTopic   = "gadget/{board}/{kind}/{pin}"
+	DefaultCommandTopic = "gadget/{board}/{kind}/{pin}/set"
+)
+
+// Config configures a Bridge.
+type Config struct {
+	// The board's name in topics. Defaults to "arduino".
+	Name string
+
+	// Topic templates for readings and commands. Default to
+	// DefaultStateTopic and DefaultCommandTopic.
+	StateTopic, CommandTopic string
+
+	QoS    byte
+	Retain bool // Retain published readings.
+
+	// Publish readings as JSON objects rather than bare numbers:
+	//
+	//	{"board": "arduino", "kind": "digital", "pin": 2, "value": 1,
+	//	 "time": "2024-03-01T12:00:00Z"}
+	//
+	// Commands are accepted in either form regardless.
+	JSON bool
+
+	// Pins to publish. Analog pins are given by their A0 style number.
+	Analog, Digital []byte
+
+	// Called with errors from the background publisher and command
+	// handlers.
+	OnError func(error)
+
+	// Announce the pins to Home Assistant when the bridge starts. Nil
+	// disables discovery.
+	Discovery *Discovery
+}
+
+// Bridge publishes the readings of a Board's pins to MQTT whenever they
+// change, and writes to pins when a command topic receives a message.
+type Bridge struct {
+	board  *gadget.Board
+	client Client
+	cfg    Config
+
+	m         sync.Mutex
+	last      map[string]int // Last value published to each topic.
+	sub       *gadget.Subscription
+	reporting []byte // Pins the bridge turned reporting on for.
+}
+
+// NewBridge returns a Bridge between the board and an MQTT client.
+func NewBridge(b *gadget.Board, c Client, cfg Config) *Bridge {
+	if cfg.Name == "" {
+		cfg.Name = "arduino"
+	}
+	if cfg.StateTopic == "" {
+		cfg.StateTopic = DefaultStateTopic
+	}
+	if cfg.CommandTopic == "" {
+		cfg.CommandTopic = DefaultCommandTopic
+	}
+	return &Bridge{
+		board:  b,
+		client: c,
+		cfg:    cfg,
+		last:   make(map[string]int),
+	}
+}
+
+// Start subscribes to the command topics and starts publishing. The
+// configured analog pins and digital inputs are asked to report, until
+// Halt.
+func (br *Bridge) Start() (err error) {
+	br.m.Lock()
+	defer br.m.Unlock()
+
+	if br.sub != nil {
+		return fmt.Errorf("MQTT bridge already started")
+	}
+	if err = br.startReporting(); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			br.stopReporting()
+		}
+	}()
+
+	sub := expandTopic(br.cfg.CommandTopic, br.cfg.Name, "+", "+")
+	if err = br.client.Subscribe(sub, br.cfg.QoS, br.handleCommand); err != nil {
+		return err
+	}
+	if br.cfg.Discovery != nil {
+		if err = br.publishDiscovery(); err != nil {
+			return err
+		}
+	}
+
+	br.sub = br.board.Events().Subscribe("#", 64)
+	go br.publishLoop(br.sub)
+	return
+}
+
+// Halt stops publishing and the reporting turned on by Start. Commands
+// already subscribed to keep being delivered by the client until it
+// unsubscribes or disconnects.
+func (br *Bridge) Halt() (err error) {
+	br.m.Lock()
+	defer br.m.Unlock()
+
+	if br.sub != nil {
+		br.sub.Unsubscribe()
+		br.sub = nil
+	}
+	return br.stopReporting()
+}
+
+// Turns on reporting of the configured pins that are inputs and aren't
+// reporting yet. Digital pins in other modes are left to commands.
+func (br *Bridge) startReporting() error {
+	var pins []byte
+	mapping := br.board.AnalogMapping()
+	for _, a := range br.cfg.Analog {
+		if int(a) < len(mapping) {
+			pins = append(pins, mapping[a])
+		}
+	}
+	for _, n := range br.cfg.Digital {
+		if mode, _ := br.board.PinMode(n); mode == gadget.INPUT || mode == gadget.PULLUP {
+			pins = append(pins, n)
+		}
+	}
+
+	for _, n := range pins {
+		if p, err := br.board.Pin(n); err != nil || p.Reporting {
+			continue
+		}
+		if err := br.board.SetPinReporting(n, true); err != nil {
+			br.stopReporting()
+			return err
+		}
+		br.reporting = append(br.reporting, n)
+	}
+	return nil
+}
+
+// Turns off the reporting turned on by startReporting.
+func (br *Bridge) stopReporting() (err error) {
+	for _, n := range br.reporting {
+		if e := br.board.SetPinReporting(n, false); err == nil {
+			err = e
+		}
+	}
+	br.reporting = nil
+	return
+}
+
+// Publishes the configured pins, then each again as its events arrive.
+// Everything is published again if the board's pins change, as the
+// analog mapping may have too.
+func (br *Bridge) publishLoop(sub *gadget.Subscription) {
+	br.publishAll()
+
+	mapping := br.board.AnalogMapping()
+	for e := range sub.C {
+		if e.Topic == gadget.TopicHardware {
+			mapping = br.board.AnalogMapping()
+			br.publishAll()
+			continue
+		}
+		if !strings.HasPrefix(e.Topic, "pin/") {
+			continue
+		}
+		for a, pin := range mapping {
+			if pin == e.Pin && bytes.IndexByte(br.cfg.Analog, byte(a)) >= 0 {
+				v, err := br.board.AnalogRead(pin)
+				br.publish(KindAnalog, byte(a), v, err)
+			}
+		}
+		if bytes.IndexByte(br.cfg.Digital, e.Pin) >= 0 {
+			v, err := br.board.DigitalRead(e.Pin)
+			br.publish(KindDigital, e.Pin, int(v), err)
+		}
+	}
+}
+
+// Publishes every configured pin.
+func (br *Bridge) publishAll() {
+	mapping := br.board.AnalogMapping()
+	for _, a := range br.cfg.Analog {
+		if int(a) >= len(mapping) {
+			br.reportError(fmt.Errorf("Invalid analog pin: A%d", a))
+			continue
+		}
+		v, err := br.board.AnalogRead(mapping[a])
+		br.publish(KindAnalog, a, v, err)
+	}
+	for _, pin := range br.cfg.Digital {
+		v, err := br.board.DigitalRead(pin)
+		br.publish(KindDigital, pin, int(v), err)
+	}
+}
+
+func (br *Bridge) publish(kind string, pin byte, v int, err error) {
+	if err != nil {
+		br.reportError(err)
+		return
+	}
+	topic := expandTopic(br.cfg.StateTopic, br.cfg.Name, kind, strconv.Itoa(int(pin)))
+
+	br.m.Lock()
+	last, seen := br.last[topic]
+	br.last[topic] = v
+	br.m.Unlock()
+
+	if seen && last == v {
+		return
+	}
+	payload := []byte(strconv.Itoa(v))
+	if br.cfg.JSON {
+		payload, _ = json.Marshal(Reading{
+			Board: br.cfg.Name,
+			Kind:  kind,
+			Pin:   pin,
+			Value: v,
+			Time:  time.Now(),
+		})
+	}
+	if err = br.client.Publish(topic, br.cfg.QoS, br.cfg.Retain, payload); err != nil {
+		br.reportError(err)
+	}
+}
+
+// Writes a command message to its pin.
+func (br *Bridge) handleCommand(topic string, payload []byte) {
+	f, ok := matchTopic(br.cfg.CommandTopic, topic)
+	if !ok || f["board"] != br.cfg.Name {
+		return
+	}
+	pin, err := strconv.ParseUint(f["pin"], 10, 8)
+	if err != nil {
+		br.reportError(fmt.Errorf("Invalid pin in topic '%s'", topic))
+		return
+	}
+	v, err := parseValue(string(payload))
+	if err != nil {
+		br.reportError(fmt.Errorf("Invalid payload on '%s': %s", topic, err))
+		return
+	}
+
+	switch f["kind"] {
+	case KindDigital:
+		s := gadget.LOW
+		if v != 0 {
+			s = gadget.HIGH
+		}
+		err = br.board.DigitalWrite(byte(pin), s)
+	case KindPWM:
+		err = br.board.AnalogWrite(byte(pin), byte(max(0, min(v, 255))))
+	case KindAnalog:
+		// Analog topics number pins A0 style, which are inputs.
+		err = fmt.Errorf("Analog pins are read only, got a command on '%s'", topic)
+	default:
+		err = fmt.Errorf("Unknown pin kind in topic '%s'", topic)
+	}
+	if err != nil {
+		br.reportError(err)
+	}
+}
+
+func (br *Bridge) reportError(err error) {
+	if br.cfg.OnError != nil {
+		br.cfg.OnError(err)
+	}
+}
+
+// Parses a command payload: a number, on/off, true/false, high/low, or
+// a JSON object with a value field.
+func parseValue(s string) (int, error) {
+	if strings.HasPrefix(strings.TrimSpace(s), "{") {
+		var c struct{ Value *json.RawMessage }
+		if err := json.Unmarshal([]byte(s), &c); err != nil {
+			return 0, err
+		}
+		if c.Value == nil {
+			return 0, fmt.Errorf("missing value")
+		}
+		return parseValue(strings.Trim(string(*c.Value), `"`))
+	}
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "on", "true", "high":
+		return 1, nil
+	case "off", "false", "low":
+		return 0, nil
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(s))
+	if err == nil && v < 0 {
+		return 0, fmt.Errorf("negative value %d", v)
+	}
+	return v, err
+}
+
+// Fills in a topic template.
+func expandTopic(tmpl, board, kind, pin string) string {
+	r := strings.NewReplacer("{board}", board, "{kind}", kind, "{pin}", pin)
+	return r.Replace(tmpl)
+}
+
+// Matches a topic against a template, returning the value of each
+// placeholder level.
+func matchTopic(tmpl, topic string) (fields map[string]string, ok bool) {
+	tl := strings.Split(tmpl, "/")
+	l := strings.Split(topic, "/")
+	if len(tl) != len(l) {
+		return nil, false
+	}
+
+	fields = make(map[string]string)
+	for i, t := range tl {
+		if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") {
+			fields[t[1:len(t)-1]] = l[i]
+		} else if t != l[i] {
+			return nil, false
+		}
+	}
+	return fields, true
+}
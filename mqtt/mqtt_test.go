@@ -0,0 +1,100 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ZachMassia/GoGoGadget"
+)
+
+func TestTopicTemplates(t *testing.T) {
+	topic := expandTopic(DefaultCommandTopic, "bench", KindDigital, "13")
+	if topic != "gadget/bench/digital/13/set" {
+		t.Fatalf("expandTopic = '%s'", topic)
+	}
+
+	f, ok := matchTopic(DefaultCommandTopic, topic)
+	if !ok || f["board"] != "bench" || f["kind"] != KindDigital || f["pin"] != "13" {
+		t.Fatalf("matchTopic = %v, %v", f, ok)
+	}
+
+	if _, ok := matchTopic(DefaultCommandTopic, "gadget/bench/digital/13"); ok {
+		t.Fatalf("State topic should not match the command template")
+	}
+}
+
+func TestAnalogCommandRejected(t *testing.T) {
+	var errs []error
+	br := NewBridge(&gadget.Board{}, nil, Config{OnError: func(err error) { errs = append(errs, err) }})
+	br.handleCommand("gadget/arduino/analog/0/set", []byte("128"))
+	if len(errs) != 1 {
+		t.Fatalf("Expected an error for a command on an analog pin, got %v", errs)
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := map[string]int{
+		"1": 1, " 255 ": 255, "ON": 1, "off": 0, "true": 1, "Low": 0,
+		`{"value": 128}`: 128, `{"value": true}`: 1, `{"value": "off"}`: 0,
+	}
+	for in, want := range tests {
+		if got, err := parseValue(in); err != nil || got != want {
+			t.Errorf("parseValue(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "-1", "maybe", `{"mode": "OUTPUT"}`, `{"value": `} {
+		if _, err := parseValue(in); err == nil {
+			t.Errorf("parseValue(%q) should fail", in)
+		}
+	}
+}
+
+func TestDiscoveryMessages(t *testing.T) {
+	br := NewBridge(nil, nil, Config{
+		Name:    "bench",
+		Analog:  []byte{0},
+		Digital: []byte{2, 7},
+		Discovery: &Discovery{Entities: []Entity{
+			TemperatureSensor("Temp", 0, "{{ value }}"),
+			Button("Door", 2),
+			Relay("Fan", 7),
+		}},
+	})
+
+	msgs := br.discoveryMessages("StandardFirmata", "2.3")
+	if len(msgs) != 3 {
+		t.Fatalf("Expected 3 discovery messages, got %d", len(msgs))
+	}
+
+	want := []string{
+		"homeassistant/sensor/bench/analog_0/config",
+		"homeassistant/binary_sensor/bench/digital_2/config",
+		"homeassistant/switch/bench/digital_7/config",
+	}
+	for i, m := range msgs {
+		if m.topic != want[i] {
+			t.Errorf("Topic %d = '%s', want '%s'", i, m.topic, want[i])
+		}
+	}
+
+	var sw map[string]interface{}
+	if err := json.Unmarshal(msgs[2].payload, &sw); err != nil {
+		t.Fatalf("Invalid payload: %s", err)
+	}
+	if sw["command_topic"] != "gadget/bench/digital/7/set" || sw["state_topic"] != "gadget/bench/digital/7" ||
+		sw["unique_id"] != "bench_digital_7" || sw["payload_on"] != "1" {
+		t.Errorf("Unexpected switch config: %s", msgs[2].payload)
+	}
+
+	var button map[string]interface{}
+	json.Unmarshal(msgs[1].payload, &button)
+	if button["payload_on"] != "0" || button["payload_off"] != "1" {
+		t.Errorf("Button should be inverted: %s", msgs[1].payload)
+	}
+
+	// Without entities every configured pin is announced.
+	br.cfg.Discovery.Entities = nil
+	if n := len(br.discoveryMessages("", "")); n != 3 {
+		t.Errorf("Expected 3 default entities, got %d", n)
+	}
+}
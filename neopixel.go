@@ -0,0 +1,238 @@
+package gadget
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// Default sysex command of the NeoPixel firmware extension, from
+	// the range Firmata reserves for user-defined commands. node-pixel
+	// builds use 0x51.
+	NeoPixelSysex byte = 0x0F
+
+	// Sub commands, following node-pixel's layout.
+	pixelConfig   byte = 0x01
+	pixelShow     byte = 0x02
+	pixelSetPixel byte = 0x03
+	pixelSetStrip byte = 0x04
+)
+
+// Color is a 24-bit RGB color, 0xRRGGBB.
+type Color uint32
+
+// RGB returns the Color with the given components.
+func RGB(r, g, b byte) Color {
+	return Color(r)<<16 | Color(g)<<8 | Color(b)
+}
+
+// Components returns the red, green and blue parts of c.
+func (c Color) Components() (r, g, b byte) {
+	return byte(c >> 16), byte(c >> 8), byte(c)
+}
+
+// Scale returns c with every component scaled by level/255.
+func (c Color) Scale(level byte) Color {
+	r, g, b := c.Components()
+	s := func(v byte) byte { return byte(uint16(v) * uint16(level) / 255) }
+	return RGB(s(r), s(g), s(b))
+}
+
+// Wheel returns a color from a 256 step rainbow, going red to green
+// to blue and back to red.
+func Wheel(pos byte) Color {
+	switch {
+	case pos < 85:
+		return RGB(255-pos*3, pos*3, 0)
+	case pos < 170:
+		pos -= 85
+		return RGB(0, 255-pos*3, pos*3)
+	default:
+		pos -= 170
+		return RGB(pos*3, 0, 255-pos*3)
+	}
+}
+
+// NeoPixel is a strip of WS2812 (NeoPixel) LEDs driven by a NeoPixel
+// Firmata extension. Changes are made to a buffer and sent by Show.
+type NeoPixel struct {
+	board *Board
+	pin   byte
+	cmd   byte
+
+	m          sync.Mutex
+	pixels     []Color
+	sent       []Color // What the strip is showing, after brightness.
+	brightness byte
+}
+
+// NewNeoPixel configures a strip of n pixels on the given pin, using
+// the firmware extension's sysex command cmd (usually NeoPixelSysex).
+func NewNeoPixel(b *Board, pin byte, n int, cmd byte) (p *NeoPixel, err error) {
+	if n < 1 || n > 0x3FFF {
+		return nil, fmt.Errorf("Invalid NeoPixel strip length: %d", n)
+	}
+
+	// The config message has five bits for the pin.
+	if pin > 0x1F {
+		return nil, fmt.Errorf("Pin %d can't drive a NeoPixel strip, only pins 0-31 can", pin)
+	}
+	b.m.RLock()
+	bp, ok := b.pins[pin]
+	supported := ok && bp.supports(OUTPUT)
+	b.m.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("Invalid pin: %d", pin)
+	}
+	if !supported {
+		return nil, fmt.Errorf("Pin %d does not support OUTPUT mode", pin)
+	}
+
+	p = &NeoPixel{
+		board:      b,
+		pin:        pin,
+		cmd:        cmd,
+		pixels:     make([]Color, n),
+		sent:       make([]Color, n),
+		brightness: 255,
+	}
+
+	_, err = b.sendSysex([]byte{cmd, pixelConfig, pin, byte(n) & 0x7F, byte(n>>7) & 0x7F})
+	if err != nil {
+		return nil, err
+	}
+	if err = p.sendStrip(0); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Len returns the number of pixels.
+func (p *NeoPixel) Len() int {
+	return len(p.pixels)
+}
+
+// Set sets pixel i in the buffer.
+func (p *NeoPixel) Set(i int, c Color) error {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if i < 0 || i >= len(p.pixels) {
+		return fmt.Errorf("Invalid pixel: %d", i)
+	}
+	p.pixels[i] = c
+	return nil
+}
+
+// Get returns pixel i from the buffer.
+func (p *NeoPixel) Get(i int) (c Color, err error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if i < 0 || i >= len(p.pixels) {
+		return 0, fmt.Errorf("Invalid pixel: %d", i)
+	}
+	return p.pixels[i], nil
+}
+
+// Fill sets every pixel in the buffer.
+func (p *NeoPixel) Fill(c Color) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	for i := range p.pixels {
+		p.pixels[i] = c
+	}
+}
+
+// SetBrightness scales every pixel by level/255 when shown.
+func (p *NeoPixel) SetBrightness(level byte) {
+	p.m.Lock()
+	p.brightness = level
+	p.m.Unlock()
+}
+
+// Show sends the buffer to the strip. Only pixels that changed since
+// the last Show are sent.
+func (p *NeoPixel) Show() (err error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	// A uniform buffer is sent as a single strip command.
+	uniform := true
+	for _, c := range p.pixels[1:] {
+		uniform = uniform && c == p.pixels[0]
+	}
+	if uniform {
+		return p.sendStrip(p.pixels[0].Scale(p.brightness))
+	}
+
+	for i, c := range p.pixels {
+		c = c.Scale(p.brightness)
+		if c == p.sent[i] {
+			continue
+		}
+		msg := append([]byte{p.cmd, pixelSetPixel, byte(i) & 0x7F, byte(i>>7) & 0x7F}, packColor(c)...)
+		if _, err = p.board.sendSysex(msg); err != nil {
+			return err
+		}
+		p.sent[i] = c
+	}
+	_, err = p.board.sendSysex([]byte{p.cmd, pixelShow})
+	return
+}
+
+// ColorWipe fills the strip with c one pixel at a time, showing each
+// step and waiting delay between them.
+func (p *NeoPixel) ColorWipe(c Color, delay time.Duration) (err error) {
+	for i := range p.pixels {
+		if err = p.Set(i, c); err != nil {
+			return err
+		}
+		if err = p.Show(); err != nil {
+			return err
+		}
+		time.Sleep(delay)
+	}
+	return
+}
+
+// Rainbow cycles a rainbow spread along the strip through the given
+// number of full cycles, waiting delay between frames.
+func (p *NeoPixel) Rainbow(cycles int, delay time.Duration) (err error) {
+	n := len(p.pixels)
+	for step := 0; step < 256*cycles; step++ {
+		for i := 0; i < n; i++ {
+			p.Set(i, Wheel(byte(i*256/n+step)))
+		}
+		if err = p.Show(); err != nil {
+			return err
+		}
+		time.Sleep(delay)
+	}
+	return
+}
+
+// Sets every pixel on the strip to c and shows it. The lock must be held.
+func (p *NeoPixel) sendStrip(c Color) (err error) {
+	msg := append([]byte{p.cmd, pixelSetStrip}, packColor(c)...)
+	if _, err = p.board.sendSysex(msg); err != nil {
+		return err
+	}
+	for i := range p.sent {
+		p.sent[i] = c
+	}
+	_, err = p.board.sendSysex([]byte{p.cmd, pixelShow})
+	return
+}
+
+// Packs a color into four 7-bit bytes.
+func packColor(c Color) []byte {
+	return []byte{
+		byte(c) & 0x7F,
+		byte(c>>7) & 0x7F,
+		byte(c>>14) & 0x7F,
+		byte(c>>21) & 0x7F,
+	}
+}
@@ -0,0 +1,81 @@
+package gadget
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestColor(t *testing.T) {
+	if c := RGB(0x12, 0x34, 0x56); c != 0x123456 {
+		t.Fatalf("RGB = %#06x, want 0x123456", c)
+	}
+	if c := RGB(255, 128, 2).Scale(128); c != RGB(128, 64, 1) {
+		t.Fatalf("Scale(128) = %#06x, want %#06x", c, RGB(128, 64, 1))
+	}
+	for pos, want := range map[byte]Color{0: RGB(255, 0, 0), 85: RGB(0, 255, 0), 170: RGB(0, 0, 255)} {
+		if c := Wheel(pos); c != want {
+			t.Fatalf("Wheel(%d) = %#06x, want %#06x", pos, c, want)
+		}
+	}
+}
+
+func TestNeoPixel(t *testing.T) {
+	var out bytes.Buffer
+	output := []Capability{{OUTPUT, 1}}
+	b := newTestBoard(t, &out, nil, map[byte][]Capability{6: output, 7: {{INPUT, 1}}, 40: output})
+	show := []byte{startSysex, NeoPixelSysex, pixelShow, endSysex}
+
+	// Pins that don't exist, can't be outputs or don't fit the config
+	// message are refused.
+	out.Reset()
+	for _, pin := range []byte{5, 7, 40} {
+		if _, err := NewNeoPixel(b, pin, 3, NeoPixelSysex); err == nil || out.Len() != 0 {
+			t.Fatalf("NewNeoPixel on pin %d should fail without sending anything", pin)
+		}
+	}
+
+	p, err := NewNeoPixel(b, 6, 3, NeoPixelSysex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append([]byte{
+		startSysex, NeoPixelSysex, pixelConfig, 6, 3, 0, endSysex,
+		startSysex, NeoPixelSysex, pixelSetStrip, 0, 0, 0, 0, endSysex,
+	}, show...)
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("NewNeoPixel sent % X, want % X", out.Bytes(), want)
+	}
+
+	// Only the changed pixel is sent, as four 7-bit bytes.
+	out.Reset()
+	p.Set(1, 0xFF8001)
+	if err = p.Show(); err != nil {
+		t.Fatal(err)
+	}
+	want = append([]byte{startSysex, NeoPixelSysex, pixelSetPixel, 1, 0, 0x01, 0x00, 0x7E, 0x07, endSysex}, show...)
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("Show sent % X, want % X", out.Bytes(), want)
+	}
+	out.Reset()
+	p.Show()
+	if !bytes.Equal(out.Bytes(), show) {
+		t.Fatalf("Unchanged Show sent % X, want % X", out.Bytes(), show)
+	}
+
+	// A uniform strip is one command, scaled by the brightness.
+	out.Reset()
+	p.Fill(RGB(0, 0, 255))
+	p.SetBrightness(128)
+	p.Show()
+	want = append([]byte{startSysex, NeoPixelSysex, pixelSetStrip, 0x00, 0x01, 0, 0, endSysex}, show...)
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("Uniform Show sent % X, want % X", out.Bytes(), want)
+	}
+
+	if err = p.Set(3, 0); err == nil {
+		t.Fatalf("Expected an error for pixel 3")
+	}
+	if _, err = NewNeoPixel(b, 6, 0, NeoPixelSysex); err == nil {
+		t.Fatalf("Expected an error for an empty strip")
+	}
+}
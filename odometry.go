@@ -0,0 +1,136 @@
+package gadget
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// OdometryEvent is published on TopicOdometry by a started Odometer.
+type OdometryEvent struct {
+	Name     string  // The Odometer's Name.
+	Distance float64 // Meters since the last Reset, negative if reversing.
+	Velocity float64 // Meters per second.
+}
+
+// Odometer turns a wheel encoder's ticks into distance and speed.
+type Odometer struct {
+	board *Board
+	enc   Encoder
+
+	// Names the odometer in its events, e.g. "left".
+	Name string
+
+	WheelDiameter float64 // In meters.
+	TicksPerRev   float64 // Encoder ticks per wheel revolution.
+
+	// How often the velocity is updated and reported while started.
+	Interval time.Duration
+
+	m         sync.Mutex
+	zero      int64 // Ticks at the last Reset.
+	lastTicks int64
+	lastTime  time.Time
+	velocity  float64
+	stop      func()
+}
+
+// NewOdometer returns an Odometer for a wheel of the given diameter,
+// in meters, with enc counting ticksPerRev per revolution.
+func NewOdometer(b *Board, enc Encoder, wheelDiameter, ticksPerRev float64) *Odometer {
+	return &Odometer{
+		board:         b,
+		enc:           enc,
+		WheelDiameter: wheelDiameter,
+		TicksPerRev:   ticksPerRev,
+		Interval:      100 * time.Millisecond,
+	}
+}
+
+// Distance returns the meters travelled since the last Reset, negative
+// if the wheel has gone backwards overall.
+func (o *Odometer) Distance() (float64, error) {
+	ticks, err := o.enc.Ticks()
+	if err != nil {
+		return 0, err
+	}
+	o.m.Lock()
+	defer o.m.Unlock()
+	return o.meters(ticks - o.zero), nil
+}
+
+// Velocity returns the speed in meters per second over the last
+// Interval. It is only updated while started.
+func (o *Odometer) Velocity() float64 {
+	o.m.Lock()
+	defer o.m.Unlock()
+	return o.velocity
+}
+
+// Reset zeroes the distance.
+func (o *Odometer) Reset() error {
+	ticks, err := o.enc.Ticks()
+	if err != nil {
+		return err
+	}
+	o.m.Lock()
+	o.zero = ticks
+	o.m.Unlock()
+	return nil
+}
+
+// Start updates the velocity every Interval in the background,
+// publishing an OdometryEvent each time.
+func (o *Odometer) Start() (err error) {
+	o.m.Lock()
+	defer o.m.Unlock()
+
+	if o.stop != nil {
+		return fmt.Errorf("Odometer already started")
+	}
+	if o.WheelDiameter <= 0 || o.TicksPerRev <= 0 {
+		return fmt.Errorf("Odometer needs WheelDiameter and TicksPerRev")
+	}
+	if o.lastTicks, err = o.enc.Ticks(); err != nil {
+		return err
+	}
+	o.lastTime = time.Now()
+	o.stop = poll(o.Interval, func() { o.update(time.Now()) })
+	return
+}
+
+// Halt stops updating the velocity, which is left at 0.
+func (o *Odometer) Halt() (err error) {
+	o.m.Lock()
+	defer o.m.Unlock()
+
+	if o.stop != nil {
+		o.stop()
+		o.stop = nil
+	}
+	o.velocity = 0
+	return
+}
+
+func (o *Odometer) update(now time.Time) {
+	ticks, err := o.enc.Ticks()
+	if err != nil {
+		return
+	}
+
+	o.m.Lock()
+	if dt := now.Sub(o.lastTime).Seconds(); dt > 0 {
+		o.velocity = o.meters(ticks-o.lastTicks) / dt
+	}
+	o.lastTicks, o.lastTime = ticks, now
+	e := OdometryEvent{Name: o.Name, Distance: o.meters(ticks - o.zero), Velocity: o.velocity}
+	o.m.Unlock()
+
+	o.board.bus.Publish(Event{Topic: TopicOdometry, Time: now, Data: e})
+}
+
+// Converts ticks to meters. Must be called with o.m held.
+func (o *Odometer) meters(ticks int64) float64 {
+	return float64(ticks) * math.Pi * o.WheelDiameter / o.TicksPerRev
+}
@@ -0,0 +1,37 @@
+package gadget
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+type fakeEncoder struct{ ticks int64 }
+
+func (e *fakeEncoder) Ticks() (int64, error) { return e.ticks, nil }
+
+func TestOdometer(t *testing.T) {
+	b := &Board{bus: NewEventBus()}
+	enc := &fakeEncoder{ticks: 100}
+	// A wheel 1/π m across travels 1m per revolution.
+	o := NewOdometer(b, enc, 1/math.Pi, 200)
+	o.Name = "left"
+	o.Reset()
+	sub := b.bus.Subscribe(TopicOdometry, 1)
+
+	start := time.Now()
+	o.lastTicks, o.lastTime = enc.ticks, start
+	enc.ticks = 200
+	o.update(start.Add(100 * time.Millisecond))
+
+	if d, _ := o.Distance(); math.Abs(d-0.5) > 1e-9 {
+		t.Fatalf("Distance = %f, want 0.5", d)
+	}
+	if v := o.Velocity(); math.Abs(v-5) > 1e-9 {
+		t.Fatalf("Velocity = %f, want 5", v)
+	}
+	e := (<-sub.C).Data.(OdometryEvent)
+	if e.Name != "left" || math.Abs(e.Distance-0.5) > 1e-9 || math.Abs(e.Velocity-5) > 1e-9 {
+		t.Fatalf("Event %+v", e)
+	}
+}
@@ -0,0 +1,124 @@
+package gadget
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Option configures a Board, see New.
+type Option func(*Board)
+
+// WithLogger sends the board's log messages to l instead of
+// slog.Default(). Errors talking to the board are logged at LevelError,
+// problems configuring it at LevelWarn and opening and closing at
+// LevelInfo. Every message received and every pin written or
+// configured is logged at LevelDebug, with pin, port and cmd fields.
+func WithLogger(l *slog.Logger) Option {
+	return func(b *Board) { b.logger = l }
+}
+
+// Logger returns the logger set by WithLogger, or slog.Default(), for
+// packages built on the board to log alongside it.
+func (b *Board) Logger() *slog.Logger {
+	return b.log()
+}
+
+// Returns the board's logger.
+func (b *Board) log() *slog.Logger {
+	if b.logger == nil {
+		return slog.Default()
+	}
+	return b.logger
+}
+
+// WithBaud sets the serial baud rate, which must match the firmware's
+// Firmata.begin call. The default, 57600, suits StandardFirmata over
+// USB. Other common rates are:
+//
+//	115200  ConfigurableFirmata builds set for it
+//	9600    Firmata over Bluetooth serial modules such as the HC-05 and
+//	        HC-06, whose UART runs at 9600 unless reconfigured
+func WithBaud(baud int) Option {
+	return func(b *Board) { b.cfg.Baud = baud }
+}
+
+// WithSettleDelay waits d after opening the port before talking to the
+// board. Opening the port resets most Arduinos, and anything sent while
+// the bootloader runs, about 1.5s on an Uno, is lost or mistaken for an
+// upload. Bytes received during the delay are discarded.
+func WithSettleDelay(d time.Duration) Option {
+	return func(b *Board) { b.settle = d }
+}
+
+// WithoutReset is for boards that do not reset when the port is opened,
+// e.g. with a capacitor between RESET and GND or a USB serial adapter
+// without DTR. Such boards never announce their firmware, so New asks
+// for it instead of waiting.
+func WithoutReset() Option {
+	return func(b *Board) { b.noReset = true }
+}
+
+// WithReconnect lets a Heartbeat recover a board that stops answering,
+// e.g. after its USB cable was pulled and plugged back in, by closing
+// and reopening the port. The board's configuration is then restored as
+// after a reset, see TopicReset. It has no effect on ports given with
+// WithTransport.
+func WithReconnect() Option {
+	return func(b *Board) { b.reconnect = true }
+}
+
+// WithResetOnClose sends SYSTEM_RESET as the last message on Close,
+// returning the firmware's pins to their startup modes with reporting
+// off. Safe values are written before it, see SetSafeValue, but may not
+// outlast it.
+func WithResetOnClose() Option {
+	return func(b *Board) { b.resetOnClose = true }
+}
+
+// WithTransport talks to the board over port instead of opening the
+// device given to New, e.g. a net.Conn to StandardFirmataWiFi or a
+// serial port opened by another library. The device is then only used
+// in logs and String.
+func WithTransport(port io.ReadWriteCloser) Option {
+	return func(b *Board) { b.serial = port }
+}
+
+// WithBaudDetection finds the firmware's baud rate with DetectBaud
+// before opening the board, trying the given rates or the common ones.
+// It replaces WithBaud and adds a few seconds per wrong rate.
+func WithBaudDetection(rates ...int) Option {
+	return func(b *Board) {
+		if rates == nil {
+			rates = commonBauds
+		}
+		b.detectBauds = rates
+	}
+}
+
+// WithAnalogReference sets the analog reference voltage, e.g. 3.3 for
+// 3.3V boards. See SetAnalogReference.
+func WithAnalogReference(volts float64) Option {
+	return func(b *Board) { b.analogRef = volts }
+}
+
+// WithFirmwareUpload lets New fix a board without Firmata: if the
+// handshake times out, or the firmware's name does not contain
+// "Firmata", the port is closed, upload is called to put Firmata on the
+// board and New tries once more. The firmware package can upload a
+// StandardFirmata build:
+//
+//	gadget.WithFirmwareUpload(func(ctx context.Context, device string) error {
+//		return firmware.FlashFile(ctx, device, "uno", "StandardFirmata.ino.hex")
+//	})
+func WithFirmwareUpload(upload func(ctx context.Context, device string) error) Option {
+	return func(b *Board) { b.upload = upload }
+}
+
+// Reports whether a firmware name is one of the Firmata sketches, e.g.
+// StandardFirmata.ino or ConfigurableFirmata.
+func looksLikeFirmata(name string) bool {
+	return strings.Contains(strings.ToLower(name), "firmata")
+}
@@ -0,0 +1,138 @@
+package osc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Message is an OSC message. Args hold int32, float32 or string values.
+type Message struct {
+	Address string
+	Args    []interface{}
+}
+
+// MarshalBinary encodes the message in the OSC 1.0 wire format.
+func (m Message) MarshalBinary() ([]byte, error) {
+	buf := appendString(nil, m.Address)
+	tags := []byte{','}
+	var args []byte
+	for _, a := range m.Args {
+		switch v := a.(type) {
+		case int32:
+			tags = append(tags, 'i')
+			args = binary.BigEndian.AppendUint32(args, uint32(v))
+		case int:
+			tags = append(tags, 'i')
+			args = binary.BigEndian.AppendUint32(args, uint32(int32(v)))
+		case float32:
+			tags = append(tags, 'f')
+			args = binary.BigEndian.AppendUint32(args, math.Float32bits(v))
+		case string:
+			tags = append(tags, 's')
+			args = appendString(args, v)
+		default:
+			return nil, fmt.Errorf("Unsupported OSC argument type %T", a)
+		}
+	}
+	buf = appendString(buf, string(tags))
+	return append(buf, args...), nil
+}
+
+// Appends a null terminated string padded to a multiple of 4 bytes.
+func appendString(buf []byte, s string) []byte {
+	buf = append(buf, s...)
+	return append(buf, make([]byte, 4-len(s)%4)...)
+}
+
+// Decodes a packet, flattening bundles into their messages.
+func parsePacket(b []byte) (msgs []Message, err error) {
+	if bytes.HasPrefix(b, []byte("#bundle\x00")) {
+		if len(b) < 16 {
+			return nil, fmt.Errorf("Truncated OSC bundle")
+		}
+		// Skip the 8 byte time tag, bundle elements are applied
+		// immediately.
+		b = b[16:]
+		for len(b) > 0 {
+			if len(b) < 4 {
+				return nil, fmt.Errorf("Truncated OSC bundle")
+			}
+			n := int(binary.BigEndian.Uint32(b))
+			if n > len(b)-4 {
+				return nil, fmt.Errorf("Truncated OSC bundle element")
+			}
+			inner, err := parsePacket(b[4 : 4+n])
+			if err != nil {
+				return nil, err
+			}
+			msgs = append(msgs, inner...)
+			b = b[4+n:]
+		}
+		return msgs, nil
+	}
+
+	m, err := parseMessage(b)
+	if err != nil {
+		return nil, err
+	}
+	return []Message{m}, nil
+}
+
+func parseMessage(b []byte) (m Message, err error) {
+	if m.Address, b, err = readString(b); err != nil {
+		return
+	}
+	if len(m.Address) == 0 || m.Address[0] != '/' {
+		return m, fmt.Errorf("Invalid OSC address '%s'", m.Address)
+	}
+
+	tags, b, err := readString(b)
+	if err != nil || len(tags) == 0 || tags[0] != ',' {
+		// Very old implementations omit the type tags.
+		return m, nil
+	}
+
+	for _, t := range tags[1:] {
+		switch t {
+		case 'i', 'f':
+			if len(b) < 4 {
+				return m, fmt.Errorf("Truncated OSC argument")
+			}
+			u := binary.BigEndian.Uint32(b)
+			b = b[4:]
+			if t == 'i' {
+				m.Args = append(m.Args, int32(u))
+			} else {
+				m.Args = append(m.Args, math.Float32frombits(u))
+			}
+		case 's':
+			var s string
+			if s, b, err = readString(b); err != nil {
+				return
+			}
+			m.Args = append(m.Args, s)
+		case 'T':
+			m.Args = append(m.Args, int32(1))
+		case 'F':
+			m.Args = append(m.Args, int32(0))
+		default:
+			return m, fmt.Errorf("Unsupported OSC type tag '%c'", t)
+		}
+	}
+	return
+}
+
+// Reads a padded string, returning the rest of b.
+func readString(b []byte) (s string, rest []byte, err error) {
+	i := bytes.IndexByte(b, 0)
+	if i < 0 {
+		return "", nil, fmt.Errorf("Unterminated OSC string")
+	}
+	n := (i + 4) &^ 3
+	if n > len(b) {
+		return "", nil, fmt.Errorf("Truncated OSC string")
+	}
+	return string(b[:i]), b[n:], nil
+}
@@ -0,0 +1,283 @@
+// Package osc bridges a gadget.Board to Open Sound Control, so tools
+// such as TouchOSC, Max/MSP and Pure Data can drive pins and receive
+// readings.
+//
+// Pins are addressed as {prefix}/{kind}/{pin}, e.g. /gadget/pwm/9.
+// Messages received on the bridge's port write the first argument to
+// the pin:
+//
+//	/gadget/digital/13 1     DigitalWrite(13, HIGH)
+//	/gadget/pwm/9 0.5        AnalogWrite(9, 127)
+//	/gadget/servo/10 90      ServoWrite(10, 90)
+//
+// Float arguments between 0 and 1, as sent by faders and toggles, are
+// scaled to the pin's range. Readings of the configured input pins are
+// sent to the remote address as int32 arguments whenever they change.
+package osc
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ZachMassia/GoGoGadget"
+)
+
+// Pin kinds used in addresses.
+const (
+	KindAnalog  = "analog"  // Analog inputs, by A0 style number.
+	KindDigital = "digital" // Digital pins.
+	KindPWM     = "pwm"     // PWM outputs.
+	KindServo   = "servo"   // Servo outputs, in degrees.
+)
+
+// Config configures a Bridge.
+type Config struct {
+	// The first part of every address. Defaults to "/gadget".
+	Prefix string
+
+	// The UDP address to receive messages on. Defaults to ":8000".
+	Listen string
+
+	// Where readings are sent, e.g. "192.168.1.20:9000". Readings are
+	// not sent when empty.
+	Remote string
+
+	// Pins whose readings are sent. Analog pins are given by their A0
+	// style number.
+	Analog, Digital []byte
+
+	// Called with errors from the background sender and receiver.
+	OnError func(error)
+}
+
+// Bridge maps OSC addresses to a Board's pins.
+type Bridge struct {
+	board *gadget.Board
+	cfg   Config
+
+	m      sync.Mutex
+	conn   net.PacketConn
+	remote net.Addr
+	last   map[string]int
+	sub    *gadget.Subscription
+}
+
+// NewBridge returns a Bridge for the board.
+func NewBridge(b *gadget.Board, cfg Config) *Bridge {
+	if cfg.Prefix == "" {
+		cfg.Prefix = "/gadget"
+	}
+	cfg.Prefix = strings.TrimSuffix(cfg.Prefix, "/")
+	if cfg.Listen == "" {
+		cfg.Listen = ":8000"
+	}
+	return &Bridge{board: b, cfg: cfg, last: make(map[string]int)}
+}
+
+// Start opens the UDP port and starts receiving and sending messages.
+func (br *Bridge) Start() (err error) {
+	br.m.Lock()
+	defer br.m.Unlock()
+
+	if br.conn != nil {
+		return fmt.Errorf("OSC bridge already started")
+	}
+	if br.cfg.Remote != "" {
+		if br.remote, err = net.ResolveUDPAddr("udp", br.cfg.Remote); err != nil {
+			return err
+		}
+	}
+	if br.conn, err = net.ListenPacket("udp", br.cfg.Listen); err != nil {
+		return err
+	}
+
+	go br.receiveLoop(br.conn)
+	if br.remote != nil {
+		br.sub = br.board.Events().Subscribe("pin/#", 64)
+		go br.sendLoop(br.sub)
+	}
+	return
+}
+
+// Halt closes the port.
+func (br *Bridge) Halt() (err error) {
+	br.m.Lock()
+	defer br.m.Unlock()
+
+	if br.sub != nil {
+		br.sub.Unsubscribe()
+		br.sub = nil
+	}
+	if br.conn != nil {
+		err = br.conn.Close()
+		br.conn = nil
+	}
+	return
+}
+
+// Addr returns the address the bridge is receiving on, or nil if it
+// has not been started.
+func (br *Bridge) Addr() net.Addr {
+	br.m.Lock()
+	defer br.m.Unlock()
+
+	if br.conn == nil {
+		return nil
+	}
+	return br.conn.LocalAddr()
+}
+
+func (br *Bridge) receiveLoop(conn net.PacketConn) {
+	buf := make([]byte, 65536)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return // Closed by Halt.
+		}
+		msgs, err := parsePacket(buf[:n])
+		if err != nil {
+			br.reportError(err)
+			continue
+		}
+		for _, m := range msgs {
+			if err = br.handle(m); err != nil {
+				br.reportError(err)
+			}
+		}
+	}
+}
+
+// Writes a message's first argument to its pin.
+func (br *Bridge) handle(m Message) error {
+	kind, pin, ok := br.parseAddress(m.Address)
+	if !ok {
+		return fmt.Errorf("Unknown OSC address '%s'", m.Address)
+	}
+	if len(m.Args) == 0 {
+		return fmt.Errorf("OSC message to '%s' has no arguments", m.Address)
+	}
+
+	switch kind {
+	case KindDigital:
+		s := gadget.LOW
+		if v, err := argValue(m.Args[0], 1); err != nil {
+			return err
+		} else if v != 0 {
+			s = gadget.HIGH
+		}
+		return br.board.DigitalWrite(pin, s)
+	case KindPWM:
+		v, err := argValue(m.Args[0], 255)
+		if err != nil {
+			return err
+		}
+		return br.board.AnalogWrite(pin, byte(max(0, min(v, 255))))
+	case KindServo:
+		v, err := argValue(m.Args[0], 180)
+		if err != nil {
+			return err
+		}
+		return br.board.ServoWrite(pin, v)
+	}
+	return fmt.Errorf("OSC address '%s' cannot be written", m.Address)
+}
+
+// Splits {prefix}/{kind}/{pin}.
+func (br *Bridge) parseAddress(addr string) (kind string, pin byte, ok bool) {
+	rest, ok := strings.CutPrefix(addr, br.cfg.Prefix+"/")
+	if !ok {
+		return
+	}
+	kind, num, ok := strings.Cut(rest, "/")
+	if !ok {
+		return
+	}
+	n, err := strconv.ParseUint(num, 10, 8)
+	return kind, byte(n), err == nil
+}
+
+// Converts an argument to an int. Floats from 0 to 1 are scaled to
+// 0-full, larger floats are rounded.
+func argValue(a interface{}, full int) (int, error) {
+	switch v := a.(type) {
+	case int32:
+		return int(v), nil
+	case float32:
+		if v >= 0 && v <= 1 {
+			return int(v * float32(full)), nil
+		}
+		return int(math.Round(float64(v))), nil
+	case string:
+		return strconv.Atoi(v)
+	}
+	return 0, fmt.Errorf("Unsupported OSC argument %v", a)
+}
+
+// Sends the configured pins, then each again as its events arrive.
+func (br *Bridge) sendLoop(sub *gadget.Subscription) {
+	br.sendAll()
+
+	mapping := br.board.AnalogMapping()
+	for e := range sub.C {
+		for a, pin := range mapping {
+			if pin == e.Pin && bytes.IndexByte(br.cfg.Analog, byte(a)) >= 0 {
+				v, err := br.board.AnalogRead(pin)
+				br.send(KindAnalog, byte(a), v, err)
+			}
+		}
+		if bytes.IndexByte(br.cfg.Digital, e.Pin) >= 0 {
+			v, err := br.board.DigitalRead(e.Pin)
+			br.send(KindDigital, e.Pin, int(v), err)
+		}
+	}
+}
+
+// Sends every configured pin.
+func (br *Bridge) sendAll() {
+	mapping := br.board.AnalogMapping()
+	for _, a := range br.cfg.Analog {
+		if int(a) >= len(mapping) {
+			br.reportError(fmt.Errorf("Invalid analog pin: A%d", a))
+			continue
+		}
+		v, err := br.board.AnalogRead(mapping[a])
+		br.send(KindAnalog, a, v, err)
+	}
+	for _, pin := range br.cfg.Digital {
+		v, err := br.board.DigitalRead(pin)
+		br.send(KindDigital, pin, int(v), err)
+	}
+}
+
+func (br *Bridge) send(kind string, pin byte, v int, err error) {
+	if err != nil {
+		br.reportError(err)
+		return
+	}
+	addr := br.cfg.Prefix + "/" + kind + "/" + strconv.Itoa(int(pin))
+
+	br.m.Lock()
+	last, seen := br.last[addr]
+	br.last[addr] = v
+	conn := br.conn
+	br.m.Unlock()
+
+	if (seen && last == v) || conn == nil {
+		return
+	}
+	msg, _ := Message{Address: addr, Args: []interface{}{int32(v)}}.MarshalBinary()
+	if _, err = conn.WriteTo(msg, br.remote); err != nil {
+		br.reportError(err)
+	}
+}
+
+func (br *Bridge) reportError(err error) {
+	if br.cfg.OnError != nil {
+		br.cfg.OnError(err)
+	}
+}
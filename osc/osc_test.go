@@ -0,0 +1,87 @@
+package osc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	m := Message{Address: "/gadget/pwm/9", Args: []interface{}{int32(128), float32(0.5), "hi"}}
+	b, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary returned error: %s", err)
+	}
+	if len(b)%4 != 0 {
+		t.Fatalf("Encoded message is not 4 byte aligned: % X", b)
+	}
+
+	msgs, err := parsePacket(b)
+	if err != nil {
+		t.Fatalf("parsePacket returned error: %s", err)
+	}
+	if len(msgs) != 1 || !reflect.DeepEqual(msgs[0], m) {
+		t.Fatalf("parsePacket = %+v, want %+v", msgs, m)
+	}
+}
+
+func TestParseSpecExample(t *testing.T) {
+	// "/oscillator/4/frequency" 440.0, from the OSC 1.0 spec.
+	b := []byte("/oscillator/4/frequency\x00,f\x00\x00\x43\xdc\x00\x00")
+	msgs, err := parsePacket(b)
+	if err != nil || len(msgs) != 1 || msgs[0].Address != "/oscillator/4/frequency" || msgs[0].Args[0] != float32(440) {
+		t.Fatalf("parsePacket = %+v, %v", msgs, err)
+	}
+}
+
+func TestParseBundle(t *testing.T) {
+	a, _ := Message{Address: "/a", Args: []interface{}{int32(1)}}.MarshalBinary()
+	c, _ := Message{Address: "/c"}.MarshalBinary()
+
+	var b bytes.Buffer
+	b.WriteString("#bundle\x00")
+	b.Write(make([]byte, 8)) // Time tag.
+	for _, m := range [][]byte{a, c} {
+		binary.Write(&b, binary.BigEndian, uint32(len(m)))
+		b.Write(m)
+	}
+
+	msgs, err := parsePacket(b.Bytes())
+	if err != nil || len(msgs) != 2 || msgs[0].Address != "/a" || msgs[1].Address != "/c" {
+		t.Fatalf("parsePacket = %+v, %v", msgs, err)
+	}
+
+	if _, err := parsePacket(b.Bytes()[:b.Len()-2]); err == nil {
+		t.Fatalf("Truncated bundle should fail")
+	}
+}
+
+func TestAddressAndArgs(t *testing.T) {
+	br := NewBridge(nil, Config{Prefix: "/bench/"})
+	kind, pin, ok := br.parseAddress("/bench/servo/10")
+	if !ok || kind != KindServo || pin != 10 {
+		t.Fatalf("parseAddress = %s, %d, %v", kind, pin, ok)
+	}
+	for _, addr := range []string{"/gadget/servo/10", "/bench/servo", "/bench/servo/300"} {
+		if _, _, ok := br.parseAddress(addr); ok {
+			t.Errorf("parseAddress('%s') should fail", addr)
+		}
+	}
+
+	tests := []struct {
+		arg  interface{}
+		want int
+	}{
+		{int32(90), 90},
+		{float32(0.5), 127},
+		{float32(1), 255},
+		{float32(200.4), 200},
+		{"12", 12},
+	}
+	for _, tt := range tests {
+		if got, err := argValue(tt.arg, 255); err != nil || got != tt.want {
+			t.Errorf("argValue(%v) = %d, %v; want %d", tt.arg, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package gadget
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// How often a slewing PanTilt moves its servos.
+const slewInterval = 20 * time.Millisecond
+
+// PanTilt is a two servo pan/tilt bracket, as used for cameras and
+// range finders. Travel limits are set on each servo.
+type PanTilt struct {
+	PanServo, TiltServo *Servo
+
+	// Slew rate in degrees per second. 0 moves at the servos'
+	// full speed.
+	Speed float64
+
+	// The position Center returns to. Defaults to the middle of
+	// each servo's travel limits.
+	HomePan, HomeTilt float64
+
+	m sync.Mutex // One move at a time.
+}
+
+// NewPanTilt returns a PanTilt with servos on the given pins.
+func NewPanTilt(b *Board, panPin, tiltPin byte) (pt *PanTilt, err error) {
+	pan, err := NewServo(b, panPin)
+	if err != nil {
+		return nil, err
+	}
+	tilt, err := NewServo(b, tiltPin)
+	if err != nil {
+		return nil, err
+	}
+	return &PanTilt{PanServo: pan, TiltServo: tilt, HomePan: 90, HomeTilt: 90}, nil
+}
+
+// Pan turns to the given pan angle, keeping the tilt.
+func (pt *PanTilt) Pan(deg float64) error {
+	return pt.MoveTo(deg, pt.TiltServo.Angle())
+}
+
+// Tilt turns to the given tilt angle, keeping the pan.
+func (pt *PanTilt) Tilt(deg float64) error {
+	return pt.MoveTo(pt.PanServo.Angle(), deg)
+}
+
+// Center returns to the home position.
+func (pt *PanTilt) Center() error {
+	return pt.MoveTo(pt.HomePan, pt.HomeTilt)
+}
+
+// MoveTo moves both axes to the given angles, slewing at Speed so
+// both arrive together. It returns once the move is done.
+func (pt *PanTilt) MoveTo(pan, tilt float64) (err error) {
+	pt.m.Lock()
+	defer pt.m.Unlock()
+
+	pan = math.Max(pt.PanServo.Min, math.Min(pt.PanServo.Max, pan))
+	tilt = math.Max(pt.TiltServo.Min, math.Min(pt.TiltServo.Max, tilt))
+
+	p0, t0 := pt.PanServo.Angle(), pt.TiltServo.Angle()
+	dist := math.Max(math.Abs(pan-p0), math.Abs(tilt-t0))
+
+	steps := 1
+	if pt.Speed > 0 {
+		perStep := pt.Speed * slewInterval.Seconds()
+		steps = int(math.Ceil(dist / perStep))
+	}
+
+	for i := 1; i <= steps; i++ {
+		f := float64(i) / float64(steps)
+		if err = pt.PanServo.Move(p0 + f*(pan-p0)); err != nil {
+			return err
+		}
+		if err = pt.TiltServo.Move(t0 + f*(tilt-t0)); err != nil {
+			return err
+		}
+		if i < steps {
+			time.Sleep(slewInterval)
+		}
+	}
+	return
+}
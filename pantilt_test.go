@@ -0,0 +1,62 @@
+package gadget
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+func TestPanTilt(t *testing.T) {
+	var out bytes.Buffer
+	caps := []Capability{{OUTPUT, 1}, {SERVO, 14}}
+	b := newTestBoard(t, &out, nil, map[byte][]Capability{9: caps, 10: caps})
+	pt, err := NewPanTilt(b, 9, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt.TiltServo.Min, pt.TiltServo.Max = 30, 150
+
+	// Expects a pulse for each pan and tilt angle, in order.
+	expect := func(what string, angles ...float64) {
+		t.Helper()
+		b.out.Flush()
+		var want []byte
+		for i, a := range angles {
+			pulse := defaultServoMinPulse + int(math.Round(a/180*(defaultServoMaxPulse-defaultServoMinPulse)))
+			want = append(want, firmatawire.AnalogWrite(byte(9+i%2), pulse)...)
+		}
+		if !bytes.Equal(out.Bytes(), want) {
+			t.Fatalf("%s sent % X, want % X", what, out.Bytes(), want)
+		}
+		out.Reset()
+	}
+	out.Reset()
+
+	// Angles are clamped to each servo's limits.
+	if err = pt.MoveTo(-20, 170); err != nil {
+		t.Fatal(err)
+	}
+	expect("MoveTo(-20, 170)", 0, 150)
+	if pt.PanServo.Angle() != 0 || pt.TiltServo.Angle() != 150 {
+		t.Fatalf("Expected 0, 150, got %g, %g", pt.PanServo.Angle(), pt.TiltServo.Angle())
+	}
+
+	// At 500°/s each 20ms step moves 10°, both axes arriving together.
+	pt.Speed = 500
+	if err = pt.MoveTo(30, 135); err != nil {
+		t.Fatal(err)
+	}
+	expect("MoveTo(30, 135)", 10, 145, 20, 140, 30, 135)
+
+	pt.Speed = 0
+	if err = pt.Tilt(60); err != nil {
+		t.Fatal(err)
+	}
+	expect("Tilt(60)", 30, 60)
+	if err = pt.Center(); err != nil {
+		t.Fatal(err)
+	}
+	expect("Center", 90, 90)
+}
@@ -0,0 +1,124 @@
+package gadget
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// PID is a proportional-integral-derivative controller.
+//
+// The derivative acts on the input rather than the error, so changing
+// the setpoint does not kick the output, and the integral term is
+// clamped to the output limits to stop it winding up while the output
+// is saturated.
+type PID struct {
+	Kp, Ki, Kd float64
+	Setpoint   float64
+
+	// Output limits. Default to no limit.
+	OutMin, OutMax float64
+
+	// How often Run samples the source. Defaults to 100ms.
+	SampleTime time.Duration
+
+	// Called by Run when the source or sink returns an error.
+	OnError func(error)
+
+	m         sync.Mutex
+	integral  float64
+	lastInput float64
+	primed    bool // Has lastInput been set.
+	stop      func()
+}
+
+// NewPID returns a PID controller with the given gains.
+func NewPID(kp, ki, kd float64) *PID {
+	return &PID{
+		Kp:         kp,
+		Ki:         ki,
+		Kd:         kd,
+		OutMin:     math.Inf(-1),
+		OutMax:     math.Inf(1),
+		SampleTime: 100 * time.Millisecond,
+	}
+}
+
+// Update computes the output for a new input, dt after the last one.
+func (p *PID) Update(input float64, dt time.Duration) (out float64) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	s := dt.Seconds()
+	e := p.Setpoint - input
+
+	p.integral = p.clamp(p.integral + p.Ki*e*s)
+
+	var deriv float64
+	if p.primed && s > 0 {
+		deriv = (input - p.lastInput) / s
+	}
+	p.lastInput, p.primed = input, true
+
+	return p.clamp(p.Kp*e + p.integral - p.Kd*deriv)
+}
+
+// Reset clears the integral and derivative history, for example after
+// the loop was paused.
+func (p *PID) Reset() {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	p.integral, p.lastInput, p.primed = 0, 0, false
+}
+
+// Run reads source every SampleTime and writes the controller's output
+// to sink, in the background, until Halt is called.
+func (p *PID) Run(source func() (float64, error), sink func(float64) error) (err error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if p.stop != nil {
+		return fmt.Errorf("PID controller already running")
+	}
+
+	last := time.Now()
+	p.stop = poll(p.SampleTime, func() {
+		in, err := source()
+		if err != nil {
+			p.reportError(err)
+			return
+		}
+		now := time.Now()
+		out := p.Update(in, now.Sub(last))
+		last = now
+
+		if err = sink(out); err != nil {
+			p.reportError(err)
+		}
+	})
+	return
+}
+
+// Halt stops a running controller.
+func (p *PID) Halt() (err error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if p.stop != nil {
+		p.stop()
+		p.stop = nil
+	}
+	return
+}
+
+func (p *PID) reportError(err error) {
+	if p.OnError != nil {
+		p.OnError(err)
+	}
+}
+
+func (p *PID) clamp(v float64) float64 {
+	return math.Max(p.OutMin, math.Min(p.OutMax, v))
+}
@@ -0,0 +1,50 @@
+package gadget
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPIDUpdate(t *testing.T) {
+	p := NewPID(2, 1, 0)
+	p.Setpoint = 10
+
+	// P: 2*10, I: 1*10*1s.
+	if out := p.Update(0, time.Second); out != 30 {
+		t.Fatalf("Update = %v, want 30", out)
+	}
+}
+
+func TestPIDAntiWindup(t *testing.T) {
+	p := NewPID(0, 1, 0)
+	p.Setpoint = 100
+	p.OutMin, p.OutMax = 0, 10
+
+	// A long saturated stretch must not build up the integral
+	// beyond the output limit.
+	for i := 0; i < 100; i++ {
+		p.Update(0, time.Second)
+	}
+	if p.integral != 10 {
+		t.Fatalf("Integral wound up to %v, want 10", p.integral)
+	}
+
+	// Once the input overshoots, the output drops straight away.
+	if out := p.Update(200, time.Second); out >= 10 {
+		t.Fatalf("Output stayed saturated at %v after overshoot", out)
+	}
+}
+
+func TestPIDDerivativeOnInput(t *testing.T) {
+	p := NewPID(0, 0, 1)
+	p.Update(5, time.Second)
+
+	// Changing the setpoint alone must not kick the output.
+	p.Setpoint = 50
+	if out := p.Update(5, time.Second); out != 0 {
+		t.Fatalf("Setpoint change kicked output to %v", out)
+	}
+	if out := p.Update(7, time.Second); out != -2 {
+		t.Fatalf("Update = %v, want -2", out)
+	}
+}
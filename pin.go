@@ -4,49 +4,78 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"time"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
 )
 
 const (
 	// Pin modes
-	INPUT  byte = iota // Digital pin in input mode.
-	OUTPUT             // Digital pin in output mode.
-	ANALOG             // Analog pin in analogInput mode.
-	PWM                // Digital pin in PWM output mode.
-	SERVO              // Digital pin in Servo output mode.
-	SHIFT              // shiftIn/shiftOut mode.
-	I2C                // Pin included in I2C setup.
+	INPUT   byte = iota // Digital pin in input mode.
+	OUTPUT              // Digital pin in output mode.
+	ANALOG              // Analog pin in analogInput mode.
+	PWM                 // Digital pin in PWM output mode.
+	SERVO               // Digital pin in Servo output mode.
+	SHIFT               // shiftIn/shiftOut mode.
+	I2C                 // Pin included in I2C setup.
+	ONEWIRE             // OneWire bus, not driven by this package.
+	STEPPER             // Stepper motor, not driven by this package.
+	ENCODER             // Rotary encoder, not driven by this package.
+	SERIAL              // Hardware or software serial, not driven by this package.
+	PULLUP              // Digital input with the internal pull-up enabled.
+	SPI                 // Pin included in SPI setup.
 
 	// Pin states
 	LOW  byte = 0
 	HIGH byte = 1
 )
 
+// Analog reports and REPORT_ANALOG carry the channel in the low nibble
+// of the command, so only A0-A15 can report. Firmata has no extended
+// form for them.
+const maxReportedAnalog = 15
+
 var (
 	// String representation of pin mode bytes.
 	PinModeString = map[byte]string{
-		INPUT:  "INPUT",
-		OUTPUT: "OUTPUT",
-		ANALOG: "ANALOG",
-		PWM:    "PWM",
-		SERVO:  "SERVO",
-		SHIFT:  "SHIFT",
-		I2C:    "I2C",
+		INPUT:   "INPUT",
+		OUTPUT:  "OUTPUT",
+		ANALOG:  "ANALOG",
+		PWM:     "PWM",
+		SERVO:   "SERVO",
+		SHIFT:   "SHIFT",
+		I2C:     "I2C",
+		ONEWIRE: "ONEWIRE",
+		STEPPER: "STEPPER",
+		ENCODER: "ENCODER",
+		SERIAL:  "SERIAL",
+		PULLUP:  "PULLUP",
+		SPI:     "SPI",
 	}
 
 	// Slice of all valid pin modes.
-	validPinModes = []byte{INPUT, OUTPUT, ANALOG, PWM, SERVO, SHIFT, I2C}
+	validPinModes = []byte{INPUT, OUTPUT, ANALOG, PWM, SERVO, SHIFT, I2C, PULLUP}
 )
 
-// Reads the slice of mode+res pairs for a single pin.
-func unpackPinModeDataSlice(data []byte) (modes []byte) {
+// Capability is a mode supported by a pin and its resolution.
+type Capability struct {
+	Mode       byte // One of INPUT, OUTPUT, ANALOG, etc.
+	Resolution byte // In bits, e.g. 10 for ANALOG on most boards.
+}
+
+// MarshalJSON encodes the mode by name, e.g. {"mode":"PWM","resolution":8}.
+func (c Capability) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`{"mode":%q,"resolution":%d}`, PinModeString[c.Mode], c.Resolution)), nil
+}
+
+// Parses the mode+res pairs of a single pin from a capability response.
+func parseCapabilities(data []byte) (caps []Capability) {
 	// Must be even number of elements
 	if len(data)%2 != 0 {
 		return nil
 	}
-
-	// Unpack the data
 	for i := 0; i < len(data); i += 2 {
-		modes = append(modes, data[i])
+		caps = append(caps, Capability{Mode: data[i], Resolution: data[i+1]})
 	}
 	return
 }
@@ -72,26 +101,47 @@ type pin struct {
 	// When in INPUT/ANALOG mode, these hold the last
 	// reported value. In PWM/OUPUT, they hold the last
 	// set value.
-	analogVal  byte
+	analogVal  int
 	digitalVal byte
+	updated    time.Time // When a value was last reported or set.
+
+	mode      byte         // The current mode.
+	reporting bool         // Is the pin (or port in digital mode) reporting.
+	caps      []Capability // The valid modes for this pin.
+
+	filter Filter     // Smooths analog reports, see SetFilter.
+	decim  *decimator // Limits published analog reports, see SetDecimation.
 
-	mode           byte   // The current mode.
-	reporting      bool   // Is the pin (or port in digital mode) reporting.
-	supportedModes []byte // The valid modes for this pin.
+	history *history // The latest readings, see KeepHistory.
+	safe    *int     // Written on Close, see SetSafeValue.
+	pullup  bool     // Is the input's pull-up on, see QueryPinState.
+
+	servoPulses [2]int // The pulse range set by ServoConfig.
+
+	// Open streams of the pin's reports, and whether they turned
+	// reporting on.
+	streams         map[*Stream]bool
+	streamReporting bool
 }
 
-// Returns an analog pin.
-func newPin(s io.Writer, pinNum, aPinNum byte, modes []byte) (p *pin) {
+// Returns a pin configured from its capabilities.
+func newPin(s io.Writer, pinNum, aPinNum byte, caps []Capability) (p *pin) {
 	p = &pin{
-		serial:         s,
-		num:            pinNum,
-		analogNum:      aPinNum,
-		port:           pinToPort(pinNum),
-		supportedModes: modes,
+		serial:    s,
+		num:       pinNum,
+		analogNum: aPinNum,
+		port:      pinToPort(pinNum),
+		caps:      caps,
 	}
 
-	// Set the default pin mode.
-	if bytes.Contains(p.supportedModes, []byte{ANALOG}) {
+	// Set the default pin mode. Pins above 127 can't be set, so they are
+	// assumed to be in it already.
+	if pinNum >= addressablePins {
+		p.mode = OUTPUT
+		if p.supports(ANALOG) {
+			p.mode = ANALOG
+		}
+	} else if p.supports(ANALOG) {
 		p.setMode(ANALOG)
 		// Analog pins report by default. Turn it off
 		// until requested by the user.
@@ -109,9 +159,13 @@ func (p *pin) setMode(mode byte) (err error) {
 	case !bytes.Contains(validPinModes, []byte{mode}):
 		return fmt.Errorf("Pin mode %X not valid", mode)
 
-	case !bytes.Contains(p.supportedModes, []byte{mode}):
+	case !p.supports(mode):
 		return fmt.Errorf("Pin mode %s not supported by pin %d", PinModeString[mode], p.num)
 
+	case p.num >= addressablePins:
+		return fmt.Errorf("Pin %d can't be set to %s mode, SET_PIN_MODE only reaches pins 0-%d",
+			p.num, PinModeString[mode], addressablePins-1)
+
 	case mode == p.mode:
 		// TODO: Should this return nil? Technically not an error.
 		return fmt.Errorf("Pin %d already in %s mode", p.num, PinModeString[mode])
@@ -119,30 +173,87 @@ func (p *pin) setMode(mode byte) (err error) {
 
 	// Update the pins mode flag.
 	p.mode = mode
+	p.pullup = mode == PULLUP
 
 	// Send the message.
-	msg := []byte{setPinMode, p.num, mode}
-	p.serial.Write(msg)
+	p.serial.Write(firmatawire.SetMode(p.num, mode))
+	return
+}
+
+// Records a reported or written digital value and when it happened,
+// returning whether it changed.
+func (p *pin) setDigital(v byte, at time.Time) (changed bool) {
+	changed = p.digitalVal != v
+	p.digitalVal = v
+	p.updated = at
+	if p.history != nil {
+		p.history.add(Reading{at, int(v)})
+	}
 	return
 }
 
+// Records a reported or written analog value and when it happened,
+// returning whether it changed.
+func (p *pin) setAnalog(v int, at time.Time) (changed bool) {
+	changed = p.analogVal != v
+	p.analogVal = v
+	p.updated = at
+	if p.history != nil {
+		p.history.add(Reading{at, v})
+	}
+	return
+}
+
+// Reports whether pin p is a digital input, with or without its
+// pull-up.
+func (p *pin) isInput() bool {
+	return p.mode == INPUT || p.mode == PULLUP
+}
+
+// Reports whether pin p supports the given mode.
+func (p *pin) supports(mode byte) bool {
+	return supportsMode(p.caps, mode)
+}
+
+// Returns the resolution in bits of the given mode, or 0 if
+// the mode is not supported by pin p.
+func (p *pin) resolution(mode byte) byte {
+	for _, c := range p.caps {
+		if c.Mode == mode {
+			return c.Resolution
+		}
+	}
+	return 0
+}
+
+func supportsMode(caps []Capability, mode byte) bool {
+	for _, c := range caps {
+		if c.Mode == mode {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *pin) setReporting(newState bool) (err error) {
 	// Do not turn on reporting for non input pin.
-	if newState && (p.mode != INPUT && p.mode != ANALOG) {
-		return fmt.Errorf("Pin %d not in INPUT or ANALOG mode", p.num)
+	if newState && (!p.isInput() && p.mode != ANALOG) {
+		return fmt.Errorf("Pin %d not in INPUT, PULLUP or ANALOG mode", p.num)
+	}
+	if p.mode == ANALOG && p.analogNum > maxReportedAnalog {
+		if newState {
+			return fmt.Errorf("Pin %d is A%d, but Firmata can only report A0-A%d", p.num, p.analogNum, maxReportedAnalog)
+		}
+		// Never reporting, and a message would address another channel.
+		p.reporting = false
+		return
 	}
 	p.reporting = newState
 
-	var msg []byte
-
-	// Create the message
-	switch p.mode {
-	case ANALOG:
-		msg = []byte{reportAnalog | p.analogNum, boolToByte(newState)}
-
-	case INPUT:
-		msg = []byte{reportDigital | p.port, boolToByte(newState)}
+	// Digital pins report by port, which the board tracks, see
+	// Board.setReporting.
+	if p.mode == ANALOG {
+		p.serial.Write(firmatawire.ReportAnalogPin(p.analogNum, newState))
 	}
-	p.serial.Write(msg)
 	return
 }
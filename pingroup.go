@@ -0,0 +1,64 @@
+package gadget
+
+import "fmt"
+
+// PinGroup is an ordered group of digital pins read or written as one
+// number, the first pin being the least significant bit. It suits LED
+// bars, resistor ladder DACs and parallel buses.
+type PinGroup struct {
+	board *Board
+	pins  []byte
+}
+
+// NewPinGroup returns a group of up to 32 pins, all put in mode, which
+// must be OUTPUT, INPUT or PULLUP. Input pins have reporting turned on.
+func NewPinGroup(b *Board, mode byte, pins ...byte) (g *PinGroup, err error) {
+	if mode != INPUT && mode != PULLUP && mode != OUTPUT {
+		return nil, fmt.Errorf("Pin group mode must be INPUT, PULLUP or OUTPUT, got %s", PinModeString[mode])
+	}
+	if len(pins) == 0 || len(pins) > 32 {
+		return nil, fmt.Errorf("Pin group needs 1-32 pins, got %d", len(pins))
+	}
+
+	for _, pin := range pins {
+		if err = b.ensurePinMode(pin, mode); err != nil {
+			return nil, err
+		}
+		if mode != OUTPUT {
+			if err = b.SetPinReporting(pin, true); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &PinGroup{board: b, pins: append([]byte(nil), pins...)}, nil
+}
+
+// Width returns the number of pins in the group.
+func (g *PinGroup) Width() int {
+	return len(g.pins)
+}
+
+// Write sets the pins to the bits of v. Pins sharing a port change
+// together, see DigitalWritePins.
+func (g *PinGroup) Write(v uint32) error {
+	if len(g.pins) < 32 && v>>len(g.pins) != 0 {
+		return fmt.Errorf("Value %d does not fit in %d pins", v, len(g.pins))
+	}
+	states := make(map[byte]byte, len(g.pins))
+	for i, pin := range g.pins {
+		states[pin] = byte(v>>i) & 1
+	}
+	return g.board.DigitalWritePins(states)
+}
+
+// Read returns the pins' last reported values as a number.
+func (g *PinGroup) Read() (v uint32, err error) {
+	for i, pin := range g.pins {
+		s, err := g.board.DigitalRead(pin)
+		if err != nil {
+			return 0, err
+		}
+		v |= uint32(s&1) << i
+	}
+	return
+}
@@ -0,0 +1,34 @@
+package gadget
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPinGroup(t *testing.T) {
+	var out bytes.Buffer
+	caps := []Capability{{INPUT, 1}, {OUTPUT, 1}}
+	b := newTestBoard(t, &out, nil, map[byte][]Capability{6: caps, 7: caps, 8: caps})
+
+	// Bit 0 on pin 8, bits 1 and 2 on pins 7 and 6.
+	g, err := NewPinGroup(b, OUTPUT, 8, 7, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err = g.Write(5); err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{digitalMessage, 0x40, 0x00, digitalMessage | 1, 0x01, 0x00}; !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("Write sent % X, want % X", out.Bytes(), want)
+	}
+	if v, _ := g.Read(); v != 5 {
+		t.Fatalf("Read = %d, want 5", v)
+	}
+	if err = g.Write(8); err == nil {
+		t.Fatalf("8 should not fit in 3 pins")
+	}
+	if _, err = NewPinGroup(b, PWM, 6); err == nil {
+		t.Fatalf("PWM groups should be rejected")
+	}
+}
@@ -0,0 +1,150 @@
+package gadget
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Pot is a potentiometer wired as a voltage divider to an analog pin.
+type Pot struct {
+	board *Board
+	pin   byte // Normal (not A0 style) pin number.
+	max   int  // Full scale raw reading.
+
+	// Output range, defaults to 0.0-1.0.
+	Min, Max float64
+
+	// Fraction of travel at each end that snaps to Min or Max,
+	// hiding pots that never quite reach their end stops.
+	DeadZone float64
+
+	// Exponential smoothing factor, from 0 (off) up to but not
+	// including 1 (heaviest).
+	Smoothing float64
+
+	// Minimum movement, as a fraction of full travel, needed to
+	// fire a change event. Defaults to 1%.
+	Threshold float64
+
+	// How often the pin is sampled while started.
+	Interval time.Duration
+
+	m       sync.Mutex
+	pos     float64 // Smoothed position, 0-1.
+	primed  bool    // Has pos been set by a reading.
+	last    float64 // Position of the last change event.
+	changes chan float64
+	stop    func()
+}
+
+// NewPot returns a Pot on the given analog pin, with analog
+// reporting turned on.
+func NewPot(b *Board, pin byte) (p *Pot, err error) {
+	bits, err := b.AnalogResolution(pin)
+	if err != nil {
+		return nil, err
+	}
+	if err = b.ensurePinMode(pin, ANALOG); err != nil {
+		return nil, err
+	}
+	if err = b.SetPinReporting(pin, true); err != nil {
+		return nil, err
+	}
+
+	p = &Pot{
+		board:     b,
+		pin:       pin,
+		max:       analogMax(bits),
+		Max:       1,
+		Threshold: 0.01,
+		Interval:  defaultSampleDelay,
+		changes:   make(chan float64, 1),
+	}
+	return
+}
+
+// Start samples the pot in the background, sending the scaled value
+// on Changes whenever it moves by more than Threshold.
+func (p *Pot) Start() (err error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if p.stop != nil {
+		return fmt.Errorf("Pot on pin %d already started", p.pin)
+	}
+	p.stop = poll(p.Interval, func() {
+		pos, err := p.sample()
+		if err != nil {
+			return
+		}
+
+		p.m.Lock()
+		moved := math.Abs(pos-p.last) >= p.Threshold
+		if moved {
+			p.last = pos
+		}
+		p.m.Unlock()
+
+		if moved {
+			sendLatest(p.changes, p.scale(pos))
+		}
+	})
+	return
+}
+
+// Halt stops background sampling.
+func (p *Pot) Halt() (err error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if p.stop != nil {
+		p.stop()
+		p.stop = nil
+	}
+	return
+}
+
+// Changes returns the channel change events are delivered on. Only
+// the latest value is kept if the reader falls behind.
+func (p *Pot) Changes() <-chan float64 {
+	return p.changes
+}
+
+// Value returns the current scaled value.
+func (p *Pot) Value() (v float64, err error) {
+	pos, err := p.sample()
+	if err != nil {
+		return 0, err
+	}
+	return p.scale(pos), nil
+}
+
+// Reads the pin and returns the smoothed position (0-1).
+func (p *Pot) sample() (pos float64, err error) {
+	raw, err := p.board.AnalogRead(p.pin)
+	if err != nil {
+		return 0, err
+	}
+
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	x := float64(raw) / float64(p.max)
+	if p.primed {
+		p.pos = p.Smoothing*p.pos + (1-p.Smoothing)*x
+	} else {
+		p.pos, p.last, p.primed = x, x, true
+	}
+	return p.pos, nil
+}
+
+// Applies the dead zone and maps a position (0-1) to the output range.
+func (p *Pot) scale(pos float64) float64 {
+	if dz := p.DeadZone; dz > 0 && dz < 0.5 {
+		pos = (pos - dz) / (1 - 2*dz)
+	}
+	pos = math.Max(0, math.Min(1, pos))
+	return p.Min + pos*(p.Max-p.Min)
+}
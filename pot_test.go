@@ -0,0 +1,35 @@
+package gadget
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPotScale(t *testing.T) {
+	p := &Pot{Min: -10, Max: 10, DeadZone: 0.1}
+	for pos, want := range map[float64]float64{0: -10, 0.05: -10, 0.1: -10, 0.5: 0, 0.7: 5, 0.95: 10, 1: 10} {
+		if got := p.scale(pos); math.Abs(got-want) > 1e-9 {
+			t.Errorf("scale(%.2f) = %f, want %f", pos, got, want)
+		}
+	}
+}
+
+func TestPotSmoothing(t *testing.T) {
+	b := newTestBoard(t, nil, map[byte][]Capability{14: {{ANALOG, 10}}}, nil)
+	p, err := NewPot(b, 14)
+	if err != nil {
+		t.Fatalf("NewPot: %s", err)
+	}
+	p.Min, p.Max, p.Smoothing = 0, 100, 0.5
+
+	// The first reading is taken as is, later ones move half way.
+	for _, tc := range []struct {
+		raw  int
+		want float64
+	}{{0, 0}, {1023, 50}, {1023, 75}, {0, 37.5}} {
+		b.pins[14].analogVal = tc.raw
+		if v, err := p.Value(); err != nil || math.Abs(v-tc.want) > 1e-9 {
+			t.Fatalf("Value after %d = %f, %v, want %f", tc.raw, v, err, tc.want)
+		}
+	}
+}
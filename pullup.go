@@ -0,0 +1,101 @@
+package gadget
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+// How long QueryPinState waits for the board's reply.
+const pinStateTimeout = time.Second
+
+// PinState is a pin's configuration as reported by the firmware.
+type PinState struct {
+	Mode byte
+
+	// For outputs the value last written. For INPUT pins whether the
+	// pull-up is on (1) or not (0); PULLUP pins always report 1.
+	State int
+}
+
+// QueryPinState asks the firmware for the pin's mode and state, rather
+// than trusting what this side last sent. The pin's pull-up flag, shown
+// in its PinInfo, is updated from the reply.
+func (b *Board) QueryPinState(pin byte) (s PinState, err error) {
+	b.m.RLock()
+	p, err := b.sendablePin(pin)
+	b.m.RUnlock()
+	if err != nil {
+		return s, err
+	}
+
+	b.pinStateMutex.Lock()
+	defer b.pinStateMutex.Unlock()
+
+	// Drop any stale reply left by a previous timed out query.
+	select {
+	case <-b.pinStates:
+	default:
+	}
+
+	if _, err = b.out.Write(firmatawire.QueryPinState(pin)); err != nil {
+		return s, err
+	}
+	if err = b.out.Flush(); err != nil {
+		return s, err
+	}
+
+	expired := time.After(pinStateTimeout)
+	for {
+		select {
+		case body := <-b.pinStates:
+			mode, state, ok := firmatawire.PinStateReply(body, pin)
+			if !ok {
+				continue
+			}
+			s = PinState{Mode: mode, State: state}
+
+			b.m.Lock()
+			if p.mode == mode && p.isInput() {
+				p.pullup = mode == PULLUP || state != 0
+			}
+			b.m.Unlock()
+			return s, nil
+		case <-expired:
+			return s, fmt.Errorf("Timed out waiting for state of pin %d", pin)
+		}
+	}
+}
+
+// Pullup reports whether the input's pull-up is on, asking the firmware
+// when the pin is in INPUT mode.
+func (b *Board) Pullup(pin byte) (bool, error) {
+	mode, err := b.PinMode(pin)
+	if err != nil {
+		return false, err
+	}
+	switch mode {
+	case PULLUP:
+		return true, nil
+	case INPUT:
+		s, err := b.QueryPinState(pin)
+		if err != nil {
+			return false, err
+		}
+		return s.Mode == PULLUP || (s.Mode == INPUT && s.State != 0), nil
+	}
+	return false, fmt.Errorf("Pin %d not in INPUT or PULLUP mode, got %s", pin, PinModeString[mode])
+}
+
+// Passes a pinStateResponse to a waiting QueryPinState.
+func (b *Board) handlePinStateResponse(m message) {
+	// Sysex start, cmd, pin, mode, state (1-5), end.
+	if len(m.data) < 6 {
+		return
+	}
+	select {
+	case b.pinStates <- append([]byte(nil), m.body()...):
+	default:
+	}
+}
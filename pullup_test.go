@@ -0,0 +1,46 @@
+package gadget
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+// Answers every pin state query as a board with pin 2's pull-up on.
+type pinStateResponder struct{ b *Board }
+
+func (r pinStateResponder) Write(p []byte) (int, error) {
+	if bytes.Equal(p, firmatawire.Sysex(pinStateQuery, 2)) {
+		go r.b.handleCallback(message{t: sysexMsg, data: firmatawire.Sysex(pinStateResponse, 2, INPUT, 1)})
+	}
+	return len(p), nil
+}
+
+func TestPullup(t *testing.T) {
+	r := &pinStateResponder{}
+	digital := []Capability{{INPUT, 1}, {OUTPUT, 1}, {PULLUP, 1}}
+	b := newTestBoard(t, r, nil, map[byte][]Capability{2: digital, 3: digital, 4: digital})
+	b.msgHandlers = b.coreHandlers()
+	r.b = b
+
+	b.SetPinMode(2, INPUT)
+	b.SetPinMode(3, PULLUP)
+	b.SetPinMode(4, OUTPUT)
+
+	if b.Pins()[0].Pullup {
+		t.Fatalf("Pin 2 pull-up known before querying")
+	}
+	if on, err := b.Pullup(2); err != nil || !on {
+		t.Fatalf("Pullup(2) = %t, %v, want true", on, err)
+	}
+	if !b.Pins()[0].Pullup {
+		t.Fatalf("Pin 2 pull-up not recorded")
+	}
+	if on, err := b.Pullup(3); err != nil || !on {
+		t.Fatalf("Pullup(3) = %t, %v, want true", on, err)
+	}
+	if _, err := b.Pullup(4); err == nil {
+		t.Fatalf("Expected error for OUTPUT pin")
+	}
+}
@@ -0,0 +1,59 @@
+package gadget
+
+import (
+	"fmt"
+	"time"
+)
+
+// PulseIn measures a pulse on a digital input, like the Arduino's
+// pulseIn: it waits for the pin to change to state, then back, and
+// returns the time between the two reports. It fails if the pulse has
+// not ended within timeout. The pin must be in INPUT or PULLUP mode
+// with reporting on.
+//
+// The pulse is timed from when the reports arrive, so it is only as
+// accurate as the board's loop and the serial link: a few milliseconds.
+// That is enough for switches and slow sensors, not for RC receivers or
+// ultrasonic rangers.
+func (b *Board) PulseIn(pin, state byte, timeout time.Duration) (d time.Duration, err error) {
+	mode, err := b.PinMode(pin)
+	if err != nil {
+		return 0, err
+	}
+	if mode != INPUT && mode != PULLUP {
+		return 0, fmt.Errorf("Pin %d not in INPUT or PULLUP mode, got %s", pin, PinModeString[mode])
+	}
+
+	sub := b.bus.Subscribe(PinTopic(pin, KindDigital), 8)
+	defer sub.Unsubscribe()
+	expired := time.After(timeout)
+
+	var start time.Time
+	for {
+		select {
+		case e := <-sub.C:
+			switch {
+			case start.IsZero() && byte(e.Value) == state:
+				start = e.Time
+			case !start.IsZero() && byte(e.Value) != state:
+				return e.Time.Sub(start), nil
+			}
+		case <-expired:
+			return 0, fmt.Errorf("Timed out waiting for a pulse on pin %d", pin)
+		}
+	}
+}
+
+// Calls fn with the time of every report of the pin changing to state,
+// until the returned subscription is unsubscribed.
+func (b *Board) onEdge(pin, state byte, fn func(at time.Time)) *Subscription {
+	sub := b.bus.Subscribe(PinTopic(pin, KindDigital), 256)
+	go func() {
+		for e := range sub.C {
+			if byte(e.Value) == state {
+				fn(e.Time)
+			}
+		}
+	}()
+	return sub
+}
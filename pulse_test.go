@@ -0,0 +1,37 @@
+package gadget
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPulseIn(t *testing.T) {
+	b := newTestBoard(t, nil, nil, map[byte][]Capability{2: {{INPUT, 1}, {OUTPUT, 1}}})
+	b.pins[2].mode = INPUT
+
+	type result struct {
+		d   time.Duration
+		err error
+	}
+	done := make(chan result)
+	go func() {
+		d, err := b.PulseIn(2, HIGH, time.Second)
+		done <- result{d, err}
+	}()
+	for subscribed := false; !subscribed; time.Sleep(time.Millisecond) {
+		b.bus.m.RLock()
+		subscribed = len(b.bus.subs) > 0
+		b.bus.m.RUnlock()
+	}
+
+	at := time.Now()
+	b.handleDigitalMessage(message{data: []byte{digitalMessage, 0x04, 0}, at: at})
+	b.handleDigitalMessage(message{data: []byte{digitalMessage, 0x00, 0}, at: at.Add(15 * time.Millisecond)})
+	if r := <-done; r.err != nil || r.d != 15*time.Millisecond {
+		t.Fatalf("PulseIn = %s, %v; want 15ms", r.d, r.err)
+	}
+
+	if _, err := b.PulseIn(2, HIGH, time.Millisecond); err == nil {
+		t.Fatalf("Expected a timeout")
+	}
+}
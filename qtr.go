@@ -0,0 +1,224 @@
+package gadget
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// Default sysex command of the QTR-RC firmware extension, from the
+	// range Firmata reserves for user-defined commands. RC sensors are
+	// read by timing a capacitor's discharge, which must be done on
+	// the board.
+	QTRSysex byte = 0x0C
+
+	// Sub commands.
+	qtrConfig byte = 0x01 // timeout (2 bytes), pins...
+	qtrRead   byte = 0x02
+	qtrData   byte = 0x03 // discharge time in µs of each sensor, 2 bytes each
+
+	// Discharge time read as fully dark.
+	qtrRCTimeout = 2500
+
+	// Calibrated readings run from 0 (white) to this.
+	qtrMaxCalibrated = 1000
+
+	// Calibrated readings below this are treated as noise when
+	// finding the line.
+	qtrNoise = 50
+
+	// At least one sensor must read above this for the line to count
+	// as seen.
+	qtrOnLine = 200
+)
+
+// LineSensor is a Pololu QTR style reflectance sensor array, for
+// following a line. Readings are higher over darker surfaces.
+type LineSensor struct {
+	read func() ([]int, error)
+	n    int
+
+	// Set to follow a white line on a dark surface.
+	WhiteLine bool
+
+	m       sync.Mutex
+	min     []int // Calibrated white level of each sensor.
+	max     []int // Calibrated black level of each sensor.
+	last    float64
+	replies chan []int
+}
+
+// NewQTRA returns an analog (QTR-A) array with a sensor on each of the
+// given analog pins, with analog reporting turned on.
+func NewQTRA(b *Board, pins []byte) (s *LineSensor, err error) {
+	for _, pin := range pins {
+		if err = b.ensurePinMode(pin, ANALOG); err != nil {
+			return nil, err
+		}
+		if err = b.SetPinReporting(pin, true); err != nil {
+			return nil, err
+		}
+	}
+
+	s = newLineSensor(len(pins))
+	s.read = func() (v []int, err error) {
+		v = make([]int, len(pins))
+		for i, pin := range pins {
+			if v[i], err = b.AnalogRead(pin); err != nil {
+				return nil, err
+			}
+		}
+		return
+	}
+	return
+}
+
+// NewQTRRC returns an RC (QTR-RC) array with a sensor on each of the
+// given digital pins, read through the firmware extension's sysex
+// command cmd (usually QTRSysex).
+func NewQTRRC(b *Board, pins []byte, cmd byte) (s *LineSensor, err error) {
+	if err = b.checkPins(pins...); err != nil {
+		return nil, err
+	}
+	s = newLineSensor(len(pins))
+	s.replies = make(chan []int, 1)
+	b.addHandler(cmd, s.handleReply)
+
+	msg := []byte{cmd, qtrConfig, qtrRCTimeout & 0x7F, (qtrRCTimeout >> 7) & 0x7F}
+	if _, err = b.sendSysex(append(msg, pins...)); err != nil {
+		return nil, err
+	}
+
+	s.read = func() (v []int, err error) {
+		select {
+		case <-s.replies:
+		default:
+		}
+		if _, err = b.sendSysex([]byte{cmd, qtrRead}); err != nil {
+			return nil, err
+		}
+		select {
+		case v = <-s.replies:
+			return v, nil
+		case <-time.After(time.Second):
+			return nil, fmt.Errorf("Timed out reading QTR-RC sensors")
+		}
+	}
+	return
+}
+
+func newLineSensor(n int) *LineSensor {
+	s := &LineSensor{
+		n:   n,
+		min: make([]int, n),
+		max: make([]int, n),
+	}
+	for i := range s.min {
+		s.min[i], s.max[i] = -1, -1
+	}
+	return s
+}
+
+// ReadRaw returns the uncalibrated reading of each sensor.
+func (s *LineSensor) ReadRaw() ([]int, error) {
+	return s.read()
+}
+
+// Calibrate records the lightest and darkest reading of each sensor
+// while the array is swept back and forth across the line for the
+// given duration. Calibration accumulates over calls.
+func (s *LineSensor) Calibrate(d time.Duration) (err error) {
+	for end := time.Now().Add(d); time.Now().Before(end); time.Sleep(defaultSampleDelay) {
+		v, err := s.read()
+		if err != nil {
+			return err
+		}
+
+		s.m.Lock()
+		for i, x := range v {
+			if s.min[i] < 0 || x < s.min[i] {
+				s.min[i] = x
+			}
+			if s.max[i] < 0 || x > s.max[i] {
+				s.max[i] = x
+			}
+		}
+		s.m.Unlock()
+	}
+	return
+}
+
+// ReadCalibrated returns each sensor's reading scaled between its
+// calibrated white (0) and black (1000) levels.
+func (s *LineSensor) ReadCalibrated() (v []int, err error) {
+	v, err = s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	for i, x := range v {
+		lo, hi := s.min[i], s.max[i]
+		if lo < 0 || hi <= lo {
+			return nil, fmt.Errorf("QTR sensor %d is not calibrated", i)
+		}
+		c := (x - lo) * qtrMaxCalibrated / (hi - lo)
+		v[i] = max(0, min(qtrMaxCalibrated, c))
+		if s.WhiteLine {
+			v[i] = qtrMaxCalibrated - v[i]
+		}
+	}
+	return
+}
+
+// Position returns the line's position under the array, from 0 (under
+// the first sensor) to 1000*(n-1) (under the last). If the line is
+// lost, the extreme it was last seen nearest is returned.
+func (s *LineSensor) Position() (pos float64, err error) {
+	v, err := s.ReadCalibrated()
+	if err != nil {
+		return 0, err
+	}
+
+	var sum, weighted float64
+	onLine := false
+	for i, x := range v {
+		onLine = onLine || x > qtrOnLine
+		if x > qtrNoise {
+			sum += float64(x)
+			weighted += float64(x) * float64(i*qtrMaxCalibrated)
+		}
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if !onLine {
+		if s.last < float64((s.n-1)*qtrMaxCalibrated)/2 {
+			return 0, nil
+		}
+		return float64((s.n - 1) * qtrMaxCalibrated), nil
+	}
+	s.last = weighted / sum
+	return s.last, nil
+}
+
+// Passes discharge times from the RC firmware extension to a waiting read.
+func (s *LineSensor) handleReply(m message) {
+	// Sysex start, cmd, sub cmd, 2 bytes per sensor, end.
+	if len(m.data) != 4+2*s.n || m.data[2] != qtrData {
+		return
+	}
+	v := make([]int, s.n)
+	for i := range v {
+		v[i] = int(m.data[3+2*i]) | int(m.data[4+2*i])<<7
+	}
+
+	select {
+	case s.replies <- v:
+	default:
+	}
+}
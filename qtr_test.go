@@ -0,0 +1,68 @@
+package gadget
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLineSensorPosition(t *testing.T) {
+	var raw []int
+	s := newLineSensor(3)
+	s.read = func() ([]int, error) { return append([]int(nil), raw...), nil }
+
+	raw = []int{100, 1100, 100}
+	if _, err := s.Position(); err == nil {
+		t.Fatalf("Expected an error before calibration")
+	}
+	s.min = []int{100, 100, 100}
+	s.max = []int{1100, 1100, 1100}
+
+	for _, tc := range []struct {
+		raw []int
+		pos float64
+	}{
+		{[]int{100, 1100, 100}, 1000},
+		{[]int{100, 600, 1100}, 5000.0 / 3},
+		{[]int{120, 120, 120}, 2000}, // Lost, last seen on the right.
+		{[]int{1100, 100, 50}, 0},
+		{[]int{120, 120, 120}, 0}, // Lost, last seen on the left.
+	} {
+		raw = tc.raw
+		if pos, err := s.Position(); err != nil || pos != tc.pos {
+			t.Fatalf("Position of %v = %v, %v, want %v", tc.raw, pos, err, tc.pos)
+		}
+	}
+
+	// A white line reads low.
+	s.WhiteLine = true
+	raw = []int{1100, 100, 1100}
+	if pos, err := s.Position(); err != nil || pos != 1000 {
+		t.Fatalf("White line Position = %v, %v, want 1000", pos, err)
+	}
+}
+
+func TestQTRRC(t *testing.T) {
+	var out bytes.Buffer
+	caps := []Capability{{INPUT, 1}, {OUTPUT, 1}}
+	b := newTestBoard(t, &out, nil, map[byte][]Capability{2: caps, 3: caps, 4: caps})
+	out.Reset()
+	s, err := NewQTRRC(b, []byte{2, 3, 4}, QTRSysex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{startSysex, QTRSysex, qtrConfig, 0x44, 0x13, 2, 3, 4, endSysex}; !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("NewQTRRC sent % X, want % X", out.Bytes(), want)
+	}
+
+	// Discharge times of 5µs, 300µs and the 2500µs timeout.
+	s.handleReply(message{t: sysexMsg, data: []byte{startSysex, QTRSysex, qtrData, 5, 0, 0x2C, 0x02, 0x44, 0x13, endSysex}})
+	if v := <-s.replies; v[0] != 5 || v[1] != 300 || v[2] != qtrRCTimeout {
+		t.Fatalf("Decoded %v, want [5 300 2500]", v)
+	}
+
+	// Replies for a different number of sensors are ignored.
+	s.handleReply(message{t: sysexMsg, data: []byte{startSysex, QTRSysex, qtrData, 5, 0, endSysex}})
+	if len(s.replies) != 0 {
+		t.Fatalf("Short reply was delivered")
+	}
+}
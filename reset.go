@@ -0,0 +1,81 @@
+package gadget
+
+import (
+	"sort"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+// Puts a board that has reset, e.g. by its reset button or the port's
+// DTR line, back the way it was configured: every pin's mode, output
+// value and reporting, and the sampling interval. TopicReset is
+// published once done. The pins are then queried again, in case a
+// different board is now on the port, see HardwareChange.
+func (b *Board) restore() {
+	b.log().Warn("Board reset, restoring its configuration")
+	sysex := func(m []byte) []byte { return firmatawire.Sysex(m[0], m[1:]...) }
+
+	b.m.RLock()
+	pins := make([]*pin, 0, len(b.pins))
+	for _, p := range b.pins {
+		pins = append(pins, p)
+	}
+	sort.Slice(pins, func(i, j int) bool { return pins[i].num < pins[j].num })
+
+	var msgs [][]byte
+	reported := make(map[byte]bool) // Digital ports already reporting.
+	for _, p := range pins {
+		if p.num >= addressablePins {
+			// Never set by this side, see QueryPinState.
+		} else if p.mode == SERVO && p.servoPulses[1] > 0 {
+			msgs = append(msgs, sysex(servoConfigMsg(p.num, p.servoPulses[0], p.servoPulses[1])))
+		} else {
+			msgs = append(msgs, firmatawire.SetMode(p.num, p.mode))
+		}
+
+		switch p.mode {
+		case PWM, SERVO:
+			msgs = append(msgs, firmatawire.AnalogWrite(p.num, p.analogVal))
+		case ANALOG:
+			if p.analogNum <= maxReportedAnalog {
+				msgs = append(msgs, firmatawire.ReportAnalogPin(p.analogNum, p.reporting))
+			}
+		case INPUT, PULLUP:
+			if p.reporting && !reported[p.port] {
+				msgs = append(msgs, firmatawire.ReportDigitalPort(p.port, true))
+				reported[p.port] = true
+			}
+		}
+	}
+
+	// Digital outputs are written a port at a time.
+	for port := range b.ports {
+		var mask byte
+		for i, p := range b.ports[port] {
+			if p != nil && p.mode == OUTPUT && p.digitalVal != LOW {
+				mask |= 1 << byte(i)
+			}
+		}
+		if mask != 0 {
+			msgs = append(msgs, firmatawire.DigitalWrite(byte(port), mask))
+		}
+	}
+
+	if b.sampling > 0 {
+		msgs = append(msgs, sysex(samplingIntervalMsg(b.sampling)))
+	}
+	b.m.RUnlock()
+
+	for _, msg := range msgs {
+		if _, err := b.out.Write(msg); err != nil {
+			b.reportError(err)
+			return
+		}
+	}
+	b.out.Flush()
+	b.bus.Publish(Event{Topic: TopicReset})
+
+	b.sendAnalogMappingQuery()
+	b.sendCapabilityQuery()
+	b.out.Flush()
+}
@@ -0,0 +1,45 @@
+package gadget
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+func TestRestoreAfterReset(t *testing.T) {
+	var out bytes.Buffer
+	digital := []Capability{{INPUT, 1}, {OUTPUT, 1}, {PWM, 8}}
+	b := newTestBoard(t, &out, map[byte][]Capability{14: {{ANALOG, 10}}}, map[byte][]Capability{3: digital, 4: digital})
+	b.msgHandlers = b.coreHandlers()
+
+	b.SetPinMode(3, PWM)
+	b.AnalogWrite(3, 100)
+	b.DigitalWrite(4, HIGH)
+	b.SetPinReporting(14, true)
+	b.SetSamplingInterval(50 * time.Millisecond)
+
+	sub := b.bus.Subscribe(TopicReset, 1)
+	out.Reset()
+	b.handleCallback(message{t: sysexMsg, data: firmatawire.Sysex(reportFirmware, 2, 5, 'S')})
+
+	var want []byte
+	want = append(want, firmatawire.SetMode(3, PWM)...)
+	want = append(want, firmatawire.AnalogWrite(3, 100)...)
+	want = append(want, firmatawire.SetMode(4, OUTPUT)...)
+	want = append(want, firmatawire.SetMode(14, ANALOG)...)
+	want = append(want, firmatawire.ReportAnalogPin(0, true)...)
+	want = append(want, firmatawire.DigitalWrite(0, 1<<4)...)
+	want = append(want, firmatawire.Sysex(samplingInterval, 50, 0)...)
+	want = append(want, firmatawire.Sysex(analogMappingQuery)...)
+	want = append(want, firmatawire.Sysex(capabilityQuery)...)
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("Restore wrote\n% X\nwant\n% X", out.Bytes(), want)
+	}
+	select {
+	case <-sub.C:
+	default:
+		t.Fatalf("Expected TopicReset")
+	}
+}
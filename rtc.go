@@ -0,0 +1,211 @@
+package gadget
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// Fixed I2C address of the DS3231 and DS1307.
+	RTCAddress byte = 0x68
+
+	rtcRegTime    byte = 0x00 // 7 bytes, seconds to year.
+	rtcRegAlarm1  byte = 0x07 // DS3231 only, 4 bytes.
+	rtcRegAlarm2  byte = 0x0B // DS3231 only, 3 bytes.
+	rtcRegControl byte = 0x0E // DS3231 only.
+	rtcRegStatus  byte = 0x0F // DS3231 only.
+
+	rtcClockHalt byte = 0x80 // DS1307 seconds register.
+	rtc12Hour    byte = 0x40 // Hours register.
+	rtcPM        byte = 0x20 // Hours register, in 12 hour mode.
+	rtcCentury   byte = 0x80 // DS3231 month register.
+	rtcAlarmMask byte = 0x80 // AxMy bits of the alarm registers.
+	rtcAlarmDay  byte = 0x40 // DY/DT bit, match day of week.
+	rtcINTCN     byte = 0x04 // Control register, alarms drive INT/SQW.
+)
+
+// AlarmMatch selects which parts of the alarm time must match the
+// clock for a DS3231 alarm to fire.
+type AlarmMatch byte
+
+const (
+	AlarmEvery   AlarmMatch = iota // Every second (alarm 1) or minute (alarm 2).
+	AlarmSeconds                   // Seconds match. Alarm 1 only.
+	AlarmMinutes                   // Minutes (and seconds) match.
+	AlarmHours                     // Hours, minutes (and seconds) match.
+	AlarmDate                      // Day of month and time match.
+	AlarmWeekday                   // Day of week and time match.
+)
+
+// RTC is a Maxim DS3231 or DS1307 real time clock.
+//
+// The clock does not store a time zone, times are read and written
+// as wall clock time in Location.
+type RTC struct {
+	board  *Board
+	ds3231 bool
+
+	// Defaults to time.Local.
+	Location *time.Location
+}
+
+// NewDS3231 returns the DS3231. I2CConfig must already have been called.
+func NewDS3231(b *Board) *RTC {
+	return &RTC{board: b, ds3231: true, Location: time.Local}
+}
+
+// NewDS1307 returns the DS1307. I2CConfig must already have been called.
+func NewDS1307(b *Board) *RTC {
+	return &RTC{board: b, Location: time.Local}
+}
+
+// Now returns the clock's current time.
+func (r *RTC) Now() (t time.Time, err error) {
+	d, err := r.board.I2CReadRegister(RTCAddress, rtcRegTime, 7)
+	if err != nil {
+		return t, err
+	}
+	if !r.ds3231 && d[0]&rtcClockHalt != 0 {
+		return t, fmt.Errorf("DS1307 clock is halted, it must be Set first")
+	}
+	return decodeRTCTime(d, r.Location), nil
+}
+
+// Set sets the clock, starting it if it was halted.
+func (r *RTC) Set(t time.Time) (err error) {
+	t = t.In(r.Location)
+	if t.Year() < 2000 || t.Year() > 2199 || (!r.ds3231 && t.Year() > 2099) {
+		return fmt.Errorf("Year %d out of range for RTC", t.Year())
+	}
+	return r.board.I2CWrite(RTCAddress, append([]byte{rtcRegTime}, encodeRTCTime(t)...))
+}
+
+// SetAlarm sets DS3231 alarm 1 or 2 to fire at t, comparing only the
+// fields selected by match. The INT/SQW pin is driven low while the
+// alarm is pending.
+func (r *RTC) SetAlarm(n int, t time.Time, match AlarmMatch) (err error) {
+	if !r.ds3231 {
+		return fmt.Errorf("Alarms are only supported by the DS3231")
+	}
+	if n != 1 && n != 2 {
+		return fmt.Errorf("Invalid alarm: %d", n)
+	}
+	if n == 2 && match == AlarmSeconds {
+		return fmt.Errorf("Alarm 2 cannot match seconds")
+	}
+	t = t.In(r.Location)
+
+	regs := []byte{
+		toBCD(byte(t.Second())),
+		toBCD(byte(t.Minute())),
+		toBCD(byte(t.Hour())),
+		toBCD(byte(t.Day())),
+	}
+	if match == AlarmWeekday {
+		regs[3] = byte(t.Weekday()) + 1 | rtcAlarmDay
+	}
+
+	// Set the mask bit of every field that is not compared.
+	masked := map[AlarmMatch]int{
+		AlarmEvery:   4,
+		AlarmSeconds: 3,
+		AlarmMinutes: 2,
+		AlarmHours:   1,
+	}[match]
+	for i := 4 - masked; i < 4; i++ {
+		regs[i] |= rtcAlarmMask
+	}
+
+	reg := rtcRegAlarm1
+	if n == 2 {
+		reg, regs = rtcRegAlarm2, regs[1:] // Alarm 2 has no seconds.
+	}
+	if err = r.board.I2CWrite(RTCAddress, append([]byte{reg}, regs...)); err != nil {
+		return err
+	}
+	if err = r.ClearAlarm(n); err != nil {
+		return err
+	}
+
+	ctrl, err := r.board.I2CReadRegister(RTCAddress, rtcRegControl, 1)
+	if err != nil {
+		return err
+	}
+	return r.board.I2CWrite(RTCAddress, []byte{rtcRegControl, ctrl[0] | rtcINTCN | 1<<uint(n-1)})
+}
+
+// AlarmFired reports whether DS3231 alarm 1 or 2 has fired since it
+// was last cleared.
+func (r *RTC) AlarmFired(n int) (fired bool, err error) {
+	if !r.ds3231 {
+		return false, fmt.Errorf("Alarms are only supported by the DS3231")
+	}
+	s, err := r.board.I2CReadRegister(RTCAddress, rtcRegStatus, 1)
+	if err != nil {
+		return false, err
+	}
+	return s[0]&(1<<uint(n-1)) != 0, nil
+}
+
+// ClearAlarm clears the fired flag of DS3231 alarm 1 or 2, releasing
+// the INT/SQW pin.
+func (r *RTC) ClearAlarm(n int) (err error) {
+	if !r.ds3231 {
+		return fmt.Errorf("Alarms are only supported by the DS3231")
+	}
+	s, err := r.board.I2CReadRegister(RTCAddress, rtcRegStatus, 1)
+	if err != nil {
+		return err
+	}
+	return r.board.I2CWrite(RTCAddress, []byte{rtcRegStatus, s[0] &^ (1 << uint(n-1))})
+}
+
+// Decodes the seven time registers.
+func decodeRTCTime(d []byte, loc *time.Location) time.Time {
+	sec := fromBCD(d[0] &^ rtcClockHalt)
+	min := fromBCD(d[1])
+
+	var hour byte
+	if d[2]&rtc12Hour != 0 {
+		hour = fromBCD(d[2]&0x1F) % 12
+		if d[2]&rtcPM != 0 {
+			hour += 12
+		}
+	} else {
+		hour = fromBCD(d[2] & 0x3F)
+	}
+
+	day := fromBCD(d[4])
+	month := fromBCD(d[5] &^ rtcCentury)
+	year := 2000 + int(fromBCD(d[6]))
+	if d[5]&rtcCentury != 0 {
+		year += 100
+	}
+
+	return time.Date(year, time.Month(month), int(day), int(hour), int(min), int(sec), 0, loc)
+}
+
+// Encodes t as the seven time registers, in 24 hour mode.
+func encodeRTCTime(t time.Time) []byte {
+	month := toBCD(byte(t.Month()))
+	if t.Year() >= 2100 {
+		month |= rtcCentury
+	}
+	return []byte{
+		toBCD(byte(t.Second())),
+		toBCD(byte(t.Minute())),
+		toBCD(byte(t.Hour())),
+		byte(t.Weekday()) + 1,
+		toBCD(byte(t.Day())),
+		month,
+		toBCD(byte(t.Year() % 100)),
+	}
+}
+
+func toBCD(n byte) byte {
+	return (n/10)<<4 | n%10
+}
+
+func fromBCD(b byte) byte {
+	return (b>>4)*10 + b&0x0F
+}
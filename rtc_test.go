@@ -0,0 +1,33 @@
+package gadget
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestRTCTimeEncoding(t *testing.T) {
+	tm := time.Date(2107, time.December, 31, 23, 59, 58, 0, time.UTC)
+	regs := encodeRTCTime(tm)
+
+	want := []byte{0x58, 0x59, 0x23, byte(tm.Weekday()) + 1, 0x31, 0x92, 0x07}
+	if !bytes.Equal(regs, want) {
+		t.Fatalf("encodeRTCTime = % X, want % X", regs, want)
+	}
+	if got := decodeRTCTime(regs, time.UTC); !got.Equal(tm) {
+		t.Fatalf("decodeRTCTime = %s, want %s", got, tm)
+	}
+}
+
+func TestRTCDecode12Hour(t *testing.T) {
+	// 12:30:00 AM and 12:30:00 PM, on 2024-03-05.
+	am := []byte{0x00, 0x30, rtc12Hour | 0x12, 3, 0x05, 0x03, 0x24}
+	pm := []byte{0x00, 0x30, rtc12Hour | rtcPM | 0x12, 3, 0x05, 0x03, 0x24}
+
+	if h := decodeRTCTime(am, time.UTC).Hour(); h != 0 {
+		t.Errorf("12 AM decoded as hour %d", h)
+	}
+	if h := decodeRTCTime(pm, time.UTC).Hour(); h != 12 {
+		t.Errorf("12 PM decoded as hour %d", h)
+	}
+}
@@ -0,0 +1,452 @@
+// Package rules runs simple automations on a gadget.Board: when a
+// condition on an input holds, actions are applied to outputs.
+//
+// Rules are plain data so they can be saved and loaded as JSON:
+//
+//	{
+//	  "name": "lights",
+//	  "when": {"pin": 14, "below": 300},
+//	  "then": [{"action": "write", "pin": 13, "value": 1}]
+//	}
+//
+// A condition is either on a pin or a schedule:
+//
+//	above, below  The pin's value is above and/or below a threshold.
+//	edge          The pin changes: "rising", "falling" or "change".
+//	every         Repeatedly, e.g. "30s".
+//	at            Daily at a local time, e.g. "07:30".
+//
+// Threshold rules fire once when their condition becomes true and again
+// only after it has been false. Actions are:
+//
+//	write   Write value to pin according to its mode.
+//	pulse   Write value to pin, then 0 after duration.
+//	notify  Pass message to the Engine's Notify func.
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ZachMassia/GoGoGadget"
+)
+
+// Edges a condition can trigger on.
+const (
+	EdgeRising  = "rising"
+	EdgeFalling = "falling"
+	EdgeChange  = "change"
+)
+
+// Action types.
+const (
+	ActionWrite  = "write"
+	ActionPulse  = "pulse"
+	ActionNotify = "notify"
+)
+
+// Duration is a time.Duration written as a string such as "500ms" in
+// JSON.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	*d = Duration(v)
+	return err
+}
+
+// Condition triggers a rule.
+type Condition struct {
+	Pin   byte   `json:"pin"`
+	Above *int   `json:"above,omitempty"`
+	Below *int   `json:"below,omitempty"`
+	Edge  string `json:"edge,omitempty"`
+
+	Every Duration `json:"every,omitempty"`
+	At    string   `json:"at,omitempty"` // "15:04" local time.
+}
+
+// Action is applied when a rule fires.
+type Action struct {
+	Action   string   `json:"action"`
+	Pin      byte     `json:"pin,omitempty"`
+	Value    int      `json:"value,omitempty"`
+	Duration Duration `json:"duration,omitempty"`
+	Message  string   `json:"message,omitempty"`
+}
+
+// Rule applies its actions when its condition triggers.
+type Rule struct {
+	Name string    `json:"name"`
+	When Condition `json:"when"`
+	Then []Action  `json:"then"`
+}
+
+// Validate checks that the rule is complete and unambiguous.
+func (r Rule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("Rule has no name")
+	}
+
+	c := r.When
+	onPin := c.Above != nil || c.Below != nil || c.Edge != ""
+	scheduled := c.Every != 0 || c.At != ""
+	switch {
+	case onPin == scheduled:
+		return fmt.Errorf("Rule '%s' needs either a pin or a schedule condition", r.Name)
+	case c.Edge != "" && (c.Above != nil || c.Below != nil):
+		return fmt.Errorf("Rule '%s' mixes an edge and a threshold", r.Name)
+	case c.Edge != "" && c.Edge != EdgeRising && c.Edge != EdgeFalling && c.Edge != EdgeChange:
+		return fmt.Errorf("Rule '%s' has unknown edge '%s'", r.Name, c.Edge)
+	case c.Every < 0 || (c.Every != 0 && c.At != ""):
+		return fmt.Errorf("Rule '%s' has an invalid schedule", r.Name)
+	}
+	if c.At != "" {
+		if _, err := time.Parse("15:04", c.At); err != nil {
+			return fmt.Errorf("Rule '%s' has invalid time '%s'", r.Name, c.At)
+		}
+	}
+
+	if len(r.Then) == 0 {
+		return fmt.Errorf("Rule '%s' has no actions", r.Name)
+	}
+	for _, a := range r.Then {
+		switch a.Action {
+		case ActionWrite, ActionNotify:
+		case ActionPulse:
+			if a.Duration <= 0 {
+				return fmt.Errorf("Rule '%s' pulse needs a duration", r.Name)
+			}
+		default:
+			return fmt.Errorf("Rule '%s' has unknown action '%s'", r.Name, a.Action)
+		}
+	}
+	return nil
+}
+
+// Evaluation state of a rule.
+type state struct {
+	seen   bool // A value has been read.
+	last   int
+	active bool      // The threshold or edge condition held last time.
+	next   time.Time // When a scheduled rule next fires.
+}
+
+// Reports whether the pin condition fires for the value v.
+func (c Condition) fires(s *state, v int) (fire bool) {
+	switch c.Edge {
+	case EdgeRising:
+		fire = s.seen && s.last == 0 && v != 0
+	case EdgeFalling:
+		fire = s.seen && s.last != 0 && v == 0
+	case EdgeChange:
+		fire = s.seen && s.last != v
+	default:
+		active := (c.Above == nil || v > *c.Above) && (c.Below == nil || v < *c.Below)
+		fire = active && !s.active
+		s.active = active
+	}
+	s.seen, s.last = true, v
+	return
+}
+
+// Returns when a scheduled condition next fires after now.
+func (c Condition) nextRun(now time.Time) time.Time {
+	if c.Every > 0 {
+		return now.Add(time.Duration(c.Every))
+	}
+	at, _ := time.Parse("15:04", c.At)
+	next := time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// Engine evaluates rules against a board.
+type Engine struct {
+	board *gadget.Board
+
+	// Called by notify actions. Defaults to logging the message with
+	// the board's logger.
+	Notify func(r Rule, message string)
+
+	// Called with errors from reading pins and applying actions.
+	OnError func(error)
+
+	m     sync.Mutex
+	rules []Rule
+	state map[string]*state
+	stop  chan bool
+	added chan bool // Wakes the running engine for new rules.
+}
+
+// NewEngine returns an Engine with no rules for the board.
+func NewEngine(b *gadget.Board) *Engine {
+	return &Engine{
+		board: b,
+		state: make(map[string]*state),
+		added: make(chan bool, 1),
+	}
+}
+
+// Add adds a rule, replacing any rule with the same name.
+func (e *Engine) Add(r Rule) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	e.remove(r.Name)
+	e.rules = append(e.rules, r)
+	s := &state{}
+	if r.When.Every != 0 || r.When.At != "" {
+		s.next = r.When.nextRun(time.Now())
+	}
+	e.state[r.Name] = s
+
+	select {
+	case e.added <- true:
+	default:
+	}
+	return nil
+}
+
+// Remove removes the named rule.
+func (e *Engine) Remove(name string) {
+	e.m.Lock()
+	defer e.m.Unlock()
+	e.remove(name)
+}
+
+func (e *Engine) remove(name string) {
+	for i, r := range e.rules {
+		if r.Name == name {
+			e.rules = append(e.rules[:i], e.rules[i+1:]...)
+			delete(e.state, name)
+			return
+		}
+	}
+}
+
+// Rules returns a copy of the engine's rules.
+func (e *Engine) Rules() []Rule {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return append([]Rule(nil), e.rules...)
+}
+
+// Save writes the rules as JSON.
+func (e *Engine) Save(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(e.Rules())
+}
+
+// Load adds the rules in JSON written by Save. No rules are added if
+// any are invalid.
+func (e *Engine) Load(r io.Reader) error {
+	var rules []Rule
+	if err := json.NewDecoder(r).Decode(&rules); err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, rule := range rules {
+		e.Add(rule)
+	}
+	return nil
+}
+
+// Start begins evaluating the rules in the background. Pin rules are
+// evaluated each time the board publishes a change to their pin, and
+// once when started or added.
+func (e *Engine) Start() (err error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	if e.stop != nil {
+		return fmt.Errorf("Rules engine already started")
+	}
+	e.stop = make(chan bool)
+	// Subscribe before the first evaluation so no change is missed.
+	go e.run(e.stop, e.board.Events().Subscribe("pin/#", 64))
+	return
+}
+
+// Halt stops evaluating the rules.
+func (e *Engine) Halt() (err error) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	if e.stop != nil {
+		close(e.stop)
+		e.stop = nil
+	}
+	return
+}
+
+func (e *Engine) run(stop chan bool, sub *gadget.Subscription) {
+	defer sub.Unsubscribe()
+
+	t := time.NewTimer(0)
+	defer t.Stop()
+
+	e.readPins()
+	for {
+		// Wait for the next scheduled rule, if any.
+		if !t.Stop() {
+			select {
+			case <-t.C:
+			default:
+			}
+		}
+		var due <-chan time.Time
+		if next := e.nextDue(); !next.IsZero() {
+			t.Reset(time.Until(next))
+			due = t.C
+		}
+
+		select {
+		case <-stop:
+			return
+		case ev := <-sub.C:
+			e.handle(ev)
+		case <-e.added:
+			e.readPins()
+		case now := <-due:
+			e.runDue(now)
+		}
+	}
+}
+
+// Evaluates the rules on a pin event's pin. Mode changes change what
+// the pin's value means, so it is read again.
+func (e *Engine) handle(ev gadget.Event) {
+	v := ev.Value
+	if strings.HasSuffix(ev.Topic, "/"+gadget.KindMode) {
+		_, val, err := e.board.ReadValue(ev.Pin)
+		if err != nil {
+			e.reportError(err)
+			return
+		}
+		v = val
+	}
+	e.evaluate(func(c Condition) (int, bool) { return v, c.Pin == ev.Pin })
+}
+
+// Evaluates every pin rule with its pin's current value.
+func (e *Engine) readPins() {
+	e.evaluate(func(c Condition) (int, bool) {
+		_, v, err := e.board.ReadValue(c.Pin)
+		if err != nil {
+			e.reportError(err)
+			return 0, false
+		}
+		return v, true
+	})
+}
+
+// Evaluates the pin rules for which value returns a value, applying the
+// actions of those that fire.
+func (e *Engine) evaluate(value func(Condition) (int, bool)) {
+	var fired []Rule
+
+	e.m.Lock()
+	for _, r := range e.rules {
+		c := r.When
+		if c.Every != 0 || c.At != "" {
+			continue
+		}
+		if v, ok := value(c); ok && c.fires(e.state[r.Name], v) {
+			fired = append(fired, r)
+		}
+	}
+	e.m.Unlock()
+
+	e.applyAll(fired)
+}
+
+// Returns when the next scheduled rule is due, or the zero time if
+// there are none.
+func (e *Engine) nextDue() (next time.Time) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	for _, r := range e.rules {
+		s := e.state[r.Name]
+		if !s.next.IsZero() && (next.IsZero() || s.next.Before(next)) {
+			next = s.next
+		}
+	}
+	return
+}
+
+// Fires the scheduled rules due at now.
+func (e *Engine) runDue(now time.Time) {
+	var fired []Rule
+
+	e.m.Lock()
+	for _, r := range e.rules {
+		s := e.state[r.Name]
+		if !s.next.IsZero() && !now.Before(s.next) {
+			s.next = r.When.nextRun(now)
+			fired = append(fired, r)
+		}
+	}
+	e.m.Unlock()
+
+	e.applyAll(fired)
+}
+
+func (e *Engine) applyAll(fired []Rule) {
+	for _, r := range fired {
+		for _, a := range r.Then {
+			e.apply(r, a)
+		}
+	}
+}
+
+func (e *Engine) apply(r Rule, a Action) {
+	switch a.Action {
+	case ActionWrite:
+		e.write(a.Pin, a.Value)
+	case ActionPulse:
+		e.write(a.Pin, a.Value)
+		time.AfterFunc(time.Duration(a.Duration), func() { e.write(a.Pin, 0) })
+	case ActionNotify:
+		if e.Notify != nil {
+			e.Notify(r, a.Message)
+		} else {
+			e.board.Logger().Info("Rule notification", "rule", r.Name, "message", a.Message)
+		}
+	}
+}
+
+func (e *Engine) write(pin byte, v int) {
+	if err := e.board.WriteValue(pin, v); err != nil {
+		e.reportError(err)
+	}
+}
+
+func (e *Engine) reportError(err error) {
+	if e.OnError != nil {
+		e.OnError(err)
+	}
+}
@@ -0,0 +1,132 @@
+package rules
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ZachMassia/GoGoGadget"
+)
+
+func TestConditionFires(t *testing.T) {
+	above := 500
+	tests := []struct {
+		name   string
+		c      Condition
+		values []int
+		want   []bool
+	}{
+		{"threshold", Condition{Above: &above}, []int{600, 700, 400, 501}, []bool{true, false, false, true}},
+		{"rising", Condition{Edge: EdgeRising}, []int{1, 0, 1, 1}, []bool{false, false, true, false}},
+		{"falling", Condition{Edge: EdgeFalling}, []int{1, 0, 0, 1}, []bool{false, true, false, false}},
+		{"change", Condition{Edge: EdgeChange}, []int{5, 5, 6, 5}, []bool{false, false, true, true}},
+	}
+	for _, tt := range tests {
+		s := &state{}
+		for i, v := range tt.values {
+			if got := tt.c.fires(s, v); got != tt.want[i] {
+				t.Errorf("%s: value %d at %d fired = %v, want %v", tt.name, v, i, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	now := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	c := Condition{At: "07:30"}
+	if got, want := c.nextRun(now), time.Date(2024, 3, 2, 7, 30, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("nextRun(07:30) = %s, want %s", got, want)
+	}
+	c = Condition{At: "09:15"}
+	if got, want := c.nextRun(now), time.Date(2024, 3, 1, 9, 15, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("nextRun(09:15) = %s, want %s", got, want)
+	}
+	c = Condition{Every: Duration(time.Minute)}
+	if got, want := c.nextRun(now), now.Add(time.Minute); !got.Equal(want) {
+		t.Errorf("nextRun(every 1m) = %s, want %s", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	below := 10
+	bad := []Rule{
+		{Then: []Action{{Action: ActionWrite}}},
+		{Name: "none", Then: []Action{{Action: ActionWrite}}},
+		{Name: "both", When: Condition{Below: &below, At: "07:00"}, Then: []Action{{Action: ActionWrite}}},
+		{Name: "edge", When: Condition{Edge: "sideways"}, Then: []Action{{Action: ActionWrite}}},
+		{Name: "time", When: Condition{At: "25:00"}, Then: []Action{{Action: ActionWrite}}},
+		{Name: "pulse", When: Condition{Edge: EdgeRising}, Then: []Action{{Action: ActionPulse}}},
+		{Name: "empty", When: Condition{Edge: EdgeRising}},
+	}
+	for _, r := range bad {
+		if r.Validate() == nil {
+			t.Errorf("Rule %+v should be invalid", r)
+		}
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	below := 300
+	e := NewEngine(nil)
+	rule := Rule{
+		Name: "lights",
+		When: Condition{Pin: 14, Below: &below},
+		Then: []Action{{Action: ActionPulse, Pin: 13, Value: 1, Duration: Duration(time.Second)}},
+	}
+	if err := e.Add(rule); err != nil {
+		t.Fatalf("Add returned error: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err := e.Save(&buf); err != nil {
+		t.Fatalf("Save returned error: %s", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"duration": "1s"`)) {
+		t.Errorf("Durations should be saved as strings:\n%s", buf.String())
+	}
+
+	loaded := NewEngine(nil)
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatalf("Load returned error: %s", err)
+	}
+	got := loaded.Rules()
+	if len(got) != 1 || got[0].Name != "lights" || *got[0].When.Below != 300 || got[0].Then[0].Duration != Duration(time.Second) {
+		t.Fatalf("Loaded rules = %+v", got)
+	}
+}
+
+func TestEngineEvents(t *testing.T) {
+	var notes []string
+	e := NewEngine(nil)
+	e.Notify = func(r Rule, msg string) { notes = append(notes, msg) }
+	e.Add(Rule{Name: "button", When: Condition{Pin: 2, Edge: EdgeRising}, Then: []Action{{Action: ActionNotify, Message: "pressed"}}})
+
+	// Every event is evaluated, so a short press between two others
+	// isn't missed.
+	for _, v := range []int{0, 1, 0, 1, 1} {
+		e.handle(gadget.Event{Topic: gadget.PinTopic(2, gadget.KindDigital), Pin: 2, Value: v})
+	}
+	e.handle(gadget.Event{Topic: gadget.PinTopic(3, gadget.KindDigital), Pin: 3, Value: 0})
+	e.handle(gadget.Event{Topic: gadget.PinTopic(3, gadget.KindDigital), Pin: 3, Value: 1})
+	if len(notes) != 2 {
+		t.Fatalf("Expected 2 notifications, got %v", notes)
+	}
+}
+
+func TestEngineSchedule(t *testing.T) {
+	var notes []string
+	e := NewEngine(nil)
+	e.Notify = func(r Rule, msg string) { notes = append(notes, msg) }
+	e.Add(Rule{Name: "tick", When: Condition{Every: Duration(time.Minute)}, Then: []Action{{Action: ActionNotify, Message: "tick"}}})
+
+	next := e.nextDue()
+	if until := time.Until(next); until <= 0 || until > time.Minute {
+		t.Fatalf("Expected the rule due within a minute, got %s", next)
+	}
+	e.runDue(next.Add(-time.Second))
+	e.runDue(next)
+	if len(notes) != 1 || !e.nextDue().Equal(next.Add(time.Minute)) {
+		t.Fatalf("Expected one notification and the next a minute later, got %v, %s", notes, e.nextDue())
+	}
+}
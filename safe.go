@@ -0,0 +1,132 @@
+package gadget
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+)
+
+// SetSafeValue sets the value written to the pin when the board is
+// closed or WriteSafe is called, e.g. 0 to turn off a heater's relay or
+// stop a motor. The value is written according to the pin's mode at the
+// time: OUTPUT pins take 0 or 1, PWM pins 0-255 and SERVO pins an angle
+// or pulse width. Pins in other modes are left alone.
+func (b *Board) SetSafeValue(pin byte, v int) error {
+	if v < 0 {
+		return fmt.Errorf("Invalid safe value: %d", v)
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	p, ok := b.pins[pin]
+	if !ok {
+		return fmt.Errorf("Invalid pin: %d", pin)
+	}
+	p.safe = &v
+	return nil
+}
+
+// ClearSafeValue removes the pin's safe value.
+func (b *Board) ClearSafeValue(pin byte) error {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	p, ok := b.pins[pin]
+	if !ok {
+		return fmt.Errorf("Invalid pin: %d", pin)
+	}
+	p.safe = nil
+	return nil
+}
+
+// WriteSafe writes every pin's safe value, in pin order, and flushes
+// them to the queue. Every pin is attempted; the first error is
+// returned.
+func (b *Board) WriteSafe() (err error) {
+	type safeValue struct {
+		pin, mode byte
+		v         int
+	}
+	var vals []safeValue
+
+	b.m.RLock()
+	for n, p := range b.pins {
+		if p.safe != nil {
+			vals = append(vals, safeValue{n, p.mode, *p.safe})
+		}
+	}
+	b.m.RUnlock()
+	sort.Slice(vals, func(i, j int) bool { return vals[i].pin < vals[j].pin })
+
+	for _, s := range vals {
+		var werr error
+		switch s.mode {
+		case OUTPUT:
+			werr = b.DigitalWrite(s.pin, byte(min(s.v, 1)))
+		case PWM:
+			werr = b.AnalogWrite(s.pin, byte(min(s.v, 255)))
+		case SERVO:
+			werr = b.ServoWrite(s.pin, s.v)
+		default:
+			continue
+		}
+		if werr != nil {
+			b.log().Warn("Could not write safe value", "pin", s.pin, "err", werr)
+			if err == nil {
+				err = werr
+			}
+		}
+	}
+	if ferr := b.out.Flush(); err == nil {
+		err = ferr
+	}
+	return
+}
+
+// SafeOnSignal closes the board, writing the safe values, when one of
+// sigs arrives, then raises the signal again so the program exits as
+// it would have. With no sigs, SIGINT and SIGTERM are caught. Call the
+// returned function to stop catching them.
+func (b *Board) SafeOnSignal(sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	c := make(chan os.Signal, 1)
+	done := make(chan bool)
+	signal.Notify(c, sigs...)
+
+	go func() {
+		select {
+		case <-done:
+			return
+		case sig := <-c:
+			b.log().Warn("Closing board on signal", "signal", sig)
+			b.Close()
+			signal.Stop(c)
+			if p, err := os.FindProcess(os.Getpid()); err != nil || p.Signal(sig) != nil {
+				os.Exit(1)
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(c)
+		close(done)
+	}
+}
+
+// SafeOnPanic closes the board, writing the safe values, if the calling
+// goroutine panics, then panics again. Defer it at the top of main and
+// of any goroutine driving outputs:
+//
+//	defer b.SafeOnPanic()
+func (b *Board) SafeOnPanic() {
+	if r := recover(); r != nil {
+		b.log().Error("Closing board on panic", "panic", r)
+		b.Close()
+		panic(r)
+	}
+}
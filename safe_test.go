@@ -0,0 +1,44 @@
+package gadget
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteSafe(t *testing.T) {
+	var out bytes.Buffer
+	b := newTestBoard(t, &out, nil, map[byte][]Capability{
+		3: {{OUTPUT, 1}, {PWM, 8}},
+		4: {{INPUT, 1}, {OUTPUT, 1}},
+		5: {{INPUT, 1}, {OUTPUT, 1}},
+	})
+	b.pins[3].mode = PWM
+	b.pins[3].analogVal = 200
+	b.pins[4].digitalVal = HIGH
+	b.pins[5].mode = INPUT
+
+	for _, n := range []byte{3, 4, 5} {
+		if err := b.SetSafeValue(n, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.SetSafeValue(9, 0); err == nil {
+		t.Fatalf("Missing pin should be rejected")
+	}
+
+	out.Reset()
+	if err := b.WriteSafe(); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{analogMessage | 3, 0, 0, digitalMessage, 0, 0}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("WriteSafe wrote %X, want %X", out.Bytes(), want)
+	}
+
+	b.ClearSafeValue(3)
+	out.Reset()
+	b.WriteSafe()
+	if want = want[3:]; !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("Cleared pin was written: %X", out.Bytes())
+	}
+}
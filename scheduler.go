@@ -0,0 +1,210 @@
+package gadget
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Task is a function scheduled on a Board. Tasks stop when the board
+// is closed.
+type Task struct {
+	board *Board
+	quit  chan bool
+	once  sync.Once
+}
+
+// Stop cancels the task. A run already in progress is not interrupted.
+func (t *Task) Stop() {
+	t.once.Do(func() {
+		close(t.quit)
+		t.board.tm.Lock()
+		delete(t.board.tasks, t)
+		t.board.tm.Unlock()
+	})
+}
+
+// Every calls fn every d until the task is stopped. Runs never overlap:
+// if fn takes longer than d the next run starts as soon as it returns.
+func (b *Board) Every(d time.Duration, fn func()) *Task {
+	return b.schedule(func(last time.Time) time.Time { return last.Add(d) }, fn, false)
+}
+
+// After calls fn once after d, unless the task is stopped first.
+func (b *Board) After(d time.Duration, fn func()) *Task {
+	return b.schedule(func(start time.Time) time.Time { return start.Add(d) }, fn, true)
+}
+
+// Cron calls fn at the times matching a cron expression in local time.
+// The expression has the standard five fields, minute, hour, day of
+// month, month and day of week, each a '*', a number, a range "1-5", a
+// list "1,15" or a step "*/10":
+//
+//	b.Cron("30 7 * * 1-5", fn) // 7:30 on weekdays.
+func (b *Board) Cron(expr string, fn func()) (*Task, error) {
+	s, err := parseCron(expr)
+	if err != nil {
+		return nil, err
+	}
+	return b.schedule(s.next, fn, false), nil
+}
+
+// Runs fn at the times returned by next until stopped, or only once.
+// Next is given the previous run's scheduled time.
+func (b *Board) schedule(next func(time.Time) time.Time, fn func(), once bool) *Task {
+	t := &Task{board: b, quit: make(chan bool)}
+
+	b.tm.Lock()
+	if b.tasks == nil {
+		b.tasks = make(map[*Task]bool)
+	}
+	b.tasks[t] = true
+	b.tm.Unlock()
+
+	go func() {
+		at := time.Now()
+		for {
+			// Runs missed while fn was running are skipped.
+			if at = next(at); at.Before(time.Now()) {
+				at = time.Now()
+			}
+			timer := time.NewTimer(time.Until(at))
+			select {
+			case <-t.quit:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+
+			fn()
+			if once {
+				t.Stop()
+				return
+			}
+		}
+	}()
+	return t
+}
+
+// Stops every scheduled task.
+func (b *Board) stopTasks() {
+	b.tm.Lock()
+	tasks := make([]*Task, 0, len(b.tasks))
+	for t := range b.tasks {
+		tasks = append(tasks, t)
+	}
+	b.tm.Unlock()
+
+	for _, t := range tasks {
+		t.Stop()
+	}
+}
+
+// A parsed cron expression. Each field holds a bit per allowed value.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// Whether the day fields were restricted. When both are, a day
+	// matching either is allowed, as in cron.
+	domSet, dowSet bool
+}
+
+func parseCron(expr string) (s cronSchedule, err error) {
+	f := strings.Fields(expr)
+	if len(f) != 5 {
+		return s, fmt.Errorf("Cron expression '%s' must have 5 fields", expr)
+	}
+	fields := []struct {
+		bits     *uint64
+		min, max int
+	}{
+		{&s.minute, 0, 59},
+		{&s.hour, 0, 23},
+		{&s.dom, 1, 31},
+		{&s.month, 1, 12},
+		{&s.dow, 0, 7},
+	}
+	for i, fd := range fields {
+		if *fd.bits, err = parseCronField(f[i], fd.min, fd.max); err != nil {
+			return s, fmt.Errorf("Cron expression '%s': %s", expr, err)
+		}
+	}
+	// Sunday may be written as 0 or 7.
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domSet, s.dowSet = f[2] != "*", f[4] != "*"
+	return
+}
+
+// Parses a comma separated list of values, ranges and steps.
+func parseCronField(field string, min, max int) (bits uint64, err error) {
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			if step, err = strconv.Atoi(stepStr); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step '%s'", stepStr)
+			}
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			first, last, isRange := strings.Cut(rng, "-")
+			if lo, err = strconv.Atoi(first); err != nil {
+				return 0, fmt.Errorf("invalid value '%s'", first)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(last); err != nil {
+					return 0, fmt.Errorf("invalid value '%s'", last)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("'%s' out of range %d-%d", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return
+}
+
+// Returns the first matching minute after t.
+func (s cronSchedule) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	// Every valid expression matches within a few years; give up after
+	// that rather than loop forever on e.g. February 30th.
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return limit
+}
+
+func (s cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domSet && s.dowSet {
+		return dom || dow
+	}
+	return dom && dow
+}
@@ -0,0 +1,63 @@
+package gadget
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	// A Friday.
+	now := time.Date(2024, 3, 1, 7, 45, 30, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 3, 1, 7, 46, 0, 0, time.UTC)},
+		{"*/20 * * * *", time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)},
+		{"30 7 * * 1-5", time.Date(2024, 3, 4, 7, 30, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2024, 3, 3, 12, 0, 0, 0, time.UTC)},
+		{"15,45 9-10 * * *", time.Date(2024, 3, 1, 9, 15, 0, 0, time.UTC)},
+		// Day of month or day of week when both are restricted.
+		{"0 0 15 * 6", time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		s, err := parseCron(tt.expr)
+		if err != nil {
+			t.Errorf("parseCron(%s) returned error: %s", tt.expr, err)
+			continue
+		}
+		if got := s.next(now); !got.Equal(tt.want) {
+			t.Errorf("next(%s) = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := parseCron(bad); err == nil {
+			t.Errorf("parseCron(%s) should fail", bad)
+		}
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	b := &Board{}
+	var every, after int32
+
+	b.Every(5*time.Millisecond, func() { atomic.AddInt32(&every, 1) })
+	b.After(5*time.Millisecond, func() { atomic.AddInt32(&after, 1) })
+	stopped := b.After(5*time.Millisecond, func() { t.Errorf("Stopped task ran") })
+	stopped.Stop()
+
+	time.Sleep(50 * time.Millisecond)
+	b.stopTasks()
+	n := atomic.LoadInt32(&every)
+	if n < 2 || atomic.LoadInt32(&after) != 1 {
+		t.Fatalf("Every ran %d times, After %d times", n, after)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if atomic.LoadInt32(&every) > n+1 {
+		t.Fatalf("Every kept running after the tasks were stopped")
+	}
+}
@@ -0,0 +1,189 @@
+package gadget
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// The port New opens.
+type portConfig struct {
+	Name string
+	Baud int
+}
+
+// Implemented by ports that can drop unread input and unsent output,
+// which is done when opening and closing the board so stale bytes from
+// a previous session are not parsed.
+type bufferResetter interface {
+	ResetBuffers() error
+}
+
+// Resets the port's buffers if it supports it.
+func resetBuffers(port io.ReadWriteCloser) error {
+	if r, ok := port.(bufferResetter); ok {
+		return r.ResetBuffers()
+	}
+	return nil
+}
+
+// Implemented by serial ports whose DTR line can be set, such as those
+// opened by OpenSerial.
+type dtrSetter interface {
+	SetDTR(on bool) error
+}
+
+// SetDTR sets the serial port's DTR line. On most Arduinos DTR is wired
+// to reset through a capacitor, so the board resets when it goes low.
+func (b *Board) SetDTR(on bool) error {
+	d, ok := b.serial.(dtrSetter)
+	if !ok {
+		return fmt.Errorf("Port '%s' has no DTR line", b.cfg.Name)
+	}
+	return d.SetDTR(on)
+}
+
+// Reset resets the board by pulsing DTR low, like the Arduino IDE does
+// before uploading. The board's pin configuration is restored once it
+// has restarted, see TopicReset.
+func (b *Board) Reset() error {
+	if err := b.SetDTR(false); err != nil {
+		return err
+	}
+	time.Sleep(100 * time.Millisecond)
+	return b.SetDTR(true)
+}
+
+// A port that can be swapped for a freshly opened one, see
+// WithReconnect. Reads interrupted by a reopen carry on from the new
+// port.
+type reopenablePort struct {
+	open func() (io.ReadWriteCloser, error)
+
+	m        sync.Mutex
+	port     io.ReadWriteCloser
+	deadline time.Time
+
+	// Closed once a reopen has succeeded. Non-nil while the port is
+	// being reopened, or failed to, so reads wait for a working port.
+	reopened chan struct{}
+	closed   bool
+}
+
+func newReopenablePort(port io.ReadWriteCloser, open func() (io.ReadWriteCloser, error)) *reopenablePort {
+	return &reopenablePort{port: port, open: open}
+}
+
+func (p *reopenablePort) current() io.ReadWriteCloser {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.port
+}
+
+func (p *reopenablePort) Read(b []byte) (n int, err error) {
+	port := p.current()
+	n, err = port.Read(b)
+	if err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		return n, err
+	}
+
+	p.m.Lock()
+	wait := p.reopened
+	p.m.Unlock()
+	if wait != nil {
+		<-wait
+	}
+
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.closed || p.port == port {
+		return n, err
+	}
+	return n, nil
+}
+
+func (p *reopenablePort) Write(b []byte) (int, error) {
+	return p.current().Write(b)
+}
+
+// Close closes the current port. Reads waiting for a reopen fail.
+func (p *reopenablePort) Close() error {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	p.closed = true
+	if p.reopened != nil {
+		close(p.reopened)
+		p.reopened = nil
+	}
+	return p.port.Close()
+}
+
+func (p *reopenablePort) ResetBuffers() error {
+	return resetBuffers(p.current())
+}
+
+func (p *reopenablePort) SetDTR(on bool) error {
+	d, ok := p.current().(dtrSetter)
+	if !ok {
+		return fmt.Errorf("Port has no DTR line")
+	}
+	return d.SetDTR(on)
+}
+
+// SetReadDeadline sets the deadline of the current port, and of the
+// ports it is swapped for.
+func (p *reopenablePort) SetReadDeadline(t time.Time) error {
+	p.m.Lock()
+	p.deadline = t
+	p.m.Unlock()
+
+	d, ok := p.current().(deadlineReader)
+	if !ok {
+		return fmt.Errorf("Port does not support read deadlines")
+	}
+	return d.SetReadDeadline(t)
+}
+
+// Closes the port and opens it again, waiting settle before clearing
+// its buffers. The old port is closed first since most systems only let
+// a serial port be opened once.
+func (p *reopenablePort) reopen(settle time.Duration) (err error) {
+	p.m.Lock()
+	if p.closed {
+		p.m.Unlock()
+		return fmt.Errorf("Port is closed")
+	}
+	if p.reopened == nil {
+		p.reopened = make(chan struct{})
+	}
+	old := p.port
+	p.m.Unlock()
+
+	old.Close()
+	port, err := p.open()
+	if err != nil {
+		return err
+	}
+	time.Sleep(settle)
+	if err = resetBuffers(port); err != nil {
+		port.Close()
+		return err
+	}
+
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.closed {
+		return port.Close()
+	}
+	if d, ok := port.(deadlineReader); ok && !p.deadline.IsZero() {
+		d.SetReadDeadline(p.deadline)
+	}
+	p.port = port
+	close(p.reopened)
+	p.reopened = nil
+	return
+}
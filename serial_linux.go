@@ -0,0 +1,91 @@
+//go:build linux
+
+package gadget
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+
+	"github.com/ZachMassia/goserial"
+)
+
+// OpenSerial opens a serial port at the given baud rate, e.g.
+// "/dev/ttyACM0" on Linux, "/dev/cu.usbmodem1411" on macOS or "COM3" on
+// Windows.
+func OpenSerial(name string, baud int) (io.ReadWriteCloser, error) {
+	port, err, fd := serial.OpenPort(&serial.Config{Name: name, Baud: baud})
+	if err != nil {
+		return nil, err
+	}
+	defer port.Close()
+	return newLinuxPort(fd, name)
+}
+
+// A serial port read through the runtime's poller, like a socket, so
+// reads can have deadlines and are interrupted by Close.
+type linuxPort struct {
+	*os.File
+}
+
+// Returns a port on a copy of fd, which is made non-blocking. The
+// original fd must be closed by the caller. Reads on it block in the
+// kernel, which rules out deadlines.
+func newLinuxPort(fd uintptr, name string) (*linuxPort, error) {
+	nfd, err := syscall.Dup(int(fd))
+	if err != nil {
+		return nil, err
+	}
+	if err = syscall.SetNonblock(nfd, true); err != nil {
+		syscall.Close(nfd)
+		return nil, err
+	}
+	return &linuxPort{os.NewFile(uintptr(nfd), name)}, nil
+}
+
+// Calls fn with the port's fd. File.Fd would put it back in blocking
+// mode.
+func (p *linuxPort) control(fn func(fd uintptr) error) error {
+	rc, err := p.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var ferr error
+	if err = rc.Control(func(fd uintptr) { ferr = fn(fd) }); err != nil {
+		return err
+	}
+	return ferr
+}
+
+func (p *linuxPort) ResetBuffers() error {
+	return p.control(func(fd uintptr) error {
+		return serial.Flush(fd, serial.TCIOFLUSH)
+	})
+}
+
+func (p *linuxPort) SetDTR(on bool) error {
+	req := syscall.TIOCMBIC
+	if on {
+		req = syscall.TIOCMBIS
+	}
+	bits := syscall.TIOCM_DTR
+	return p.control(func(fd uintptr) error {
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, uintptr(req), uintptr(unsafe.Pointer(&bits))); errno != 0 {
+			return errno
+		}
+		return nil
+	})
+}
+
+// FindSerial returns the serial ports boards are usually found on,
+// '/dev/ttyACM*' and '/dev/ttyUSB*'. Returns nil if there are none.
+func FindSerial() (s []string) {
+	acm, _ := filepath.Glob("/dev/ttyACM*")
+	usb, _ := filepath.Glob("/dev/ttyUSB*")
+
+	s = append(s, acm...)
+	s = append(s, usb...)
+	return s
+}
@@ -0,0 +1,140 @@
+//go:build linux
+
+package gadget
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+func ioctl(fd, req uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Returns both ends of a pseudo terminal, the slave in raw mode as a
+// serial port would be.
+func openPty(t *testing.T) (master, slave *os.File) {
+	t.Helper()
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("No pseudo terminals: %s", err)
+	}
+	t.Cleanup(func() { master.Close() })
+
+	var n, unlock uint32
+	if err = ioctl(master.Fd(), syscall.TIOCSPTLCK, unsafe.Pointer(&unlock)); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioctl(master.Fd(), syscall.TIOCGPTN, unsafe.Pointer(&n)); err != nil {
+		t.Fatal(err)
+	}
+	if slave, err = os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0); err != nil {
+		t.Skipf("Could not open the pty: %s", err)
+	}
+
+	var tio syscall.Termios
+	if err = ioctl(slave.Fd(), syscall.TCGETS, unsafe.Pointer(&tio)); err != nil {
+		t.Fatal(err)
+	}
+	tio.Iflag &^= syscall.ICRNL | syscall.IXON
+	tio.Oflag &^= syscall.OPOST
+	tio.Lflag &^= syscall.ICANON | syscall.ECHO | syscall.ISIG | syscall.IEXTEN
+	tio.Cc[syscall.VMIN], tio.Cc[syscall.VTIME] = 1, 0
+	if err = ioctl(slave.Fd(), syscall.TCSETS, unsafe.Pointer(&tio)); err != nil {
+		t.Fatal(err)
+	}
+	return master, slave
+}
+
+func TestLinuxPortDeadline(t *testing.T) {
+	master, slave := openPty(t)
+	p, err := newLinuxPort(slave.Fd(), slave.Name())
+	slave.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	buf := make([]byte, 8)
+	p.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+	if _, err = p.Read(buf); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Read = %v, want os.ErrDeadlineExceeded", err)
+	}
+
+	master.Write([]byte{1, 2, 3})
+	p.SetReadDeadline(time.Now().Add(time.Second))
+	if n, err := p.Read(buf); err != nil || n != 3 {
+		t.Fatalf("Read = %d, %v, want 3 bytes", n, err)
+	}
+
+	// Close interrupts a read without a deadline.
+	p.SetReadDeadline(time.Time{})
+	done := make(chan error)
+	go func() {
+		_, err := p.Read(buf)
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	p.Close()
+	select {
+	case err = <-done:
+		if err == nil {
+			t.Fatalf("Read after Close should fail")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Close did not interrupt the read")
+	}
+}
+
+func TestReadTimeoutSerial(t *testing.T) {
+	master, slave := openPty(t)
+	p, err := newLinuxPort(slave.Fd(), slave.Name())
+	slave.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &Board{serial: p, quit: make(chan bool), bus: NewEventBus()}
+	b.buf = bufio.NewReader(p)
+	b.dec = firmatawire.NewDecoder(b.buf)
+	sub := b.bus.Subscribe(TopicError, 4)
+	if err = b.SetReadTimeout(10 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	b.run()
+	defer func() {
+		close(b.quit)
+		p.Close()
+	}()
+
+	expectTimeout := func() {
+		select {
+		case e := <-sub.C:
+			if e.Err != ErrReadTimeout {
+				t.Fatalf("Expected ErrReadTimeout, got %v", e.Err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected a timeout")
+		}
+	}
+
+	// A frame cut in two by a timeout is still read whole.
+	master.Write([]byte{reportVersion, 2})
+	expectTimeout()
+	master.Write([]byte{5})
+	expectTimeout()
+	if s := b.Stats(); s.FramesIn["REPORT_VERSION"] != 1 || b.Resyncs() != 0 {
+		t.Fatalf("Expected one REPORT_VERSION and no resyncs, got %+v, %d resyncs", s, b.Resyncs())
+	}
+}
@@ -0,0 +1,73 @@
+//go:build !linux
+
+package gadget
+
+import (
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// OpenSerial opens a serial port at the given baud rate, e.g.
+// "/dev/ttyACM0" on Linux, "/dev/cu.usbmodem1411" on macOS or "COM3" on
+// Windows.
+func OpenSerial(name string, baud int) (io.ReadWriteCloser, error) {
+	port, err := serial.Open(name, &serial.Mode{BaudRate: baud})
+	if err != nil {
+		return nil, err
+	}
+	return &otherPort{Port: port}, nil
+}
+
+type otherPort struct {
+	serial.Port
+
+	// The deadline set by SetReadDeadline, as UnixNano, or 0.
+	deadline atomic.Int64
+}
+
+// SetReadDeadline maps t onto the port's read timeout, which restarts
+// with every Read.
+func (p *otherPort) SetReadDeadline(t time.Time) error {
+	if t.IsZero() {
+		p.deadline.Store(0)
+		return p.SetReadTimeout(serial.NoTimeout)
+	}
+	p.deadline.Store(t.UnixNano())
+	return p.SetReadTimeout(max(time.Until(t), time.Millisecond))
+}
+
+// Read returns os.ErrDeadlineExceeded when the read timeout passes,
+// rather than the 0 bytes and nil error the port returns.
+func (p *otherPort) Read(b []byte) (int, error) {
+	n, err := p.Port.Read(b)
+	if n == 0 && err == nil && p.deadline.Load() != 0 {
+		return 0, os.ErrDeadlineExceeded
+	}
+	return n, err
+}
+
+func (p *otherPort) ResetBuffers() error {
+	if err := p.ResetInputBuffer(); err != nil {
+		return err
+	}
+	return p.ResetOutputBuffer()
+}
+
+// FindSerial returns the serial ports boards are usually found on: USB
+// serial devices on macOS, '/dev/cu.usbmodem*' and '/dev/cu.usbserial*',
+// and every COM port on Windows. Returns nil if there are none.
+func FindSerial() (s []string) {
+	ports, _ := serial.GetPortsList()
+	for _, p := range ports {
+		if runtime.GOOS == "windows" || strings.HasPrefix(p, "/dev/cu.usb") {
+			s = append(s, p)
+		}
+	}
+	return s
+}
@@ -0,0 +1,255 @@
+package gadget
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+const (
+	// Default pulse widths used by the Arduino Servo library.
+	defaultServoMinPulse = 544
+	defaultServoMaxPulse = 2400
+
+	// Firmata takes servo values below this as angles in degrees, so
+	// no shorter pulse can be written.
+	minServoPulse = 544
+)
+
+// ServoConfig attaches a servo to the pin, putting it in SERVO mode.
+// minPulse and maxPulse are the pulse widths in µs for 0 and 180 degrees.
+// minPulse must be at least 544µs, as shorter pulses cannot be written.
+func (b *Board) ServoConfig(pin byte, minPulse, maxPulse int) (err error) {
+	if minPulse < minServoPulse || maxPulse <= minPulse || maxPulse > 0x3FFF {
+		return fmt.Errorf("Invalid servo pulse range %d-%dµs, must be within %d-%dµs", minPulse, maxPulse, minServoPulse, 0x3FFF)
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	p, err := b.sendablePin(pin)
+	if err != nil {
+		return err
+	}
+	if p.resolution(SERVO) == 0 {
+		return fmt.Errorf("Pin mode %s not supported by pin %d", PinModeString[SERVO], pin)
+	}
+
+	// The firmware attaches the servo and sets the pin mode itself.
+	if _, err = b.sendSysex(servoConfigMsg(pin, minPulse, maxPulse)); err != nil {
+		return err
+	}
+	p.mode = SERVO
+	p.servoPulses = [2]int{minPulse, maxPulse}
+	return
+}
+
+func servoConfigMsg(pin byte, minPulse, maxPulse int) []byte {
+	return []byte{
+		servoConfig,
+		pin,
+		byte(minPulse) & 0x7F, byte(minPulse>>7) & 0x7F,
+		byte(maxPulse) & 0x7F, byte(maxPulse>>7) & 0x7F,
+	}
+}
+
+// ServoWrite moves a servo. Like the Arduino Servo library, values
+// below 544 are an angle in degrees and larger values a pulse width
+// in µs. The pin must be in SERVO mode.
+func (b *Board) ServoWrite(pin byte, v int) (err error) {
+	if v < 0 || v > 0x3FFF {
+		return fmt.Errorf("Invalid servo value: %d", v)
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	p, err := b.sendablePin(pin)
+	if err != nil {
+		return err
+	}
+	if p.mode != SERVO {
+		return fmt.Errorf("Pin %d not in SERVO mode, got %s", pin, PinModeString[p.mode])
+	}
+	b.log().Debug("Servo write", "pin", pin, "value", v)
+	now := time.Now()
+	p.setAnalog(v, now)
+	_, err = b.out.Write(firmatawire.AnalogWrite(p.num, v))
+	b.publishPin(pin, KindAnalog, v, now)
+	return
+}
+
+// ContinuousServo is a continuous rotation servo, whose pulse width
+// sets its speed and direction rather than its position.
+type ContinuousServo struct {
+	board *Board
+	pin   byte
+
+	// Pulse width in µs at which the servo stands still. Most are
+	// close to 1500µs, but each needs trimming.
+	Center int
+
+	// Pulse width offset from Center in µs giving full speed.
+	// Defaults to 500µs.
+	Range int
+
+	// Swaps the direction of rotation.
+	Inverted bool
+
+	m     sync.Mutex
+	speed float64
+}
+
+// NewContinuousServo attaches a stopped ContinuousServo to the pin.
+func NewContinuousServo(b *Board, pin byte) (s *ContinuousServo, err error) {
+	if err = b.ServoConfig(pin, defaultServoMinPulse, defaultServoMaxPulse); err != nil {
+		return nil, err
+	}
+
+	s = &ContinuousServo{
+		board:  b,
+		pin:    pin,
+		Center: 1500,
+		Range:  500,
+	}
+	if err = s.Stop(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// SetSpeed turns the servo at speed, from -1 (full speed one way)
+// through 0 (stopped) to 1 (full speed the other way).
+func (s *ContinuousServo) SetSpeed(speed float64) (err error) {
+	if speed < -1 || speed > 1 || math.IsNaN(speed) {
+		return fmt.Errorf("Servo speed must be -1 to 1, got %v", speed)
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	dir := 1.0
+	if s.Inverted {
+		dir = -1
+	}
+	pulse := max(minServoPulse, s.Center+int(math.Round(dir*speed*float64(s.Range))))
+	if err = s.board.ServoWrite(s.pin, pulse); err != nil {
+		return err
+	}
+	s.speed = speed
+	return
+}
+
+// Speed returns the last speed set.
+func (s *ContinuousServo) Speed() float64 {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.speed
+}
+
+// Stop sends the Center pulse width, stopping a calibrated servo.
+func (s *ContinuousServo) Stop() error {
+	return s.SetSpeed(0)
+}
+
+// Servo is a positional hobby servo.
+type Servo struct {
+	board              *Board
+	pin                byte
+	minPulse, maxPulse int
+
+	// Travel limits in degrees, defaults to 0-180. Moves outside
+	// them are clamped.
+	Min, Max float64
+
+	m     sync.Mutex
+	angle float64
+
+	anim *Animation // The last MoveTo.
+	am   sync.Mutex
+}
+
+// NewServo attaches a Servo to the pin using the Arduino Servo
+// library's default pulse widths, and centers it.
+func NewServo(b *Board, pin byte) (*Servo, error) {
+	return NewServoPulse(b, pin, defaultServoMinPulse, defaultServoMaxPulse)
+}
+
+// NewServoPulse attaches a Servo to the pin, with minPulse and maxPulse
+// the pulse widths in µs for 0 and 180 degrees, and centers it. Servos
+// specified down to 500µs lose the first few degrees, see ServoConfig.
+func NewServoPulse(b *Board, pin byte, minPulse, maxPulse int) (s *Servo, err error) {
+	if err = b.ServoConfig(pin, minPulse, maxPulse); err != nil {
+		return nil, err
+	}
+
+	s = &Servo{
+		board:    b,
+		pin:      pin,
+		minPulse: minPulse,
+		maxPulse: maxPulse,
+		Max:      180,
+	}
+	if err = s.Move(90); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Move turns the servo to angle degrees, clamped to Min and Max.
+func (s *Servo) Move(angle float64) (err error) {
+	if math.IsNaN(angle) {
+		return fmt.Errorf("Invalid servo angle: %v", angle)
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	angle = math.Max(s.Min, math.Min(s.Max, angle))
+
+	// Write the pulse width rather than the angle, for sub-degree steps.
+	pulse := s.minPulse + int(math.Round(angle/180*float64(s.maxPulse-s.minPulse)))
+	if err = s.board.ServoWrite(s.pin, pulse); err != nil {
+		return err
+	}
+	s.angle = angle
+	return
+}
+
+// Angle returns the last angle moved to.
+func (s *Servo) Angle() float64 {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.angle
+}
+
+// MoveTo turns the servo to angle over d, following ease, or Linear if
+// nil. It returns at once; the move runs in the background. A move
+// already running is interrupted and this one starts from where it
+// stopped.
+func (s *Servo) MoveTo(angle float64, d time.Duration, ease Easing) *Animation {
+	if ease == nil {
+		ease = Linear
+	}
+
+	s.am.Lock()
+	defer s.am.Unlock()
+
+	if s.anim != nil {
+		s.anim.Stop()
+	}
+	from := s.Angle()
+	s.anim = s.board.animate(func(elapsed time.Duration) (bool, error) {
+		f := 1.0
+		if d > 0 {
+			f = math.Min(1, elapsed.Seconds()/d.Seconds())
+		}
+		return f < 1, s.Move(from + ease(f)*(angle-from))
+	})
+	return s.anim
+}
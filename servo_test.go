@@ -0,0 +1,41 @@
+package gadget
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+func TestServoMove(t *testing.T) {
+	var out bytes.Buffer
+	b := newTestBoard(t, &out, nil, map[byte][]Capability{9: {{OUTPUT, 1}, {SERVO, 14}}})
+
+	// A 500µs pulse would be written as 500 degrees.
+	if _, err := NewServoPulse(b, 9, 500, 2500); err == nil {
+		t.Fatalf("Expected error for a 500µs minimum pulse")
+	}
+	s, err := NewServoPulse(b, 9, 544, 2544)
+	if err != nil {
+		t.Fatalf("NewServoPulse: %s", err)
+	}
+
+	for _, tc := range []struct {
+		angle float64
+		pulse int
+	}{
+		{0, 544},
+		{90, 1544},
+		{180, 2544},
+		{-10, 544}, // Clamped to Min.
+	} {
+		out.Reset()
+		if err = s.Move(tc.angle); err != nil {
+			t.Fatalf("Move(%g): %s", tc.angle, err)
+		}
+		b.out.Flush()
+		if want := firmatawire.AnalogWrite(9, tc.pulse); !bytes.Equal(out.Bytes(), want) {
+			t.Fatalf("Move(%g) sent % X, want % X", tc.angle, out.Bytes(), want)
+		}
+	}
+}
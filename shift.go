@@ -0,0 +1,35 @@
+package gadget
+
+const (
+	// Bit orders for shifting data in and out.
+	LSBFIRST byte = iota // Least significant bit first.
+	MSBFIRST             // Most significant bit first.
+)
+
+// ShiftOut clocks out a byte one bit at a time on dataPin, pulsing
+// clockPin after each bit, the same as Arduino's shiftOut(). Both pins
+// must be in OUTPUT mode.
+//
+// The bits are toggled from the host, so each bit costs a few serial
+// messages.
+func (b *Board) ShiftOut(dataPin, clockPin, order, val byte) (err error) {
+	for i := 0; i < 8; i++ {
+		var bit byte
+		if order == LSBFIRST {
+			bit = (val >> uint(i)) & 0x01
+		} else {
+			bit = (val >> uint(7-i)) & 0x01
+		}
+
+		if err = b.DigitalWrite(dataPin, bit); err != nil {
+			return err
+		}
+		if err = b.DigitalWrite(clockPin, HIGH); err != nil {
+			return err
+		}
+		if err = b.DigitalWrite(clockPin, LOW); err != nil {
+			return err
+		}
+	}
+	return
+}
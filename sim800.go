@@ -0,0 +1,254 @@
+package gadget
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// How long a plain AT command may take to answer.
+	simCommandTimeout = 2 * time.Second
+
+	// Sending an SMS or fetching a page goes over the network, which
+	// can take a while.
+	simNetworkTimeout = 60 * time.Second
+
+	simCtrlZ = 0x1A // Ends an SMS body.
+)
+
+// SIM800 is a SIM800 GSM modem driven by AT commands, usually through a
+// UART. Timeouts need a port with SetReadDeadline, as UART has; without
+// one the modem is waited on forever.
+type SIM800 struct {
+	rw io.ReadWriter
+	r  *bufio.Reader
+
+	// The access point used by HTTPGet, e.g. "internet". Check with
+	// the SIM's network.
+	APN string
+
+	m sync.Mutex
+}
+
+// NewSIM800 wakes the modem on rw and turns off its command echo.
+func NewSIM800(rw io.ReadWriter) (s *SIM800, err error) {
+	s = &SIM800{rw: rw, r: bufio.NewReader(rw)}
+
+	// The first AT lets the modem detect the baud rate, so allow a
+	// couple of tries.
+	for i := 0; i < 3; i++ {
+		if _, err = s.Command("AT", simCommandTimeout); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("SIM800 not answering: %s", err)
+	}
+	if _, err = s.Command("ATE0", simCommandTimeout); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Command sends an AT command, e.g. "AT+CSQ", and returns the lines of
+// its response before the final OK. An ERROR response, or none within
+// timeout, is returned as an error.
+func (s *SIM800) Command(cmd string, timeout time.Duration) (lines []string, err error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if err = s.send(cmd+"\r", timeout); err != nil {
+		return nil, err
+	}
+	return s.response(cmd)
+}
+
+// SignalQuality returns the received signal strength indicator, 0-31,
+// or 99 if unknown.
+func (s *SIM800) SignalQuality() (rssi int, err error) {
+	lines, err := s.Command("AT+CSQ", simCommandTimeout)
+	if err != nil {
+		return 0, err
+	}
+	v, ok := simResult(lines, "+CSQ")
+	if !ok {
+		return 0, fmt.Errorf("SIM800: no signal quality in %q", lines)
+	}
+	return strconv.Atoi(strings.Split(v, ",")[0])
+}
+
+// SendSMS sends a text message to number, e.g. "+15551234567".
+func (s *SIM800) SendSMS(number, text string) (err error) {
+	if strings.ContainsRune(text, simCtrlZ) {
+		return fmt.Errorf("SMS text may not contain Ctrl-Z")
+	}
+	if _, err = s.Command("AT+CMGF=1", simCommandTimeout); err != nil {
+		return err
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	cmd := fmt.Sprintf("AT+CMGS=\"%s\"", number)
+	if err = s.send(cmd+"\r", simCommandTimeout); err != nil {
+		return err
+	}
+	if err = s.prompt(); err != nil {
+		return err
+	}
+	if err = s.send(text+string(rune(simCtrlZ)), simNetworkTimeout); err != nil {
+		return err
+	}
+	_, err = s.response(cmd)
+	return
+}
+
+// HTTPGet fetches url over GPRS, using APN, and returns the HTTP status
+// and body.
+func (s *SIM800) HTTPGet(url string) (status int, body []byte, err error) {
+	for _, cmd := range []string{
+		`AT+SAPBR=3,1,"Contype","GPRS"`,
+		fmt.Sprintf(`AT+SAPBR=3,1,"APN","%s"`, s.APN),
+	} {
+		if _, err = s.Command(cmd, simCommandTimeout); err != nil {
+			return 0, nil, err
+		}
+	}
+	// Fails if the bearer is already open, which is fine.
+	s.Command("AT+SAPBR=1,1", simNetworkTimeout)
+
+	if _, err = s.Command("AT+HTTPINIT", simCommandTimeout); err != nil {
+		return 0, nil, err
+	}
+	defer s.Command("AT+HTTPTERM", simCommandTimeout)
+
+	for _, cmd := range []string{
+		`AT+HTTPPARA="CID",1`,
+		fmt.Sprintf(`AT+HTTPPARA="URL","%s"`, url),
+		"AT+HTTPACTION=0",
+	} {
+		if _, err = s.Command(cmd, simCommandTimeout); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	// The result follows as "+HTTPACTION: 0,<status>,<length>".
+	var line string
+	for !strings.HasPrefix(line, "+HTTPACTION:") {
+		if line, err = s.line(simNetworkTimeout); err != nil {
+			return 0, nil, err
+		}
+	}
+	fields := strings.Split(strings.TrimSpace(strings.TrimPrefix(line, "+HTTPACTION:")), ",")
+	if len(fields) != 3 {
+		return 0, nil, fmt.Errorf("SIM800: invalid HTTP result %q", line)
+	}
+	status, _ = strconv.Atoi(fields[1])
+	n, _ := strconv.Atoi(fields[2])
+	if n == 0 {
+		return status, nil, nil
+	}
+
+	// The body is sent raw after "+HTTPREAD: <length>".
+	if err = s.send("AT+HTTPREAD\r", simNetworkTimeout); err != nil {
+		return status, nil, err
+	}
+	for !strings.HasPrefix(line, "+HTTPREAD:") {
+		if line, err = s.line(simNetworkTimeout); err != nil {
+			return status, nil, err
+		}
+		if line == "ERROR" {
+			return status, nil, fmt.Errorf("SIM800 AT+HTTPREAD: %s", line)
+		}
+	}
+	if n, err = strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "+HTTPREAD:"))); err != nil {
+		return status, nil, fmt.Errorf("SIM800: invalid HTTP read %q", line)
+	}
+	body = make([]byte, n)
+	if _, err = io.ReadFull(s.r, body); err != nil {
+		return status, nil, err
+	}
+	_, err = s.response("AT+HTTPREAD")
+	return
+}
+
+// Writes cmd and sets the deadline for its response. Must be called
+// with s.m held.
+func (s *SIM800) send(cmd string, timeout time.Duration) (err error) {
+	if dr, ok := s.rw.(deadlineReader); ok {
+		dr.SetReadDeadline(time.Now().Add(timeout))
+	}
+	_, err = io.WriteString(s.rw, cmd)
+	return
+}
+
+// Reads lines up to the final result code. Must be called with s.m
+// held.
+func (s *SIM800) response(cmd string) (lines []string, err error) {
+	for {
+		line, err := s.line(0)
+		if err != nil {
+			return nil, fmt.Errorf("SIM800 %s: %s", cmd, err)
+		}
+		switch {
+		case line == "OK":
+			return lines, nil
+		case line == "ERROR", strings.HasPrefix(line, "+CME ERROR"), strings.HasPrefix(line, "+CMS ERROR"):
+			return nil, fmt.Errorf("SIM800 %s: %s", cmd, line)
+		case line == cmd:
+			// Echo, if it is still on.
+		default:
+			lines = append(lines, line)
+		}
+	}
+}
+
+// Returns the next non-empty line, extending the deadline to timeout if
+// it is not zero. Must be called with s.m held.
+func (s *SIM800) line(timeout time.Duration) (string, error) {
+	if dr, ok := s.rw.(deadlineReader); ok && timeout > 0 {
+		dr.SetReadDeadline(time.Now().Add(timeout))
+	}
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			return line, nil
+		}
+	}
+}
+
+// Waits for the "> " prompt for an SMS body. Must be called with s.m
+// held.
+func (s *SIM800) prompt() error {
+	for {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("SIM800 waiting for SMS prompt: %s", err)
+		}
+		if c == '>' {
+			s.r.ReadByte() // The space after it.
+			return nil
+		}
+	}
+}
+
+// Returns the value of the "+NAME: value" line in lines.
+func simResult(lines []string, name string) (string, bool) {
+	for _, l := range lines {
+		if v, ok := strings.CutPrefix(l, name+":"); ok {
+			return strings.TrimSpace(v), true
+		}
+	}
+	return "", false
+}
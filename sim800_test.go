@@ -0,0 +1,78 @@
+package gadget
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+)
+
+// Answers AT commands as a SIM800 with echo on would, recording the
+// commands and SMS bodies received.
+func fakeSIM800(conn net.Conn, got *[]string) {
+	r := bufio.NewReader(conn)
+	for {
+		cmd, err := r.ReadString('\r')
+		if err != nil {
+			return
+		}
+		cmd = strings.TrimSpace(cmd)
+		*got = append(*got, cmd)
+
+		reply := "\r\nOK\r\n"
+		switch {
+		case cmd == "AT":
+			reply = cmd + "\r" + reply
+		case cmd == "AT+CSQ":
+			reply = "\r\n+CSQ: 17,0\r\n" + reply
+		case strings.HasPrefix(cmd, "AT+CMGS"):
+			conn.Write([]byte("\r\n> "))
+			body, _ := r.ReadString(0x1A)
+			*got = append(*got, strings.TrimSuffix(body, "\x1A"))
+			reply = "\r\n+CMGS: 4\r\n" + reply
+		case cmd == "AT+SAPBR=1,1":
+			reply = "\r\nERROR\r\n" // Already open.
+		case cmd == "AT+HTTPACTION=0":
+			reply += "\r\n+HTTPACTION: 0,200,5\r\n"
+		case cmd == "AT+HTTPREAD":
+			reply = "\r\n+HTTPREAD: 5\r\nhello" + reply
+		case cmd == "AT+BAD":
+			reply = "\r\n+CME ERROR: 100\r\n"
+		}
+		conn.Write([]byte(reply))
+	}
+}
+
+func TestSIM800(t *testing.T) {
+	host, modem := net.Pipe()
+	defer host.Close()
+	var got []string
+	go fakeSIM800(modem, &got)
+
+	s, err := NewSIM800(host)
+	if err != nil {
+		t.Fatalf("NewSIM800: %s", err)
+	}
+	if rssi, err := s.SignalQuality(); err != nil || rssi != 17 {
+		t.Fatalf("SignalQuality = %d, %v, want 17", rssi, err)
+	}
+	if _, err = s.Command("AT+BAD", simCommandTimeout); err == nil {
+		t.Fatalf("Expected error for +CME ERROR")
+	}
+
+	if err = s.SendSMS("+15551234567", "hi there"); err != nil {
+		t.Fatalf("SendSMS: %s", err)
+	}
+	if got[len(got)-1] != "hi there" {
+		t.Fatalf("Modem got SMS %q", got[len(got)-1])
+	}
+
+	s.APN = "internet"
+	status, body, err := s.HTTPGet("http://example.com/")
+	if err != nil || status != 200 || string(body) != "hello" {
+		t.Fatalf("HTTPGet = %d, %q, %v, want 200 hello", status, body, err)
+	}
+	if got[len(got)-1] != "AT+HTTPTERM" {
+		t.Fatalf("HTTP session not terminated, last command %q", got[len(got)-1])
+	}
+}
@@ -0,0 +1,120 @@
+package gadget
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Snapshot is the state of a board at a point in time, suitable for
+// encoding as JSON for diagnostics and bug reports.
+type Snapshot struct {
+	Device   string    `json:"device"`
+	Firmware string    `json:"firmware"`
+	Version  string    `json:"version"`
+	Time     time.Time `json:"time"`
+	Pins     []PinInfo `json:"pins"`
+}
+
+// PinInfo describes a pin.
+type PinInfo struct {
+	Pin byte `json:"pin"`
+
+	// The A0 style analog number, or -1 for digital only pins.
+	Analog int `json:"analog"`
+
+	// The port used for digital reporting and writes.
+	Port byte `json:"port"`
+
+	Mode      string `json:"mode"`
+	Reporting bool   `json:"reporting"`
+
+	// Whether the input's pull-up is on. Always set in PULLUP mode; in
+	// INPUT mode only known after QueryPinState.
+	Pullup bool `json:"pullup"`
+
+	// The digital value in INPUT, PULLUP and OUTPUT mode, otherwise
+	// the analog value.
+	Value int `json:"value"`
+
+	// When the value was last reported or written. Zero if it never
+	// has been.
+	Updated time.Time `json:"updated"`
+
+	Capabilities []Capability `json:"capabilities"`
+}
+
+// Snapshot returns the board's current state.
+func (b *Board) Snapshot() Snapshot {
+	s := Snapshot{
+		Device:   b.cfg.Name,
+		Firmware: b.firmware,
+		Version:  b.Version(),
+		Time:     time.Now(),
+	}
+
+	s.Pins = b.Pins()
+	return s
+}
+
+// Pins describes every pin, in order of pin number.
+func (b *Board) Pins() (pins []PinInfo) {
+	b.m.RLock()
+	for _, p := range b.pins {
+		pins = append(pins, p.info())
+	}
+	b.m.RUnlock()
+
+	sort.Slice(pins, func(i, j int) bool { return pins[i].Pin < pins[j].Pin })
+	return
+}
+
+// Pin describes a single pin.
+func (b *Board) Pin(n byte) (info PinInfo, err error) {
+	b.m.RLock()
+	defer b.m.RUnlock()
+
+	p, ok := b.pins[n]
+	if !ok {
+		return info, fmt.Errorf("Invalid pin: %d", n)
+	}
+	return p.info(), nil
+}
+
+// LastUpdated returns when pin n's value was last reported by the board
+// or written, or the zero time if it never was. Reported values are
+// stamped when their message arrived. The time carries a monotonic
+// reading, so time.Since gives a reading's age even if the wall clock
+// is changed.
+func (b *Board) LastUpdated(n byte) (time.Time, error) {
+	b.m.RLock()
+	defer b.m.RUnlock()
+
+	p, ok := b.pins[n]
+	if !ok {
+		return time.Time{}, fmt.Errorf("Invalid pin: %d", n)
+	}
+	return p.updated, nil
+}
+
+// Returns the exported description of pin p.
+func (p *pin) info() PinInfo {
+	i := PinInfo{
+		Pin:          p.num,
+		Analog:       -1,
+		Port:         p.port,
+		Mode:         PinModeString[p.mode],
+		Reporting:    p.reporting,
+		Pullup:       p.pullup,
+		Value:        p.analogVal,
+		Updated:      p.updated,
+		Capabilities: append([]Capability(nil), p.caps...),
+	}
+	if p.analogNum != 0x7F {
+		i.Analog = int(p.analogNum)
+	}
+	if p.isInput() || p.mode == OUTPUT {
+		i.Value = int(p.digitalVal)
+	}
+	return i
+}
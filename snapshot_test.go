@@ -0,0 +1,45 @@
+package gadget
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	var out bytes.Buffer
+	b := &Board{
+		cfg:      &portConfig{Name: "/dev/ttyACM0"},
+		firmware: "StandardFirmata",
+		maj:      2,
+		min:      5,
+		pins: map[byte]*pin{
+			13: newPin(&out, 13, 0x7F, []Capability{{INPUT, 1}, {OUTPUT, 1}, {PWM, 8}}),
+			14: newPin(&out, 14, 0, []Capability{{INPUT, 1}, {OUTPUT, 1}, {ANALOG, 10}}),
+		},
+	}
+	b.pins[13].setDigital(HIGH, time.Now())
+	b.pins[14].analogVal = 512
+
+	s := b.Snapshot()
+	if s.Device != "/dev/ttyACM0" || s.Version != "2.5" || len(s.Pins) != 2 || s.Pins[0].Pin != 13 {
+		t.Fatalf("Unexpected snapshot: %+v", s)
+	}
+
+	led, a0 := s.Pins[0], s.Pins[1]
+	if led.Analog != -1 || led.Port != 1 || led.Updated.IsZero() || led.Mode != "OUTPUT" || led.Value != 1 || led.Capabilities[2] != (Capability{PWM, 8}) {
+		t.Errorf("Unexpected pin 13: %+v", led)
+	}
+	if a0.Analog != 0 || !a0.Updated.IsZero() || a0.Mode != "ANALOG" || a0.Value != 512 || a0.Capabilities[2] != (Capability{ANALOG, 10}) {
+		t.Errorf("Unexpected pin 14: %+v", a0)
+	}
+
+	js, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Snapshot should encode as JSON: %s", err)
+	}
+	if !bytes.Contains(js, []byte(`{"mode":"PWM","resolution":8}`)) {
+		t.Errorf("Capabilities should encode modes by name: %s", js)
+	}
+}
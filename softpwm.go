@@ -0,0 +1,86 @@
+package gadget
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// The fastest software PWM, since every edge is a message over the
+// serial port.
+const maxSoftPWMFrequency = 100
+
+// SoftPWM generates PWM on a pin without hardware PWM by switching it
+// from Go, e.g. to dim an LED or run a heater slowly. Each period is one
+// HIGH and one LOW write, so the frequency is limited to 100Hz and the
+// timing jitters by a few milliseconds; it is not suitable for servos.
+type SoftPWM struct {
+	board  *Board
+	pin    byte
+	period time.Duration
+	task   *Task
+
+	m    sync.Mutex
+	duty float64
+}
+
+// NewSoftPWM returns a SoftPWM on the pin at freq Hz, with the pin LOW.
+// Call Stop to release the pin.
+func NewSoftPWM(b *Board, pin byte, freq float64) (s *SoftPWM, err error) {
+	if freq <= 0 || freq > maxSoftPWMFrequency {
+		return nil, fmt.Errorf("Software PWM frequency must be 0-%dHz, got %g", maxSoftPWMFrequency, freq)
+	}
+	if err = b.ensurePinMode(pin, OUTPUT); err != nil {
+		return nil, err
+	}
+	if err = b.DigitalWrite(pin, LOW); err != nil {
+		return nil, err
+	}
+
+	s = &SoftPWM{
+		board:  b,
+		pin:    pin,
+		period: time.Duration(float64(time.Second) / freq),
+	}
+	s.task = b.Every(s.period, s.cycle)
+	return
+}
+
+// Runs one period.
+func (s *SoftPWM) cycle() {
+	on := time.Duration(s.Duty() * float64(s.period))
+	switch {
+	case on <= 0:
+		s.board.DigitalWrite(s.pin, LOW)
+	case on >= s.period:
+		s.board.DigitalWrite(s.pin, HIGH)
+	default:
+		s.board.DigitalWrite(s.pin, HIGH)
+		time.Sleep(on)
+		s.board.DigitalWrite(s.pin, LOW)
+	}
+}
+
+// SetDuty sets the fraction of each period the pin is HIGH, from 0 to 1.
+func (s *SoftPWM) SetDuty(duty float64) error {
+	if duty < 0 || duty > 1 {
+		return fmt.Errorf("Duty cycle must be 0-1, got %g", duty)
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.duty = duty
+	return nil
+}
+
+// Duty returns the duty cycle.
+func (s *SoftPWM) Duty() float64 {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.duty
+}
+
+// Stop stops switching the pin and leaves it LOW.
+func (s *SoftPWM) Stop() error {
+	s.task.Stop()
+	return s.board.DigitalWrite(s.pin, LOW)
+}
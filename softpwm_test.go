@@ -0,0 +1,38 @@
+package gadget
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSoftPWM(t *testing.T) {
+	var l writeLog
+	b := newTestBoard(t, &l, nil, map[byte][]Capability{4: {{INPUT, 1}, {OUTPUT, 1}}})
+
+	if _, err := NewSoftPWM(b, 4, 1000); err == nil {
+		t.Fatalf("1kHz should be rejected")
+	}
+	s, err := NewSoftPWM(b, 4, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.SetDuty(1.5); err == nil {
+		t.Fatalf("Duty above 1 should be rejected")
+	}
+
+	sub := b.bus.Subscribe(PinTopic(4, KindDigital), 8)
+	s.SetDuty(0.5)
+	for _, want := range []int{1, 0} {
+		select {
+		case e := <-sub.C:
+			if e.Value != want {
+				t.Fatalf("Expected %d, got %d", want, e.Value)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Pin was not switched")
+		}
+	}
+	if err = s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+}
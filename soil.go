@@ -0,0 +1,214 @@
+package gadget
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// How often soil sensors are sampled while started. Moisture
+	// changes slowly.
+	defaultSoilInterval = time.Second
+
+	// Percentage points above Threshold a dry sensor must read before
+	// it counts as wet again, so readings near the threshold do not
+	// flap.
+	soilHysteresis = 2
+)
+
+// SoilEvent is sent when a SoilSensor crosses its threshold.
+type SoilEvent struct {
+	Pin      byte
+	Moisture float64 // Percent.
+	Dry      bool    // Below the threshold.
+}
+
+// SoilCalibration is a soil sensor's raw reading in dry air and in
+// water, which read as 0% and 100% moisture.
+type SoilCalibration struct {
+	Dry int `json:"dry"`
+	Wet int `json:"wet"`
+}
+
+// SoilConfig holds the calibrations of several soil sensors by name, so
+// they can be measured once and saved.
+type SoilConfig map[string]SoilCalibration
+
+// LoadSoilConfig reads a SoilConfig from a JSON file. A missing file
+// gives an empty config.
+func LoadSoilConfig(path string) (SoilConfig, error) {
+	c := make(SoilConfig)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("Invalid soil config %s: %s", path, err)
+	}
+	return c, nil
+}
+
+// Save writes the config to a JSON file.
+func (c SoilConfig) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// SoilSensor is a capacitive or resistive soil moisture probe on an
+// analog pin.
+type SoilSensor struct {
+	board *Board
+	pin   byte // Normal (not A0 style) pin number.
+
+	// The readings for 0% and 100% moisture. Defaults to full scale
+	// and 0, since most probes read higher the drier the soil; use
+	// CalibrateDry and CalibrateWet to measure them.
+	Calibration SoilCalibration
+
+	// Moisture, in percent, below which the soil is dry. An event is
+	// published on TopicSoil whenever the sensor crosses it. Defaults
+	// to 30.
+	Threshold float64
+
+	// How often the pin is sampled while started.
+	Interval time.Duration
+
+	m    sync.Mutex
+	dry  bool
+	stop func()
+}
+
+// NewSoilSensor returns a SoilSensor on the given analog pin, with
+// analog reporting turned on.
+func NewSoilSensor(b *Board, pin byte) (s *SoilSensor, err error) {
+	bits, err := b.AnalogResolution(pin)
+	if err != nil {
+		return nil, err
+	}
+	if err = b.ensurePinMode(pin, ANALOG); err != nil {
+		return nil, err
+	}
+	if err = b.SetPinReporting(pin, true); err != nil {
+		return nil, err
+	}
+
+	s = &SoilSensor{
+		board:       b,
+		pin:         pin,
+		Calibration: SoilCalibration{Dry: analogMax(bits)},
+		Threshold:   30,
+		Interval:    defaultSoilInterval,
+	}
+	return
+}
+
+// Raw returns the last analog reading.
+func (s *SoilSensor) Raw() (int, error) {
+	return s.board.AnalogRead(s.pin)
+}
+
+// Moisture returns the soil moisture in percent, 0-100.
+func (s *SoilSensor) Moisture() (pct float64, err error) {
+	v, err := s.Raw()
+	if err != nil {
+		return 0, err
+	}
+	s.m.Lock()
+	c := s.Calibration
+	s.m.Unlock()
+	return soilMoisture(v, c)
+}
+
+// CalibrateDry uses the current reading as 0%, with the probe in dry
+// air or dry soil.
+func (s *SoilSensor) CalibrateDry() error {
+	v, err := s.Raw()
+	if err != nil {
+		return err
+	}
+	s.m.Lock()
+	s.Calibration.Dry = v
+	s.m.Unlock()
+	return nil
+}
+
+// CalibrateWet uses the current reading as 100%, with the probe in
+// water up to its line.
+func (s *SoilSensor) CalibrateWet() error {
+	v, err := s.Raw()
+	if err != nil {
+		return err
+	}
+	s.m.Lock()
+	s.Calibration.Wet = v
+	s.m.Unlock()
+	return nil
+}
+
+// Start samples the sensor in the background, publishing a SoilEvent
+// on TopicSoil when the moisture crosses Threshold.
+func (s *SoilSensor) Start() (err error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.stop != nil {
+		return fmt.Errorf("Soil sensor on pin %d already started", s.pin)
+	}
+	s.stop = poll(s.Interval, s.check)
+	return
+}
+
+// Halt stops background sampling.
+func (s *SoilSensor) Halt() (err error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.stop != nil {
+		s.stop()
+		s.stop = nil
+	}
+	return
+}
+
+// Publishes an event if the moisture crossed the threshold.
+func (s *SoilSensor) check() {
+	pct, err := s.Moisture()
+	if err != nil {
+		return
+	}
+
+	s.m.Lock()
+	changed := false
+	switch {
+	case !s.dry && pct < s.Threshold:
+		s.dry, changed = true, true
+	case s.dry && pct >= s.Threshold+soilHysteresis:
+		s.dry, changed = false, true
+	}
+	dry := s.dry
+	s.m.Unlock()
+
+	if changed {
+		s.board.bus.Publish(Event{Topic: TopicSoil, Data: SoilEvent{Pin: s.pin, Moisture: pct, Dry: dry}})
+	}
+}
+
+// Converts a raw reading to percent moisture, clamped to 0-100.
+func soilMoisture(raw int, c SoilCalibration) (float64, error) {
+	if c.Dry == c.Wet {
+		return 0, fmt.Errorf("Soil calibration needs different dry and wet readings, both are %d", c.Dry)
+	}
+	pct := 100 * float64(raw-c.Dry) / float64(c.Wet-c.Dry)
+	return math.Max(0, math.Min(100, pct)), nil
+}
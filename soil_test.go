@@ -0,0 +1,68 @@
+package gadget
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSoilMoisture(t *testing.T) {
+	capacitive := SoilCalibration{Dry: 800, Wet: 400}
+	resistive := SoilCalibration{Dry: 0, Wet: 600}
+	for _, tc := range []struct {
+		raw  int
+		c    SoilCalibration
+		want float64
+	}{
+		{800, capacitive, 0},
+		{600, capacitive, 50},
+		{300, capacitive, 100}, // Wetter than calibrated.
+		{150, resistive, 25},
+	} {
+		if got, err := soilMoisture(tc.raw, tc.c); err != nil || got != tc.want {
+			t.Fatalf("soilMoisture(%d, %+v) = %f, %v, want %f", tc.raw, tc.c, got, err, tc.want)
+		}
+	}
+	if _, err := soilMoisture(10, SoilCalibration{}); err == nil {
+		t.Fatalf("Expected error for an uncalibrated sensor")
+	}
+}
+
+func TestSoilConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "soil.json")
+	c, err := LoadSoilConfig(path)
+	if err != nil || len(c) != 0 {
+		t.Fatalf("Missing config = %v, %v, want empty", c, err)
+	}
+
+	c["tomatoes"] = SoilCalibration{Dry: 810, Wet: 390}
+	if err = c.Save(path); err != nil {
+		t.Fatalf("Save: %s", err)
+	}
+	if c, err = LoadSoilConfig(path); err != nil || c["tomatoes"] != (SoilCalibration{Dry: 810, Wet: 390}) {
+		t.Fatalf("Loaded %v, %v", c, err)
+	}
+}
+
+func TestSoilThreshold(t *testing.T) {
+	b := newTestBoard(t, nil, map[byte][]Capability{14: {{ANALOG, 10}}}, nil)
+	s, err := NewSoilSensor(b, 14)
+	if err != nil {
+		t.Fatalf("NewSoilSensor: %s", err)
+	}
+	s.Calibration = SoilCalibration{Dry: 1000, Wet: 0}
+	sub := b.bus.Subscribe(TopicSoil, 4)
+
+	for _, raw := range []int{500, 750, 710, 690, 670} { // 50, 25, 29, 31, 33%.
+		b.pins[14].analogVal = raw
+		s.check()
+	}
+	if len(sub.C) != 2 {
+		t.Fatalf("Expected a dry and a wet event, got %d", len(sub.C))
+	}
+	if e := (<-sub.C).Data.(SoilEvent); !e.Dry || e.Moisture != 25 {
+		t.Fatalf("First event %+v, want dry at 25%%", e)
+	}
+	if e := (<-sub.C).Data.(SoilEvent); e.Dry || e.Moisture != 33 {
+		t.Fatalf("Second event %+v, want wet at 33%%", e)
+	}
+}
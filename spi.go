@@ -0,0 +1,160 @@
+package gadget
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+const (
+	// SPI sub commands.
+	spiBegin        byte = 0x00 // channel
+	spiDeviceConfig byte = 0x01 // device, mode and bit order, speed (5 bytes), word size, cs options, cs pin
+	spiTransfer     byte = 0x02 // device, request id, deselect, words, data as 7-bit pairs
+	spiRead         byte = 0x04 // device, request id, deselect, words
+	spiReply        byte = 0x05 // device, request id, words, data as 7-bit pairs
+
+	// Enables chip select handling by the firmware.
+	spiCSEnable byte = 0x01
+
+	// How long SPI reads wait for the board to reply.
+	spiReplyTimeout = time.Second
+
+	// Device ids are 4 bits.
+	spiMaxDevices = 16
+)
+
+// A decoded SPI reply.
+type spiReplyData struct {
+	id   byte
+	data []byte
+}
+
+// SPIDevice is a device on the board's SPI bus, driven by the SpiFirmata
+// extension of ConfigurableFirmata. The firmware drives its chip select
+// pin around each transfer.
+type SPIDevice struct {
+	board   *Board
+	dev     byte // Device id and channel, as sent.
+	cs      byte
+	replies chan spiReplyData
+
+	m   sync.Mutex // One transfer at a time.
+	req byte       // Id of the next request.
+}
+
+// SPIDevice sets up the device selected by csPin. Mode is the SPI mode,
+// 0-3, and speed the maximum clock rate in Hz. Bytes are sent most
+// significant bit first.
+func (b *Board) SPIDevice(csPin, mode byte, speed int) (d *SPIDevice, err error) {
+	if err = b.require(FeatureSPI); err != nil {
+		return nil, err
+	}
+	if mode > 3 {
+		return nil, fmt.Errorf("Invalid SPI mode: %d", mode)
+	}
+	if speed <= 0 {
+		return nil, fmt.Errorf("Invalid SPI speed: %d", speed)
+	}
+
+	b.m.Lock()
+	if _, err = b.sendablePin(csPin); err != nil {
+		b.m.Unlock()
+		return nil, err
+	}
+	if b.spiDevices == spiMaxDevices {
+		b.m.Unlock()
+		return nil, fmt.Errorf("Too many SPI devices, the maximum is %d", spiMaxDevices)
+	}
+	id := b.spiDevices
+	b.spiDevices++
+	b.m.Unlock()
+
+	d = &SPIDevice{board: b, dev: id << 3, cs: csPin, replies: make(chan spiReplyData, 1)}
+	b.addHandler(spiData, d.handleReply)
+
+	if id == 0 {
+		if _, err = b.sendSysex([]byte{spiData, spiBegin, 0}); err != nil {
+			return nil, err
+		}
+	}
+	msg := []byte{spiData, spiDeviceConfig, d.dev, mode<<1 | 1}
+	for i := 0; i < 5; i++ {
+		msg = append(msg, byte(speed>>(7*i))&0x7F)
+	}
+	msg = append(msg, 0, spiCSEnable, csPin) // 8-bit words.
+	if _, err = b.sendSysex(msg); err != nil {
+		return nil, err
+	}
+	return d, b.out.Flush()
+}
+
+// Read clocks in n bytes, sending zeros.
+func (d *SPIDevice) Read(n int) ([]byte, error) {
+	return d.request(spiRead, nil, n)
+}
+
+// Transfer sends data and returns the bytes clocked in at the same
+// time.
+func (d *SPIDevice) Transfer(data []byte) ([]byte, error) {
+	return d.request(spiTransfer, data, len(data))
+}
+
+// Sends a read or transfer and waits for its reply.
+func (d *SPIDevice) request(cmd byte, data []byte, n int) (reply []byte, err error) {
+	if n < 1 || n > 0x7F {
+		return nil, fmt.Errorf("SPI transfers must be 1-127 bytes, got %d", n)
+	}
+
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	// Drop any stale reply left by a previous timed out request.
+	select {
+	case <-d.replies:
+	default:
+	}
+
+	id := d.req
+	d.req = (d.req + 1) & 0x7F
+	msg := []byte{spiData, cmd, d.dev, id, 1, byte(n)} // Deselect after.
+	msg = firmatawire.AppendBytes7(msg, data)
+	if _, err = d.board.sendSysex(msg); err != nil {
+		return nil, err
+	}
+	if err = d.board.out.Flush(); err != nil {
+		return nil, err
+	}
+
+	expired := time.After(spiReplyTimeout)
+	for {
+		select {
+		case r := <-d.replies:
+			if r.id != id {
+				continue
+			}
+			if len(r.data) != n {
+				return nil, fmt.Errorf("SPI device on pin %d: expected %d bytes, got %d", d.cs, n, len(r.data))
+			}
+			return r.data, nil
+		case <-expired:
+			return nil, fmt.Errorf("Timed out waiting for SPI device on pin %d", d.cs)
+		}
+	}
+}
+
+// Passes a reply for this device to the waiting request.
+func (d *SPIDevice) handleReply(m message) {
+	// Sysex start, cmd, sub cmd, device, request id, words, data, end.
+	if len(m.data) < 7 || m.data[2] != spiReply || m.data[3] != d.dev {
+		return
+	}
+	r := spiReplyData{id: m.data[4], data: firmatawire.Bytes7(m.data[6 : len(m.data)-1])}
+
+	select {
+	case d.replies <- r:
+	default:
+	}
+}
@@ -0,0 +1,100 @@
+package gadget
+
+import (
+	"expvar"
+	"io"
+	"sync/atomic"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+// Stats are counters of the traffic with a board since it was opened.
+type Stats struct {
+	// Messages by Firmata command name, e.g. "ANALOG_MESSAGE".
+	FramesIn  map[string]int64
+	FramesOut map[string]int64
+
+	BytesIn  int64
+	BytesOut int64
+
+	ParseErrors   int64 // Read errors other than lost sync and timeouts.
+	Resyncs       int64 // Times the stream was found out of step.
+	WriteErrors   int64 // Failed writes to the serial port.
+	DroppedEvents int64 // Events not delivered to a full subscriber.
+}
+
+// The live counters behind Stats.
+type counters struct {
+	framesIn, framesOut [256]atomic.Int64 // By command.
+
+	bytesIn, bytesOut atomic.Int64
+	parseErrors       atomic.Int64
+	writeErrors       atomic.Int64
+}
+
+// Counts the messages in a write to the serial port. Only the command
+// bytes are looked at, so batched writes are counted correctly.
+func (c *counters) countOut(p []byte) {
+	c.bytesOut.Add(int64(len(p)))
+	inSysex := false
+	for i, d := range p {
+		switch {
+		case d == endSysex:
+			inSysex = false
+		case inSysex || d < 0x80:
+		case d == startSysex:
+			inSysex = true
+			if i+1 < len(p) {
+				c.framesOut[p[i+1]].Add(1)
+			}
+		default:
+			c.framesOut[firmatawire.Command(p[i:i+1])].Add(1)
+		}
+	}
+}
+
+// Counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	c *counters
+}
+
+func (cw countingWriter) Write(p []byte) (n int, err error) {
+	cw.c.countOut(p)
+	if n, err = cw.w.Write(p); err != nil {
+		cw.c.writeErrors.Add(1)
+	}
+	return
+}
+
+// Stats returns the board's traffic counters.
+func (b *Board) Stats() Stats {
+	s := Stats{
+		FramesIn:      make(map[string]int64),
+		FramesOut:     make(map[string]int64),
+		BytesIn:       b.stats.bytesIn.Load(),
+		BytesOut:      b.stats.bytesOut.Load(),
+		ParseErrors:   b.stats.parseErrors.Load(),
+		WriteErrors:   b.stats.writeErrors.Load(),
+		DroppedEvents: b.bus.Dropped(),
+	}
+	if b.dec != nil {
+		s.Resyncs = b.dec.Resyncs()
+	}
+	for cmd := range b.stats.framesIn {
+		if n := b.stats.framesIn[cmd].Load(); n > 0 {
+			s.FramesIn[firmatawire.CommandName(byte(cmd))] = n
+		}
+		if n := b.stats.framesOut[cmd].Load(); n > 0 {
+			s.FramesOut[firmatawire.CommandName(byte(cmd))] = n
+		}
+	}
+	return s
+}
+
+// PublishExpvar publishes the board's Stats as the expvar variable name,
+// served at /debug/vars by the expvar package. Like expvar.Publish it
+// panics if the name is already in use.
+func (b *Board) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() any { return b.Stats() }))
+}
@@ -0,0 +1,29 @@
+package gadget
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	var out bytes.Buffer
+	b := &Board{bus: NewEventBus()}
+	w := countingWriter{&out, &b.stats}
+
+	// A batched write holding three messages.
+	w.Write([]byte{setPinMode, 13, 1, startSysex, samplingInterval, 0x13, 0, endSysex, digitalMessage | 1, 0x20, 0})
+	w.Write([]byte{digitalMessage | 2, 0x01, 0})
+
+	sub := b.bus.Subscribe("#", 0)
+	defer sub.Unsubscribe()
+	b.bus.Publish(Event{Topic: TopicReady})
+
+	s := b.Stats()
+	if s.BytesOut != 14 || s.FramesOut["DIGITAL_MESSAGE"] != 2 || s.FramesOut["SAMPLING_INTERVAL"] != 1 ||
+		s.FramesOut["SET_PIN_MODE"] != 1 || len(s.FramesOut) != 3 {
+		t.Errorf("Unexpected output stats: %+v", s)
+	}
+	if s.DroppedEvents != 1 {
+		t.Errorf("Expected 1 dropped event, got %d", s.DroppedEvents)
+	}
+}
@@ -0,0 +1,111 @@
+package gadget
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+// How many readings a Stream holds for a slow receiver before dropping
+// new ones.
+const streamBuffer = 64
+
+// Stream delivers every report of an analog pin, see Board.Stream.
+type Stream struct {
+	// Receives a Reading for every report, after any filter. Closed by
+	// Close.
+	C <-chan Reading
+
+	board *Board
+	pin   byte
+	c     chan Reading
+	once  sync.Once
+}
+
+// Stream opens a stream of the analog pin's readings. The pin is put in
+// ANALOG mode and reporting is turned on while any stream of it is open.
+//
+// A positive rate, in samples per second, sets the board's sampling
+// interval. The interval is shared by every pin, so the most recent
+// setting wins.
+func (b *Board) Stream(pin byte, rate float64) (s *Stream, err error) {
+	if rate > 0 {
+		if err = b.SetSamplingInterval(time.Duration(float64(time.Second) / rate)); err != nil {
+			return nil, err
+		}
+	}
+	if err = b.ensurePinMode(pin, ANALOG); err != nil {
+		return nil, err
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	p, ok := b.pins[pin]
+	if !ok {
+		return nil, fmt.Errorf("Invalid pin: %d", pin)
+	}
+	if len(p.streams) == 0 && !p.reporting {
+		if err = b.setReporting(p, true); err != nil {
+			return nil, err
+		}
+		p.streamReporting = true
+	}
+
+	c := make(chan Reading, streamBuffer)
+	s = &Stream{C: c, board: b, pin: pin, c: c}
+	if p.streams == nil {
+		p.streams = make(map[*Stream]bool)
+	}
+	p.streams[s] = true
+	return
+}
+
+// Close stops the stream and closes C. Reporting is turned off when the
+// pin's last stream closes, unless it was on before the first opened.
+func (s *Stream) Close() (err error) {
+	s.once.Do(func() {
+		b := s.board
+		b.m.Lock()
+		defer b.m.Unlock()
+
+		p := b.pins[s.pin]
+		delete(p.streams, s)
+		close(s.c)
+		if len(p.streams) == 0 && p.streamReporting {
+			p.streamReporting = false
+			err = b.setReporting(p, false)
+		}
+	})
+	return
+}
+
+// Sends a reading to the pin's streams. Must be called with b.m held.
+func (p *pin) stream(r Reading) {
+	for s := range p.streams {
+		select {
+		case s.c <- r:
+		default: // Dropped, the receiver is not keeping up.
+		}
+	}
+}
+
+// SetSamplingInterval sets how often the board reports analog inputs
+// and I2C continuous reads. Firmata's default is 19ms.
+func (b *Board) SetSamplingInterval(d time.Duration) (err error) {
+	ms := d.Milliseconds()
+	if ms < 1 || ms > 0x3FFF {
+		return fmt.Errorf("Invalid sampling interval: %s", d)
+	}
+	b.m.Lock()
+	b.sampling = d
+	b.m.Unlock()
+	_, err = b.sendSysex(samplingIntervalMsg(d))
+	return
+}
+
+func samplingIntervalMsg(d time.Duration) []byte {
+	return firmatawire.AppendUint14([]byte{samplingInterval}, int(d.Milliseconds()))
+}
@@ -0,0 +1,42 @@
+package gadget
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	var out bytes.Buffer
+	b := &Board{
+		pins:           map[byte]*pin{14: newPin(&out, 14, 0, []Capability{{ANALOG, 10}})},
+		analogToNormal: []byte{14},
+		bus:            NewEventBus(),
+		out:            newBatchWriter(&out),
+	}
+	out.Reset()
+
+	s, err := b.Stream(14, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{startSysex, samplingInterval, 10, 0, endSysex, reportAnalog, 1}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("Stream sent % X, want % X", out.Bytes(), want)
+	}
+
+	// Repeated values are streamed even though no event is published.
+	for i := 0; i < 2; i++ {
+		b.handleAnalogMessage(message{data: []byte{analogMessage, 0x10, 0x01}})
+	}
+	for i := 0; i < 2; i++ {
+		if r := <-s.C; r.Value != 0x90 {
+			t.Fatalf("Reading %d = %d, want %d", i, r.Value, 0x90)
+		}
+	}
+
+	out.Reset()
+	s.Close()
+	if _, ok := <-s.C; ok || !bytes.Equal(out.Bytes(), []byte{reportAnalog, 0}) {
+		t.Fatalf("Close should close C and stop reporting, sent % X", out.Bytes())
+	}
+}
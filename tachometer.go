@@ -0,0 +1,115 @@
+package gadget
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Tachometer measures the speed of a shaft from a hall effect sensor's
+// pulses, timed from the pin's digital reports. The time between
+// pulses is only as accurate as the board's loop and the serial link,
+// a millisecond or so, so it suits slow shafts and few pulses per
+// revolution best.
+type Tachometer struct {
+	board *Board
+	pin   byte
+
+	// Pulses per revolution, e.g. the number of magnets. Defaults to 1.
+	PulsesPerRev int
+
+	// The number of pulse periods averaged. Defaults to PulsesPerRev,
+	// a whole revolution, which hides uneven magnet spacing.
+	Average int
+
+	// The shaft counts as stopped when no pulse arrives for this
+	// long. Defaults to 2 seconds, 30 RPM with one pulse per
+	// revolution.
+	Timeout time.Duration
+
+	m      sync.Mutex
+	pulses []time.Time // The latest pulses, oldest first.
+	sub    *Subscription
+}
+
+// NewTachometer returns a Tachometer on the given digital pin, which is
+// put in PULLUP mode, as most hall sensors have open collector outputs,
+// with reporting turned on. Pulses are counted on falling edges, when
+// a magnet arrives.
+func NewTachometer(b *Board, pin byte) (t *Tachometer, err error) {
+	if err = b.ensurePinMode(pin, PULLUP); err != nil {
+		return nil, err
+	}
+	if err = b.SetPinReporting(pin, true); err != nil {
+		return nil, err
+	}
+
+	t = &Tachometer{
+		board:        b,
+		pin:          pin,
+		PulsesPerRev: 1,
+		Timeout:      2 * time.Second,
+	}
+	return
+}
+
+// Start times pulses in the background.
+func (t *Tachometer) Start() (err error) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	if t.sub != nil {
+		return fmt.Errorf("Tachometer on pin %d already started", t.pin)
+	}
+	t.pulses = nil
+	t.sub = t.board.onEdge(t.pin, LOW, t.pulse)
+	return
+}
+
+// Halt stops timing.
+func (t *Tachometer) Halt() (err error) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	if t.sub != nil {
+		t.sub.Unsubscribe()
+		t.sub = nil
+	}
+	return
+}
+
+// RPM returns the shaft's speed in revolutions per minute, 0 if it has
+// stopped.
+func (t *Tachometer) RPM() float64 {
+	return t.rpm(time.Now())
+}
+
+func (t *Tachometer) pulse(at time.Time) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	t.pulses = append(t.pulses, at)
+	if n := t.periods() + 1; len(t.pulses) > n {
+		t.pulses = append(t.pulses[:0], t.pulses[len(t.pulses)-n:]...)
+	}
+}
+
+func (t *Tachometer) rpm(now time.Time) float64 {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	n := len(t.pulses)
+	if n < 2 || now.Sub(t.pulses[n-1]) > t.Timeout {
+		return 0
+	}
+	period := t.pulses[n-1].Sub(t.pulses[0]) / time.Duration(n-1)
+	return 60 / (period.Seconds() * float64(max(t.PulsesPerRev, 1)))
+}
+
+// Returns the number of periods averaged. Must be called with t.m held.
+func (t *Tachometer) periods() int {
+	if t.Average > 0 {
+		return t.Average
+	}
+	return max(t.PulsesPerRev, 1)
+}
@@ -0,0 +1,50 @@
+package gadget
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTachometer(t *testing.T) {
+	b := newTestBoard(t, nil, nil, map[byte][]Capability{3: {{INPUT, 1}, {OUTPUT, 1}, {PULLUP, 1}}})
+	tach, err := NewTachometer(b, 3)
+	if err != nil {
+		t.Fatalf("NewTachometer: %s", err)
+	}
+	tach.PulsesPerRev = 2
+
+	// Two magnets passing every 50ms is 600 RPM.
+	start := time.Now()
+	if r := tach.rpm(start); r != 0 {
+		t.Fatalf("RPM with no pulses = %f, want 0", r)
+	}
+	for i := 0; i < 10; i++ {
+		tach.pulse(start.Add(time.Duration(i) * 50 * time.Millisecond))
+	}
+	last := start.Add(450 * time.Millisecond)
+	if r := tach.rpm(last); math.Abs(r-600) > 0.01 {
+		t.Fatalf("RPM = %f, want 600", r)
+	}
+	if len(tach.pulses) != 3 {
+		t.Fatalf("Kept %d pulses, want 3", len(tach.pulses))
+	}
+	if r := tach.rpm(last.Add(3 * time.Second)); r != 0 {
+		t.Fatalf("RPM after stopping = %f, want 0", r)
+	}
+
+	// Pulses are timed from falling edges.
+	tach.Start()
+	defer tach.Halt()
+	at := time.Now()
+	for i := 0; i < 3; i++ {
+		b.handleDigitalMessage(message{data: []byte{digitalMessage, 0x08, 0}, at: at})
+		at = at.Add(100 * time.Millisecond)
+		b.handleDigitalMessage(message{data: []byte{digitalMessage, 0x00, 0}, at: at})
+	}
+	for deadline := time.Now().Add(time.Second); tach.rpm(at) != 300; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("RPM from edges = %f, want 300", tach.rpm(at))
+		}
+	}
+}
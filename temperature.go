@@ -0,0 +1,106 @@
+package gadget
+
+import (
+	"time"
+)
+
+// StandardFirmata's default sampling interval, plus a little slack.
+const defaultSampleDelay = 20 * time.Millisecond
+
+// TempModel describes how an analog temperature sensor's output
+// voltage relates to temperature.
+type TempModel struct {
+	Offset float64 // Output voltage at 0°C.
+	Scale  float64 // Volts per °C.
+}
+
+var (
+	// TMP36 outputs 500mV at 0°C, 10mV/°C.
+	TMP36 = TempModel{Offset: 0.5, Scale: 0.01}
+
+	// LM35 outputs 0mV at 0°C, 10mV/°C.
+	LM35 = TempModel{Offset: 0, Scale: 0.01}
+)
+
+// TempSensor is an analog temperature sensor such as the TMP36 or LM35.
+type TempSensor struct {
+	board *Board
+	pin   byte // Normal (not A0 style) pin number.
+	max   int  // Full scale raw reading.
+	model TempModel
+
+	// The analog reference voltage, defaults to the board's, see
+	// SetAnalogReference.
+	Reference float64
+
+	// Number of readings averaged per measurement, defaults to 1.
+	// Readings are taken SampleDelay apart so each one is a fresh
+	// report from the board.
+	Samples     int
+	SampleDelay time.Duration
+}
+
+// NewTempSensor returns a TempSensor of the given model on an analog
+// pin, with analog reporting turned on.
+func NewTempSensor(b *Board, pin byte, model TempModel) (s *TempSensor, err error) {
+	bits, err := b.AnalogResolution(pin)
+	if err != nil {
+		return nil, err
+	}
+	if err = b.ensurePinMode(pin, ANALOG); err != nil {
+		return nil, err
+	}
+	if err = b.SetPinReporting(pin, true); err != nil {
+		return nil, err
+	}
+
+	s = &TempSensor{
+		board:       b,
+		pin:         pin,
+		max:         analogMax(bits),
+		model:       model,
+		Reference:   b.AnalogReference(),
+		Samples:     1,
+		SampleDelay: defaultSampleDelay,
+	}
+	return
+}
+
+// Voltage returns the (averaged) sensor output in volts.
+func (s *TempSensor) Voltage() (v float64, err error) {
+	n := s.Samples
+	if n < 1 {
+		n = 1
+	}
+
+	sum := 0
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			time.Sleep(s.SampleDelay)
+		}
+		raw, err := s.board.AnalogRead(s.pin)
+		if err != nil {
+			return 0, err
+		}
+		sum += raw
+	}
+	return s.Reference * float64(sum) / float64(n*s.max), nil
+}
+
+// Celsius returns the temperature in °C.
+func (s *TempSensor) Celsius() (c float64, err error) {
+	v, err := s.Voltage()
+	if err != nil {
+		return 0, err
+	}
+	return (v - s.model.Offset) / s.model.Scale, nil
+}
+
+// Fahrenheit returns the temperature in °F.
+func (s *TempSensor) Fahrenheit() (f float64, err error) {
+	c, err := s.Celsius()
+	if err != nil {
+		return 0, err
+	}
+	return c*9/5 + 32, nil
+}
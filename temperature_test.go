@@ -0,0 +1,32 @@
+package gadget
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTempSensor(t *testing.T) {
+	b := newTestBoard(t, nil, map[byte][]Capability{14: {{ANALOG, 10}}}, nil)
+	b.pins[14].analogVal = 750
+
+	for _, tc := range []struct {
+		model TempModel
+		c, f  float64
+	}{
+		{TMP36, 25, 77},
+		{LM35, 75, 167},
+	} {
+		s, err := NewTempSensor(b, 14, tc.model)
+		if err != nil {
+			t.Fatalf("NewTempSensor: %s", err)
+		}
+		s.Reference = 1.023 // 1mV per step.
+
+		if c, err := s.Celsius(); err != nil || math.Abs(c-tc.c) > 1e-9 {
+			t.Errorf("%+v: Celsius = %f, %v, want %f", tc.model, c, err, tc.c)
+		}
+		if f, err := s.Fahrenheit(); err != nil || math.Abs(f-tc.f) > 1e-9 {
+			t.Errorf("%+v: Fahrenheit = %f, %v, want %f", tc.model, f, err, tc.f)
+		}
+	}
+}
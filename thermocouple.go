@@ -0,0 +1,98 @@
+package gadget
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Faults reported by a thermocouple amplifier.
+var (
+	ErrThermocoupleOpen     = errors.New("Thermocouple open or not connected")
+	ErrThermocoupleShortGND = errors.New("Thermocouple shorted to GND")
+	ErrThermocoupleShortVCC = errors.New("Thermocouple shorted to VCC")
+)
+
+// ThermocoupleModel is a thermocouple amplifier chip.
+type ThermocoupleModel byte
+
+const (
+	MAX6675  ThermocoupleModel = iota // 0-1024°C, 0.25°C steps.
+	MAX31855                          // -270-1800°C, 0.25°C steps, with cold junction reading.
+)
+
+// Thermocouple is a K-type thermocouple read through a MAX6675 or
+// MAX31855 amplifier on the SPI bus.
+type Thermocouple struct {
+	dev   *SPIDevice
+	model ThermocoupleModel
+}
+
+// NewThermocouple returns the amplifier of the given model selected by
+// csPin.
+func NewThermocouple(b *Board, csPin byte, model ThermocoupleModel) (t *Thermocouple, err error) {
+	if model != MAX6675 && model != MAX31855 {
+		return nil, fmt.Errorf("Unknown thermocouple model: %d", model)
+	}
+	// Both chips clock out on the falling edge, at up to 4-5MHz.
+	dev, err := b.SPIDevice(csPin, 0, 4000000)
+	if err != nil {
+		return nil, err
+	}
+	return &Thermocouple{dev: dev, model: model}, nil
+}
+
+// Read returns the thermocouple's temperature in °C, or one of the
+// Thermocouple errors if the chip reports a fault.
+func (t *Thermocouple) Read() (c float64, err error) {
+	c, _, err = t.ReadAll()
+	return
+}
+
+// ReadAll returns the thermocouple's temperature and the chip's own,
+// cold junction, temperature in °C. The MAX6675 has no cold junction
+// reading so it is always 0.
+func (t *Thermocouple) ReadAll() (c, internal float64, err error) {
+	if t.model == MAX6675 {
+		d, err := t.dev.Read(2)
+		if err != nil {
+			return 0, 0, err
+		}
+		c, err = decodeMAX6675(uint16(d[0])<<8 | uint16(d[1]))
+		return c, 0, err
+	}
+
+	d, err := t.dev.Read(4)
+	if err != nil {
+		return 0, 0, err
+	}
+	return decodeMAX31855(uint32(d[0])<<24 | uint32(d[1])<<16 | uint32(d[2])<<8 | uint32(d[3]))
+}
+
+// Decodes a MAX6675 reading: 12 bits of temperature in 0.25°C steps
+// from bit 3, with bit 2 set if the thermocouple is open.
+func decodeMAX6675(v uint16) (float64, error) {
+	if v&0x04 != 0 {
+		return 0, ErrThermocoupleOpen
+	}
+	return float64(v>>3) * 0.25, nil
+}
+
+// Decodes a MAX31855 reading: a signed 14-bit thermocouple temperature
+// in 0.25°C steps from bit 18, a fault flag at bit 16, a signed 12-bit
+// internal temperature in 0.0625°C steps from bit 4 and the fault
+// causes in bits 0-2.
+func decodeMAX31855(v uint32) (c, internal float64, err error) {
+	if v&0x10000 != 0 {
+		switch {
+		case v&0x01 != 0:
+			return 0, 0, ErrThermocoupleOpen
+		case v&0x02 != 0:
+			return 0, 0, ErrThermocoupleShortGND
+		default:
+			return 0, 0, ErrThermocoupleShortVCC
+		}
+	}
+	c = float64(int32(v)>>18) * 0.25
+	internal = float64(int32(v<<16)>>20) * 0.0625
+	return
+}
@@ -0,0 +1,73 @@
+package gadget
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+func TestDecodeThermocouple(t *testing.T) {
+	if c, err := decodeMAX6675(0x0C80); err != nil || c != 100 {
+		t.Fatalf("MAX6675 = %f, %v, want 100", c, err)
+	}
+	if _, err := decodeMAX6675(0x0C84); err != ErrThermocoupleOpen {
+		t.Fatalf("Expected open thermocouple, got %v", err)
+	}
+
+	// From the MAX31855 datasheet: 100°C and 25°C, then -250°C and
+	// -0.0625°C.
+	for _, tc := range []struct {
+		v           uint32
+		c, internal float64
+	}{
+		{0x06401900, 100, 25},
+		{0xF060FFF0, -250, -0.0625},
+	} {
+		c, internal, err := decodeMAX31855(tc.v)
+		if err != nil || c != tc.c || internal != tc.internal {
+			t.Fatalf("MAX31855 %08X = %f, %f, %v, want %f, %f", tc.v, c, internal, err, tc.c, tc.internal)
+		}
+	}
+	for v, want := range map[uint32]error{
+		0x00010001: ErrThermocoupleOpen,
+		0x00010002: ErrThermocoupleShortGND,
+		0x00010004: ErrThermocoupleShortVCC,
+	} {
+		if _, _, err := decodeMAX31855(v); err != want {
+			t.Fatalf("MAX31855 %08X: got %v, want %v", v, err, want)
+		}
+	}
+}
+
+// Answers every SPI read with the MAX6675 reading for 100°C.
+type fakeMAX6675 struct{ b *Board }
+
+func (f fakeMAX6675) Write(p []byte) (int, error) {
+	if len(p) > 5 && p[1] == spiData && p[2] == spiRead {
+		reply := firmatawire.AppendBytes7([]byte{spiReply, p[3], p[4], 2}, []byte{0x0C, 0x80})
+		go f.b.handleCallback(message{t: sysexMsg, data: firmatawire.Sysex(spiData, reply...)})
+	}
+	return len(p), nil
+}
+
+func TestThermocouple(t *testing.T) {
+	var out bytes.Buffer
+	b := newTestBoard(t, &out, nil, map[byte][]Capability{10: {{OUTPUT, 1}, {SPI, 1}}})
+	out.Reset()
+
+	tc, err := NewThermocouple(b, 10, MAX6675)
+	if err != nil {
+		t.Fatalf("NewThermocouple: %s", err)
+	}
+	want := firmatawire.Sysex(spiData, spiBegin, 0)
+	want = append(want, firmatawire.Sysex(spiData, spiDeviceConfig, 0, 1, 0x00, 0x12, 0x74, 0x01, 0x00, 0, spiCSEnable, 10)...)
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("Setup sent\n% X\nwant\n% X", out.Bytes(), want)
+	}
+
+	b.out = newBatchWriter(fakeMAX6675{b})
+	if c, err := tc.Read(); err != nil || c != 100 {
+		t.Fatalf("Read = %f, %v, want 100", c, err)
+	}
+}
@@ -0,0 +1,55 @@
+package gadget
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+// WithTrace writes a line to w for every message sent to or received
+// from the board, e.g.
+//
+//	14:02:07.120391 > SET_PIN_MODE F4 0D 01
+//	14:02:07.141876 < ANALOG_MESSAGE E0 12 04
+//
+// ">" lines are sent and "<" lines received. Errors reading from the
+// board, such as lost sync, are written as "!" lines.
+func WithTrace(w io.Writer) Option {
+	return func(b *Board) { b.tracer = &tracer{w: w} }
+}
+
+// Writes trace lines from the reading and writing goroutines.
+type tracer struct {
+	m sync.Mutex
+	w io.Writer
+}
+
+func (t *tracer) frame(dir string, at time.Time, frame []byte) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	fmt.Fprintf(t.w, "%s %s %s % X\n", at.Format("15:04:05.000000"), dir, firmatawire.CommandName(firmatawire.Command(frame)), frame)
+}
+
+func (t *tracer) error(at time.Time, err error) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	fmt.Fprintf(t.w, "%s ! %s\n", at.Format("15:04:05.000000"), err)
+}
+
+// Traces the messages written to w. A write may hold several messages
+// when they were batched.
+type traceWriter struct {
+	w io.Writer
+	t *tracer
+}
+
+func (tw traceWriter) Write(p []byte) (n int, err error) {
+	now := time.Now()
+	for _, f := range firmatawire.Split(p) {
+		tw.t.frame(">", now, f)
+	}
+	return tw.w.Write(p)
+}
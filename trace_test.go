@@ -0,0 +1,22 @@
+package gadget
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTraceWriter(t *testing.T) {
+	var trace, out bytes.Buffer
+	tw := traceWriter{&out, &tracer{w: &trace}}
+	tw.Write([]byte{setPinMode, 13, 1, analogMessage | 3, 0x7F, 0x01})
+
+	lines := strings.Split(strings.TrimSpace(trace.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "> SET_PIN_MODE F4 0D 01") ||
+		!strings.HasSuffix(lines[1], "> ANALOG_MESSAGE E3 7F 01") {
+		t.Fatalf("Unexpected trace:\n%s", trace.String())
+	}
+	if out.Len() != 6 {
+		t.Fatalf("Trace should pass writes through, got % X", out.Bytes())
+	}
+}
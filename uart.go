@@ -0,0 +1,193 @@
+package gadget
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+// UARTPort is one of the board's serial ports, as numbered by
+// SerialFirmata.
+type UARTPort byte
+
+const (
+	HWSerial0 UARTPort = 0x00 // Usually the port Firmata itself uses.
+	HWSerial1 UARTPort = 0x01
+	HWSerial2 UARTPort = 0x02
+	HWSerial3 UARTPort = 0x03
+	SWSerial0 UARTPort = 0x08 // Software serial, on any two pins.
+	SWSerial1 UARTPort = 0x09
+	SWSerial2 UARTPort = 0x0A
+	SWSerial3 UARTPort = 0x0B
+)
+
+const (
+	// Serial message sub commands, or'd with the port.
+	uartConfig byte = 0x10 // baud (3 bytes), rx pin, tx pin
+	uartWrite  byte = 0x20 // data as 7-bit pairs
+	uartRead   byte = 0x30 // read mode
+	uartReply  byte = 0x40 // data as 7-bit pairs
+	uartClose  byte = 0x50
+
+	// Read modes.
+	uartReadContinuous byte = 0x00
+	uartStopReading    byte = 0x01
+)
+
+// UART is a serial port on the board, passed through to the host by the
+// SerialFirmata extension, e.g. to talk to a GPS or GSM module. It is an
+// io.ReadWriteCloser.
+type UART struct {
+	board *Board
+	port  UARTPort
+
+	m        sync.Mutex
+	buf      bytes.Buffer
+	deadline time.Time
+	closed   bool
+	arrived  chan bool // Signalled when data arrives or the port closes.
+}
+
+// OpenUART configures the port at baud and starts passing through what
+// it receives. Software serial ports are on pins rx and tx, which are
+// ignored for hardware ports.
+func (b *Board) OpenUART(port UARTPort, baud int, rx, tx byte) (u *UART, err error) {
+	if err = b.require(FeatureSerial); err != nil {
+		return nil, err
+	}
+	if baud <= 0 || baud >= 1<<21 {
+		return nil, fmt.Errorf("Invalid baud rate: %d", baud)
+	}
+
+	if port >= SWSerial0 {
+		if err = b.checkPins(rx, tx); err != nil {
+			return nil, err
+		}
+	}
+	u = &UART{board: b, port: port, arrived: make(chan bool, 1)}
+	b.addHandler(serialMessage, u.handleReply)
+
+	msg := []byte{serialMessage, uartConfig | byte(port), byte(baud) & 0x7F, byte(baud>>7) & 0x7F, byte(baud>>14) & 0x7F}
+	if port >= SWSerial0 {
+		msg = append(msg, rx, tx)
+	}
+	if _, err = b.sendSysex(msg); err != nil {
+		return nil, err
+	}
+	if _, err = b.sendSysex([]byte{serialMessage, uartRead | byte(port), uartReadContinuous}); err != nil {
+		return nil, err
+	}
+	return u, b.out.Flush()
+}
+
+// Write sends p out of the port.
+func (u *UART) Write(p []byte) (n int, err error) {
+	if u.isClosed() {
+		return 0, io.ErrClosedPipe
+	}
+	msg := firmatawire.AppendBytes7([]byte{serialMessage, uartWrite | byte(u.port)}, p)
+	if _, err = u.board.sendSysex(msg); err != nil {
+		return 0, err
+	}
+	return len(p), u.board.out.Flush()
+}
+
+// Read reads what the port has received, waiting for data if there is
+// none. It fails with os.ErrDeadlineExceeded once the deadline set by
+// SetReadDeadline passes.
+func (u *UART) Read(p []byte) (n int, err error) {
+	for {
+		u.m.Lock()
+		if u.buf.Len() > 0 {
+			n, _ = u.buf.Read(p)
+			u.m.Unlock()
+			return n, nil
+		}
+		closed, deadline := u.closed, u.deadline
+		u.m.Unlock()
+
+		if closed {
+			return 0, io.EOF
+		}
+		if deadline.IsZero() {
+			<-u.arrived
+			continue
+		}
+		d := time.Until(deadline)
+		if d <= 0 {
+			return 0, os.ErrDeadlineExceeded
+		}
+		t := time.NewTimer(d)
+		select {
+		case <-u.arrived:
+			t.Stop()
+		case <-t.C:
+			return 0, os.ErrDeadlineExceeded
+		}
+	}
+}
+
+// SetReadDeadline sets when reads give up waiting, including a read
+// already waiting. A zero time waits forever.
+func (u *UART) SetReadDeadline(t time.Time) error {
+	u.m.Lock()
+	u.deadline = t
+	u.m.Unlock()
+	u.signal()
+	return nil
+}
+
+// Close stops the passthrough and closes the port on the board.
+// Waiting reads return io.EOF.
+func (u *UART) Close() (err error) {
+	u.m.Lock()
+	if u.closed {
+		u.m.Unlock()
+		return nil
+	}
+	u.closed = true
+	u.m.Unlock()
+	u.signal()
+
+	if _, err = u.board.sendSysex([]byte{serialMessage, uartRead | byte(u.port), uartStopReading}); err != nil {
+		return err
+	}
+	if _, err = u.board.sendSysex([]byte{serialMessage, uartClose | byte(u.port)}); err != nil {
+		return err
+	}
+	return u.board.out.Flush()
+}
+
+func (u *UART) isClosed() bool {
+	u.m.Lock()
+	defer u.m.Unlock()
+	return u.closed
+}
+
+func (u *UART) signal() {
+	select {
+	case u.arrived <- true:
+	default:
+	}
+}
+
+// Buffers data received by this port.
+func (u *UART) handleReply(m message) {
+	// Sysex start, cmd, sub cmd and port, data, end.
+	if len(m.data) < 4 || m.data[2] != uartReply|byte(u.port) {
+		return
+	}
+	data := firmatawire.Bytes7(m.data[3 : len(m.data)-1])
+
+	u.m.Lock()
+	if !u.closed {
+		u.buf.Write(data)
+	}
+	u.m.Unlock()
+	u.signal()
+}
@@ -0,0 +1,57 @@
+package gadget
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ZachMassia/GoGoGadget/firmatawire"
+)
+
+func TestUART(t *testing.T) {
+	var out bytes.Buffer
+	b := newTestBoard(t, &out, nil, nil)
+	if _, err := b.OpenUART(SWSerial0, 9600, 10, 11); err == nil {
+		t.Fatalf("Expected error without SERIAL support")
+	}
+	b.initPins(nil, map[byte][]Capability{10: {{SERIAL, 1}}, 11: {{SERIAL, 1}}})
+
+	u, err := b.OpenUART(SWSerial0, 9600, 10, 11)
+	if err != nil {
+		t.Fatalf("OpenUART: %s", err)
+	}
+	var want []byte
+	want = append(want, firmatawire.Sysex(serialMessage, uartConfig|0x08, 0x00, 0x4B, 0x00, 10, 11)...)
+	want = append(want, firmatawire.Sysex(serialMessage, uartRead|0x08, uartReadContinuous)...)
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("OpenUART sent\n% X\nwant\n% X", out.Bytes(), want)
+	}
+
+	out.Reset()
+	u.Write([]byte("AT\r"))
+	want = firmatawire.Sysex(serialMessage, firmatawire.AppendBytes7([]byte{uartWrite | 0x08}, []byte("AT\r"))...)
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("Write sent % X, want % X", out.Bytes(), want)
+	}
+
+	// Replies for other ports are ignored.
+	b.handleCallback(message{t: sysexMsg, data: firmatawire.Sysex(serialMessage, firmatawire.AppendBytes7([]byte{uartReply | 0x01}, []byte("no"))...)})
+	b.handleCallback(message{t: sysexMsg, data: firmatawire.Sysex(serialMessage, firmatawire.AppendBytes7([]byte{uartReply | 0x08}, []byte("OK\r\n"))...)})
+	buf := make([]byte, 16)
+	if n, err := u.Read(buf); err != nil || string(buf[:n]) != "OK\r\n" {
+		t.Fatalf("Read = %q, %v, want OK", buf[:n], err)
+	}
+
+	u.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	if _, err := u.Read(buf); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Expected deadline error, got %v", err)
+	}
+
+	u.Close()
+	if _, err := u.Read(buf); err != io.EOF {
+		t.Fatalf("Expected EOF after Close, got %v", err)
+	}
+}
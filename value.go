@@ -0,0 +1,116 @@
+package gadget
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+)
+
+// PinValue is a pin's mode and value at a point in time, see ReadValue.
+type PinValue struct {
+	Pin   byte
+	Mode  byte
+	Value int
+	Time  time.Time
+}
+
+// ReadValue returns the pin's mode and its value in that mode: the
+// digital value of INPUT, PULLUP and OUTPUT pins and the analog value of
+// all others.
+func (b *Board) ReadValue(pin byte) (mode byte, v int, err error) {
+	if mode, err = b.PinMode(pin); err != nil {
+		return mode, 0, err
+	}
+
+	switch mode {
+	case INPUT, PULLUP, OUTPUT:
+		var s byte
+		s, err = b.DigitalRead(pin)
+		v = int(s)
+	default:
+		v, err = b.AnalogRead(pin)
+	}
+	return
+}
+
+// WatchValues calls fn with the PinValue of each of the pins, then
+// again each time one's mode or value changes, until ctx is done or fn
+// returns an error. Nil pins watches every pin.
+func (b *Board) WatchValues(ctx context.Context, pins []byte, fn func(PinValue) error) error {
+	// Subscribe before reading the current state so no change is missed.
+	sub := b.bus.Subscribe("pin/#", 64)
+	defer sub.Unsubscribe()
+
+	if pins == nil {
+		for _, p := range b.Pins() {
+			pins = append(pins, p.Pin)
+		}
+	}
+	last := make(map[byte]PinValue)
+	send := func(n byte, t time.Time) error {
+		mode, v, err := b.ReadValue(n)
+		if err != nil {
+			return err
+		}
+		if l, ok := last[n]; ok && l.Mode == mode && l.Value == v {
+			return nil
+		}
+		last[n] = PinValue{Pin: n, Mode: mode, Value: v, Time: t}
+		return fn(last[n])
+	}
+
+	for _, n := range pins {
+		if err := send(n, time.Now()); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e := <-sub.C:
+			if bytes.IndexByte(pins, e.Pin) < 0 {
+				continue
+			}
+			if err := send(e.Pin, e.Time); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// WriteValue writes v according to the pin's mode: OUTPUT pins take 0
+// or 1, PWM pins 0-255 and SERVO pins an angle or pulse width.
+func (b *Board) WriteValue(pin byte, v int) error {
+	mode, err := b.PinMode(pin)
+	if err != nil {
+		return err
+	}
+
+	switch mode {
+	case OUTPUT:
+		if v != 0 && v != 1 {
+			return fmt.Errorf("Digital value must be 0 or 1, got %d", v)
+		}
+		return b.DigitalWrite(pin, byte(v))
+	case PWM:
+		if v < 0 || v > 255 {
+			return fmt.Errorf("PWM value must be 0-255, got %d", v)
+		}
+		return b.AnalogWrite(pin, byte(v))
+	case SERVO:
+		return b.ServoWrite(pin, v)
+	}
+	return fmt.Errorf("Pin %d in %s mode cannot be written", pin, PinModeString[mode])
+}
+
+// ParsePinMode returns the mode named s, as in PinModeString.
+func ParsePinMode(s string) (mode byte, ok bool) {
+	for m, name := range PinModeString {
+		if name == s {
+			return m, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,93 @@
+package gadget
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReadWriteValue(t *testing.T) {
+	caps := []Capability{{INPUT, 1}, {OUTPUT, 1}, {PWM, 8}}
+	b := newTestBoard(t, nil, map[byte][]Capability{14: {{ANALOG, 10}}}, map[byte][]Capability{3: caps})
+	b.pins[14].analogVal = 512
+
+	if mode, v, err := b.ReadValue(14); err != nil || mode != ANALOG || v != 512 {
+		t.Fatalf("ReadValue(14) = %s, %d, %v, want ANALOG, 512", PinModeString[mode], v, err)
+	}
+	if err := b.WriteValue(3, 2); err == nil {
+		t.Fatalf("Expected error writing 2 to an OUTPUT pin")
+	}
+	if err := b.WriteValue(3, 1); err != nil {
+		t.Fatalf("WriteValue: %s", err)
+	}
+	if mode, v, _ := b.ReadValue(3); mode != OUTPUT || v != 1 {
+		t.Fatalf("ReadValue(3) = %s, %d, want OUTPUT, 1", PinModeString[mode], v)
+	}
+
+	mode, ok := ParsePinMode("PWM")
+	if !ok || mode != PWM {
+		t.Fatalf("ParsePinMode(PWM) = %d, %v", mode, ok)
+	}
+	b.SetPinMode(3, mode)
+	if err := b.WriteValue(3, 200); err != nil {
+		t.Fatalf("WriteValue: %s", err)
+	}
+	if _, v, _ := b.ReadValue(3); v != 200 {
+		t.Fatalf("ReadValue(3) = %d after writing 200", v)
+	}
+	if err := b.WriteValue(14, 1); err == nil {
+		t.Fatalf("Expected error writing an ANALOG pin")
+	}
+}
+
+func TestWatchValues(t *testing.T) {
+	b := newTestBoard(t, nil, map[byte][]Capability{14: {{ANALOG, 10}}}, map[byte][]Capability{3: {{OUTPUT, 1}, {PWM, 8}}})
+	ctx, cancel := context.WithCancel(context.Background())
+	got := make(chan PinValue, 8)
+	done := make(chan error)
+	go func() {
+		done <- b.WatchValues(ctx, []byte{3}, func(v PinValue) error {
+			got <- v
+			return nil
+		})
+	}()
+	next := func() (v PinValue) {
+		select {
+		case v = <-got:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for a value")
+		}
+		return
+	}
+
+	if v := next(); v.Pin != 3 || v.Mode != OUTPUT || v.Value != 0 {
+		t.Fatalf("Expected pin 3's current state first, got %+v", v)
+	}
+	// Unchanged values and other pins are skipped.
+	b.Events().Publish(Event{Topic: PinTopic(3, KindDigital), Pin: 3})
+	b.Events().Publish(Event{Topic: PinTopic(14, KindAnalog), Pin: 14, Value: 7})
+	b.DigitalWrite(3, 1)
+	if v := next(); v.Pin != 3 || v.Value != 1 {
+		t.Fatalf("Expected pin 3 to go to 1, got %+v", v)
+	}
+	b.SetPinMode(3, PWM)
+	if v := next(); v.Pin != 3 || v.Mode != PWM || v.Value != 0 {
+		t.Fatalf("Expected pin 3 in PWM mode, got %+v", v)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("WatchValues returned %s when cancelled", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("Unexpected value %+v", <-got)
+	}
+
+	// Errors from fn end the watch.
+	stop := errors.New("stop")
+	err := b.WatchValues(context.Background(), nil, func(PinValue) error { return stop })
+	if err != stop {
+		t.Fatalf("WatchValues returned %v, want fn's error", err)
+	}
+}
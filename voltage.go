@@ -0,0 +1,60 @@
+package gadget
+
+import "fmt"
+
+// Analog reference voltage of 5V AVR boards.
+const defaultAnalogReference = 5.0
+
+// SetAnalogReference declares the voltage of a full scale analog
+// reading, 5V by default. It must match the board: 3.3 for 3.3V boards
+// such as the Due and Zero, or the voltage on AREF if the sketch calls
+// analogReference(EXTERNAL). Firmata has no message to change the
+// reference, so this only affects the conversions done here.
+func (b *Board) SetAnalogReference(volts float64) error {
+	if volts <= 0 {
+		return fmt.Errorf("Invalid analog reference: %gV", volts)
+	}
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.analogRef = volts
+	return nil
+}
+
+// AnalogReference returns the analog reference voltage.
+func (b *Board) AnalogReference() float64 {
+	b.m.RLock()
+	defer b.m.RUnlock()
+	if b.analogRef <= 0 {
+		return defaultAnalogReference
+	}
+	return b.analogRef
+}
+
+// ReadVoltage returns the last reading of an analog pin in volts, from
+// the analog reference and the pin's resolution.
+func (b *Board) ReadVoltage(pin byte) (v float64, err error) {
+	bits, err := b.AnalogResolution(pin)
+	if err != nil {
+		return 0, err
+	}
+	raw, err := b.AnalogRead(pin)
+	if err != nil {
+		return 0, err
+	}
+	return b.AnalogReference() * float64(raw) / float64(analogMax(bits)), nil
+}
+
+// WriteVoltage sets the pin's analog output to the nearest value to v
+// volts, for DAC outputs, see AnalogOut.
+func (b *Board) WriteVoltage(pin byte, v float64) error {
+	bits, err := b.AnalogOutResolution(pin)
+	if err != nil {
+		return err
+	}
+	ref := b.AnalogReference()
+	if v < 0 || v > ref {
+		return fmt.Errorf("Voltage must be 0-%gV, got %gV", ref, v)
+	}
+	full := analogMax(bits)
+	return b.AnalogOut(pin, int(v/ref*float64(full)+0.5))
+}
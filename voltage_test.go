@@ -0,0 +1,32 @@
+package gadget
+
+import (
+	"math"
+	"testing"
+)
+
+func TestVoltage(t *testing.T) {
+	b := newTestBoard(t, nil, map[byte][]Capability{14: {{ANALOG, 12}}}, map[byte][]Capability{3: {{OUTPUT, 1}, {PWM, 8}}})
+	WithAnalogReference(3.3)(b)
+	b.pins[14].analogVal = 4095
+
+	if v, err := b.ReadVoltage(14); err != nil || v != 3.3 {
+		t.Fatalf("ReadVoltage = %g, %v; want 3.3", v, err)
+	}
+	b.SetAnalogReference(5)
+	b.pins[14].analogVal = 2048
+	if v, _ := b.ReadVoltage(14); math.Abs(v-2.5) > 0.01 {
+		t.Fatalf("ReadVoltage = %g, want 2.5", v)
+	}
+
+	b.SetPinMode(3, PWM)
+	if err := b.WriteVoltage(3, 2.5); err != nil {
+		t.Fatal(err)
+	}
+	if b.pins[3].analogVal != 128 {
+		t.Fatalf("WriteVoltage wrote %d, want 128", b.pins[3].analogVal)
+	}
+	if err := b.WriteVoltage(3, 6); err == nil {
+		t.Fatalf("6V should be rejected")
+	}
+}
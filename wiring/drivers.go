@@ -0,0 +1,291 @@
+package wiring
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/ZachMassia/GoGoGadget"
+)
+
+// Builder creates a driver from its configuration, with its pins
+// already resolved by role.
+type Builder func(b *gadget.Board, d Driver, pins map[string]byte) (interface{}, error)
+
+type builder struct {
+	fn  Builder
+	i2c bool // I2C is enabled before building.
+}
+
+// The driver types and their pin roles and params:
+//
+//	servo             signal; params {"min_pulse", "max_pulse"} in µs
+//	continuous-servo  signal
+//	pan-tilt          pan, tilt
+//	motor             in1, in2, enable
+//	light-sensor      signal
+//	temperature       signal; params {"model": "TMP36" or "LM35"}
+//	pot               signal
+//	joystick          x, y, optional button
+//	output-expander   data, clock, latch; params {"chips"}
+//	max7219           data, clock, cs; params {"devices"}
+//	mcp23017, mcp23008, ads1115, ads1015, bme280, bmp180, hmc5883l,
+//	qmc5883l, ds3231, ds1307
+//	lcd               params {"cols", "rows"}, default 16x2
+var (
+	builders = map[string]builder{
+		"servo":            {fn: buildServo},
+		"continuous-servo": {fn: buildContinuousServo},
+		"pan-tilt":         {fn: buildPanTilt},
+		"motor":            {fn: buildMotor},
+		"light-sensor":     {fn: buildLightSensor},
+		"temperature":      {fn: buildTemperature},
+		"pot":              {fn: buildPot},
+		"joystick":         {fn: buildJoystick},
+		"output-expander":  {fn: buildOutputExpander},
+		"max7219":          {fn: buildMAX7219},
+
+		"mcp23017": {fn: buildMCP23017, i2c: true},
+		"mcp23008": {fn: buildMCP23008, i2c: true},
+		"ads1115":  {fn: buildADS1115, i2c: true},
+		"ads1015":  {fn: buildADS1015, i2c: true},
+		"bme280":   {fn: buildBME280, i2c: true},
+		"bmp180":   {fn: buildBMP180, i2c: true},
+		"hmc5883l": {fn: buildHMC5883L, i2c: true},
+		"qmc5883l": {fn: buildQMC5883L, i2c: true},
+		"ds3231":   {fn: buildDS3231, i2c: true},
+		"ds1307":   {fn: buildDS1307, i2c: true},
+		"lcd":      {fn: buildLCD, i2c: true},
+	}
+	buildersMutex sync.RWMutex
+)
+
+// Register adds a driver type. Set i2c if the driver needs I2C enabled.
+// It must be called before the config using the type is parsed.
+func Register(typ string, i2c bool, fn Builder) {
+	buildersMutex.Lock()
+	defer buildersMutex.Unlock()
+	builders[typ] = builder{fn: fn, i2c: i2c}
+}
+
+// Returns the builder for a driver type.
+func lookup(typ string) (builder, bool) {
+	buildersMutex.RLock()
+	defer buildersMutex.RUnlock()
+	bld, ok := builders[typ]
+	return bld, ok
+}
+
+// Returns the pin with the given role.
+func pin(pins map[string]byte, role string) (byte, error) {
+	n, ok := pins[role]
+	if !ok {
+		return 0, fmt.Errorf("Missing '%s' pin", role)
+	}
+	return n, nil
+}
+
+// Returns the pins with the given roles.
+func pinsFor(pins map[string]byte, roles ...string) (nums []byte, err error) {
+	for _, r := range roles {
+		n, err := pin(pins, r)
+		if err != nil {
+			return nil, err
+		}
+		nums = append(nums, n)
+	}
+	return
+}
+
+// Decodes the driver's params into v, leaving defaults when absent.
+func params(d Driver, v interface{}) error {
+	if len(d.Params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(d.Params, v); err != nil {
+		return fmt.Errorf("Invalid params: %s", err)
+	}
+	return nil
+}
+
+// Returns the configured I2C address, or def.
+func address(d Driver, def byte) byte {
+	if d.Address != 0 {
+		return d.Address
+	}
+	return def
+}
+
+func buildServo(b *gadget.Board, d Driver, pins map[string]byte) (interface{}, error) {
+	p, err := pin(pins, "signal")
+	if err != nil {
+		return nil, err
+	}
+	var cfg struct {
+		MinPulse int `json:"min_pulse"`
+		MaxPulse int `json:"max_pulse"`
+	}
+	if err = params(d, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.MinPulse != 0 || cfg.MaxPulse != 0 {
+		return gadget.NewServoPulse(b, p, cfg.MinPulse, cfg.MaxPulse)
+	}
+	return gadget.NewServo(b, p)
+}
+
+func buildContinuousServo(b *gadget.Board, d Driver, pins map[string]byte) (interface{}, error) {
+	p, err := pin(pins, "signal")
+	if err != nil {
+		return nil, err
+	}
+	return gadget.NewContinuousServo(b, p)
+}
+
+func buildPanTilt(b *gadget.Board, d Driver, pins map[string]byte) (interface{}, error) {
+	p, err := pinsFor(pins, "pan", "tilt")
+	if err != nil {
+		return nil, err
+	}
+	return gadget.NewPanTilt(b, p[0], p[1])
+}
+
+func buildMotor(b *gadget.Board, d Driver, pins map[string]byte) (interface{}, error) {
+	p, err := pinsFor(pins, "in1", "in2", "enable")
+	if err != nil {
+		return nil, err
+	}
+	return gadget.NewMotor(b, p[0], p[1], p[2])
+}
+
+func buildLightSensor(b *gadget.Board, d Driver, pins map[string]byte) (interface{}, error) {
+	p, err := pin(pins, "signal")
+	if err != nil {
+		return nil, err
+	}
+	return gadget.NewLightSensor(b, p)
+}
+
+func buildTemperature(b *gadget.Board, d Driver, pins map[string]byte) (interface{}, error) {
+	p, err := pin(pins, "signal")
+	if err != nil {
+		return nil, err
+	}
+	cfg := struct {
+		Model string `json:"model"`
+	}{"TMP36"}
+	if err = params(d, &cfg); err != nil {
+		return nil, err
+	}
+	switch cfg.Model {
+	case "TMP36":
+		return gadget.NewTempSensor(b, p, gadget.TMP36)
+	case "LM35":
+		return gadget.NewTempSensor(b, p, gadget.LM35)
+	}
+	return nil, fmt.Errorf("Unknown temperature sensor model '%s'", cfg.Model)
+}
+
+func buildPot(b *gadget.Board, d Driver, pins map[string]byte) (interface{}, error) {
+	p, err := pin(pins, "signal")
+	if err != nil {
+		return nil, err
+	}
+	return gadget.NewPot(b, p)
+}
+
+func buildJoystick(b *gadget.Board, d Driver, pins map[string]byte) (interface{}, error) {
+	p, err := pinsFor(pins, "x", "y")
+	if err != nil {
+		return nil, err
+	}
+	j, err := gadget.NewJoystick(b, p[0], p[1])
+	if err != nil {
+		return nil, err
+	}
+	if button, ok := pins["button"]; ok {
+		if err = j.AttachButton(button); err != nil {
+			return nil, err
+		}
+	}
+	return j, nil
+}
+
+func buildOutputExpander(b *gadget.Board, d Driver, pins map[string]byte) (interface{}, error) {
+	p, err := pinsFor(pins, "data", "clock", "latch")
+	if err != nil {
+		return nil, err
+	}
+	cfg := struct {
+		Chips int `json:"chips"`
+	}{1}
+	if err = params(d, &cfg); err != nil {
+		return nil, err
+	}
+	return gadget.NewOutputExpander(b, p[0], p[1], p[2], cfg.Chips)
+}
+
+func buildMAX7219(b *gadget.Board, d Driver, pins map[string]byte) (interface{}, error) {
+	p, err := pinsFor(pins, "data", "clock", "cs")
+	if err != nil {
+		return nil, err
+	}
+	cfg := struct {
+		Devices int `json:"devices"`
+	}{1}
+	if err = params(d, &cfg); err != nil {
+		return nil, err
+	}
+	return gadget.NewMAX7219(b, p[0], p[1], p[2], cfg.Devices)
+}
+
+func buildMCP23017(b *gadget.Board, d Driver, pins map[string]byte) (interface{}, error) {
+	return gadget.NewMCP23017(b, address(d, 0x20))
+}
+
+func buildMCP23008(b *gadget.Board, d Driver, pins map[string]byte) (interface{}, error) {
+	return gadget.NewMCP23008(b, address(d, 0x20))
+}
+
+func buildADS1115(b *gadget.Board, d Driver, pins map[string]byte) (interface{}, error) {
+	return gadget.NewADS1115(b, address(d, 0x48)), nil
+}
+
+func buildADS1015(b *gadget.Board, d Driver, pins map[string]byte) (interface{}, error) {
+	return gadget.NewADS1015(b, address(d, 0x48)), nil
+}
+
+func buildBME280(b *gadget.Board, d Driver, pins map[string]byte) (interface{}, error) {
+	return gadget.NewBME280(b, address(d, 0x76))
+}
+
+func buildBMP180(b *gadget.Board, d Driver, pins map[string]byte) (interface{}, error) {
+	return gadget.NewBMP180(b)
+}
+
+func buildHMC5883L(b *gadget.Board, d Driver, pins map[string]byte) (interface{}, error) {
+	return gadget.NewHMC5883L(b)
+}
+
+func buildQMC5883L(b *gadget.Board, d Driver, pins map[string]byte) (interface{}, error) {
+	return gadget.NewQMC5883L(b)
+}
+
+func buildDS3231(b *gadget.Board, d Driver, pins map[string]byte) (interface{}, error) {
+	return gadget.NewDS3231(b), nil
+}
+
+func buildDS1307(b *gadget.Board, d Driver, pins map[string]byte) (interface{}, error) {
+	return gadget.NewDS1307(b), nil
+}
+
+func buildLCD(b *gadget.Board, d Driver, pins map[string]byte) (interface{}, error) {
+	cfg := struct {
+		Cols int `json:"cols"`
+		Rows int `json:"rows"`
+	}{16, 2}
+	if err := params(d, &cfg); err != nil {
+		return nil, err
+	}
+	return gadget.NewLCD(b, address(d, 0x27), cfg.Cols, cfg.Rows)
+}
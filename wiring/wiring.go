@@ -0,0 +1,275 @@
+// Package wiring applies a hardware layout described in a JSON file to
+// a gadget.Board, so pin modes, names and attached drivers can live in
+// configuration rather than code:
+//
+//	{
+//	  "device": "/dev/ttyACM0",
+//	  "pins": [
+//	    {"pin": 13, "alias": "led", "mode": "OUTPUT", "value": 0},
+//	    {"pin": "A0", "alias": "knob", "mode": "ANALOG", "report": true}
+//	  ],
+//	  "drivers": [
+//	    {"name": "pan", "type": "servo", "pins": {"signal": 9}},
+//	    {"name": "climate", "type": "bme280", "address": 118}
+//	  ]
+//	}
+//
+// Pins may be given by number, by A0 style analog number or by an alias
+// defined in the pins section. See Register for the driver types.
+package wiring
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ZachMassia/GoGoGadget"
+)
+
+// Config is a hardware layout.
+type Config struct {
+	// The serial device, used by Open. Found with gadget.FindSerial
+	// when empty.
+	Device string `json:"device,omitempty"`
+
+	Pins    []Pin    `json:"pins,omitempty"`
+	Drivers []Driver `json:"drivers,omitempty"`
+}
+
+// Pin configures a single pin.
+type Pin struct {
+	Pin    PinRef `json:"pin"`
+	Alias  string `json:"alias,omitempty"`
+	Mode   string `json:"mode,omitempty"`   // e.g. "OUTPUT" or "PWM".
+	Report bool   `json:"report,omitempty"` // Enable input reporting.
+	Value  *int   `json:"value,omitempty"`  // Initial output value.
+}
+
+// Driver attaches a driver to the board.
+type Driver struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+
+	// The driver's pins by role, e.g. {"x": "A0", "y": "A1"}.
+	Pins map[string]PinRef `json:"pins,omitempty"`
+
+	// The 7-bit address of I2C devices. Defaults to the device's usual
+	// address where it has one.
+	Address byte `json:"address,omitempty"`
+
+	// Type specific settings.
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// PinRef is a pin number, an A0 style analog pin such as "A3", or an
+// alias. In JSON it is a number or a string.
+type PinRef string
+
+func (r *PinRef) UnmarshalJSON(b []byte) error {
+	var n uint8
+	if err := json.Unmarshal(b, &n); err == nil {
+		*r = PinRef(strconv.Itoa(int(n)))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("Pin must be a number or a name, got %s", b)
+	}
+	*r = PinRef(s)
+	return nil
+}
+
+// Setup is a board with a layout applied.
+type Setup struct {
+	Board *gadget.Board
+
+	// Pin numbers by alias.
+	Aliases map[string]byte
+
+	// The created drivers by name, e.g. a *gadget.Servo.
+	Drivers map[string]interface{}
+}
+
+// Pin returns the number of a pin given by number, analog number or
+// alias.
+func (s *Setup) Pin(ref string) (byte, error) {
+	return resolve(PinRef(ref), s.Aliases, s.Board.AnalogMapping())
+}
+
+// Load reads a Config from a JSON file.
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads a Config in JSON.
+func Parse(r io.Reader) (c *Config, err error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	c = &Config{}
+	if err = dec.Decode(c); err != nil {
+		return nil, fmt.Errorf("Invalid wiring config: %s", err)
+	}
+
+	names := make(map[string]bool)
+	for _, d := range c.Drivers {
+		if d.Name == "" {
+			return nil, fmt.Errorf("Driver of type '%s' has no name", d.Type)
+		}
+		if names[d.Name] {
+			return nil, fmt.Errorf("Duplicate driver name '%s'", d.Name)
+		}
+		names[d.Name] = true
+		if _, ok := lookup(d.Type); !ok {
+			return nil, fmt.Errorf("Driver '%s' has unknown type '%s'", d.Name, d.Type)
+		}
+	}
+	return c, nil
+}
+
+// Open connects to the configured device and applies the layout.
+func (c *Config) Open() (*Setup, error) {
+	device := c.Device
+	if device == "" {
+		found := gadget.FindSerial()
+		if len(found) == 0 {
+			return nil, fmt.Errorf("No board found")
+		}
+		device = found[0]
+	}
+
+	b, err := gadget.New(device)
+	if err != nil {
+		return nil, err
+	}
+	s, err := c.Apply(b)
+	if err != nil {
+		b.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Apply configures the board's pins, then creates the drivers and adds
+// them to the board with AddDriver, which starts them and halts them on
+// Close.
+func (c *Config) Apply(b *gadget.Board) (s *Setup, err error) {
+	s = &Setup{
+		Board:   b,
+		Aliases: make(map[string]byte),
+		Drivers: make(map[string]interface{}),
+	}
+	analog := b.AnalogMapping()
+
+	for _, p := range c.Pins {
+		n, err := resolve(p.Pin, nil, analog)
+		if err != nil {
+			return nil, err
+		}
+		if p.Alias != "" {
+			s.Aliases[p.Alias] = n
+		}
+
+		if err = configurePin(b, n, p); err != nil {
+			return nil, fmt.Errorf("Pin %s: %s", p.Pin, err)
+		}
+		if p.Report {
+			if err = b.SetPinReporting(n, true); err != nil {
+				return nil, fmt.Errorf("Pin %s: %s", p.Pin, err)
+			}
+		}
+	}
+
+	i2cEnabled := false
+	for _, d := range c.Drivers {
+		bld, ok := lookup(d.Type)
+		if !ok {
+			return nil, fmt.Errorf("Driver '%s' has unknown type '%s'", d.Name, d.Type)
+		}
+		if bld.i2c && !i2cEnabled {
+			if err = b.I2CConfig(0); err != nil {
+				return nil, err
+			}
+			i2cEnabled = true
+		}
+
+		pins := make(map[string]byte)
+		for role, ref := range d.Pins {
+			if pins[role], err = resolve(ref, s.Aliases, analog); err != nil {
+				return nil, fmt.Errorf("Driver '%s': %s", d.Name, err)
+			}
+		}
+		v, err := bld.fn(b, d, pins)
+		if err != nil {
+			return nil, fmt.Errorf("Driver '%s': %s", d.Name, err)
+		}
+		if err = b.AddDriver(asDriver(d.Name, v, pins)); err != nil {
+			return nil, err
+		}
+		s.Drivers[d.Name] = v
+	}
+	return s, nil
+}
+
+// Returns the device as a driver for AddDriver, so it is halted with
+// the board. Devices that do not declare their Requirements claim their
+// configured pins.
+func asDriver(name string, v interface{}, pins map[string]byte) gadget.Driver {
+	d := gadget.AsDriver(name, v)
+	if _, ok := v.(gadget.Requirer); ok {
+		return d
+	}
+	var req gadget.Requirements
+	for _, n := range pins {
+		req.Pins = append(req.Pins, n)
+	}
+	sort.Slice(req.Pins, func(i, j int) bool { return req.Pins[i] < req.Pins[j] })
+	return gadget.Requiring(d, req)
+}
+
+// Sets a pin's mode, then its value, as given.
+func configurePin(b *gadget.Board, n byte, p Pin) error {
+	if p.Mode != "" {
+		mode, ok := gadget.ParsePinMode(strings.ToUpper(p.Mode))
+		if !ok {
+			return fmt.Errorf("Unknown pin mode '%s'", p.Mode)
+		}
+		if cur, _ := b.PinMode(n); cur != mode {
+			if err := b.SetPinMode(n, mode); err != nil {
+				return err
+			}
+		}
+	}
+	if p.Value == nil {
+		return nil
+	}
+	return b.WriteValue(n, *p.Value)
+}
+
+// Resolves a pin reference against the aliases and analog mapping.
+func resolve(ref PinRef, aliases map[string]byte, analog []byte) (byte, error) {
+	s := string(ref)
+	if n, ok := aliases[s]; ok {
+		return n, nil
+	}
+	if n, err := strconv.ParseUint(s, 10, 8); err == nil {
+		return byte(n), nil
+	}
+	if a, ok := strings.CutPrefix(strings.ToUpper(s), "A"); ok {
+		if n, err := strconv.Atoi(a); err == nil {
+			if n < 0 || n >= len(analog) {
+				return 0, fmt.Errorf("Invalid analog pin: %s", s)
+			}
+			return analog[n], nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown pin '%s'", s)
+}
@@ -0,0 +1,70 @@
+package wiring
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ZachMassia/GoGoGadget"
+)
+
+const example = `{
+  "pins": [
+    {"pin": 13, "alias": "led", "mode": "OUTPUT", "value": 0},
+    {"pin": "A0", "alias": "knob", "mode": "ANALOG", "report": true}
+  ],
+  "drivers": [
+    {"name": "pan", "type": "servo", "pins": {"signal": 9}, "params": {"min_pulse": 600, "max_pulse": 2500}},
+    {"name": "stick", "type": "joystick", "pins": {"x": "knob", "y": "A1"}}
+  ]
+}`
+
+func TestParse(t *testing.T) {
+	c, err := Parse(strings.NewReader(example))
+	if err != nil {
+		t.Fatalf("Parse returned error: %s", err)
+	}
+	if len(c.Pins) != 2 || c.Pins[0].Pin != "13" || c.Pins[1].Pin != "A0" || *c.Pins[0].Value != 0 {
+		t.Fatalf("Unexpected pins: %+v", c.Pins)
+	}
+	if len(c.Drivers) != 2 || c.Drivers[1].Pins["x"] != "knob" {
+		t.Fatalf("Unexpected drivers: %+v", c.Drivers)
+	}
+
+	bad := []string{
+		`{"drivers": [{"name": "x", "type": "flux-capacitor"}]}`,
+		`{"drivers": [{"type": "servo"}]}`,
+		`{"drivers": [{"name": "x", "type": "pot"}, {"name": "x", "type": "pot"}]}`,
+		`{"pins": [{"pin": true}]}`,
+		`{"pinz": []}`,
+	}
+	for _, in := range bad {
+		if _, err := Parse(strings.NewReader(in)); err == nil {
+			t.Errorf("Parse(%s) should fail", in)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	aliases := map[string]byte{"knob": 14}
+	analog := []byte{14, 15, 16}
+
+	tests := map[PinRef]byte{"13": 13, "knob": 14, "A1": 15, "a2": 16}
+	for ref, want := range tests {
+		if got, err := resolve(ref, aliases, analog); err != nil || got != want {
+			t.Errorf("resolve(%s) = %d, %v; want %d", ref, got, err, want)
+		}
+	}
+	for _, ref := range []PinRef{"A3", "lamp", "300"} {
+		if _, err := resolve(ref, aliases, analog); err == nil {
+			t.Errorf("resolve(%s) should fail", ref)
+		}
+	}
+}
+
+func TestApplyUnknownType(t *testing.T) {
+	// Configs built in code skip Parse's checks.
+	c := &Config{Drivers: []Driver{{Name: "x", Type: "flux-capacitor"}}}
+	if _, err := c.Apply(&gadget.Board{}); err == nil {
+		t.Fatalf("Apply should fail for an unknown driver type")
+	}
+}
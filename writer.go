@@ -0,0 +1,244 @@
+package gadget
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// Pending output is flushed early once it reaches this size, so a
+	// long burst of writes cannot grow the buffer without bound.
+	maxBatchSize = 1024
+
+	// How many writes may wait for the serial port.
+	writeQueueSize = 256
+)
+
+// WritePolicy is what happens to a message sent while the write queue
+// is full, which happens when messages are sent faster than the serial
+// port can carry them.
+type WritePolicy byte
+
+const (
+	WriteBlock WritePolicy = iota // Wait for space in the queue (default).
+	WriteFail                     // Return ErrWriteQueueFull.
+)
+
+// ErrWriteQueueFull is returned by writes to the board when the write
+// queue is full and the WriteFail policy is set.
+var ErrWriteQueueFull = errors.New("Write queue full")
+
+// A queueWriter serializes every write to the serial port through one
+// goroutine. Errors are passed to onError since the caller has moved on
+// by the time they happen.
+type queueWriter struct {
+	w       io.Writer
+	onError func(error)
+
+	m      sync.RWMutex // Guards policy and closed against Close.
+	policy WritePolicy
+	closed bool
+
+	queue chan []byte
+	done  chan bool
+}
+
+func newQueueWriter(w io.Writer, onError func(error)) *queueWriter {
+	q := &queueWriter{
+		w:       w,
+		onError: onError,
+		queue:   make(chan []byte, writeQueueSize),
+		done:    make(chan bool),
+	}
+	go q.run()
+	return q
+}
+
+func (q *queueWriter) run() {
+	defer close(q.done)
+	for p := range q.queue {
+		if _, err := q.w.Write(p); err != nil && q.onError != nil {
+			q.onError(fmt.Errorf("Error writing to serial: %s", err))
+		}
+	}
+}
+
+// Write queues a copy of p according to the policy.
+func (q *queueWriter) Write(p []byte) (n int, err error) {
+	q.m.RLock()
+	defer q.m.RUnlock()
+
+	if q.closed {
+		return 0, io.ErrClosedPipe
+	}
+	p = append([]byte(nil), p...)
+	if q.policy == WriteFail {
+		select {
+		case q.queue <- p:
+		default:
+			return 0, ErrWriteQueueFull
+		}
+	} else {
+		q.queue <- p
+	}
+	return len(p), nil
+}
+
+func (q *queueWriter) SetPolicy(p WritePolicy) {
+	q.m.Lock()
+	defer q.m.Unlock()
+	q.policy = p
+}
+
+// Close waits for the queued writes to finish. Later writes fail.
+func (q *queueWriter) Close() {
+	q.m.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.queue)
+	}
+	q.m.Unlock()
+	<-q.done
+}
+
+// A batchWriter coalesces the small messages sent to the board into
+// fewer writes to the serial port. With a zero delay every message is
+// written straight through.
+type batchWriter struct {
+	w io.Writer
+
+	m     sync.Mutex
+	buf   []byte
+	delay time.Duration
+	timer *time.Timer
+	err   error // From a timed flush, returned by the next call.
+	held  int   // Writes are only buffered while above zero, see hold.
+}
+
+func newBatchWriter(w io.Writer) *batchWriter {
+	return &batchWriter{w: w}
+}
+
+// Write queues p to be flushed after the delay. The returned error is
+// from an earlier flush, if it failed.
+func (bw *batchWriter) Write(p []byte) (n int, err error) {
+	bw.m.Lock()
+	defer bw.m.Unlock()
+
+	if bw.err != nil {
+		err, bw.err = bw.err, nil
+		return 0, err
+	}
+	if bw.delay == 0 && len(bw.buf) == 0 && bw.held == 0 {
+		return bw.w.Write(p)
+	}
+
+	bw.buf = append(bw.buf, p...)
+	if bw.held > 0 {
+		return len(p), nil
+	}
+	if len(bw.buf) >= maxBatchSize || bw.delay == 0 {
+		return len(p), bw.flush()
+	}
+	if bw.timer == nil {
+		bw.timer = time.AfterFunc(bw.delay, bw.timedFlush)
+	}
+	return len(p), nil
+}
+
+// Flush writes any pending messages now, unless held.
+func (bw *batchWriter) Flush() (err error) {
+	bw.m.Lock()
+	defer bw.m.Unlock()
+
+	if bw.err != nil {
+		err, bw.err = bw.err, nil
+		return
+	}
+	if bw.held > 0 {
+		return nil
+	}
+	return bw.flush()
+}
+
+// hold buffers every write, regardless of the delay and size, until
+// the matching release, which flushes them as one write.
+func (bw *batchWriter) hold() {
+	bw.m.Lock()
+	defer bw.m.Unlock()
+	bw.held++
+}
+
+func (bw *batchWriter) release() error {
+	bw.m.Lock()
+	defer bw.m.Unlock()
+
+	if bw.held--; bw.held > 0 {
+		return nil
+	}
+	return bw.flush()
+}
+
+// SetDelay sets how long writes are held before being flushed. Pending
+// writes are flushed first.
+func (bw *batchWriter) SetDelay(d time.Duration) error {
+	bw.m.Lock()
+	defer bw.m.Unlock()
+
+	bw.delay = d
+	if bw.held > 0 {
+		return nil
+	}
+	return bw.flush()
+}
+
+func (bw *batchWriter) timedFlush() {
+	bw.m.Lock()
+	defer bw.m.Unlock()
+
+	if bw.held > 0 {
+		bw.timer = nil // Flushed by release.
+		return
+	}
+	if err := bw.flush(); err != nil {
+		bw.err = err
+	}
+}
+
+// Must be called with bw.m held.
+func (bw *batchWriter) flush() (err error) {
+	if bw.timer != nil {
+		bw.timer.Stop()
+		bw.timer = nil
+	}
+	if len(bw.buf) == 0 {
+		return nil
+	}
+	_, err = bw.w.Write(bw.buf)
+	bw.buf = bw.buf[:0]
+	return
+}
+
+// SetWriteDelay enables batching of outgoing messages. Messages sent
+// within d of each other are written to the serial port together,
+// saving a system call per message when driving many outputs, at the
+// cost of up to d of extra latency. A zero delay, the default, writes
+// every message immediately.
+func (b *Board) SetWriteDelay(d time.Duration) error {
+	return b.out.SetDelay(d)
+}
+
+// SetWritePolicy sets what happens when messages are sent faster than
+// the serial port can carry them. See WritePolicy.
+func (b *Board) SetWritePolicy(p WritePolicy) {
+	b.queue.SetPolicy(p)
+}
+
+// Flush writes any messages held back by SetWriteDelay now, e.g. at the
+// end of an animation frame.
+func (b *Board) Flush() error {
+	return b.out.Flush()
+}
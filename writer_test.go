@@ -0,0 +1,102 @@
+package gadget
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Records each Write separately.
+type writeLog struct {
+	m      sync.Mutex
+	writes [][]byte
+}
+
+func (l *writeLog) Write(p []byte) (int, error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	l.writes = append(l.writes, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func (l *writeLog) count() int {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return len(l.writes)
+}
+
+func TestBatchWriter(t *testing.T) {
+	var l writeLog
+	bw := newBatchWriter(&l)
+
+	// Without a delay every message is written through.
+	bw.Write([]byte{1})
+	bw.Write([]byte{2})
+	if l.count() != 2 {
+		t.Fatalf("Expected 2 writes, got %d", l.count())
+	}
+
+	bw.SetDelay(time.Hour)
+	bw.Write([]byte{3})
+	bw.Write([]byte{4, 5})
+	if l.count() != 2 {
+		t.Fatalf("Writes should be held, got %d", l.count())
+	}
+	if err := bw.Flush(); err != nil || l.count() != 3 || !bytes.Equal(l.writes[2], []byte{3, 4, 5}) {
+		t.Fatalf("Flush wrote %v, %v", l.writes, err)
+	}
+
+	// A full buffer is flushed without waiting.
+	bw.Write(make([]byte, maxBatchSize))
+	if l.count() != 4 {
+		t.Fatalf("Full buffer should be flushed, got %d writes", l.count())
+	}
+
+	bw.SetDelay(time.Millisecond)
+	bw.Write([]byte{6})
+	bw.Write([]byte{7})
+	deadline := time.Now().Add(time.Second)
+	for l.count() != 5 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if l.count() != 5 || !bytes.Equal(l.writes[4], []byte{6, 7}) {
+		t.Fatalf("Timer should flush both messages together, got %v", l.writes[4:])
+	}
+}
+
+// Blocks every Write until released.
+type stuckWriter chan bool
+
+func (s stuckWriter) Write(p []byte) (int, error) {
+	<-s
+	return len(p), nil
+}
+
+func TestQueueWriter(t *testing.T) {
+	var l writeLog
+	q := newQueueWriter(&l, nil)
+	for i := byte(0); i < 10; i++ {
+		q.Write([]byte{i})
+	}
+	q.Close()
+	if l.count() != 10 || l.writes[9][0] != 9 {
+		t.Fatalf("Close should drain the queue in order, got %v", l.writes)
+	}
+	if _, err := q.Write([]byte{1}); err == nil {
+		t.Fatalf("Write after Close should fail")
+	}
+
+	stuck := make(stuckWriter)
+	q = newQueueWriter(stuck, nil)
+	q.SetPolicy(WriteFail)
+	var err error
+	for i := 0; i < writeQueueSize+2 && err == nil; i++ {
+		_, err = q.Write([]byte{0})
+	}
+	if err != ErrWriteQueueFull {
+		t.Fatalf("Expected ErrWriteQueueFull, got %v", err)
+	}
+	close(stuck)
+	q.Close()
+}